@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/interest"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+)
+
+const accrueInterestUsage = "usage: accrue-interest [-through YYYY-MM-DD]"
+
+//runAccrueInterest implements the accrue-interest subcommand for running the accrual from cron,
+//every day up to the given one (yesterday by default) that was not accrued yet is accrued
+func runAccrueInterest(config util.Config, args []string) error {
+	flags := flag.NewFlagSet("accrue-interest", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	through := flags.String("through", "", "last day to accrue, in UTC")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errors.New(accrueInterestUsage)
+	}
+	if config.DB.Driver == db.MemoryDriver {
+		return errors.New("the in-memory store has no accounts to accrue")
+	}
+
+	day := time.Now().UTC().AddDate(0, 0, -1)
+	if *through != "" {
+		var err error
+		day, err = time.Parse("2006-01-02", *through)
+		if err != nil {
+			return errors.New(accrueInterestUsage)
+		}
+	}
+	//today's end of day balance is not known yet
+	if !db.InterestDay(day).Before(db.InterestDay(time.Now())) {
+		return fmt.Errorf("cannot accrue %s, only days that are over", day.Format("2006-01-02"))
+	}
+
+	store, err := newStore(config)
+	if err != nil {
+		return err
+	}
+
+	report, err := interest.NewAccruer(store).AccrueThrough(context.Background(), day)
+	if err != nil {
+		return err
+	}
+
+	out := json.NewEncoder(os.Stdout)
+	out.SetIndent("", "  ")
+	if err := out.Encode(report); err != nil {
+		return err
+	}
+
+	if len(report.Failures) > 0 {
+		return fmt.Errorf("%d accounts could not be accrued", len(report.Failures))
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kingsleyocran/simple_bank_bankend/admin"
+	"github.com/kingsleyocran/simple_bank_bankend/api"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/mail"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+)
+
+const adminUsage = "usage: admin create-user|create-account|freeze|unfreeze|adjust|reconcile|history [flags]"
+
+//runAdmin implements the admin subcommand. Every command goes through the store with the rules of the API
+//and is recorded in the audit trail under cli:$USER, the result is printed as JSON on stdout.
+func runAdmin(config util.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(adminUsage)
+	}
+	command := args[0]
+
+	flags := flag.NewFlagSet("admin "+command, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	var run func(ctx context.Context, admin *admin.Admin) (interface{}, error)
+	switch command {
+	case "create-user":
+		var req admin.CreateUserRequest
+		flags.StringVar(&req.Username, "username", "", "username of the new user")
+		flags.StringVar(&req.Password, "password", "", "initial password")
+		flags.StringVar(&req.FullName, "full-name", "", "full name of the user")
+		flags.StringVar(&req.Email, "email", "", "email address, a verification email is sent to it")
+		flags.StringVar(&req.Role, "role", util.DepositorRole, "depositor, banker or admin")
+		run = func(ctx context.Context, admin *admin.Admin) (interface{}, error) {
+			return admin.CreateUser(ctx, req)
+		}
+
+	case "create-account":
+		var req admin.CreateAccountRequest
+		flags.StringVar(&req.OwnerName, "owner", "", "username of the owner")
+		flags.StringVar(&req.Currency, "currency", "", "currency of the account")
+		run = func(ctx context.Context, admin *admin.Admin) (interface{}, error) {
+			return admin.CreateAccount(ctx, req)
+		}
+
+	case "freeze", "unfreeze":
+		req := admin.AccountStatusRequest{Status: db.AccountStatusFrozen}
+		if command == "unfreeze" {
+			req.Status = db.AccountStatusActive
+		}
+		flags.Int64Var(&req.AccountID, "account", 0, "id of the account")
+		flags.StringVar(&req.Reason, "reason", "", "why, recorded in the audit trail")
+		run = func(ctx context.Context, admin *admin.Admin) (interface{}, error) {
+			return admin.SetAccountStatus(ctx, req)
+		}
+
+	case "adjust":
+		var req admin.AdjustRequest
+		flags.Int64Var(&req.AccountID, "account", 0, "id of the account to correct")
+		flags.Int64Var(&req.AgainstAccountID, "against", 0, "id of the bank account the amount is taken from or given to")
+		flags.Int64Var(&req.Amount, "amount", 0, "amount added to the account, negative to take it away")
+		flags.StringVar(&req.Reason, "reason", "", "why, recorded in the audit trail")
+		run = func(ctx context.Context, admin *admin.Admin) (interface{}, error) {
+			return admin.Adjust(ctx, req)
+		}
+
+	case "reconcile":
+		run = func(ctx context.Context, admin *admin.Admin) (interface{}, error) {
+			return admin.Reconcile(ctx)
+		}
+
+	case "history":
+		var req admin.HistoryRequest
+		flags.Int64Var(&req.AccountID, "account", 0, "id of the account")
+		page := flags.Int("page", 1, "page number")
+		pageSize := flags.Int("page-size", 50, "entries and events per page")
+		run = func(ctx context.Context, admin *admin.Admin) (interface{}, error) {
+			req.PageID = int32(*page)
+			req.PageSize = int32(*pageSize)
+			return admin.History(ctx, req)
+		}
+
+	default:
+		return errors.New(adminUsage)
+	}
+
+	if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 0 {
+		return errors.New(adminUsage)
+	}
+	if config.DB.Driver == db.MemoryDriver {
+		return errors.New("the in-memory store would lose the changes on exit")
+	}
+
+	store, err := newStore(config)
+	if err != nil {
+		return err
+	}
+
+	mailer, err := mail.NewMailer(config)
+	if err != nil {
+		return err
+	}
+
+	actor := "cli"
+	if user := os.Getenv("USER"); user != "" {
+		actor = "cli:" + user
+	}
+
+	result, err := run(context.Background(), admin.New(store, admin.Options{
+		Actor:               actor,
+		VerifyEmailDuration: config.Auth.VerifyEmailDuration,
+		AfterCreateUser:     api.VerifyEmailSender(config, mailer),
+	}))
+	if err != nil {
+		return err
+	}
+
+	out := json.NewEncoder(os.Stdout)
+	out.SetIndent("", "  ")
+	if err := out.Encode(result); err != nil {
+		return err
+	}
+
+	if report, ok := result.(admin.Reconciliation); ok && len(report.Mismatches) > 0 {
+		return fmt.Errorf("%d accounts don't match their entries", len(report.Mismatches))
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+)
+
+// Options control who the operations are recorded for and how new users are welcomed
+type Options struct {
+	// Actor is recorded in the audit trail, e.g. cli:alice
+	Actor               string
+	VerifyEmailDuration time.Duration
+	// AfterCreateUser is called once a new user is committed, e.g. to send the verification email
+	AfterCreateUser func(user db.User, verifyEmail db.VerifyEmail) error
+}
+
+// Admin runs the operations of the admin subcommand on the store. Requests are checked with the
+// binding rules of the matching API requests and every change is recorded in the audit trail.
+type Admin struct {
+	store    db.Store
+	validate *validator.Validate
+	opts     Options
+}
+
+// New creates an admin working on store
+func New(store db.Store, opts Options) *Admin {
+	validate := validator.New()
+	validate.SetTagName("binding")
+	util.RegisterValidators(validate)
+
+	//report the command line flag instead of the Go field name
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("flag")
+	})
+
+	return &Admin{
+		store:    store,
+		validate: validate,
+		opts:     opts,
+	}
+}
+
+// CreateUserRequest carries the rules of the API sign up, plus the role only an admin can give
+type CreateUserRequest struct {
+	Username string `flag:"username" binding:"required,alphanum"`
+	Password string `flag:"password" binding:"required,min=6"`
+	FullName string `flag:"full-name" binding:"required"`
+	Email    string `flag:"email" binding:"required,email"`
+	Role     string `flag:"role" binding:"required,role"`
+}
+
+// CreateUser signs a user up like the API does, including the verification email, and then gives it its role
+func (admin *Admin) CreateUser(ctx context.Context, req CreateUserRequest) (db.User, error) {
+	if err := admin.validate.Struct(req); err != nil {
+		return db.User{}, err
+	}
+
+	hashedPassword, err := util.HashPassword(req.Password)
+	if err != nil {
+		return db.User{}, err
+	}
+	secretCode, err := util.GenerateSecret(32)
+	if err != nil {
+		return db.User{}, err
+	}
+
+	result, err := admin.store.CreateUserTx(ctx, db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       req.Username,
+			HashedPassword: hashedPassword,
+			FullName:       req.FullName,
+			Email:          req.Email,
+		},
+		VerifyEmail: db.CreateVerifyEmailParams{
+			SecretCode: secretCode,
+			ExpiredAt:  time.Now().Add(admin.opts.VerifyEmailDuration),
+		},
+	})
+	if err != nil {
+		return db.User{}, err
+	}
+	user := result.User
+
+	//every user starts as a depositor, exactly like PUT /admin/users/:username/role afterwards
+	if req.Role != user.Role {
+		user, err = admin.store.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+			Username: user.Username,
+			Role:     req.Role,
+		})
+		if err != nil {
+			return user, err
+		}
+	}
+
+	err = admin.audit(ctx, db.AuditActionCreateUser, 0, map[string]string{
+		"username": user.Username,
+		"role":     user.Role,
+	})
+	if err != nil || admin.opts.AfterCreateUser == nil {
+		return user, err
+	}
+
+	if err := admin.opts.AfterCreateUser(user, result.VerifyEmail); err != nil {
+		return user, fmt.Errorf("user %s was created but: %w", user.Username, err)
+	}
+	return user, nil
+}
+
+// CreateAccountRequest carries the rules of the API request staff use to open an account for a user
+type CreateAccountRequest struct {
+	OwnerName string `flag:"owner" binding:"required,alphanum"`
+	Currency  string `flag:"currency" binding:"required,currency"`
+}
+
+// CreateAccount opens an account with a zero balance for the owner
+func (admin *Admin) CreateAccount(ctx context.Context, req CreateAccountRequest) (db.Account, error) {
+	if err := admin.validate.Struct(req); err != nil {
+		return db.Account{}, err
+	}
+
+	account, err := admin.store.CreateAccount(ctx, db.CreateAccountParams{
+		OwnerName: req.OwnerName,
+		Currency:  req.Currency,
+		Balance:   0,
+	})
+	if err != nil {
+		return account, err
+	}
+
+	err = admin.audit(ctx, db.AuditActionCreateAccount, account.ID, map[string]string{
+		"owner_name": account.OwnerName,
+		"currency":   account.Currency,
+	})
+	return account, err
+}
+
+// AccountStatusRequest freezes or unfreezes an account
+type AccountStatusRequest struct {
+	AccountID int64  `flag:"account" binding:"required,min=1"`
+	Status    string `flag:"status" binding:"required,oneof=active frozen"`
+	Reason    string `flag:"reason" binding:"max=255"`
+}
+
+// SetAccountStatus freezes or unfreezes an account, the same as the API endpoints
+func (admin *Admin) SetAccountStatus(ctx context.Context, req AccountStatusRequest) (db.Account, error) {
+	if err := admin.validate.Struct(req); err != nil {
+		return db.Account{}, err
+	}
+
+	result, err := admin.store.UpdateAccountStatusTx(ctx, db.UpdateAccountStatusTxParams{
+		ID:     req.AccountID,
+		Status: req.Status,
+		Actor:  admin.opts.Actor,
+		Reason: req.Reason,
+	})
+	return result.Account, err
+}
+
+// AdjustRequest posts a manual correction, a reason is always required
+type AdjustRequest struct {
+	AccountID        int64  `flag:"account" binding:"required,min=1"`
+	AgainstAccountID int64  `flag:"against" binding:"required,min=1,nefield=AccountID"`
+	Amount           int64  `flag:"amount" binding:"required"`
+	Reason           string `flag:"reason" binding:"required,max=255"`
+}
+
+// Adjust adds Amount to the account, taking it from the against account, or the other way round when negative
+func (admin *Admin) Adjust(ctx context.Context, req AdjustRequest) (db.AdjustBalanceTxResult, error) {
+	if err := admin.validate.Struct(req); err != nil {
+		return db.AdjustBalanceTxResult{}, err
+	}
+
+	return admin.store.AdjustBalanceTx(ctx, db.AdjustBalanceTxParams{
+		AccountID:        req.AccountID,
+		AgainstAccountID: req.AgainstAccountID,
+		Amount:           req.Amount,
+		Actor:            admin.opts.Actor,
+		Reason:           req.Reason,
+	})
+}
+
+// Reconciliation lists the accounts whose balance is not the sum of their entries
+type Reconciliation struct {
+	CheckedAt  time.Time                        `json:"checked_at"`
+	Mismatches []db.ListUnreconciledAccountsRow `json:"mismatches"`
+}
+
+// Reconcile checks every account balance against its entries
+func (admin *Admin) Reconcile(ctx context.Context) (Reconciliation, error) {
+	report := Reconciliation{CheckedAt: time.Now().UTC()}
+
+	var err error
+	report.Mismatches, err = admin.store.ListUnreconciledAccounts(ctx)
+	return report, err
+}
+
+// HistoryRequest pages through the history of an account, oldest first
+type HistoryRequest struct {
+	AccountID int64 `flag:"account" binding:"required,min=1"`
+	PageID    int32 `flag:"page" binding:"required,min=1"`
+	PageSize  int32 `flag:"page-size" binding:"required,min=1,max=1000"`
+}
+
+// History is a page of the entries of an account and of what staff did to it
+type History struct {
+	Account db.Account      `json:"account"`
+	Entries []db.Entry      `json:"entries"`
+	Events  []db.AuditEvent `json:"events"`
+}
+
+// History returns the account with a page of its entries and audit events
+func (admin *Admin) History(ctx context.Context, req HistoryRequest) (History, error) {
+	var history History
+	if err := admin.validate.Struct(req); err != nil {
+		return history, err
+	}
+
+	var err error
+	history.Account, err = admin.store.GetAccount(ctx, req.AccountID)
+	if err != nil {
+		return history, err
+	}
+
+	history.Entries, err = admin.store.ListEntries(ctx, db.ListEntriesParams{
+		AccountID: req.AccountID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		return history, err
+	}
+
+	history.Events, err = admin.store.ListAuditEventsByAccount(ctx, db.ListAuditEventsByAccountParams{
+		AccountID: req.AccountID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	return history, err
+}
+
+// audit records an operation that has no transaction of its own
+func (admin *Admin) audit(ctx context.Context, action string, accountID int64, details map[string]string) error {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	_, err = admin.store.CreateAuditEvent(ctx, db.CreateAuditEventParams{
+		Actor:     admin.opts.Actor,
+		Action:    action,
+		AccountID: accountID,
+		Details:   data,
+	})
+	return err
+}
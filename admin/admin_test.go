@@ -0,0 +1,166 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAdmin(store db.Store) *Admin {
+	return New(store, Options{Actor: "cli:test", VerifyEmailDuration: time.Hour})
+}
+
+func requireInvalidFlag(t *testing.T, err error, flag string) {
+	var fieldErrs validator.ValidationErrors
+	require.True(t, errors.As(err, &fieldErrs), "expected validation errors, got %v", err)
+	require.Equal(t, flag, fieldErrs[0].Field())
+}
+
+func TestCreateUserAndAccount(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+
+	var mailed []string
+	admin := New(store, Options{
+		Actor:               "cli:test",
+		VerifyEmailDuration: time.Hour,
+		AfterCreateUser: func(user db.User, verifyEmail db.VerifyEmail) error {
+			mailed = append(mailed, verifyEmail.Email)
+			return nil
+		},
+	})
+
+	req := CreateUserRequest{
+		Username: util.RandomOwnerName(),
+		Password: "secret",
+		FullName: util.RandomOwnerName(),
+		Email:    util.RandomEmail(),
+		Role:     util.AdminRole,
+	}
+	user, err := admin.CreateUser(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, util.AdminRole, user.Role)
+	require.NoError(t, util.CheckPassword("secret", user.HashedPassword))
+	require.Equal(t, []string{req.Email}, mailed)
+
+	//the rules of the API apply
+	invalid := req
+	invalid.Username = "not alphanumeric"
+	_, err = admin.CreateUser(ctx, invalid)
+	requireInvalidFlag(t, err, "username")
+
+	invalid = req
+	invalid.Role = "superuser"
+	_, err = admin.CreateUser(ctx, invalid)
+	requireInvalidFlag(t, err, "role")
+
+	_, err = admin.CreateUser(ctx, req)
+	require.Error(t, err)
+
+	account, err := admin.CreateAccount(ctx, CreateAccountRequest{OwnerName: user.Username, Currency: util.USD})
+	require.NoError(t, err)
+	require.Zero(t, account.Balance)
+
+	_, err = admin.CreateAccount(ctx, CreateAccountRequest{OwnerName: user.Username, Currency: "XYZ"})
+	requireInvalidFlag(t, err, "currency")
+
+	events, err := store.ListAuditEventsByAccount(ctx, db.ListAuditEventsByAccountParams{AccountID: account.ID, Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, db.AuditActionCreateAccount, events[0].Action)
+	require.Equal(t, "cli:test", events[0].Actor)
+
+	events, err = store.ListAuditEventsByAccount(ctx, db.ListAuditEventsByAccountParams{AccountID: 0, Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, db.AuditActionCreateUser, events[0].Action)
+	require.JSONEq(t, `{"username":"`+user.Username+`","role":"admin"}`, string(events[0].Details))
+}
+
+func TestAdjustAndReconcile(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	admin := newTestAdmin(store)
+
+	createAccount := func() db.Account {
+		user, err := admin.CreateUser(ctx, CreateUserRequest{
+			Username: util.RandomOwnerName(),
+			Password: "secret",
+			FullName: util.RandomOwnerName(),
+			Email:    util.RandomEmail(),
+			Role:     util.DepositorRole,
+		})
+		require.NoError(t, err)
+
+		account, err := admin.CreateAccount(ctx, CreateAccountRequest{OwnerName: user.Username, Currency: util.USD})
+		require.NoError(t, err)
+		return account
+	}
+	customer := createAccount()
+	suspense := createAccount()
+
+	//frozen accounts can still be corrected
+	account, err := admin.SetAccountStatus(ctx, AccountStatusRequest{AccountID: customer.ID, Status: db.AccountStatusFrozen, Reason: "dispute"})
+	require.NoError(t, err)
+	require.Equal(t, db.AccountStatusFrozen, account.Status)
+
+	result, err := admin.Adjust(ctx, AdjustRequest{AccountID: customer.ID, AgainstAccountID: suspense.ID, Amount: 50, Reason: "refund of duplicate fee"})
+	require.NoError(t, err)
+	require.Equal(t, int64(50), result.Account.Balance)
+	require.Equal(t, int64(-50), result.AgainstAccount.Balance)
+	require.Equal(t, "adjustment: refund of duplicate fee", result.Entry.Description)
+	require.Equal(t, suspense.ID, result.Transfer.FromAccountID)
+
+	result, err = admin.Adjust(ctx, AdjustRequest{AccountID: customer.ID, AgainstAccountID: suspense.ID, Amount: -20, Reason: "partial reversal"})
+	require.NoError(t, err)
+	require.Equal(t, int64(30), result.Account.Balance)
+	require.Equal(t, customer.ID, result.Transfer.FromAccountID)
+	require.Equal(t, int64(20), result.Transfer.Amount)
+
+	_, err = admin.Adjust(ctx, AdjustRequest{AccountID: customer.ID, AgainstAccountID: suspense.ID, Amount: 10})
+	requireInvalidFlag(t, err, "reason")
+	_, err = admin.Adjust(ctx, AdjustRequest{AccountID: customer.ID, AgainstAccountID: customer.ID, Amount: 10, Reason: "loop"})
+	requireInvalidFlag(t, err, "against")
+	_, err = admin.Adjust(ctx, AdjustRequest{AccountID: customer.ID, AgainstAccountID: suspense.ID, Reason: "nothing"})
+	requireInvalidFlag(t, err, "amount")
+
+	history, err := admin.History(ctx, HistoryRequest{AccountID: customer.ID, PageID: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, int64(30), history.Account.Balance)
+	require.Len(t, history.Entries, 2)
+
+	actions := make([]string, len(history.Events))
+	for i, event := range history.Events {
+		actions[i] = event.Action
+	}
+	require.Equal(t, []string{db.AuditActionCreateAccount, db.AuditActionAccountStatus, db.AuditActionAdjustment, db.AuditActionAdjustment}, actions)
+	require.Equal(t, "dispute", history.Events[1].Reason)
+	require.JSONEq(t, `{"from":"active","to":"frozen"}`, string(history.Events[1].Details))
+
+	var details struct {
+		Amount int64 `json:"amount"`
+	}
+	require.NoError(t, json.Unmarshal(history.Events[3].Details, &details))
+	require.Equal(t, int64(-20), details.Amount)
+
+	report, err := admin.Reconcile(ctx)
+	require.NoError(t, err)
+	require.Empty(t, report.Mismatches)
+
+	//a balance changed behind the ledger's back is found
+	_, err = store.AddAccountBalance(ctx, db.AddAccountBalanceParams{ID: suspense.ID, Amount: 7})
+	require.NoError(t, err)
+
+	report, err = admin.Reconcile(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []db.ListUnreconciledAccountsRow{
+		{ID: suspense.ID, Currency: util.USD, Balance: -23, EntriesTotal: -30},
+	}, report.Mismatches)
+}
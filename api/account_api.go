@@ -2,20 +2,21 @@ package api
 
 import (
 	"database/sql"
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
-	"github.com/lib/pq"
 )
 
 //Struct to handle request from http
 //When a new account is created, its initial balance should always be 0, so we can remove the balance field.
 //We also use binding for validation
 //To be safe copy the createAccountParams to maintain the names and json
+//Depositors always open accounts for themselves, only staff may set owner_name
 type createAccountRequest struct {
-	OwnerName string `json:"owner_name" binding:"required"`
+	OwnerName string `json:"owner_name" binding:"omitempty,alphanum"`
 	Currency  string `json:"currency" binding:"required,currency"`
 }
 
@@ -25,13 +26,24 @@ func (server *Server) createAccount(ctx *gin.Context) {
 	//Note that we used ShouldBindJSON for json parameters
 	var req createAccountRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
+	authPayload := getAuthPayload(ctx)
+	ownerName := authPayload.Username
+	if req.OwnerName != "" && req.OwnerName != ownerName {
+		if !isStaff(getAuthUser(ctx)) {
+			err := errors.New("depositors can only open accounts for themselves")
+			writeError(ctx, http.StatusForbidden, err)
+			return
+		}
+		ownerName = req.OwnerName
+	}
+
 	//create arg for query
 	arg := db.CreateAccountParams{
-		OwnerName: req.OwnerName,
+		OwnerName: ownerName,
 		Currency:  req.Currency,
 		Balance:   0,
 	}
@@ -39,30 +51,18 @@ func (server *Server) createAccount(ctx *gin.Context) {
 	//run createAccount with arg
 	account, err := server.store.CreateAccount(ctx, arg)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			//First print error and see how creating a new account without a user will be
-			//In pur case it's  "foreign_key_violation" and "unique_violation"
-			//This is how you return a custom http error code that is not what you want
-			log.Println(pqErr.Code.Name())
-
-			switch pqErr.Code.Name() {
-			case "foreign_key_violation", "unique_violation":
-				ctx.JSON(http.StatusForbidden, errorResponse(err))
-				return
-			}
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
 	//return StatusOk if passed as response
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, newAccountResponse(account))
 }
 
 //Get account request
-//Takes an id as a URI parameter Eg. accounts/:id
+//Takes the public id as a URI parameter Eg. accounts/acc_01HF3Z...
 type getAccountRequest struct {
-	ID int64 `uri:"id" binding:"required,min=1"`
+	ID string `uri:"id" binding:"required,publicid=acc_"`
 }
 
 //createAccount request and response handler function
@@ -70,23 +70,16 @@ func (server *Server) getAccount(ctx *gin.Context) {
 	//Note that for URI parameters we use ShouldBindUri
 	var req getAccountRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	account, err := server.store.GetAccount(ctx, req.ID)
-	if err != nil {
-		//if returns emptyRow error: sql.ErrNoRows
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	account, valid := server.accessibleAccount(ctx, req.ID)
+	if !valid {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, newAccountResponse(account))
 }
 
 //listAccount struct to get paginated data
@@ -101,23 +94,129 @@ func (server *Server) listAccount(ctx *gin.Context) {
 	//Note that we used ShouldBindQuery for quesry string validation
 	var req listAccountRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
 	//Note that Limit is simply the req.PageSize.
 	//Offset is the number of records that the database should skip,
 	//we we have to calculate it from the page id and page size using this formula: (req.PageID - 1) * req.PageSize
-	arg := db.ListAccountsParams{
-		Limit:  req.PageSize,
-		Offset: (req.PageID - 1) * req.PageSize,
+	//Staff see every account, depositors only their own
+	var accounts []db.Account
+	var err error
+
+	authPayload := getAuthPayload(ctx)
+	if isStaff(getAuthUser(ctx)) {
+		accounts, err = server.store.ListAccounts(ctx, db.ListAccountsParams{
+			Limit:  req.PageSize,
+			Offset: (req.PageID - 1) * req.PageSize,
+		})
+	} else {
+		accounts, err = server.store.ListAccountsByOwner(ctx, db.ListAccountsByOwnerParams{
+			OwnerName: authPayload.Username,
+			Limit:     req.PageSize,
+			Offset:    (req.PageID - 1) * req.PageSize,
+		})
+	}
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
 	}
 
-	accounts, err := server.store.ListAccounts(ctx, arg)
+	ctx.JSON(http.StatusOK, newResponses(accounts, newAccountResponse))
+}
+
+//accessibleAccount loads an account by public id and makes sure the authenticated user may see it
+func (server *Server) accessibleAccount(ctx *gin.Context, publicID string) (db.Account, bool) {
+	account, valid := server.accountByPublicID(ctx, publicID)
+	if !valid {
+		return account, false
+	}
+
+	return account, server.canAccessAccount(ctx, account)
+}
+
+//accountByPublicID loads the account a request names, the internal id is only known from here on.
+//It writes the error response itself so callers only need to return.
+func (server *Server) accountByPublicID(ctx *gin.Context, publicID string) (db.Account, bool) {
+	account, err := server.store.GetAccountByPublicID(ctx, publicID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		if err == sql.ErrNoRows {
+			writeError(ctx, http.StatusNotFound, accountNotFound(publicID))
+			return account, false
+		}
+
+		writeError(ctx, http.StatusInternalServerError, err)
+		return account, false
+	}
+
+	return account, true
+}
+
+//canAccessAccount lets staff access any account and depositors only their own.
+//It writes the 403 response itself so callers only need to return.
+func (server *Server) canAccessAccount(ctx *gin.Context, account db.Account) bool {
+	authPayload := getAuthPayload(ctx)
+	if isStaff(getAuthUser(ctx)) || account.OwnerName == authPayload.Username {
+		return true
+	}
+
+	err := fmt.Errorf("account [%s] doesn't belong to the authenticated user", account.PublicID)
+	writeError(ctx, http.StatusForbidden, err)
+	return false
+}
+
+//freezeAccount stops all money movement in and out of an account
+func (server *Server) freezeAccount(ctx *gin.Context) {
+	server.setAccountStatus(ctx, db.AccountStatusFrozen)
+}
+
+//unfreezeAccount allows money movement on a frozen account again
+func (server *Server) unfreezeAccount(ctx *gin.Context) {
+	server.setAccountStatus(ctx, db.AccountStatusActive)
+}
+
+//accountStatusRequest optionally says why the status is changed, it is kept in the audit trail
+type accountStatusRequest struct {
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+func (server *Server) setAccountStatus(ctx *gin.Context, status string) {
+	var req getAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	//the body is optional, older clients send none
+	var body accountStatusRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			writeError(ctx, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	account, valid := server.accountByPublicID(ctx, req.ID)
+	if !valid {
+		return
+	}
+
+	result, err := server.store.UpdateAccountStatusTx(ctx, db.UpdateAccountStatusTxParams{
+		ID:     account.ID,
+		Status: status,
+		Actor:  getAuthPayload(ctx).Username,
+		Reason: body.Reason,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(ctx, http.StatusNotFound, accountNotFound(req.ID))
+			return
+		}
+
+		writeError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, accounts)
+	ctx.JSON(http.StatusOK, newAccountResponse(result.Account))
 }
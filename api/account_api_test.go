@@ -5,14 +5,18 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	mockdb "github.com/kingsleyocran/simple_bank_bankend/db/mock"
 	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/token"
 	"github.com/kingsleyocran/simple_bank_bankend/util"
 	"github.com/stretchr/testify/require"
 )
@@ -23,6 +27,8 @@ func randomAccount(randomUsername string) db.Account {
 		OwnerName: randomUsername,
 		Balance:   util.RandomMoney(),
 		Currency:  util.RandomCurrency(),
+		Status:    db.AccountStatusActive,
+		PublicID:  db.NewPublicID(db.AccountIDPrefix, time.Now()),
 	}
 }
 
@@ -30,22 +36,22 @@ func requireBodyMatchAccount(t *testing.T, body *bytes.Buffer, account db.Accoun
 	data, err := ioutil.ReadAll(body)
 	require.NoError(t, err)
 
-	var gotAccount db.Account
+	var gotAccount accountResponse
 
 	// call json.Unmarshal to unmarshal the data to the gotAccount object.
 	err = json.Unmarshal(data, &gotAccount)
 	require.NoError(t, err)
-	require.Equal(t, account, gotAccount)
+	require.Equal(t, newAccountResponse(account), gotAccount)
 }
 
 func requireBodyMatchAccounts(t *testing.T, body *bytes.Buffer, accounts []db.Account) {
 	data, err := ioutil.ReadAll(body)
 	require.NoError(t, err)
 
-	var gotAccounts []db.Account
+	var gotAccounts []accountResponse
 	err = json.Unmarshal(data, &gotAccounts)
 	require.NoError(t, err)
-	require.Equal(t, accounts, gotAccounts)
+	require.Equal(t, newResponses(accounts, newAccountResponse), gotAccounts)
 }
 
 func TestGetAccountAPI(t *testing.T) {
@@ -56,16 +62,20 @@ func TestGetAccountAPI(t *testing.T) {
 
 	testCases := []struct {
 		name          string
-		accountID     int64
+		accountID     string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, recoder *httptest.ResponseRecorder)
 	}{
 		{
 			name:      "OK",
-			accountID: account.ID,
+			accountID: account.PublicID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).
 					Times(1).
 					Return(account, nil)
 			},
@@ -74,12 +84,62 @@ func TestGetAccountAPI(t *testing.T) {
 				requireBodyMatchAccount(t, recorder.Body, account)
 			},
 		},
+		{
+			name:      "BankerCanViewAnyAccount",
+			accountID: account.PublicID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "banker", util.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name:      "UnauthorizedUser",
+			accountID: account.PublicID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			accountID: account.PublicID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccountByPublicID(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
 		{
 			name:      "NotFound",
-			accountID: account.ID,
+			accountID: account.PublicID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).
 					Times(1).
 					Return(db.Account{}, sql.ErrNoRows)
 			},
@@ -89,10 +149,13 @@ func TestGetAccountAPI(t *testing.T) {
 		},
 		{
 			name:      "InternalError",
-			accountID: account.ID,
+			accountID: account.PublicID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).
 					Times(1).
 					Return(db.Account{}, sql.ErrConnDone)
 			},
@@ -101,11 +164,14 @@ func TestGetAccountAPI(t *testing.T) {
 			},
 		},
 		{
-			name:      "InvalidID",
-			accountID: 0,
+			name:      "SequentialID",
+			accountID: fmt.Sprint(account.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					GetAccount(gomock.Any(), gomock.Any()).
+					GetAccountByPublicID(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -123,294 +189,487 @@ func TestGetAccountAPI(t *testing.T) {
 
 			store := mockdb.NewMockStore(ctrl)
 			tc.buildStubs(store)
+			expectAuthUser(store)
 
-			server := NewServer(store)
+			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
 
-			url := fmt.Sprintf("/accounts/%d", tc.accountID)
+			url := fmt.Sprintf("/v1/accounts/%s", tc.accountID)
 			request, err := http.NewRequest(http.MethodGet, url, nil)
 			require.NoError(t, err)
 
+			tc.setupAuth(t, request, server.tokenMaker)
 			server.router.ServeHTTP(recorder, request)
 			tc.checkResponse(t, recorder)
 		})
 	}
+}
 
-	/*
-		/Next we need to create a new mock store using this mockdb.NewMockStore() generated function.
-		/It expects a gomock.Controller object as input, so we have to create this controller by
-		/calling gomock.NewController and pass in the testing.T object.
+func TestCreateAccountAPI(t *testing.T) {
+	username := util.RandomOwnerName()
+	account := randomAccount(username)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"currency": account.Currency,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateAccountParams{
+					OwnerName: account.OwnerName,
+					Currency:  account.Currency,
+					Balance:   0,
+				}
+
+				store.EXPECT().
+					CreateAccount(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name: "BankerOpensAccountForCustomer",
+			body: gin.H{
+				"owner_name": account.OwnerName,
+				"currency":   account.Currency,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "banker", util.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateAccountParams{
+					OwnerName: account.OwnerName,
+					Currency:  account.Currency,
+					Balance:   0,
+				}
 
-		ctrl := gomock.NewController(t)
-		defer ctrl.Finish()
+				store.EXPECT().
+					CreateAccount(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "DepositorOpensAccountForSomeoneElse",
+			body: gin.H{
+				"owner_name": "someoneelse",
+				"currency":   account.Currency,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: gin.H{
+				"currency": account.Currency,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"currency": account.Currency,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAccount(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCurrency",
+			body: gin.H{
+				"currency": "invalid",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
 
-		store := mockdb.NewMockStore(ctrl)
+	for i := range testCases {
+		tc := testCases[i]
 
-		/The first context argument could be any value, so we use gomock.Any() matcher for it.
-		/The second argument should equal to the ID of the random account we created above.
-		/So we use this matcher: gomock.Eq() and pass the account.ID to it.
-		/Here Times(1) means we expect this function to be called exactly 1 time.
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
 
-		store.EXPECT().
-			GetAccount(gomock.Any(), gomock.Eq(account.ID)).
-			Times(1).
-			Return(account, nil)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
 
-		/For testing an HTTP API in Go, we don’t have to start a real HTTP server.
-		/Instead, we can just use the recording feature of the httptest package to record the response of the API request.
-		/So here we call httptest.NewRecorder() to create a new ResponseRecorder.
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
 
-		server := NewServer(store)
-		recorder := httptest.NewRecorder()
+			// Marshal body data to JSON
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
 
-		url := fmt.Sprintf("/accounts/%d", account.ID)
-		request, err := http.NewRequest(http.MethodGet, url, nil)
-		require.NoError(t, err)
+			url := "/v1/accounts"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
 
-		server.router.ServeHTTP(recorder, request)
-		require.Equal(t, http.StatusOK, recorder.Code)
-		requireBodyMatchAccount(t, recorder.Body, account)
-	*/
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
 }
 
-func TestCreateAccountAPI(t *testing.T) {
-	/*
-		username := util.RandomOwnerName()
-		account := randomAccount(username)
-
-		testCases := []struct {
-			name          string
-			body          gin.H
-			buildStubs    func(store *mockdb.MockStore)
-			checkResponse func(recoder *httptest.ResponseRecorder)
-		}{
-			{
-				name: "OK",
-				body: gin.H{
-					"currency": account.Currency,
-				},
-				buildStubs: func(store *mockdb.MockStore) {
-					arg := db.CreateAccountParams{
-						OwnerName: account.OwnerName,
-						Currency:  account.Currency,
-						Balance:   0,
-					}
-
-					store.EXPECT().
-						CreateAccount(gomock.Any(), gomock.Eq(arg)).
-						Times(1).
-						Return(account, nil)
-				},
-				checkResponse: func(recorder *httptest.ResponseRecorder) {
-					require.Equal(t, http.StatusOK, recorder.Code)
-					requireBodyMatchAccount(t, recorder.Body, account)
-				},
-			},
-			{
-				name: "NoAuthorization",
-				body: gin.H{
-					"currency": account.Currency,
-				},
-				buildStubs: func(store *mockdb.MockStore) {
-					store.EXPECT().
-						CreateAccount(gomock.Any(), gomock.Any()).
-						Times(0)
-				},
-				checkResponse: func(recorder *httptest.ResponseRecorder) {
-					require.Equal(t, http.StatusUnauthorized, recorder.Code)
-				},
-			},
-			{
-				name: "InternalError",
-				body: gin.H{
-					"currency": account.Currency,
-				},
-				buildStubs: func(store *mockdb.MockStore) {
-					store.EXPECT().
-						CreateAccount(gomock.Any(), gomock.Any()).
-						Times(1).
-						Return(db.Account{}, sql.ErrConnDone)
-				},
-				checkResponse: func(recorder *httptest.ResponseRecorder) {
-					require.Equal(t, http.StatusInternalServerError, recorder.Code)
-				},
-			},
-			{
-				name: "InvalidCurrency",
-				body: gin.H{
-					"currency": "invalid",
-				},
-				buildStubs: func(store *mockdb.MockStore) {
-					store.EXPECT().
-						CreateAccount(gomock.Any(), gomock.Any()).
-						Times(0)
-				},
-				checkResponse: func(recorder *httptest.ResponseRecorder) {
-					require.Equal(t, http.StatusBadRequest, recorder.Code)
-				},
-			},
-		}
-
-		for i := range testCases {
-			tc := testCases[i]
-
-			t.Run(tc.name, func(t *testing.T) {
-				ctrl := gomock.NewController(t)
-				defer ctrl.Finish()
-
-				store := mockdb.NewMockStore(ctrl)
-				tc.buildStubs(store)
-
-				server := NewServer(store)
-				recorder := httptest.NewRecorder()
-
-				// Marshal body data to JSON
-				data, err := json.Marshal(tc.body)
-				require.NoError(t, err)
+func TestListAccountsAPI(t *testing.T) {
+	username := util.RandomOwnerName()
 
-				url := "/accounts"
-				request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
-				require.NoError(t, err)
+	n := 5
+	accounts := make([]db.Account, n)
+	for i := 0; i < n; i++ {
+		accounts[i] = randomAccount(username)
+	}
 
-				server.router.ServeHTTP(recorder, request)
-				tc.checkResponse(recorder)
-			})
-		}
-	*/
+	type Query struct {
+		pageID   int
+		pageSize int
+	}
+
+	testCases := []struct {
+		name          string
+		query         Query
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			query: Query{
+				pageID:   1,
+				pageSize: n,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsByOwnerParams{
+					OwnerName: username,
+					Limit:     int32(n),
+					Offset:    0,
+				}
+
+				store.EXPECT().
+					ListAccountsByOwner(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(accounts, nil)
+				store.EXPECT().
+					ListAccounts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccounts(t, recorder.Body, accounts)
+			},
+		},
+		{
+			name: "BankerListsAllAccounts",
+			query: Query{
+				pageID:   2,
+				pageSize: n,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "banker", util.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsParams{
+					Limit:  int32(n),
+					Offset: int32(n),
+				}
+
+				store.EXPECT().
+					ListAccounts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(accounts, nil)
+				store.EXPECT().
+					ListAccountsByOwner(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccounts(t, recorder.Body, accounts)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			query: Query{
+				pageID:   1,
+				pageSize: n,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAccountsByOwner(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			query: Query{
+				pageID:   1,
+				pageSize: n,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAccountsByOwner(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Account{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidPageID",
+			query: Query{
+				pageID:   -1,
+				pageSize: n,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAccountsByOwner(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidPageSize",
+			query: Query{
+				pageID:   1,
+				pageSize: 100000,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAccountsByOwner(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := "/v1/accounts"
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			// Add query parameters to request URL
+			q := request.URL.Query()
+			q.Add("page_id", fmt.Sprintf("%d", tc.query.pageID))
+			q.Add("page_size", fmt.Sprintf("%d", tc.query.pageSize))
+			request.URL.RawQuery = q.Encode()
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
 }
 
-func TestListAccountsAPI(t *testing.T) {
-	/*
-		username := util.RandomOwnerName()
-
-		n := 5
-		accounts := make([]db.Account, n)
-		for i := 0; i < n; i++ {
-			accounts[i] = randomAccount(username)
-		}
-
-		type Query struct {
-			pageID   int
-			pageSize int
-		}
-
-		testCases := []struct {
-			name          string
-			query         Query
-			buildStubs    func(store *mockdb.MockStore)
-			checkResponse func(recoder *httptest.ResponseRecorder)
-		}{
-			{
-				name: "OK",
-				query: Query{
-					pageID:   1,
-					pageSize: n,
-				},
-				buildStubs: func(store *mockdb.MockStore) {
-					arg := db.ListAccountsParams{
-						Limit:  int32(n),
-						Offset: 0,
-					}
-
-					store.EXPECT().
-						ListAccounts(gomock.Any(), gomock.Eq(arg)).
-						Times(1).
-						Return(accounts, nil)
-				},
-				checkResponse: func(recorder *httptest.ResponseRecorder) {
-					require.Equal(t, http.StatusOK, recorder.Code)
-					requireBodyMatchAccounts(t, recorder.Body, accounts)
-				},
-			},
-			{
-				name: "NoAuthorization",
-				query: Query{
-					pageID:   1,
-					pageSize: n,
-				},
-				buildStubs: func(store *mockdb.MockStore) {
-					store.EXPECT().
-						ListAccounts(gomock.Any(), gomock.Any()).
-						Times(0)
-				},
-				checkResponse: func(recorder *httptest.ResponseRecorder) {
-					require.Equal(t, http.StatusUnauthorized, recorder.Code)
-				},
-			},
-			{
-				name: "InternalError",
-				query: Query{
-					pageID:   1,
-					pageSize: n,
-				},
-				buildStubs: func(store *mockdb.MockStore) {
-					store.EXPECT().
-						ListAccounts(gomock.Any(), gomock.Any()).
-						Times(1).
-						Return([]db.Account{}, sql.ErrConnDone)
-				},
-				checkResponse: func(recorder *httptest.ResponseRecorder) {
-					require.Equal(t, http.StatusInternalServerError, recorder.Code)
-				},
-			},
-			{
-				name: "InvalidPageID",
-				query: Query{
-					pageID:   -1,
-					pageSize: n,
-				},
-				buildStubs: func(store *mockdb.MockStore) {
-					store.EXPECT().
-						ListAccounts(gomock.Any(), gomock.Any()).
-						Times(0)
-				},
-				checkResponse: func(recorder *httptest.ResponseRecorder) {
-					require.Equal(t, http.StatusBadRequest, recorder.Code)
-				},
-			},
-			{
-				name: "InvalidPageSize",
-				query: Query{
-					pageID:   1,
-					pageSize: 100000,
-				},
-				buildStubs: func(store *mockdb.MockStore) {
-					store.EXPECT().
-						ListAccounts(gomock.Any(), gomock.Any()).
-						Times(0)
-				},
-				checkResponse: func(recorder *httptest.ResponseRecorder) {
-					require.Equal(t, http.StatusBadRequest, recorder.Code)
-				},
-			},
-		}
-
-		for i := range testCases {
-			tc := testCases[i]
-
-			t.Run(tc.name, func(t *testing.T) {
-				ctrl := gomock.NewController(t)
-				defer ctrl.Finish()
-
-				store := mockdb.NewMockStore(ctrl)
-				tc.buildStubs(store)
-
-				server := NewServer(store)
-				recorder := httptest.NewRecorder()
-
-				url := "/accounts"
-				request, err := http.NewRequest(http.MethodGet, url, nil)
+func TestFreezeAccountAPI(t *testing.T) {
+	account := randomAccount(util.RandomOwnerName())
+	frozen := account
+	frozen.Status = db.AccountStatusFrozen
+
+	testCases := []struct {
+		name          string
+		role          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(account, nil)
+				arg := db.UpdateAccountStatusTxParams{
+					ID:     account.ID,
+					Status: db.AccountStatusFrozen,
+					Actor:  "operator",
+				}
+				store.EXPECT().
+					UpdateAccountStatusTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.UpdateAccountStatusTxResult{Account: frozen}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, frozen)
+			},
+		},
+		{
+			name: "WithReason",
+			role: util.AdminRole,
+			body: gin.H{"reason": "suspected account takeover"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(account, nil)
+				arg := db.UpdateAccountStatusTxParams{
+					ID:     account.ID,
+					Status: db.AccountStatusFrozen,
+					Actor:  "operator",
+					Reason: "suspected account takeover",
+				}
+				store.EXPECT().
+					UpdateAccountStatusTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.UpdateAccountStatusTxResult{Account: frozen}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ReasonTooLong",
+			role: util.AdminRole,
+			body: gin.H{"reason": util.RandomString(256)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateAccountStatusTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "BankerCannotFreeze",
+			role: util.BankerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateAccountStatusTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().
+					UpdateAccountStatusTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body io.Reader
+			if tc.body != nil {
+				data, err := json.Marshal(tc.body)
 				require.NoError(t, err)
+				body = bytes.NewReader(data)
+			}
+
+			url := fmt.Sprintf("/v1/admin/accounts/%s/freeze", account.PublicID)
+			request, err := http.NewRequest(http.MethodPost, url, body)
+			require.NoError(t, err)
 
-				// Add query parameters to request URL
-				q := request.URL.Query()
-				q.Add("page_id", fmt.Sprintf("%d", tc.query.pageID))
-				q.Add("page_size", fmt.Sprintf("%d", tc.query.pageSize))
-				request.URL.RawQuery = q.Encode()
-
-				server.router.ServeHTTP(recorder, request)
-				tc.checkResponse(recorder)
-			})
-		}
-	*/
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, "operator", tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
 }
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+)
+
+//accountLimitURI takes the public id of the account as a URI parameter Eg. admin/accounts/acc_01HF3Z.../limits
+type accountLimitURI struct {
+	ID string `uri:"id" binding:"required,publicid=acc_"`
+}
+
+//setAccountLimitRequest holds the new limits of an account, 0 means unlimited
+type setAccountLimitRequest struct {
+	MaxSingleTransfer int64 `json:"max_single_transfer" binding:"min=0"`
+	MaxDailyAmount    int64 `json:"max_daily_amount" binding:"min=0"`
+	MaxDailyCount     int64 `json:"max_daily_count" binding:"min=0"`
+}
+
+//getAccountLimit returns the transfer limits of an account
+//An account without limits is reported with all limits set to 0 (unlimited)
+func (server *Server) getAccountLimit(ctx *gin.Context) {
+	var uri accountLimitURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	//the account is loaded first, so that default limits are only reported for accounts that exist
+	account, valid := server.accountByPublicID(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	limit, err := server.store.GetAccountLimit(ctx, account.ID)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			writeError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		limit = db.AccountLimit{AccountID: account.ID}
+	}
+
+	ctx.JSON(http.StatusOK, server.publicIDs(ctx, account).newAccountLimitResponse(limit))
+}
+
+//setAccountLimit creates or replaces the transfer limits of an account
+func (server *Server) setAccountLimit(ctx *gin.Context) {
+	var uri accountLimitURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var req setAccountLimitRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	account, valid := server.accountByPublicID(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	arg := db.UpsertAccountLimitParams{
+		AccountID:         account.ID,
+		MaxSingleTransfer: req.MaxSingleTransfer,
+		MaxDailyAmount:    req.MaxDailyAmount,
+		MaxDailyCount:     req.MaxDailyCount,
+	}
+
+	limit, err := server.store.UpsertAccountLimit(ctx, arg)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, server.publicIDs(ctx, account).newAccountLimitResponse(limit))
+}
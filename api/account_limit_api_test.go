@@ -0,0 +1,237 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kingsleyocran/simple_bank_bankend/db/mock"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func requireBodyMatchAccountLimit(t *testing.T, body *bytes.Buffer, account db.Account, limit db.AccountLimit) {
+	var gotLimit accountLimitResponse
+	err := json.Unmarshal(body.Bytes(), &gotLimit)
+	require.NoError(t, err)
+	require.Equal(t, accountLimitResponse{
+		AccountID:         account.PublicID,
+		MaxSingleTransfer: limit.MaxSingleTransfer,
+		MaxDailyAmount:    limit.MaxDailyAmount,
+		MaxDailyCount:     limit.MaxDailyCount,
+		UpdatedAt:         limit.UpdatedAt,
+	}, gotLimit)
+}
+
+func TestGetAccountLimitAPI(t *testing.T) {
+	account := randomAccount(util.RandomOwnerName())
+	limit := db.AccountLimit{
+		AccountID:         account.ID,
+		MaxSingleTransfer: util.RandomMoney(),
+		MaxDailyAmount:    util.RandomMoney(),
+		MaxDailyCount:     util.RandomInt(1, 10),
+	}
+
+	testCases := []struct {
+		name          string
+		accountID     string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			accountID: account.PublicID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					GetAccountLimit(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(limit, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccountLimit(t, recorder.Body, account, limit)
+			},
+		},
+		{
+			name:      "NoLimits",
+			accountID: account.PublicID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					GetAccountLimit(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.AccountLimit{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccountLimit(t, recorder.Body, account, db.AccountLimit{AccountID: account.ID})
+			},
+		},
+		{
+			name:      "AccountNotFound",
+			accountID: account.PublicID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetAccountLimit(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidID",
+			accountID: "acc_0",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccountLimit(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/v1/admin/accounts/%s/limits", tc.accountID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, "admin", util.AdminRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestSetAccountLimitAPI(t *testing.T) {
+	account := randomAccount(util.RandomOwnerName())
+	limit := db.AccountLimit{
+		AccountID:         account.ID,
+		MaxSingleTransfer: 100,
+		MaxDailyAmount:    500,
+		MaxDailyCount:     5,
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"max_single_transfer": limit.MaxSingleTransfer,
+				"max_daily_amount":    limit.MaxDailyAmount,
+				"max_daily_count":     limit.MaxDailyCount,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(account, nil)
+				arg := db.UpsertAccountLimitParams{
+					AccountID:         account.ID,
+					MaxSingleTransfer: limit.MaxSingleTransfer,
+					MaxDailyAmount:    limit.MaxDailyAmount,
+					MaxDailyCount:     limit.MaxDailyCount,
+				}
+				store.EXPECT().
+					UpsertAccountLimit(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(limit, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccountLimit(t, recorder.Body, account, limit)
+			},
+		},
+		{
+			name: "AccountNotFound",
+			body: gin.H{
+				"max_single_transfer": limit.MaxSingleTransfer,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().
+					UpsertAccountLimit(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "NegativeLimit",
+			body: gin.H{
+				"max_daily_amount": -1,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpsertAccountLimit(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/v1/admin/accounts/%s/limits", account.PublicID)
+			request, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, "admin", util.AdminRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+)
+
+//accountBalanceURI takes the public id of the account as a URI parameter Eg. accounts/acc_01HF3Z.../balance
+type accountBalanceURI struct {
+	ID string `uri:"id" binding:"required,publicid=acc_"`
+}
+
+//accountBalanceQuery takes the instant as an RFC 3339 query string Eg. ?at=2024-01-31T23:59:59Z, now when it is left out
+type accountBalanceQuery struct {
+	At time.Time `form:"at" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+type accountBalanceResponse struct {
+	AccountID string    `json:"account_id"`
+	Currency  string    `json:"currency"`
+	At        time.Time `json:"at"`
+	Balance   int64     `json:"balance"`
+}
+
+//getAccountBalance returns the balance of an account at an instant, including every entry created before it
+func (server *Server) getAccountBalance(ctx *gin.Context) {
+	var uri accountBalanceURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var req accountBalanceQuery
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	now := time.Now()
+	if req.At.IsZero() {
+		req.At = now
+	}
+	if req.At.After(now) {
+		err := errors.New("at must not be in the future")
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	account, valid := server.accessibleAccount(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	rsp := accountBalanceResponse{
+		AccountID: account.PublicID,
+		Currency:  account.Currency,
+		At:        req.At,
+	}
+
+	//an account had nothing before it was opened, its opening balance has no entry to walk back
+	if req.At.Before(account.CreatedAt) {
+		ctx.JSON(http.StatusOK, rsp)
+		return
+	}
+
+	balance, err := db.BalanceAt(ctx, server.store, account.ID, req.At)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	rsp.Balance = balance
+
+	ctx.JSON(http.StatusOK, rsp)
+}
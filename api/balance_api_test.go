@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kingsleyocran/simple_bank_bankend/db/mock"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAccountBalanceAPI(t *testing.T) {
+	account := randomAccount(util.RandomOwnerName())
+	account.CreatedAt = time.Now().Add(-30 * 24 * time.Hour).UTC().Truncate(time.Second)
+	at := time.Now().Add(-24 * time.Hour).UTC().Truncate(time.Second)
+	snapshot := db.BalanceSnapshot{
+		AccountID: account.ID,
+		TakenAt:   at.Truncate(24 * time.Hour),
+		Balance:   util.RandomMoney(),
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		at            string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: account.OwnerName,
+			at:       at.Format(time.RFC3339),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					GetLatestBalanceSnapshot(gomock.Any(), gomock.Eq(db.GetLatestBalanceSnapshotParams{AccountID: account.ID, TakenAt: at})).
+					Times(1).
+					Return(snapshot, nil)
+				store.EXPECT().
+					SumEntriesBetween(gomock.Any(), gomock.Eq(db.SumEntriesBetweenParams{AccountID: account.ID, Since: snapshot.TakenAt, Before: at})).
+					Times(1).
+					Return(int64(-25), nil)
+				store.EXPECT().GetBalanceBefore(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got accountBalanceResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, snapshot.Balance-25, got.Balance)
+				require.Equal(t, account.Currency, got.Currency)
+				require.Equal(t, account.PublicID, got.AccountID)
+				require.True(t, at.Equal(got.At))
+			},
+		},
+		{
+			name:     "BeforeAccountOpened",
+			username: account.OwnerName,
+			at:       account.CreatedAt.Add(-time.Hour).Format(time.RFC3339),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(account, nil)
+				store.EXPECT().GetLatestBalanceSnapshot(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got accountBalanceResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Zero(t, got.Balance)
+			},
+		},
+		{
+			name:     "Future",
+			username: account.OwnerName,
+			at:       time.Now().Add(time.Hour).Format(time.RFC3339),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "InvalidAt",
+			username: account.OwnerName,
+			at:       "yesterday",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "OtherDepositor",
+			username: util.RandomOwnerName(),
+			at:       at.Format(time.RFC3339),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(account, nil)
+				store.EXPECT().GetLatestBalanceSnapshot(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			path := fmt.Sprintf("/v1/accounts/%s/balance?at=%s", account.PublicID, url.QueryEscape(tc.at))
+			request, err := http.NewRequest(http.MethodGet, path, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
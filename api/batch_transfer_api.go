@@ -0,0 +1,315 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/risk"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+)
+
+//Modes of a batch transfer
+const (
+	batchModeAtomic     = "atomic"
+	batchModeBestEffort = "best_effort"
+)
+
+//Statuses of the items of a batch transfer
+const (
+	batchItemSucceeded   = "succeeded"
+	batchItemFailed      = "failed"
+	batchItemNotExecuted = "not_executed"
+)
+
+type batchTransferItemRequest struct {
+	ToAccountID string `json:"to_account_id" binding:"required,publicid=acc_"`
+	Amount      int64  `json:"amount" binding:"required,gt=0"`
+}
+
+type batchTransferRequest struct {
+	FromAccountID string `json:"from_account_id" binding:"required,publicid=acc_"`
+	Currency      string `json:"currency" binding:"required,currency"`
+	Mode          string `json:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	TOTPCode      string `json:"totp_code" binding:"omitempty,len=6,numeric"`
+	//the size is bounded because an atomic batch keeps all of its accounts locked until it commits
+	Transfers []batchTransferItemRequest `json:"transfers" binding:"required,min=1,max=1000,dive"`
+}
+
+type batchTransferItemResult struct {
+	Index       int               `json:"index"`
+	ToAccountID string            `json:"to_account_id"`
+	Amount      int64             `json:"amount"`
+	Status      string            `json:"status"`
+	Transfer    *transferResponse `json:"transfer,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	Code        string            `json:"code,omitempty"`
+	//RiskDecisionID is set when fraud screening held or blocked the item
+	RiskDecisionID int64 `json:"risk_decision_id,omitempty"`
+}
+
+type batchTransferResponse struct {
+	Mode        string                    `json:"mode"`
+	FromAccount accountResponse           `json:"from_account"`
+	TotalAmount int64                     `json:"total_amount"`
+	Succeeded   int                       `json:"succeeded"`
+	Failed      int                       `json:"failed"`
+	Results     []batchTransferItemResult `json:"results"`
+}
+
+//fail marks the item as failed with the same error body a single transfer would have returned
+func (item *batchTransferItemResult) fail(apiErr *apiError) {
+	item.Status = batchItemFailed
+	item.Error = apiErr.message
+	item.Code = apiErr.code
+}
+
+//createBatchTransfer sends money from one account to many, e.g. for payroll.
+//In atomic mode (the default) either every transfer is made or none, in best effort mode
+//each transfer runs on its own and the response tells which of them failed.
+func (server *Server) createBatchTransfer(ctx *gin.Context) {
+	var req batchTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = batchModeAtomic
+	}
+
+	//the total decides the funds check and the second factor, a sum that wraps around would skip both
+	var sum int64
+	for _, transfer := range req.Transfers {
+		var ok bool
+		if sum, ok = util.AddAmount(sum, transfer.Amount); !ok {
+			err := errors.New("the transfers add up to more than an amount can hold")
+			writeError(ctx, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	authPayload := getAuthPayload(ctx)
+	if fromAccount.OwnerName != authPayload.Username {
+		err := errors.New("from account doesn't belong to the authenticated user")
+		writeError(ctx, http.StatusForbidden, err)
+		return
+	}
+
+	if !getAuthUser(ctx).IsEmailVerified {
+		writeError(ctx, http.StatusForbidden, errEmailNotVerified)
+		return
+	}
+
+	//every destination is checked before any money moves
+	results := make([]batchTransferItemResult, len(req.Transfers))
+	ids := server.publicIDs(ctx, fromAccount)
+	var items []db.BatchTransferItem
+	var toAccounts []db.Account
+	var indexes []int
+	var total int64
+	invalid := 0
+
+	for i, transfer := range req.Transfers {
+		results[i] = batchTransferItemResult{
+			Index:       i,
+			ToAccountID: transfer.ToAccountID,
+			Amount:      transfer.Amount,
+			Status:      batchItemNotExecuted,
+		}
+
+		toAccount, err := server.store.GetAccountByPublicID(ctx, transfer.ToAccountID)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				writeError(ctx, http.StatusInternalServerError, err)
+				return
+			}
+			results[i].fail(accountNotFound(transfer.ToAccountID))
+			invalid++
+			continue
+		}
+
+		if toAccount.Currency != req.Currency {
+			err := fmt.Errorf("account [%s] %s vs %s: %w", toAccount.PublicID, toAccount.Currency, req.Currency, db.ErrCurrencyMismatch)
+			results[i].fail(transferError(err))
+			invalid++
+			continue
+		}
+
+		coolingOff, err := server.coolingOffError(ctx, toAccount, transfer.Amount)
+		if err != nil {
+			writeError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		if coolingOff != nil {
+			results[i].fail(coolingOff)
+			invalid++
+			continue
+		}
+
+		ids.add(toAccount)
+		items = append(items, db.BatchTransferItem{ToAccountID: toAccount.ID, Amount: transfer.Amount})
+		toAccounts = append(toAccounts, toAccount)
+		indexes = append(indexes, i)
+		total += transfer.Amount
+	}
+
+	if invalid > 0 && req.Mode == batchModeAtomic {
+		msg := fmt.Sprintf("%d of %d transfers are invalid, nothing was transferred", invalid, len(req.Transfers))
+		writeError(ctx, http.StatusUnprocessableEntity, newAPIError(http.StatusUnprocessableEntity, codeBatchValidationFailed, msg).with("results", results))
+		return
+	}
+	if len(items) == 0 {
+		msg := "none of the transfers is valid"
+		writeError(ctx, http.StatusUnprocessableEntity, newAPIError(http.StatusUnprocessableEntity, codeBatchValidationFailed, msg).with("results", results))
+		return
+	}
+
+	//a quick refusal of batches that can't finish, every item is checked again under lock with its fees
+	if fromAccount.Balance < total {
+		msg := fmt.Sprintf("account [%s] balance %d is below the batch total %d", fromAccount.PublicID, fromAccount.Balance, total)
+		writeError(ctx, http.StatusUnprocessableEntity, newAPIError(http.StatusUnprocessableEntity, codeInsufficientFunds, msg))
+		return
+	}
+
+	//the second factor is asked for the batch as a whole, splitting a large payment must not avoid it
+	secondFactor, valid := server.requireTOTP(ctx, authPayload.Username, req.TOTPCode, total)
+	if !valid {
+		return
+	}
+
+	//every item is screened before any money moves, so each one held or blocked is recorded for review
+	var screenedItems []db.BatchTransferItem
+	var screenedIndexes []int
+	var screened []risk.Transfer
+	held, blocked := 0, 0
+	for n, item := range items {
+		record, err := server.evaluateTransfer(ctx, riskTransfer{
+			From:     fromAccount,
+			To:       toAccounts[n],
+			Amount:   item.Amount,
+			Currency: req.Currency,
+			Atomic:   req.Mode == batchModeAtomic,
+			Earlier:  screened,
+		})
+		if err != nil {
+			//fail closed, a transfer that couldn't be screened is not made
+			writeError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		screened = append(screened, risk.Transfer{From: fromAccount, To: toAccounts[n], Amount: item.Amount})
+		if record == nil {
+			screenedItems = append(screenedItems, item)
+			screenedIndexes = append(screenedIndexes, indexes[n])
+			continue
+		}
+
+		results[indexes[n]].fail(riskDecisionError(*record))
+		results[indexes[n]].RiskDecisionID = record.ID
+		if record.Decision == db.RiskDecisionBlock {
+			blocked++
+		} else {
+			held++
+		}
+	}
+
+	//the held items are recorded as refused, a reviewer can't make part of an atomic batch
+	if held+blocked > 0 && req.Mode == batchModeAtomic {
+		msg := fmt.Sprintf("%d of %d transfers were held or blocked by fraud screening, nothing was transferred", held+blocked, len(req.Transfers))
+		apiErr := newAPIError(http.StatusUnprocessableEntity, codeTransferNeedsReview, msg)
+		if blocked > 0 {
+			apiErr = newAPIError(http.StatusForbidden, codeTransferBlocked, msg)
+		}
+		writeError(ctx, apiErr.status, apiErr.with("results", results))
+		return
+	}
+	items, indexes = screenedItems, screenedIndexes
+
+	rsp := batchTransferResponse{
+		Mode:        req.Mode,
+		FromAccount: newAccountResponse(fromAccount),
+		Results:     results,
+	}
+
+	if req.Mode == batchModeAtomic {
+		server.runAtomicBatch(ctx, ids, rsp, db.BatchTransferTxParams{
+			FromAccountID: fromAccount.ID,
+			Items:         items,
+			SecondFactor:  secondFactor,
+		}, indexes)
+		return
+	}
+
+	for n, item := range items {
+		result := &rsp.Results[indexes[n]]
+
+		//the code is used up by the first transfer that goes through
+		transfer, err := server.store.TransferTx(ctx, db.TransferTxParams{
+			FromAccountID: fromAccount.ID,
+			ToAccountID:   item.ToAccountID,
+			Amount:        item.Amount,
+			SecondFactor:  secondFactor,
+			RequireFunds:  true,
+		})
+		if err != nil {
+			result.fail(transferError(err))
+			continue
+		}
+		secondFactor = nil
+
+		result.Status = batchItemSucceeded
+		transferRsp := ids.newTransferResponse(transfer.Transfer)
+		result.Transfer = &transferRsp
+		rsp.FromAccount = newAccountResponse(transfer.FromAccount)
+	}
+
+	ids.json(http.StatusOK, rsp.count())
+}
+
+//runAtomicBatch executes the valid items in one transaction and writes the response
+func (server *Server) runAtomicBatch(ctx *gin.Context, ids *publicIDs, rsp batchTransferResponse, arg db.BatchTransferTxParams, indexes []int) {
+	result, err := server.store.BatchTransferTx(ctx, arg)
+	if err != nil {
+		apiErr := transferError(err)
+
+		var itemErr *db.BatchItemError
+		if errors.As(err, &itemErr) && itemErr.Index >= 0 && itemErr.Index < len(indexes) {
+			rsp.Results[indexes[itemErr.Index]].fail(apiErr)
+			apiErr.with("index", indexes[itemErr.Index])
+		}
+
+		writeError(ctx, apiErr.status, apiErr.with("results", rsp.Results))
+		return
+	}
+
+	for n := range result.Transfers {
+		item := &rsp.Results[indexes[n]]
+		item.Status = batchItemSucceeded
+		transferRsp := ids.newTransferResponse(result.Transfers[n].Transfer)
+		item.Transfer = &transferRsp
+	}
+	rsp.FromAccount = newAccountResponse(result.FromAccount)
+
+	ids.json(http.StatusOK, rsp.count())
+}
+
+//count fills in the totals from the item results
+func (rsp batchTransferResponse) count() batchTransferResponse {
+	for _, item := range rsp.Results {
+		switch item.Status {
+		case batchItemSucceeded:
+			rsp.Succeeded++
+			rsp.TotalAmount += item.Amount
+		case batchItemFailed:
+			rsp.Failed++
+		}
+	}
+	return rsp
+}
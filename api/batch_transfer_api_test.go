@@ -0,0 +1,318 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kingsleyocran/simple_bank_bankend/db/mock"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateBatchTransferAPI(t *testing.T) {
+	amount := int64(10)
+
+	account1 := randomAccount(util.RandomOwnerName())
+	account2 := randomAccount(util.RandomOwnerName())
+	account3 := randomAccount(util.RandomOwnerName())
+	account4 := randomAccount(util.RandomOwnerName())
+
+	account1.Currency = util.USD
+	account1.Balance = 100
+	account2.Currency = util.USD
+	account3.Currency = util.USD
+	account4.Currency = util.EUR
+
+	body := func(mode string, toAccounts ...db.Account) gin.H {
+		transfers := []gin.H{}
+		for _, account := range toAccounts {
+			transfers = append(transfers, gin.H{"to_account_id": account.PublicID, "amount": amount})
+		}
+		return gin.H{
+			"from_account_id": account1.PublicID,
+			"currency":        util.USD,
+			"mode":            mode,
+			"transfers":       transfers,
+		}
+	}
+
+	expectAccounts := func(store *mockdb.MockStore, accounts ...db.Account) {
+		for _, account := range accounts {
+			store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(account, nil)
+		}
+	}
+
+	decode := func(t *testing.T, recorder *httptest.ResponseRecorder) batchTransferResponse {
+		var rsp batchTransferResponse
+		err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+		require.NoError(t, err)
+		return rsp
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "AtomicOK",
+			body: body("", account2, account3),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account1, account2, account3)
+
+				arg := db.BatchTransferTxParams{
+					FromAccountID: account1.ID,
+					Items: []db.BatchTransferItem{
+						{ToAccountID: account2.ID, Amount: amount},
+						{ToAccountID: account3.ID, Amount: amount},
+					},
+				}
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.BatchTransferTxResult{
+						Transfers: []db.TransferTxResult{
+							{Transfer: db.Transfer{ID: 1, PublicID: "tr_1", FromAccountID: account1.ID, ToAccountID: account2.ID}},
+							{Transfer: db.Transfer{ID: 2, PublicID: "tr_2", FromAccountID: account1.ID, ToAccountID: account3.ID}},
+						},
+					}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				rsp := decode(t, recorder)
+				require.Equal(t, batchModeAtomic, rsp.Mode)
+				require.Equal(t, 2, rsp.Succeeded)
+				require.Equal(t, 2*amount, rsp.TotalAmount)
+				require.Equal(t, "tr_2", rsp.Results[1].Transfer.ID)
+				require.Equal(t, account3.PublicID, rsp.Results[1].Transfer.ToAccountID)
+			},
+		},
+		{
+			name: "AtomicInvalidItem",
+			body: body(batchModeAtomic, account2, account4),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account1, account2, account4)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+				var rsp struct {
+					Code    string                    `json:"code"`
+					Results []batchTransferItemResult `json:"results"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, "BATCH_VALIDATION_FAILED", rsp.Code)
+				require.Equal(t, batchItemNotExecuted, rsp.Results[0].Status)
+				require.Equal(t, batchItemFailed, rsp.Results[1].Status)
+				require.Equal(t, "CURRENCY_MISMATCH", rsp.Results[1].Code)
+			},
+		},
+		{
+			name: "AtomicItemFailedInTx",
+			body: body(batchModeAtomic, account2, account3),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account1, account2, account3)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.BatchTransferTxResult{}, &db.BatchItemError{
+						Index: 1,
+						Err:   fmt.Errorf("account [%s]: %w", account3.PublicID, db.ErrAccountFrozen),
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+				var rsp struct {
+					Code    string                    `json:"code"`
+					Index   int                       `json:"index"`
+					Results []batchTransferItemResult `json:"results"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, "ACCOUNT_FROZEN", rsp.Code)
+				require.Equal(t, 1, rsp.Index)
+				require.Equal(t, batchItemFailed, rsp.Results[1].Status)
+			},
+		},
+		{
+			name: "BestEffortPartial",
+			body: body(batchModeBestEffort, account2, account4, account3),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account1, account2, account3, account4)
+
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: amount, RequireFunds: true})).
+					Times(1).
+					Return(db.TransferTxResult{Transfer: db.Transfer{ID: 1, FromAccountID: account1.ID, ToAccountID: account2.ID}, FromAccount: account1}, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{FromAccountID: account1.ID, ToAccountID: account3.ID, Amount: amount, RequireFunds: true})).
+					Times(1).
+					Return(db.TransferTxResult{}, &db.TransferLimitError{AccountID: account1.PublicID, Limit: db.LimitMaxDailyCount, Max: 1, Actual: 2})
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				rsp := decode(t, recorder)
+				require.Equal(t, 1, rsp.Succeeded)
+				require.Equal(t, 2, rsp.Failed)
+				require.Equal(t, batchItemSucceeded, rsp.Results[0].Status)
+				require.Equal(t, "CURRENCY_MISMATCH", rsp.Results[1].Code)
+				require.Equal(t, "TRANSFER_LIMIT_EXCEEDED", rsp.Results[2].Code)
+			},
+		},
+		{
+			name: "BestEffortFundsRunOut",
+			body: body(batchModeBestEffort, account2, account3),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account1, account2, account3)
+
+				//fees or a concurrent spend can use up the balance the handler saw
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{Transfer: db.Transfer{ID: 1, FromAccountID: account1.ID, ToAccountID: account2.ID}, FromAccount: account1}, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, fmt.Errorf("account [%s] is 5 short of the amount 10 and its fees: %w", account1.PublicID, db.ErrInsufficientFunds))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				rsp := decode(t, recorder)
+				require.Equal(t, 1, rsp.Succeeded)
+				require.Equal(t, 1, rsp.Failed)
+				require.Equal(t, "INSUFFICIENT_FUNDS", rsp.Results[1].Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			body: gin.H{
+				"from_account_id": account1.PublicID,
+				"currency":        util.USD,
+				"transfers": []gin.H{
+					{"to_account_id": account2.PublicID, "amount": 60},
+					{"to_account_id": account3.PublicID, "amount": 60},
+				},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account1, account2, account3)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			//the wrapped total would be negative and skip the funds check and the second factor
+			name: "TotalOverflows",
+			body: gin.H{
+				"from_account_id": account1.PublicID,
+				"currency":        util.USD,
+				"mode":            batchModeBestEffort,
+				"transfers": []gin.H{
+					{"to_account_id": account2.PublicID, "amount": 5},
+					{"to_account_id": account3.PublicID, "amount": int64(math.MaxInt64)},
+					{"to_account_id": account2.PublicID, "amount": int64(math.MaxInt64)},
+				},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "ToAccountNotFound",
+			body: body(batchModeBestEffort, account2),
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account1)
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account2.PublicID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "FromAccountNotOwned",
+			body: gin.H{
+				"from_account_id": account2.PublicID,
+				"currency":        util.USD,
+				"transfers":       []gin.H{{"to_account_id": account3.PublicID, "amount": amount}},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account2)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidMode",
+			body: body("sometimes", account2),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "EmptyBatch",
+			body: body(batchModeAtomic),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/v1/transfers/batch", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, account1.OwnerName, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
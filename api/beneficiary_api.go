@@ -0,0 +1,216 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+)
+
+//createBeneficiaryRequest saves an account the user sends money to under a nickname
+type createBeneficiaryRequest struct {
+	Nickname  string `json:"nickname" binding:"required,max=64"`
+	AccountID string `json:"account_id" binding:"required,publicid=acc_"`
+	Currency  string `json:"currency" binding:"required,currency"`
+}
+
+//createBeneficiary adds a beneficiary to the address book of the authenticated user
+func (server *Server) createBeneficiary(ctx *gin.Context) {
+	var req createBeneficiaryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	account, valid := server.validAccount(ctx, req.AccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	beneficiary, err := server.store.CreateBeneficiary(ctx, db.CreateBeneficiaryParams{
+		OwnerName: getAuthPayload(ctx).Username,
+		Nickname:  req.Nickname,
+		AccountID: account.ID,
+		Currency:  req.Currency,
+	})
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, server.publicIDs(ctx, account).newBeneficiaryResponse(beneficiary))
+}
+
+//beneficiaryURI takes the beneficiary id as a URI parameter Eg. beneficiaries/:id
+type beneficiaryURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+//getBeneficiary returns one beneficiary of the authenticated user
+func (server *Server) getBeneficiary(ctx *gin.Context) {
+	var uri beneficiaryURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	beneficiary, valid := server.ownBeneficiary(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	ids := server.publicIDs(ctx)
+	ids.json(http.StatusOK, ids.newBeneficiaryResponse(beneficiary))
+}
+
+type listBeneficiariesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+//listBeneficiaries returns the address book of the authenticated user
+func (server *Server) listBeneficiaries(ctx *gin.Context) {
+	var req listBeneficiariesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	beneficiaries, err := server.store.ListBeneficiaries(ctx, db.ListBeneficiariesParams{
+		OwnerName: getAuthPayload(ctx).Username,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ids := server.publicIDs(ctx)
+	ids.json(http.StatusOK, newResponses(beneficiaries, ids.newBeneficiaryResponse))
+}
+
+type updateBeneficiaryRequest struct {
+	Nickname string `json:"nickname" binding:"required,max=64"`
+}
+
+//updateBeneficiary renames a beneficiary. The account can't be changed, otherwise it would skip the cooling-off period,
+//delete the beneficiary and add it again instead.
+func (server *Server) updateBeneficiary(ctx *gin.Context) {
+	var uri beneficiaryURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var req updateBeneficiaryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, valid := server.ownBeneficiary(ctx, uri.ID); !valid {
+		return
+	}
+
+	beneficiary, err := server.store.UpdateBeneficiaryNickname(ctx, db.UpdateBeneficiaryNicknameParams{
+		ID:       uri.ID,
+		Nickname: req.Nickname,
+	})
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ids := server.publicIDs(ctx)
+	ids.json(http.StatusOK, ids.newBeneficiaryResponse(beneficiary))
+}
+
+//deleteBeneficiary removes a beneficiary from the address book, transfers already made to it are kept
+func (server *Server) deleteBeneficiary(ctx *gin.Context) {
+	var uri beneficiaryURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, valid := server.ownBeneficiary(ctx, uri.ID); !valid {
+		return
+	}
+
+	if err := server.store.DeleteBeneficiary(ctx, uri.ID); err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+//ownBeneficiary loads a beneficiary of the authenticated user, whatever the role nobody sees another user's address book.
+//It writes the error response itself so callers only need to return.
+func (server *Server) ownBeneficiary(ctx *gin.Context, id int64) (db.Beneficiary, bool) {
+	beneficiary, err := server.store.GetBeneficiary(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(ctx, http.StatusNotFound, err)
+			return beneficiary, false
+		}
+		writeError(ctx, http.StatusInternalServerError, err)
+		return beneficiary, false
+	}
+
+	if beneficiary.OwnerName != getAuthPayload(ctx).Username {
+		err := errors.New("beneficiary doesn't belong to the authenticated user")
+		writeError(ctx, http.StatusForbidden, err)
+		return beneficiary, false
+	}
+
+	return beneficiary, true
+}
+
+//coolingOff reports whether a transfer of amount to toAccount has to wait, and writes the error response
+//telling the client when it can be sent.
+func (server *Server) coolingOff(ctx *gin.Context, toAccount db.Account, amount int64) bool {
+	apiErr, err := server.coolingOffError(ctx, toAccount, amount)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return true
+	}
+	if apiErr != nil {
+		writeError(ctx, apiErr.status, apiErr)
+		return true
+	}
+	return false
+}
+
+//coolingOffError returns the error refusing a transfer of amount to an account the authenticated user added as a
+//beneficiary too recently, or nil. The beneficiary is looked up by its account, so that naming the account
+//directly, in a batch or in a split doesn't get around the wait.
+func (server *Server) coolingOffError(ctx *gin.Context, toAccount db.Account, amount int64) (*apiError, error) {
+	period := server.config.Transfers.BeneficiaryCoolingOffPeriod
+	if period <= 0 || amount <= server.config.Transfers.BeneficiaryCoolingOffAmount {
+		return nil, nil
+	}
+
+	beneficiary, err := server.store.GetBeneficiaryByAccount(ctx, db.GetBeneficiaryByAccountParams{
+		OwnerName: getAuthPayload(ctx).Username,
+		AccountID: toAccount.ID,
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	availableAt := beneficiary.CreatedAt.Add(period)
+	if !time.Now().Before(availableAt) {
+		return nil, nil
+	}
+
+	msg := "the beneficiary was added too recently to receive this amount"
+	return newAPIError(http.StatusUnprocessableEntity, codeBeneficiaryCoolingOff, msg).with("available_at", availableAt), nil
+}
@@ -0,0 +1,377 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kingsleyocran/simple_bank_bankend/db/mock"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func randomBeneficiary(owner string, account db.Account) db.Beneficiary {
+	return db.Beneficiary{
+		ID:        util.RandomInt(1, 1000),
+		OwnerName: owner,
+		Nickname:  util.RandomOwnerName(),
+		AccountID: account.ID,
+		Currency:  account.Currency,
+		CreatedAt: time.Now().UTC().Add(-48 * time.Hour).Truncate(time.Second),
+	}
+}
+
+//requireBodyMatchBeneficiary checks a beneficiary response, which names its account by public id
+func requireBodyMatchBeneficiary(t *testing.T, recorder *httptest.ResponseRecorder, beneficiary db.Beneficiary, account db.Account) {
+	var got beneficiaryResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &got)
+	require.NoError(t, err)
+	require.Equal(t, beneficiaryResponse{
+		ID:        beneficiary.ID,
+		OwnerName: beneficiary.OwnerName,
+		Nickname:  beneficiary.Nickname,
+		AccountID: account.PublicID,
+		Currency:  beneficiary.Currency,
+		CreatedAt: beneficiary.CreatedAt,
+	}, got)
+}
+
+func TestCreateBeneficiaryAPI(t *testing.T) {
+	owner := util.RandomOwnerName()
+	account := randomAccount(util.RandomOwnerName())
+	account.Currency = util.USD
+	beneficiary := randomBeneficiary(owner, account)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"nickname": beneficiary.Nickname, "account_id": account.PublicID, "currency": account.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(account, nil)
+
+				arg := db.CreateBeneficiaryParams{
+					OwnerName: owner,
+					Nickname:  beneficiary.Nickname,
+					AccountID: account.ID,
+					Currency:  account.Currency,
+				}
+				store.EXPECT().CreateBeneficiary(gomock.Any(), gomock.Eq(arg)).Times(1).Return(beneficiary, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchBeneficiary(t, recorder, beneficiary, account)
+			},
+		},
+		{
+			name: "AccountNotFound",
+			body: gin.H{"nickname": beneficiary.Nickname, "account_id": account.PublicID, "currency": account.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().CreateBeneficiary(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			body: gin.H{"nickname": beneficiary.Nickname, "account_id": account.PublicID, "currency": util.EUR},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(account, nil)
+				store.EXPECT().CreateBeneficiary(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadySaved",
+			body: gin.H{"nickname": beneficiary.Nickname, "account_id": account.PublicID, "currency": account.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					CreateBeneficiary(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Beneficiary{}, &pq.Error{Code: "23505"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "NicknameTooLong",
+			body: gin.H{"nickname": util.RandomString(65), "account_id": account.PublicID, "currency": account.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateBeneficiary(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/v1/beneficiaries", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, owner, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestBeneficiaryByIDAPI(t *testing.T) {
+	owner := util.RandomOwnerName()
+	account := randomAccount(util.RandomOwnerName())
+	beneficiary := randomBeneficiary(owner, account)
+	renamed := beneficiary
+	renamed.Nickname = util.RandomOwnerName()
+
+	testCases := []struct {
+		name          string
+		method        string
+		body          gin.H
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Get",
+			method:   http.MethodGet,
+			username: owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetBeneficiary(gomock.Any(), gomock.Eq(beneficiary.ID)).Times(1).Return(beneficiary, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchBeneficiary(t, recorder, beneficiary, account)
+			},
+		},
+		{
+			name:     "GetNotFound",
+			method:   http.MethodGet,
+			username: owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetBeneficiary(gomock.Any(), gomock.Eq(beneficiary.ID)).Times(1).Return(db.Beneficiary{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "GetOtherUser",
+			method:   http.MethodGet,
+			username: util.RandomOwnerName(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetBeneficiary(gomock.Any(), gomock.Eq(beneficiary.ID)).Times(1).Return(beneficiary, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "Rename",
+			method:   http.MethodPatch,
+			body:     gin.H{"nickname": renamed.Nickname},
+			username: owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetBeneficiary(gomock.Any(), gomock.Eq(beneficiary.ID)).Times(1).Return(beneficiary, nil)
+
+				arg := db.UpdateBeneficiaryNicknameParams{ID: beneficiary.ID, Nickname: renamed.Nickname}
+				store.EXPECT().UpdateBeneficiaryNickname(gomock.Any(), gomock.Eq(arg)).Times(1).Return(renamed, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchBeneficiary(t, recorder, renamed, account)
+			},
+		},
+		{
+			name:     "RenameOtherUser",
+			method:   http.MethodPatch,
+			body:     gin.H{"nickname": renamed.Nickname},
+			username: util.RandomOwnerName(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetBeneficiary(gomock.Any(), gomock.Eq(beneficiary.ID)).Times(1).Return(beneficiary, nil)
+				store.EXPECT().UpdateBeneficiaryNickname(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "Delete",
+			method:   http.MethodDelete,
+			username: owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetBeneficiary(gomock.Any(), gomock.Eq(beneficiary.ID)).Times(1).Return(beneficiary, nil)
+				store.EXPECT().DeleteBeneficiary(gomock.Any(), gomock.Eq(beneficiary.ID)).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name:     "DeleteOtherUser",
+			method:   http.MethodDelete,
+			username: util.RandomOwnerName(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetBeneficiary(gomock.Any(), gomock.Eq(beneficiary.ID)).Times(1).Return(beneficiary, nil)
+				store.EXPECT().DeleteBeneficiary(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var data []byte
+			if tc.body != nil {
+				var err error
+				data, err = json.Marshal(tc.body)
+				require.NoError(t, err)
+			}
+
+			url := fmt.Sprintf("/v1/beneficiaries/%d", beneficiary.ID)
+			request, err := http.NewRequest(tc.method, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCoolingOffByAccountAPI(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+
+	createAccount := func(balance int64) db.Account {
+		user, err := store.CreateUser(ctx, db.CreateUserParams{
+			Username:       util.RandomOwnerName(),
+			HashedPassword: "secret",
+			FullName:       util.RandomOwnerName(),
+			Email:          util.RandomEmail(),
+		})
+		require.NoError(t, err)
+		_, err = store.UpdateUserEmailVerified(ctx, db.UpdateUserEmailVerifiedParams{Username: user.Username, Email: user.Email})
+		require.NoError(t, err)
+
+		account, err := store.CreateAccount(ctx, db.CreateAccountParams{OwnerName: user.Username, Balance: balance, Currency: util.USD})
+		require.NoError(t, err)
+		return account
+	}
+
+	sender := createAccount(1_000)
+	payee := createAccount(0)
+	_, err := store.CreateBeneficiary(ctx, db.CreateBeneficiaryParams{
+		OwnerName: sender.OwnerName,
+		Nickname:  "Payee",
+		AccountID: payee.ID,
+		Currency:  payee.Currency,
+	})
+	require.NoError(t, err)
+
+	server := newTestServer(t, store)
+	server.config.Transfers.BeneficiaryCoolingOffPeriod = 24 * time.Hour
+	server.config.Transfers.BeneficiaryCoolingOffAmount = 200
+
+	send := func(url string, body gin.H) *httptest.ResponseRecorder {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, sender.OwnerName, util.DepositorRole, time.Minute)
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+	batch := func(amount int64) *httptest.ResponseRecorder {
+		return send("/v1/transfers/batch", gin.H{
+			"from_account_id": sender.PublicID,
+			"currency":        util.USD,
+			"transfers":       []gin.H{{"to_account_id": payee.PublicID, "amount": amount}},
+		})
+	}
+	split := func(amount int64) *httptest.ResponseRecorder {
+		return send("/v1/transfers/split", gin.H{
+			"currency": util.USD,
+			"from":     []gin.H{{"account_id": sender.PublicID, "amount": amount}},
+			"to":       []gin.H{{"account_id": payee.PublicID, "amount": amount}},
+		})
+	}
+
+	//a batch fails the item paying the new beneficiary, and atomically the whole batch
+	recorder := batch(300)
+	require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	var batchBody struct {
+		Code    string                    `json:"code"`
+		Results []batchTransferItemResult `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &batchBody))
+	require.Equal(t, "BATCH_VALIDATION_FAILED", batchBody.Code)
+	require.Equal(t, "BENEFICIARY_COOLING_OFF", batchBody.Results[0].Code)
+
+	recorder = split(300)
+	require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "BENEFICIARY_COOLING_OFF")
+
+	//amounts up to the cooling-off amount go through right away
+	require.Equal(t, http.StatusOK, batch(100).Code)
+	require.Equal(t, http.StatusOK, split(100).Code)
+
+	account, err := store.GetAccount(ctx, payee.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(200), account.Balance)
+}
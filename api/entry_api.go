@@ -9,8 +9,8 @@ import (
 )
 
 type createEntryRequest struct {
-	AccountID int64 `json:"account_id" binding:"required"`
-	Amount    int64 `json:"amount" binding:"required"`
+	AccountID string `json:"account_id" binding:"required,publicid=acc_"`
+	Amount    int64  `json:"amount" binding:"required"`
 }
 
 //CreateEntry request and response handler function
@@ -19,13 +19,18 @@ func (server *Server) createEntry(ctx *gin.Context) {
 	//Note that we used ShouldBindJSON for json parameters
 	var req createEntryRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	account, valid := server.accountByPublicID(ctx, req.AccountID)
+	if !valid {
 		return
 	}
 
 	//create arg for query
 	arg := db.CreateEntryParams{
-		AccountID: req.AccountID,
+		AccountID: account.ID,
 		Amount:    req.Amount,
 	}
 
@@ -33,18 +38,18 @@ func (server *Server) createEntry(ctx *gin.Context) {
 	entry, err := server.store.CreateEntry(ctx, arg)
 	if err != nil {
 		//return error as response
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
 	//return StatusOk if passed as response
-	ctx.JSON(http.StatusOK, entry)
+	ctx.JSON(http.StatusOK, server.publicIDs(ctx, account).newEntryResponse(entry))
 }
 
 //Get entry request
-//Takes an id as a URI parameter Eg. accounts/:id
+//Takes the public id as a URI parameter Eg. entries/ent_01HF3Z...
 type getEntryRequest struct {
-	ID int64 `uri:"id" binding:"required,min=1"`
+	ID string `uri:"id" binding:"required,publicid=ent_"`
 }
 
 //getEntry request and response handler function
@@ -52,38 +57,52 @@ func (server *Server) getEntry(ctx *gin.Context) {
 	//Note that for URI parameters we use ShouldBindUri
 	var req getEntryRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	entry, err := server.store.GetEntry(ctx, req.ID)
+	entry, err := server.store.GetEntryByPublicID(ctx, req.ID)
 	if err != nil {
 		//if returns emptyRow error: sql.ErrNoRows
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			writeError(ctx, http.StatusNotFound, err)
 			return
 		}
 
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, entry.AccountID)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if !server.canAccessAccount(ctx, account) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, entry)
+	ctx.JSON(http.StatusOK, server.publicIDs(ctx, account).newEntryResponse(entry))
 }
 
 //listAccount struct to get paginated data
 //Take a query string instead. We use `form:"variable"`
 type listEntriesRequest struct {
-	AccountID int64 `form:"account_id" binding:"required"`
-	PageID    int32 `form:"page_id" binding:"required,min=1"`
-	PageSize  int32 `form:"page_size" binding:"required,min=5,max=10"`
+	AccountID string `form:"account_id" binding:"required,publicid=acc_"`
+	PageID    int32  `form:"page_id" binding:"required,min=1"`
+	PageSize  int32  `form:"page_size" binding:"required,min=5,max=10"`
 }
 
 //listEntry request and response handler function
 func (server *Server) listEntries(ctx *gin.Context) {
 	var req listEntriesRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	account, valid := server.accessibleAccount(ctx, req.AccountID)
+	if !valid {
 		return
 	}
 
@@ -91,16 +110,16 @@ func (server *Server) listEntries(ctx *gin.Context) {
 	//Offset is the number of records that the database should skip,
 	//we we have to calculate it from the page id and page size using this formula: (req.PageID - 1) * req.PageSize
 	arg := db.ListEntriesParams{
-		AccountID: req.AccountID,
+		AccountID: account.ID,
 		Limit:     req.PageSize,
 		Offset:    (req.PageID - 1) * req.PageSize,
 	}
 
 	entries, err := server.store.ListEntries(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, entries)
+	ctx.JSON(http.StatusOK, newResponses(entries, server.publicIDs(ctx, account).newEntryResponse))
 }
@@ -0,0 +1,324 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/lib/pq"
+)
+
+//Stable error codes, clients branch on the code and never on the message.
+//Errors without a code of their own get one from their status, e.g. NOT_FOUND or CONFLICT.
+const (
+	codeValidationFailed       = "VALIDATION_FAILED"
+	codeNotFound               = "NOT_FOUND"
+	codeAccountNotFound        = "ACCOUNT_NOT_FOUND"
+	codeAlreadyExists          = "ALREADY_EXISTS"
+	codeResourceInUse          = "RESOURCE_IN_USE"
+	codeCurrencyMismatch       = "CURRENCY_MISMATCH"
+	codeInsufficientFunds      = "INSUFFICIENT_FUNDS"
+	codeAccountFrozen          = "ACCOUNT_FROZEN"
+	codeInvalidPosting         = "INVALID_POSTING"
+	codeTransferLimitExceeded  = "TRANSFER_LIMIT_EXCEEDED"
+	codeTransactionConflict    = "TRANSACTION_CONFLICT"
+	codeEmailNotVerified       = "EMAIL_NOT_VERIFIED"
+	codeTOTPRequired           = "TOTP_REQUIRED"
+	codeTOTPEnrollmentRequired = "TOTP_ENROLLMENT_REQUIRED"
+	codeInvalidTOTP            = "INVALID_TOTP"
+	codeBeneficiaryCoolingOff  = "BENEFICIARY_COOLING_OFF"
+	codeTransferBlocked        = "TRANSFER_BLOCKED"
+	codeTransferHeldForReview  = "TRANSFER_HELD_FOR_REVIEW"
+	codeTransferNeedsReview    = "TRANSFER_NEEDS_REVIEW"
+	codeBatchValidationFailed  = "BATCH_VALIDATION_FAILED"
+	codeImportRowsInvalid      = "IMPORT_ROWS_INVALID"
+	codeInternal               = "INTERNAL_ERROR"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	requestIDKey    = "request_id"
+)
+
+//errEmailNotVerified is answered to users who try to move money before verifying their email
+var errEmailNotVerified = newAPIError(http.StatusForbidden, codeEmailNotVerified, "email address must be verified before making transfers")
+
+//accountNotFound is answered when an account the request names doesn't exist
+func accountNotFound(accountID string) *apiError {
+	return newAPIError(http.StatusNotFound, codeAccountNotFound, fmt.Sprintf("account [%s] was not found", accountID))
+}
+
+//apiError is an error together with the status and the stable code it is answered with
+type apiError struct {
+	status  int
+	code    string
+	message string
+	details []fieldError
+	extra   gin.H
+}
+
+//fieldError is one rule a request field broke, e.g. {"field":"amount","rule":"gt","message":"must be greater than 0"}
+type fieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func newAPIError(status int, code string, message string) *apiError {
+	return &apiError{status: status, code: code, message: message}
+}
+
+func (apiErr *apiError) Error() string {
+	return apiErr.message
+}
+
+//with adds a field to the body, e.g. the id of the risk decision holding a transfer
+func (apiErr *apiError) with(key string, value interface{}) *apiError {
+	if apiErr.extra == nil {
+		apiErr.extra = gin.H{}
+	}
+	apiErr.extra[key] = value
+	return apiErr
+}
+
+//body is the envelope of every error response
+func (apiErr *apiError) body(requestID string) gin.H {
+	body := gin.H{}
+	for key, value := range apiErr.extra {
+		body[key] = value
+	}
+	body["error"] = apiErr.message
+	body["code"] = apiErr.code
+	body["request_id"] = requestID
+	if len(apiErr.details) > 0 {
+		body["details"] = apiErr.details
+	}
+	return body
+}
+
+//toAPIError is the central mapping from errors to responses. Errors of the store, of postgres and of the request
+//validation get their own status and code, any other error keeps the status chosen by the handler.
+//Messages of unexpected server errors are never sent, they can contain SQL or internal names.
+func toAPIError(status int, err error) *apiError {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		return validationError(fieldErrs)
+	}
+
+	var limitErr *db.TransferLimitError
+	if errors.As(err, &limitErr) {
+		return newAPIError(http.StatusUnprocessableEntity, codeTransferLimitExceeded, limitErr.Error()).with("limit", limitErr)
+	}
+
+	var pqErr *pq.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return newAPIError(http.StatusNotFound, codeNotFound, "the resource was not found")
+	case errors.Is(err, db.ErrAccountFrozen):
+		return newAPIError(http.StatusUnprocessableEntity, codeAccountFrozen, err.Error())
+	case errors.Is(err, db.ErrSecondFactorUsed):
+		return newAPIError(http.StatusForbidden, codeInvalidTOTP, err.Error())
+	case errors.Is(err, db.ErrInsufficientFunds):
+		return newAPIError(http.StatusUnprocessableEntity, codeInsufficientFunds, err.Error())
+	case errors.Is(err, db.ErrInvalidPosting):
+		return newAPIError(http.StatusBadRequest, codeInvalidPosting, err.Error())
+	case errors.Is(err, db.ErrCurrencyMismatch):
+		return newAPIError(http.StatusBadRequest, codeCurrencyMismatch, err.Error())
+	case db.IsRetryableError(err):
+		return newAPIError(http.StatusServiceUnavailable, codeTransactionConflict, "too many concurrent transfers, please retry")
+	case errors.As(err, &pqErr):
+		return postgresError(pqErr)
+	case isBindingError(err):
+		return newAPIError(http.StatusBadRequest, codeValidationFailed, "invalid request: "+err.Error())
+	}
+
+	if status >= http.StatusInternalServerError {
+		return newAPIError(status, codeInternal, "internal server error")
+	}
+	return newAPIError(status, statusCode(status), err.Error())
+}
+
+//postgresError maps the constraint violations a request can cause, anything else is a server error
+func postgresError(pqErr *pq.Error) *apiError {
+	switch string(pqErr.Code) {
+	case db.UniqueViolationCode:
+		return newAPIError(http.StatusConflict, codeAlreadyExists, "the resource already exists")
+	case db.ForeignKeyViolationCode:
+		//deleting a row still referenced vs. referencing a row that doesn't exist
+		if strings.HasPrefix(pqErr.Message, "update or delete") {
+			return newAPIError(http.StatusConflict, codeResourceInUse, "the resource is still in use")
+		}
+		if strings.Contains(pqErr.Constraint, "account_id") {
+			return newAPIError(http.StatusNotFound, codeAccountNotFound, "the account was not found")
+		}
+		return newAPIError(http.StatusNotFound, codeNotFound, "a resource the request refers to was not found")
+	case db.CheckViolationCode, db.NotNullViolationCode, "22P02":
+		return newAPIError(http.StatusBadRequest, codeValidationFailed, "invalid request: a value is not allowed")
+	}
+	return newAPIError(http.StatusInternalServerError, codeInternal, "internal server error")
+}
+
+//isBindingError reports errors of decoding a request before validation, e.g. malformed JSON or a number that isn't one
+func isBindingError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var numErr *strconv.NumError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || errors.As(err, &numErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+//validationError lists every field that failed, named as the client sent it
+func validationError(fieldErrs validator.ValidationErrors) *apiError {
+	apiErr := newAPIError(http.StatusBadRequest, codeValidationFailed, "")
+
+	messages := make([]string, len(fieldErrs))
+	for i, fieldErr := range fieldErrs {
+		field := fieldErr.Namespace()
+		if dot := strings.Index(field, "."); dot >= 0 {
+			field = field[dot+1:]
+		}
+
+		detail := fieldError{Field: field, Rule: fieldErr.Tag(), Message: describeFieldError(fieldErr)}
+		apiErr.details = append(apiErr.details, detail)
+		messages[i] = detail.Field + " " + detail.Message
+	}
+
+	apiErr.message = "invalid request: " + strings.Join(messages, "; ")
+	return apiErr
+}
+
+//describeFieldError turns a failed binding rule into the end of a sentence starting with the field
+func describeFieldError(fieldErr validator.FieldError) string {
+	unit := ""
+	switch fieldErr.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Map:
+		unit = " items"
+	}
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "len":
+		return "must be exactly " + fieldErr.Param() + unit
+	case "min", "gte":
+		return "must be at least " + fieldErr.Param() + unit
+	case "max", "lte":
+		return "must be at most " + fieldErr.Param() + unit
+	case "gt":
+		return "must be greater than " + fieldErr.Param() + unit
+	case "lt":
+		return "must be less than " + fieldErr.Param() + unit
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fieldErr.Param(), " ", ", ")
+	case "email":
+		return "must be an email address"
+	case "alphanum":
+		return "must only contain letters and digits"
+	case "numeric":
+		return "must only contain digits"
+	case "currency":
+		return "must be a supported currency"
+	case "role":
+		return "must be a supported role"
+	case "jsonobject":
+		return "must be a JSON object"
+	case "publicid":
+		return "must be an id starting with " + fieldErr.Param()
+	case "nefield":
+		return "must differ from " + fieldErr.Param()
+	}
+	return "fails the " + fieldErr.Tag() + " rule"
+}
+
+//statusCode is the code of an error that has none of its own, e.g. 404 is NOT_FOUND
+func statusCode(status int) string {
+	return strings.ToUpper(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+}
+
+//writeError writes the error envelope. Server errors are logged with the request id the client is given.
+func writeError(ctx *gin.Context, status int, err error) {
+	apiErr := toAPIError(status, err)
+	if apiErr.code == codeInternal {
+		log.Printf("request %s: %v", requestID(ctx), err)
+	}
+	if apiErr.code == codeTransactionConflict {
+		ctx.Header("Retry-After", "1")
+	}
+	ctx.JSON(apiErr.status, apiErr.body(requestID(ctx)))
+}
+
+//abortWithError writes the error envelope and stops the handler chain, for middlewares
+func abortWithError(ctx *gin.Context, status int, err error) {
+	writeError(ctx, status, err)
+	ctx.Abort()
+}
+
+//requestIDMiddleware gives every request an id, the one sent by the client in X-Request-ID when it is usable.
+//It is returned in the same header and in every error body so a failure can be found in the logs.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		ctx.Set(requestIDKey, id)
+		ctx.Header(requestIDHeader, id)
+		ctx.Next()
+	}
+}
+
+//validRequestID accepts short ids that are safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+//requestID returns the id set by requestIDMiddleware
+func requestID(ctx *gin.Context) string {
+	return ctx.GetString(requestIDKey)
+}
+
+//fieldName names request fields as the client sends them, for the details of VALIDATION_FAILED
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+//recoverError answers a panic with the error envelope instead of an empty 500
+func recoverError(ctx *gin.Context, recovered interface{}) {
+	abortWithError(ctx, http.StatusInternalServerError, fmt.Errorf("panic: %v", recovered))
+}
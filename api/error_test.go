@@ -0,0 +1,174 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kingsleyocran/simple_bank_bankend/db/mock"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+//errorBody is the envelope every error response is written with
+type errorBody struct {
+	Error     string       `json:"error"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id"`
+	Details   []fieldError `json:"details"`
+}
+
+func requireErrorBody(t *testing.T, recorder *httptest.ResponseRecorder, status int, code string) errorBody {
+	require.Equal(t, status, recorder.Code)
+
+	var body errorBody
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	require.Equal(t, code, body.Code)
+	require.NotEmpty(t, body.Error)
+	require.Equal(t, recorder.Header().Get(requestIDHeader), body.RequestID)
+	require.NotEmpty(t, body.RequestID)
+	return body
+}
+
+func TestToAPIError(t *testing.T) {
+	testCases := []struct {
+		name   string
+		status int
+		err    error
+		code   string
+		want   int
+	}{
+		{"NoRows", http.StatusInternalServerError, sql.ErrNoRows, codeNotFound, http.StatusNotFound},
+		{"Unique", http.StatusInternalServerError, &pq.Error{Code: db.UniqueViolationCode}, codeAlreadyExists, http.StatusConflict},
+		{
+			name:   "MissingAccount",
+			status: http.StatusInternalServerError,
+			err:    &pq.Error{Code: db.ForeignKeyViolationCode, Message: "insert or update on table", Constraint: "entries_account_id_fkey"},
+			code:   codeAccountNotFound,
+			want:   http.StatusNotFound,
+		},
+		{
+			name:   "StillReferenced",
+			status: http.StatusInternalServerError,
+			err:    &pq.Error{Code: db.ForeignKeyViolationCode, Message: "update or delete on table", Constraint: "entries_account_id_fkey"},
+			code:   codeResourceInUse,
+			want:   http.StatusConflict,
+		},
+		{"Check", http.StatusInternalServerError, &pq.Error{Code: db.CheckViolationCode}, codeValidationFailed, http.StatusBadRequest},
+		{"InsufficientFunds", http.StatusInternalServerError, db.ErrInsufficientFunds, codeInsufficientFunds, http.StatusUnprocessableEntity},
+		{"Retryable", http.StatusInternalServerError, &pq.Error{Code: "40001"}, codeTransactionConflict, http.StatusServiceUnavailable},
+		{"Internal", http.StatusInternalServerError, errors.New("pq: relation accounts does not exist"), codeInternal, http.StatusInternalServerError},
+		{"Forbidden", http.StatusForbidden, errors.New("not yours"), "FORBIDDEN", http.StatusForbidden},
+		{"Coded", http.StatusInternalServerError, errEmailNotVerified, codeEmailNotVerified, http.StatusForbidden},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			apiErr := toAPIError(tc.status, tc.err)
+			require.Equal(t, tc.code, apiErr.code)
+			require.Equal(t, tc.want, apiErr.status)
+			require.NotContains(t, apiErr.message, "relation")
+		})
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	username := util.RandomOwnerName()
+	account := randomAccount(username)
+
+	t.Run("AccountNotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		expectAuthUser(store)
+		store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+
+		server := newTestServer(t, store)
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/v1/accounts/%s", account.PublicID), nil)
+		require.NoError(t, err)
+		request.Header.Set(requestIDHeader, "req-42")
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+
+		server.router.ServeHTTP(recorder, request)
+		body := requireErrorBody(t, recorder, http.StatusNotFound, codeAccountNotFound)
+		require.Equal(t, "req-42", body.RequestID)
+	})
+
+	t.Run("ValidationFailed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		expectAuthUser(store)
+
+		server := newTestServer(t, store)
+		recorder := httptest.NewRecorder()
+		data := []byte(fmt.Sprintf(`{"from_account_id":%q,"to_account_id":"2","amount":-5,"currency":"XYZ"}`, account.PublicID))
+		request, err := http.NewRequest(http.MethodPost, "/v1/transfers", bytes.NewReader(data))
+		require.NoError(t, err)
+		//an id the logs can't safely hold is replaced
+		request.Header.Set(requestIDHeader, "bad id\n")
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+
+		server.router.ServeHTTP(recorder, request)
+		body := requireErrorBody(t, recorder, http.StatusBadRequest, codeValidationFailed)
+		require.NotEqual(t, "bad id\n", body.RequestID)
+		require.Equal(t, []fieldError{
+			{Field: "to_account_id", Rule: "publicid", Message: "must be an id starting with acc_"},
+			{Field: "amount", Rule: "gt", Message: "must be greater than 0"},
+			{Field: "currency", Rule: "currency", Message: "must be a supported currency"},
+		}, body.Details)
+	})
+
+	t.Run("MalformedJSON", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		expectAuthUser(store)
+
+		server := newTestServer(t, store)
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/v1/transfers", bytes.NewReader([]byte(`{"amount":`)))
+		require.NoError(t, err)
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+
+		server.router.ServeHTTP(recorder, request)
+		requireErrorBody(t, recorder, http.StatusBadRequest, codeValidationFailed)
+	})
+
+	t.Run("InternalErrorHidden", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		expectAuthUser(store)
+		store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, errors.New("pq: connection reset by peer"))
+
+		server := newTestServer(t, store)
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/v1/accounts/%s", account.PublicID), nil)
+		require.NoError(t, err)
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+
+		server.router.ServeHTTP(recorder, request)
+		body := requireErrorBody(t, recorder, http.StatusInternalServerError, codeInternal)
+		require.Equal(t, "internal server error", body.Error)
+	})
+
+	t.Run("RouteNotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		server := newTestServer(t, mockdb.NewMockStore(ctrl))
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/nowhere", nil)
+		require.NoError(t, err)
+
+		server.router.ServeHTTP(recorder, request)
+		requireErrorBody(t, recorder, http.StatusNotFound, codeNotFound)
+	})
+}
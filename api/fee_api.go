@@ -0,0 +1,222 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+)
+
+//productFeesURI takes the product code as a URI parameter Eg. admin/products/:code/fees
+type productFeesURI struct {
+	Code string `uri:"code" binding:"required"`
+}
+
+//productFeeURI takes the product code and the kind of fee Eg. admin/products/:code/fees/transfer
+type productFeeURI struct {
+	Code string `uri:"code" binding:"required"`
+	Kind string `uri:"kind" binding:"required,oneof=maintenance transfer"`
+}
+
+//setProductFeeRequest is a flat amount plus a rate in millionths (10000 is 1%), a max amount of 0 means no cap
+type setProductFeeRequest struct {
+	FlatAmount int64 `json:"flat_amount" binding:"min=0"`
+	RatePpm    int64 `json:"rate_ppm" binding:"min=0,max=1000000"`
+	MaxAmount  int64 `json:"max_amount" binding:"min=0"`
+}
+
+//setProductFee creates or replaces one fee of a product, it applies to every account on the product from now on
+func (server *Server) setProductFee(ctx *gin.Context) {
+	var uri productFeeURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var req setProductFeeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if !server.existingProduct(ctx, uri.Code) {
+		return
+	}
+
+	fee, err := server.store.UpsertProductFee(ctx, db.UpsertProductFeeParams{
+		ProductCode: uri.Code,
+		Kind:        uri.Kind,
+		FlatAmount:  req.FlatAmount,
+		RatePpm:     req.RatePpm,
+		MaxAmount:   req.MaxAmount,
+	})
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newProductFeeResponse(fee))
+}
+
+//deleteProductFee stops charging one fee of a product, deleting a fee that is not set is not an error
+func (server *Server) deleteProductFee(ctx *gin.Context) {
+	var uri productFeeURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	err := server.store.DeleteProductFee(ctx, db.DeleteProductFeeParams{
+		ProductCode: uri.Code,
+		Kind:        uri.Kind,
+	})
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+//listProductFees returns the fee schedule of a product ordered by kind
+func (server *Server) listProductFees(ctx *gin.Context) {
+	var uri productFeesURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if !server.existingProduct(ctx, uri.Code) {
+		return
+	}
+
+	fees, err := server.store.ListProductFees(ctx, uri.Code)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newResponses(fees, newProductFeeResponse))
+}
+
+//existingProduct writes a 404 when the product does not exist
+func (server *Server) existingProduct(ctx *gin.Context, code string) bool {
+	if _, err := server.store.GetAccountProduct(ctx, code); err != nil {
+		if err == sql.ErrNoRows {
+			writeError(ctx, http.StatusNotFound, err)
+			return false
+		}
+		writeError(ctx, http.StatusInternalServerError, err)
+		return false
+	}
+	return true
+}
+
+//feeRevenueAccountURI takes the currency as a URI parameter Eg. admin/fee_revenue_accounts/USD
+type feeRevenueAccountURI struct {
+	Currency string `uri:"currency" binding:"required,currency"`
+}
+
+type setFeeRevenueAccountRequest struct {
+	AccountID string `json:"account_id" binding:"required,publicid=acc_"`
+}
+
+//setFeeRevenueAccount chooses the account fees charged in a currency are paid into
+func (server *Server) setFeeRevenueAccount(ctx *gin.Context) {
+	var uri feeRevenueAccountURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var req setFeeRevenueAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	account, valid := server.validAccount(ctx, req.AccountID, uri.Currency)
+	if !valid {
+		return
+	}
+
+	revenue, err := server.store.UpsertFeeRevenueAccount(ctx, db.UpsertFeeRevenueAccountParams{
+		Currency:  uri.Currency,
+		AccountID: account.ID,
+	})
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, server.publicIDs(ctx, account).newFeeRevenueResponse(revenue))
+}
+
+type quoteTransferRequest struct {
+	FromAccountID string `json:"from_account_id" binding:"required,publicid=acc_"`
+	ToAccountID   string `json:"to_account_id" binding:"required,publicid=acc_"`
+	Amount        int64  `json:"amount" binding:"required,gt=0"`
+	Currency      string `json:"currency" binding:"required,currency"`
+}
+
+//feeQuoteResponse is a fee a transfer would be charged
+type feeQuoteResponse struct {
+	Kind   string `json:"kind"`
+	Amount int64  `json:"amount"`
+}
+
+//quoteTransferResponse is what sending amount would cost, TotalDebit is taken from the source account
+type quoteTransferResponse struct {
+	Amount     int64              `json:"amount"`
+	Fees       []feeQuoteResponse `json:"fees"`
+	TotalFees  int64              `json:"total_fees"`
+	TotalDebit int64              `json:"total_debit"`
+}
+
+//quoteTransfer returns the fees a transfer would be charged without sending it.
+//It runs the same checks as createTransfer up to the second factor, so a quote is only given for a transfer that could be made.
+func (server *Server) quoteTransfer(ctx *gin.Context) {
+	var req quoteTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	authPayload := getAuthPayload(ctx)
+	if fromAccount.OwnerName != authPayload.Username {
+		err := errors.New("from account doesn't belong to the authenticated user")
+		writeError(ctx, http.StatusForbidden, err)
+		return
+	}
+
+	_, valid = server.validAccount(ctx, req.ToAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	fees, err := db.QuoteTransferFees(ctx, server.store, fromAccount.ID, req.Amount)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := quoteTransferResponse{
+		Amount:     req.Amount,
+		Fees:       make([]feeQuoteResponse, len(fees)),
+		TotalDebit: req.Amount,
+	}
+	for i, fee := range fees {
+		rsp.Fees[i] = feeQuoteResponse{Kind: fee.Kind, Amount: fee.Amount}
+		rsp.TotalFees += fee.Amount
+	}
+	rsp.TotalDebit += rsp.TotalFees
+
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kingsleyocran/simple_bank_bankend/db/mock"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetProductFeeAPI(t *testing.T) {
+	product := randomAccountProduct()
+
+	testCases := []struct {
+		name          string
+		kind          string
+		body          gin.H
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			kind: db.FeeTransfer,
+			body: gin.H{"flat_amount": 10, "rate_ppm": 10_000, "max_amount": 50},
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountProduct(gomock.Any(), gomock.Eq(product.Code)).Times(1).Return(product, nil)
+
+				arg := db.UpsertProductFeeParams{
+					ProductCode: product.Code,
+					Kind:        db.FeeTransfer,
+					FlatAmount:  10,
+					RatePpm:     10_000,
+					MaxAmount:   50,
+				}
+				store.EXPECT().
+					UpsertProductFee(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.ProductFee{ProductCode: product.Code, Kind: db.FeeTransfer, FlatAmount: 10, RatePpm: 10_000, MaxAmount: 50}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ProductNotFound",
+			kind: db.FeeMaintenance,
+			body: gin.H{"flat_amount": 10},
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountProduct(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountProduct{}, sql.ErrNoRows)
+				store.EXPECT().UpsertProductFee(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidKind",
+			kind: "overdraft",
+			body: gin.H{"flat_amount": 10},
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertProductFee(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NegativeAmount",
+			kind: db.FeeTransfer,
+			body: gin.H{"flat_amount": -1},
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertProductFee(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "Banker",
+			kind: db.FeeTransfer,
+			body: gin.H{"flat_amount": 10},
+			role: util.BankerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertProductFee(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/v1/admin/products/%s/fees/%s", product.Code, tc.kind)
+			request, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, util.RandomOwnerName(), tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestQuoteTransferAPI(t *testing.T) {
+	user := util.RandomOwnerName()
+	account1 := randomAccount(user)
+	account2 := randomAccount(util.RandomOwnerName())
+	account2.Currency = account1.Currency
+
+	testCases := []struct {
+		name          string
+		username      string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user,
+			body:     gin.H{"from_account_id": account1.PublicID, "to_account_id": account2.PublicID, "amount": 500, "currency": account1.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account2.PublicID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					ListAccountFees(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).
+					Return([]db.ProductFee{
+						{Kind: db.FeeMaintenance, FlatAmount: 100},
+						{Kind: db.FeeTransfer, FlatAmount: 10, RatePpm: 10_000},
+					}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got quoteTransferResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, []feeQuoteResponse{{Kind: db.FeeTransfer, Amount: 15}}, got.Fees)
+				require.Equal(t, int64(15), got.TotalFees)
+				require.Equal(t, int64(515), got.TotalDebit)
+			},
+		},
+		{
+			name:     "NoProduct",
+			username: user,
+			body:     gin.H{"from_account_id": account1.PublicID, "to_account_id": account2.PublicID, "amount": 500, "currency": account1.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account2.PublicID)).Times(1).Return(account2, nil)
+				store.EXPECT().ListAccountFees(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return([]db.ProductFee{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got quoteTransferResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Empty(t, got.Fees)
+				require.Equal(t, int64(500), got.TotalDebit)
+			},
+		},
+		{
+			name:     "UnauthorizedUser",
+			username: util.RandomOwnerName(),
+			body:     gin.H{"from_account_id": account1.PublicID, "to_account_id": account2.PublicID, "amount": 500, "currency": account1.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(account1, nil)
+				store.EXPECT().ListAccountFees(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "InvalidAmount",
+			username: user,
+			body:     gin.H{"from_account_id": account1.PublicID, "to_account_id": account2.PublicID, "amount": 0, "currency": account1.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/v1/transfers/quote", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
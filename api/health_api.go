@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+)
+
+//readinessCheckTimeout bounds how long a single dependency check may take
+const readinessCheckTimeout = 2 * time.Second
+
+//checkResult is the status of one dependency as reported by /readyz
+type checkResult struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+//readinessCheck is a named dependency check run by /readyz
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+//healthz only tells the orchestrator that the process is alive and serving requests
+func (server *Server) healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+//readyz reports whether the server can take traffic. It returns 503 while draining
+//or when any of the dependency checks fails.
+func (server *Server) readyz(ctx *gin.Context) {
+	if server.isDraining() {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+		return
+	}
+
+	checks := []readinessCheck{
+		{name: "database", check: server.store.Ping},
+		{name: "migration", check: server.checkMigrationVersion},
+	}
+
+	status := http.StatusOK
+	results := make(map[string]checkResult, len(checks))
+	for _, c := range checks {
+		result := runCheck(ctx.Request.Context(), c)
+		if result.Status != "up" {
+			status = http.StatusServiceUnavailable
+		}
+		results[c.name] = result
+	}
+
+	body := gin.H{"status": "ready", "checks": results}
+	if status != http.StatusOK {
+		body["status"] = "unavailable"
+	}
+	ctx.JSON(status, body)
+}
+
+//runCheck runs a single check with a timeout and measures how long it took
+func runCheck(ctx context.Context, c readinessCheck) checkResult {
+	checkCtx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.check(checkCtx)
+	result := checkResult{
+		Status:  "up",
+		Latency: time.Since(start).String(),
+	}
+	if err != nil {
+		result.Status = "down"
+		result.Error = err.Error()
+	}
+	return result
+}
+
+//checkMigrationVersion makes sure the database schema is exactly the one this binary was built for
+func (server *Server) checkMigrationVersion(ctx context.Context) error {
+	version, dirty, err := server.store.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("migration %d is dirty", version)
+	}
+
+	if version != db.MigrationVersion {
+		return fmt.Errorf("schema version %d does not match expected version %d", version, db.MigrationVersion)
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kingsleyocran/simple_bank_bankend/db/mock"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthzAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/healthz", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestReadyzAPI(t *testing.T) {
+	testCases := []struct {
+		name          string
+		draining      bool
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					Ping(gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					SchemaVersion(gomock.Any()).
+					Times(1).
+					Return(int64(db.MigrationVersion), false, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				body := requireReadinessBody(t, recorder)
+				require.Equal(t, "ready", body.Status)
+				require.Equal(t, "up", body.Checks["database"].Status)
+				require.Equal(t, "up", body.Checks["migration"].Status)
+				require.NotEmpty(t, body.Checks["database"].Latency)
+			},
+		},
+		{
+			name: "DatabaseDown",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					Ping(gomock.Any()).
+					Times(1).
+					Return(context.DeadlineExceeded)
+				store.EXPECT().
+					SchemaVersion(gomock.Any()).
+					Times(1).
+					Return(int64(0), false, context.DeadlineExceeded)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				body := requireReadinessBody(t, recorder)
+				require.Equal(t, "unavailable", body.Status)
+				require.Equal(t, "down", body.Checks["database"].Status)
+				require.NotEmpty(t, body.Checks["database"].Error)
+			},
+		},
+		{
+			name: "MigrationMismatch",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					Ping(gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					SchemaVersion(gomock.Any()).
+					Times(1).
+					Return(int64(db.MigrationVersion-1), false, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				body := requireReadinessBody(t, recorder)
+				require.Equal(t, "up", body.Checks["database"].Status)
+				require.Equal(t, "down", body.Checks["migration"].Status)
+			},
+		},
+		{
+			name: "MigrationDirty",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					Ping(gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					SchemaVersion(gomock.Any()).
+					Times(1).
+					Return(int64(db.MigrationVersion), true, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+			},
+		},
+		{
+			name:     "Draining",
+			draining: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					Ping(gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					SchemaVersion(gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				body := requireReadinessBody(t, recorder)
+				require.Equal(t, "draining", body.Status)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			if tc.draining {
+				err := server.Shutdown(context.Background())
+				require.NoError(t, err)
+			}
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/readyz", nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+type readinessBody struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+func requireReadinessBody(t *testing.T, recorder *httptest.ResponseRecorder) readinessBody {
+	var body readinessBody
+	err := json.Unmarshal(recorder.Body.Bytes(), &body)
+	require.NoError(t, err)
+	return body
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kingsleyocran/simple_bank_bankend/importer"
+)
+
+//maxImportFileSize bounds the upload, the whole file is held in memory while it is validated
+const maxImportFileSize = 10 << 20
+
+//importRequest is the multipart form of an import, the CSV file is sent in the "file" field
+type importRequest struct {
+	Kind      string `form:"kind" binding:"required,oneof=users accounts transfers"`
+	DryRun    bool   `form:"dry_run"`
+	ChunkSize int    `form:"chunk_size" binding:"omitempty,min=1,max=1000"`
+}
+
+//createImport loads a CSV file of users, accounts or opening transfers.
+//Rows are validated with the same rules as the API, a dry run only returns the report.
+//Uploading the same file again resumes an import that stopped part way.
+func (server *Server) createImport(ctx *gin.Context) {
+	//the limit is checked up front when the size is known, the reader stops uploads that lie about it
+	if ctx.Request.ContentLength > maxImportFileSize {
+		err := fmt.Errorf("the upload must not be larger than %d bytes", maxImportFileSize)
+		writeError(ctx, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportFileSize)
+
+	var req importRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	defer file.Close()
+
+	report, err := importer.New(server.store).Import(ctx, file, importer.Options{
+		Kind:                req.Kind,
+		DryRun:              req.DryRun,
+		ChunkSize:           req.ChunkSize,
+		CreatedBy:           getAuthPayload(ctx).Username,
+		VerifyEmailDuration: server.config.Auth.VerifyEmailDuration,
+		AfterCreateUser:     server.sendVerifyEmail,
+	})
+	if err != nil {
+		if errors.Is(err, importer.ErrInvalidFile) {
+			writeError(ctx, http.StatusBadRequest, err)
+			return
+		}
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	//a real import with rejected rows is only partly done, the report says how far it got
+	if len(report.Errors) > 0 && !req.DryRun {
+		msg := fmt.Sprintf("the file has %d errors, nothing after the last committed chunk was imported", len(report.Errors))
+		writeError(ctx, http.StatusUnprocessableEntity, newAPIError(http.StatusUnprocessableEntity, codeImportRowsInvalid, msg).with("report", report))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
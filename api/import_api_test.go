@@ -0,0 +1,202 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kingsleyocran/simple_bank_bankend/db/mock"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/importer"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateImportAPI(t *testing.T) {
+	admin := util.RandomOwnerName()
+	owner := util.RandomOwnerName()
+	accounts := "owner_name,currency\n" + owner + ",USD\n" + owner + ",EUR\n"
+
+	expectNewJob := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetImportJobByHash(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(db.ImportJob{}, sql.ErrNoRows)
+		store.EXPECT().
+			ListAccountsByOwner(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return([]db.Account{}, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		fields        map[string]string
+		file          string
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			fields: map[string]string{"kind": importer.KindAccounts},
+			file:   accounts,
+			role:   util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectNewJob(store)
+				store.EXPECT().
+					CreateImportJob(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateImportJobParams) (db.ImportJob, error) {
+						require.Equal(t, admin, arg.CreatedBy)
+						require.Equal(t, int64(2), arg.TotalRows)
+						return db.ImportJob{ID: 1, Kind: arg.Kind, TotalRows: arg.TotalRows, Status: db.ImportJobRunning}, nil
+					})
+				store.EXPECT().
+					ImportTx(gomock.Any(), gomock.Eq(db.ImportTxParams{
+						JobID: 1,
+						Accounts: []db.CreateAccountParams{
+							{OwnerName: owner, Currency: util.USD},
+							{OwnerName: owner, Currency: util.EUR},
+						},
+					})).
+					Times(1).
+					Return(db.ImportTxResult{Job: db.ImportJob{ID: 1, CommittedRows: 2, Status: db.ImportJobCompleted}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var report importer.Report
+				err := json.Unmarshal(recorder.Body.Bytes(), &report)
+				require.NoError(t, err)
+				require.Equal(t, 2, report.CommittedRows)
+				require.Equal(t, db.ImportJobCompleted, report.Status)
+			},
+		},
+		{
+			name:   "DryRun",
+			fields: map[string]string{"kind": importer.KindAccounts, "dry_run": "true"},
+			file:   accounts + "bad-name,XYZ\n",
+			role:   util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectNewJob(store)
+				store.EXPECT().CreateImportJob(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ImportTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var report importer.Report
+				err := json.Unmarshal(recorder.Body.Bytes(), &report)
+				require.NoError(t, err)
+				require.True(t, report.DryRun)
+				require.Equal(t, 2, report.ValidRows)
+				require.Len(t, report.Errors, 2)
+				require.Equal(t, 4, report.Errors[0].Line)
+			},
+		},
+		{
+			name:   "InvalidRows",
+			fields: map[string]string{"kind": importer.KindAccounts},
+			file:   accounts + "bad-name,USD\n",
+			role:   util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectNewJob(store)
+				store.EXPECT().CreateImportJob(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ImportTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidFile",
+			fields: map[string]string{"kind": importer.KindTransfers},
+			file:   accounts,
+			role:   util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetImportJobByHash(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidKind",
+			fields: map[string]string{"kind": "loans"},
+			file:   accounts,
+			role:   util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetImportJobByHash(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "TooLarge",
+			fields: map[string]string{"kind": importer.KindAccounts},
+			file:   accounts + strings.Repeat(owner+",USD\n", maxImportFileSize/len(owner+",USD\n")),
+			role:   util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetImportJobByHash(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+			},
+		},
+		{
+			name:   "Banker",
+			fields: map[string]string{"kind": importer.KindAccounts},
+			file:   accounts,
+			role:   util.BankerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetImportJobByHash(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body bytes.Buffer
+			writer := multipart.NewWriter(&body)
+			for name, value := range tc.fields {
+				require.NoError(t, writer.WriteField(name, value))
+			}
+			part, err := writer.CreateFormFile("file", "import.csv")
+			require.NoError(t, err)
+			_, err = part.Write([]byte(tc.file))
+			require.NoError(t, err)
+			require.NoError(t, writer.Close())
+
+			request, err := http.NewRequest(http.MethodPost, "/v1/admin/imports", &body)
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", writer.FormDataContentType())
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, admin, tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
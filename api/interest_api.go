@@ -0,0 +1,183 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+)
+
+//createAccountProductRequest defines a product accounts can be put on, the rate is in millionths (25000 is 2.5%)
+type createAccountProductRequest struct {
+	Code          string `json:"code" binding:"required,alphanum,max=32"`
+	Name          string `json:"name" binding:"required"`
+	AnnualRatePpm int64  `json:"annual_rate_ppm" binding:"min=0,max=1000000"`
+}
+
+//createAccountProduct adds an interest bearing product, products are never changed so that past accruals stay explainable
+func (server *Server) createAccountProduct(ctx *gin.Context) {
+	var req createAccountProductRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	product, err := server.store.CreateAccountProduct(ctx, db.CreateAccountProductParams{
+		Code:          req.Code,
+		Name:          req.Name,
+		AnnualRatePpm: req.AnnualRatePpm,
+	})
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newProductResponse(product))
+}
+
+//listAccountProducts returns every product ordered by code
+func (server *Server) listAccountProducts(ctx *gin.Context) {
+	products, err := server.store.ListAccountProducts(ctx)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newResponses(products, newProductResponse))
+}
+
+//accountProductURI takes the public id of the account as a URI parameter Eg. admin/accounts/acc_01HF3Z.../product
+type accountProductURI struct {
+	ID string `uri:"id" binding:"required,publicid=acc_"`
+}
+
+type setAccountProductRequest struct {
+	ProductCode string `json:"product_code" binding:"required"`
+}
+
+//setAccountProduct puts an account on a product, the account earns interest from the end of today.
+//Moving it to another product keeps the interest accrued so far.
+func (server *Server) setAccountProduct(ctx *gin.Context) {
+	var uri accountProductURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var req setAccountProductRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	account, valid := server.accountByPublicID(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	if _, err := server.store.GetAccountProduct(ctx, req.ProductCode); err != nil {
+		if err == sql.ErrNoRows {
+			writeError(ctx, http.StatusNotFound, err)
+			return
+		}
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	interest, err := server.store.UpsertAccountInterest(ctx, db.UpsertAccountInterestParams{
+		AccountID:      account.ID,
+		ProductCode:    req.ProductCode,
+		AccruedThrough: db.InterestDay(time.Now()).AddDate(0, 0, -1),
+	})
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, server.publicIDs(ctx, account).newInterestResponse(interest))
+}
+
+//interestExpenseAccountURI takes the currency as a URI parameter Eg. admin/interest_expense_accounts/USD
+type interestExpenseAccountURI struct {
+	Currency string `uri:"currency" binding:"required,currency"`
+}
+
+type setInterestExpenseAccountRequest struct {
+	AccountID string `json:"account_id" binding:"required,publicid=acc_"`
+}
+
+//setInterestExpenseAccount chooses the account interest in a currency is paid from.
+//It is the bank's own account, its balance goes negative by the interest paid.
+func (server *Server) setInterestExpenseAccount(ctx *gin.Context) {
+	var uri interestExpenseAccountURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var req setInterestExpenseAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	account, valid := server.validAccount(ctx, req.AccountID, uri.Currency)
+	if !valid {
+		return
+	}
+
+	expense, err := server.store.UpsertInterestExpenseAccount(ctx, db.UpsertInterestExpenseAccountParams{
+		Currency:  uri.Currency,
+		AccountID: account.ID,
+	})
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, server.publicIDs(ctx, account).newInterestExpenseResponse(expense))
+}
+
+//accountInterestResponse is the interest of an account together with its product
+type accountInterestResponse struct {
+	interestResponse
+	Product productResponse `json:"product"`
+}
+
+//getAccountInterest returns the product of an account and the interest accrued but not paid yet
+func (server *Server) getAccountInterest(ctx *gin.Context) {
+	var uri accountProductURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	account, valid := server.accessibleAccount(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	interest, err := server.store.GetAccountInterest(ctx, account.ID)
+	if err != nil {
+		//an account without a product earns no interest
+		if err == sql.ErrNoRows {
+			writeError(ctx, http.StatusNotFound, err)
+			return
+		}
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	product, err := server.store.GetAccountProduct(ctx, interest.ProductCode)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accountInterestResponse{
+		interestResponse: server.publicIDs(ctx, account).newInterestResponse(interest),
+		Product:          newProductResponse(product),
+	})
+}
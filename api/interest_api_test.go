@@ -0,0 +1,303 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kingsleyocran/simple_bank_bankend/db/mock"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func randomAccountProduct() db.AccountProduct {
+	return db.AccountProduct{
+		Code:          util.RandomString(8),
+		Name:          "Savings",
+		AnnualRatePpm: util.RandomInt(0, 100_000),
+	}
+}
+
+func TestCreateAccountProductAPI(t *testing.T) {
+	product := randomAccountProduct()
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"code": product.Code, "name": product.Name, "annual_rate_ppm": product.AnnualRatePpm},
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateAccountProductParams{
+					Code:          product.Code,
+					Name:          product.Name,
+					AnnualRatePpm: product.AnnualRatePpm,
+				}
+				store.EXPECT().CreateAccountProduct(gomock.Any(), gomock.Eq(arg)).Times(1).Return(product, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got db.AccountProduct
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, product, got)
+			},
+		},
+		{
+			name: "DuplicateCode",
+			body: gin.H{"code": product.Code, "name": product.Name, "annual_rate_ppm": product.AnnualRatePpm},
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAccountProduct(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.AccountProduct{}, &pq.Error{Code: "23505"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "RateAboveHundredPercent",
+			body: gin.H{"code": product.Code, "name": product.Name, "annual_rate_ppm": 1_000_001},
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccountProduct(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "Banker",
+			body: gin.H{"code": product.Code, "name": product.Name, "annual_rate_ppm": product.AnnualRatePpm},
+			role: util.BankerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccountProduct(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/v1/admin/products", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, util.RandomOwnerName(), tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestSetAccountProductAPI(t *testing.T) {
+	account := randomAccount(util.RandomOwnerName())
+	product := randomAccountProduct()
+	yesterday := db.InterestDay(time.Now()).AddDate(0, 0, -1)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"product_code": product.Code},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountProduct(gomock.Any(), gomock.Eq(product.Code)).Times(1).Return(product, nil)
+
+				//the account earns interest from the end of today
+				arg := db.UpsertAccountInterestParams{
+					AccountID:      account.ID,
+					ProductCode:    product.Code,
+					AccruedThrough: yesterday,
+				}
+				store.EXPECT().
+					UpsertAccountInterest(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.AccountInterest{AccountID: account.ID, ProductCode: product.Code, AccruedThrough: yesterday}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ProductNotFound",
+			body: gin.H{"product_code": product.Code},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountProduct(gomock.Any(), gomock.Any()).Times(1).Return(db.AccountProduct{}, sql.ErrNoRows)
+				store.EXPECT().UpsertAccountInterest(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "AccountNotFound",
+			body: gin.H{"product_code": product.Code},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().UpsertAccountInterest(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "MissingProduct",
+			body: gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/v1/admin/accounts/%s/product", account.PublicID)
+			request, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, util.RandomOwnerName(), util.AdminRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGetAccountInterestAPI(t *testing.T) {
+	account := randomAccount(util.RandomOwnerName())
+	product := randomAccountProduct()
+	interest := db.AccountInterest{
+		AccountID:   account.ID,
+		ProductCode: product.Code,
+		Accrued:     util.RandomMoney(),
+		Paid:        util.RandomMoney(),
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: account.OwnerName,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountInterest(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(interest, nil)
+				store.EXPECT().GetAccountProduct(gomock.Any(), gomock.Eq(product.Code)).Times(1).Return(product, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got accountInterestResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, interest.Accrued, got.Accrued)
+				require.Equal(t, newProductResponse(product), got.Product)
+			},
+		},
+		{
+			name:     "NoProduct",
+			username: account.OwnerName,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountInterest(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.AccountInterest{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "OtherDepositor",
+			username: util.RandomOwnerName(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountInterest(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/v1/accounts/%s/interest", account.PublicID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -3,10 +3,38 @@ package api
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/mail"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
 )
 
+func newTestServer(t *testing.T, store db.Store) *Server {
+	config := util.Config{
+		Server: util.ServerConfig{
+			ShutdownDrainPeriod: time.Millisecond,
+			ShutdownTimeout:     time.Second,
+			AppBaseURL:          "http://localhost:8080",
+		},
+		Auth: util.AuthConfig{
+			TokenSymmetricKey:     util.RandomString(32),
+			AccessTokenDuration:   time.Minute,
+			VerifyEmailDuration:   time.Hour,
+			ResetPasswordDuration: time.Hour,
+			TOTPIssuer:            "SimpleBank",
+			TOTPTransferThreshold: 1000,
+		},
+	}
+
+	server, err := NewServer(config, store, mail.NewMemoryMailer())
+	require.NoError(t, err)
+
+	return server
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode)
 	os.Exit(m.Run())
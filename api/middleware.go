@@ -0,0 +1,255 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/ratelimit"
+	"github.com/kingsleyocran/simple_bank_bankend/tlsconfig"
+	"github.com/kingsleyocran/simple_bank_bankend/token"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+)
+
+const (
+	authorizationHeaderKey  = "authorization"
+	authorizationTypeBearer = "bearer"
+	authorizationPayloadKey = "authorization_payload"
+	authorizationUserKey    = "authorization_user"
+)
+
+//bearerPayload reads the bearer token from the Authorization header and verifies it
+func bearerPayload(ctx *gin.Context, tokenMaker token.Maker) (*token.Payload, error) {
+	authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
+	if len(authorizationHeader) == 0 {
+		return nil, errors.New("authorization header is not provided")
+	}
+
+	fields := strings.Fields(authorizationHeader)
+	if len(fields) != 2 {
+		return nil, errors.New("invalid authorization header format")
+	}
+
+	authorizationType := strings.ToLower(fields[0])
+	if authorizationType != authorizationTypeBearer {
+		return nil, fmt.Errorf("unsupported authorization type %s", authorizationType)
+	}
+
+	return tokenMaker.VerifyToken(fields[1])
+}
+
+//authMiddleware rejects requests without a valid access token and stores the token payload
+//and the user in the context. Tokens issued before the user last changed their password are revoked.
+//A service client without a token is authenticated by its client certificate instead.
+func authMiddleware(tokenMaker token.Maker, store db.Store, services tlsconfig.ServiceIdentities) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.GetHeader(authorizationHeaderKey) == "" {
+			if identity, ok := services.Lookup(ctx.Request.TLS); ok {
+				serviceAuth(ctx, identity)
+				return
+			}
+		}
+
+		payload, err := bearerPayload(ctx, tokenMaker)
+		if err != nil {
+			abortWithError(ctx, http.StatusUnauthorized, err)
+			return
+		}
+
+		user, err := store.GetUser(ctx, payload.Username)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				err = errors.New("user of the access token doesn't exist")
+				abortWithError(ctx, http.StatusUnauthorized, err)
+				return
+			}
+			abortWithError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		if payload.IssuedAt.Before(user.PasswordChangedAt) {
+			err = errors.New("access token was issued before the password was changed")
+			abortWithError(ctx, http.StatusUnauthorized, err)
+			return
+		}
+
+		ctx.Set(authorizationPayloadKey, payload)
+		ctx.Set(authorizationUserKey, user)
+		ctx.Next()
+	}
+}
+
+//serviceAuth stores a payload and a user standing for the service, so the role checks and the
+//records of who did what work the same as for users. Services have no password or TOTP of their own.
+func serviceAuth(ctx *gin.Context, identity tlsconfig.ServiceIdentity) {
+	payload, err := token.NewPayload(identity.Username(), identity.Role, 0)
+	if err != nil {
+		abortWithError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Set(authorizationPayloadKey, payload)
+	ctx.Set(authorizationUserKey, db.User{
+		Username:        payload.Username,
+		Role:            identity.Role,
+		FullName:        identity.Name,
+		IsEmailVerified: true,
+	})
+	ctx.Next()
+}
+
+//authorizeMiddleware only lets through users whose role is one of the allowed roles.
+//The role is the one stored for the user now, not the one in the token, so a demotion applies right away.
+func authorizeMiddleware(roles []string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user := getAuthUser(ctx)
+		for _, role := range roles {
+			if user.Role == role {
+				ctx.Next()
+				return
+			}
+		}
+
+		err := fmt.Errorf("role %s is not allowed to access this resource", user.Role)
+		abortWithError(ctx, http.StatusForbidden, err)
+	}
+}
+
+//getAuthPayload returns the payload stored by authMiddleware
+func getAuthPayload(ctx *gin.Context) *token.Payload {
+	return ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+}
+
+//getAuthUser returns the user loaded by authMiddleware
+func getAuthUser(ctx *gin.Context) db.User {
+	return ctx.MustGet(authorizationUserKey).(db.User)
+}
+
+//isStaff reports whether the user may act on accounts of other users
+func isStaff(user db.User) bool {
+	return user.Role == util.BankerRole || user.Role == util.AdminRole
+}
+
+//defaultRateLimitRoute is the rule applied to routes without a rule of their own
+const defaultRateLimitRoute = "*"
+
+//rateLimitMiddleware limits every route with a token bucket per client.
+//Limits are looked up by "<METHOD> <route pattern>" without the version, e.g. "POST /transfers",
+//so a route shares its bucket across the versions of the API.
+func rateLimitMiddleware(limiter ratelimit.Limiter, limits map[string]ratelimit.Limit, tokenMaker token.Maker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		route := ctx.Request.Method + " " + unversionedPath(ctx.FullPath())
+		limit, ok := limits[route]
+		if !ok {
+			route = defaultRateLimitRoute
+			limit, ok = limits[route]
+		}
+		if !ok {
+			ctx.Next()
+			return
+		}
+
+		result, err := limiter.Allow(ctx, route+"|"+rateLimitKey(ctx, tokenMaker), limit)
+		if err != nil {
+			//fail open, an unavailable limiter backend must not take the whole API down
+			log.Println("rate limiter unavailable:", err)
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+		ctx.Header("RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+		ctx.Header("RateLimit-Reset", formatSeconds(result.ResetAfter))
+
+		if !result.Allowed {
+			ctx.Header("Retry-After", formatSeconds(result.RetryAfter))
+			err := fmt.Errorf("rate limit exceeded, retry in %s seconds", formatSeconds(result.RetryAfter))
+			abortWithError(ctx, http.StatusTooManyRequests, err)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+//rateLimitKey identifies the client a request is counted against,
+//authenticated users are counted across all their devices
+func rateLimitKey(ctx *gin.Context, tokenMaker token.Maker) string {
+	if payload, err := bearerPayload(ctx, tokenMaker); err == nil {
+		return "user:" + payload.Username
+	}
+	return "ip:" + ctx.ClientIP()
+}
+
+//formatSeconds rounds a duration up to whole seconds as required by the RateLimit and Retry-After headers
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+//requestLogger logs every request at the info level, only failed ones at warn and only server errors at error.
+//The json format writes one object per line for log collectors.
+func requestLogger(config util.LogConfig) gin.HandlerFunc {
+	minStatus := 0
+	switch config.Level {
+	case util.LogLevelWarn:
+		minStatus = http.StatusBadRequest
+	case util.LogLevelError:
+		minStatus = http.StatusInternalServerError
+	}
+
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		if param.StatusCode < minStatus {
+			return ""
+		}
+		if config.Format != util.LogFormatJSON {
+			return defaultLogFormatter(param)
+		}
+
+		requestID, _ := param.Keys[requestIDKey].(string)
+		line, err := json.Marshal(struct {
+			Time      string `json:"time"`
+			Status    int    `json:"status"`
+			LatencyMS int64  `json:"latency_ms"`
+			ClientIP  string `json:"client_ip"`
+			Method    string `json:"method"`
+			Path      string `json:"path"`
+			RequestID string `json:"request_id,omitempty"`
+			Error     string `json:"error,omitempty"`
+		}{
+			Time:      param.TimeStamp.UTC().Format(time.RFC3339Nano),
+			Status:    param.StatusCode,
+			LatencyMS: param.Latency.Milliseconds(),
+			ClientIP:  param.ClientIP,
+			Method:    param.Method,
+			Path:      param.Path,
+			RequestID: requestID,
+			Error:     param.ErrorMessage,
+		})
+		if err != nil {
+			return defaultLogFormatter(param)
+		}
+		return string(line) + "\n"
+	})
+}
+
+//defaultLogFormatter is the text format of gin.Logger
+func defaultLogFormatter(param gin.LogFormatterParams) string {
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		param.StatusCode,
+		param.Latency,
+		param.ClientIP,
+		param.Method,
+		param.Path,
+		param.ErrorMessage,
+	)
+}
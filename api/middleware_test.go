@@ -0,0 +1,381 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kingsleyocran/simple_bank_bankend/db/mock"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/mail"
+	"github.com/kingsleyocran/simple_bank_bankend/token"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func addAuthorization(
+	t *testing.T,
+	request *http.Request,
+	tokenMaker token.Maker,
+	authorizationType string,
+	username string,
+	role string,
+	duration time.Duration,
+) {
+	token, payload, err := tokenMaker.CreateToken(username, role, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+	tokenRoles.Store(username, role)
+
+	authorizationHeader := fmt.Sprintf("%s %s", authorizationType, token)
+	request.Header.Set(authorizationHeaderKey, authorizationHeader)
+}
+
+//tokenRoles is the role of the last token addAuthorization issued to each user
+var tokenRoles sync.Map
+
+//expectAuthUser stubs the user lookup authMiddleware does for every authenticated request,
+//the user has the role of its last token. Tests that need a specific user have to declare their own GetUser expectation first.
+func expectAuthUser(store *mockdb.MockStore) {
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, username string) (db.User, error) {
+			role, _ := tokenRoles.Load(username)
+			user := db.User{Username: username, IsEmailVerified: true}
+			user.Role, _ = role.(string)
+			return user, nil
+		})
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	username := util.RandomOwnerName()
+
+	testCases := []struct {
+		name          string
+		roles         []string
+		buildStubs    func(store *mockdb.MockStore)
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "OK",
+			roles:      allRoles,
+			buildStubs: expectAuthUser,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:       "NoAuthorization",
+			roles:      allRoles,
+			buildStubs: expectAuthUser,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:       "UnsupportedAuthorization",
+			roles:      allRoles,
+			buildStubs: expectAuthUser,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, "unsupported", username, util.DepositorRole, time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:       "InvalidAuthorizationFormat",
+			roles:      allRoles,
+			buildStubs: expectAuthUser,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, "", username, util.DepositorRole, time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:       "ExpiredToken",
+			roles:      allRoles,
+			buildStubs: expectAuthUser,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.DepositorRole, -time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:  "UserNotFound",
+			roles: allRoles,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(username)).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:  "PasswordChangedAfterToken",
+			roles: allRoles,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(username)).
+					Times(1).
+					Return(db.User{Username: username, PasswordChangedAt: time.Now().Add(time.Second)}, nil)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:       "RoleNotAllowed",
+			roles:      adminRoles,
+			buildStubs: expectAuthUser,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, username, util.BankerRole, time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			authPath := "/auth"
+			server.router.GET(
+				authPath,
+				authMiddleware(server.tokenMaker, store, nil),
+				authorizeMiddleware(tc.roles),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, authPath, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRoutesHavePermissions(t *testing.T) {
+	server := newTestServer(t, nil)
+	for _, r := range server.routes() {
+		require.NotEmpty(t, r.roles, "%s %s", r.method, r.path)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	user1 := util.RandomOwnerName()
+	user2 := util.RandomOwnerName()
+	account := randomAccount(user1)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).
+		Times(3).
+		Return(account, nil)
+	expectAuthUser(store)
+
+	config := util.Config{
+		Server: util.ServerConfig{RateLimits: []string{"*=100/1m", "GET /accounts/:id=2/1m"}},
+		Auth:   util.AuthConfig{TokenSymmetricKey: util.RandomString(32), AccessTokenDuration: time.Minute},
+	}
+	server, err := NewServer(config, store, mail.NewMemoryMailer())
+	require.NoError(t, err)
+
+	send := func(username string, remoteAddr string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/v1/accounts/%s", account.PublicID), nil)
+		require.NoError(t, err)
+		request.RemoteAddr = remoteAddr
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, util.BankerRole, time.Minute)
+
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := send(user1, "10.0.0.1:1234")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "2", recorder.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", recorder.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "30", recorder.Header().Get("RateLimit-Reset"))
+
+	//the same user is counted across client addresses
+	recorder = send(user1, "10.0.0.2:1234")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+
+	recorder = send(user1, "10.0.0.1:1234")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "30", recorder.Header().Get("Retry-After"))
+
+	//other users have their own bucket
+	recorder = send(user2, "10.0.0.1:1234")
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestRateLimitForwardedFor(t *testing.T) {
+	send := func(server *Server, forwardedFor string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/v1/users/login", bytes.NewReader([]byte("{}")))
+		require.NoError(t, err)
+		request.RemoteAddr = "10.0.0.1:1234"
+		request.Header.Set("X-Forwarded-For", forwardedFor)
+
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+	newServer := func(trustedProxies []string) *Server {
+		config := util.Config{
+			Server: util.ServerConfig{RateLimits: []string{"POST /users/login=1/1m"}, TrustedProxies: trustedProxies},
+			Auth:   util.AuthConfig{TokenSymmetricKey: util.RandomString(32), AccessTokenDuration: time.Minute},
+		}
+		server, err := NewServer(config, nil, mail.NewMemoryMailer())
+		require.NoError(t, err)
+		return server
+	}
+
+	//without trusted proxies a new X-Forwarded-For on every request still counts against the peer address
+	server := newServer(nil)
+	require.Equal(t, http.StatusBadRequest, send(server, "1.1.1.1").Code)
+	require.Equal(t, http.StatusTooManyRequests, send(server, "2.2.2.2").Code)
+
+	//behind a trusted proxy the client address it forwards is the key
+	server = newServer([]string{"10.0.0.0/8"})
+	require.Equal(t, http.StatusBadRequest, send(server, "1.1.1.1").Code)
+	require.Equal(t, http.StatusBadRequest, send(server, "2.2.2.2").Code)
+	require.Equal(t, http.StatusTooManyRequests, send(server, "1.1.1.1").Code)
+
+	_, err := NewServer(util.Config{
+		Server: util.ServerConfig{TrustedProxies: []string{"not an address"}},
+		Auth:   util.AuthConfig{TokenSymmetricKey: util.RandomString(32), AccessTokenDuration: time.Minute},
+	}, nil, mail.NewMemoryMailer())
+	require.Error(t, err)
+}
+
+func TestRequestLogger(t *testing.T) {
+	serve := func(config util.LogConfig, status int) string {
+		var out bytes.Buffer
+		defaultWriter := gin.DefaultWriter
+		gin.DefaultWriter = &out
+		defer func() { gin.DefaultWriter = defaultWriter }()
+
+		router := gin.New()
+		router.Use(requestLogger(config))
+		router.GET("/status", func(ctx *gin.Context) { ctx.Status(status) })
+
+		request, err := http.NewRequest(http.MethodGet, "/status", nil)
+		require.NoError(t, err)
+		router.ServeHTTP(httptest.NewRecorder(), request)
+		return out.String()
+	}
+
+	require.Contains(t, serve(util.LogConfig{Level: util.LogLevelInfo, Format: util.LogFormatText}, http.StatusOK), `"/status"`)
+
+	var line struct {
+		Status int    `json:"status"`
+		Method string `json:"method"`
+		Path   string `json:"path"`
+	}
+	err := json.Unmarshal([]byte(serve(util.LogConfig{Level: util.LogLevelInfo, Format: util.LogFormatJSON}, http.StatusOK)), &line)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, line.Status)
+	require.Equal(t, http.MethodGet, line.Method)
+	require.Equal(t, "/status", line.Path)
+
+	//warn only logs the requests that failed
+	require.Empty(t, serve(util.LogConfig{Level: util.LogLevelWarn, Format: util.LogFormatJSON}, http.StatusOK))
+	require.NotEmpty(t, serve(util.LogConfig{Level: util.LogLevelWarn, Format: util.LogFormatJSON}, http.StatusNotFound))
+	require.Empty(t, serve(util.LogConfig{Level: util.LogLevelError, Format: util.LogFormatJSON}, http.StatusNotFound))
+}
+
+func TestServiceAuth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+	store.EXPECT().
+		ListRiskDecisionsByStatus(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.RiskDecision{}, nil)
+
+	config := util.Config{
+		Auth: util.AuthConfig{TokenSymmetricKey: util.RandomString(32), AccessTokenDuration: time.Minute},
+		TLS:  util.TLSConfig{ServiceIdentities: []string{"reports.internal=reporting:banker", "shop.internal=shop:depositor"}},
+	}
+	server, err := NewServer(config, store, mail.NewMemoryMailer())
+	require.NoError(t, err)
+
+	send := func(commonName string, verified bool) *httptest.ResponseRecorder {
+		request, err := http.NewRequest(http.MethodGet, "/v1/admin/risk_decisions?page_id=1&page_size=5", nil)
+		require.NoError(t, err)
+
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+		request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		if verified {
+			request.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+		}
+
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	require.Equal(t, http.StatusOK, send("reports.internal", true).Code)
+
+	//the role of the service is checked like the role of a user
+	require.Equal(t, http.StatusForbidden, send("shop.internal", true).Code)
+
+	//an unknown or unverified certificate still needs a token
+	require.Equal(t, http.StatusUnauthorized, send("intruder.internal", true).Code)
+	require.Equal(t, http.StatusUnauthorized, send("reports.internal", false).Code)
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/mail"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+)
+
+//changePasswordRequest holds the current password together with the new one
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required,min=6"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+//changePassword sets a new password for the authenticated user.
+//Every access token issued so far, including the one used for this request, stops working.
+func (server *Server) changePassword(ctx *gin.Context) {
+	var req changePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user := getAuthUser(ctx)
+	err := util.CheckPassword(req.OldPassword, user.HashedPassword)
+	if err != nil {
+		writeError(ctx, http.StatusUnauthorized, errors.New("old password is incorrect"))
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	user, err = server.store.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+		Username:          user.Username,
+		HashedPassword:    hashedPassword,
+		PasswordChangedAt: passwordChangedAt(),
+	})
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+//forgotPasswordRequest identifies the user who wants to reset their password
+type forgotPasswordRequest struct {
+	Username string `json:"username" binding:"required,alphanum"`
+}
+
+//forgotPassword mails a single use reset link to the user's email address.
+//It answers the same way whether or not the user exists so usernames can't be probed.
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	rsp := gin.H{"message": "if the user exists, a reset link has been sent to their email address"}
+
+	user, err := server.store.GetUser(ctx, req.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusAccepted, rsp)
+			return
+		}
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	resetToken, err := util.GenerateSecret(32)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	resetPassword, err := server.store.CreateResetPassword(ctx, db.CreateResetPasswordParams{
+		Username:  user.Username,
+		TokenHash: util.HashSecret(resetToken),
+		ExpiredAt: time.Now().Add(server.config.Auth.ResetPasswordDuration),
+	})
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	//a mail failure can only happen for users who exist, reporting it would tell which usernames do
+	err = server.sendResetPasswordEmail(user, resetPassword, resetToken)
+	if err != nil {
+		log.Printf("request %s: cannot send the reset password email of %s: %v", requestID(ctx), user.Username, err)
+	}
+
+	ctx.JSON(http.StatusAccepted, rsp)
+}
+
+//sendResetPasswordEmail mails the link carrying the reset token, only its hash is stored
+func (server *Server) sendResetPasswordEmail(user db.User, resetPassword db.ResetPassword, resetToken string) error {
+	query := url.Values{}
+	query.Set("token", resetToken)
+	link := fmt.Sprintf("%s/v1/users/reset_password?%s", server.config.Server.AppBaseURL, query.Encode())
+
+	return server.mailer.Send(mail.Message{
+		To:      []string{user.Email},
+		Subject: "Reset your Simple Bank password",
+		Body: fmt.Sprintf("Hello %s,\n\nA password reset was requested for your account.\n"+
+			"Open the link below before %s to choose a new password:\n\n%s\n\n"+
+			"If you didn't request it you can ignore this email.\n",
+			user.FullName, resetPassword.ExpiredAt.Format(time.RFC1123), link),
+	})
+}
+
+//errInvalidResetToken is returned for reset tokens that don't exist, expired or were already used
+var errInvalidResetToken = errors.New("reset token is invalid, expired or already used")
+
+//checkResetPasswordRequest holds the token from the link in the reset email
+type checkResetPasswordRequest struct {
+	Token string `form:"token" binding:"required,len=64"`
+}
+
+//checkResetPasswordResponse tells until when the token can be used
+type checkResetPasswordResponse struct {
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+//checkResetPassword serves the link in the reset email. It reports whether the token can still be used
+//without using it up, the new password is then sent to POST /users/reset_password with the same token.
+func (server *Server) checkResetPassword(ctx *gin.Context) {
+	var req checkResetPasswordRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	resetPassword, err := server.store.GetResetPassword(ctx, util.HashSecret(req.Token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(ctx, http.StatusBadRequest, errInvalidResetToken)
+			return
+		}
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, checkResetPasswordResponse{ExpiredAt: resetPassword.ExpiredAt})
+}
+
+//resetPasswordRequest holds the token from the reset email and the new password
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required,len=64"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+//resetPassword sets a new password using a reset token, the token can only be used once
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	user, err := server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		TokenHash:         util.HashSecret(req.Token),
+		HashedPassword:    hashedPassword,
+		PasswordChangedAt: passwordChangedAt(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(ctx, http.StatusBadRequest, errInvalidResetToken)
+			return
+		}
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+//passwordChangedAt is truncated to the precision postgres stores, so that tokens issued
+//right after the change are never considered older than it
+func passwordChangedAt() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
@@ -0,0 +1,450 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kingsleyocran/simple_bank_bankend/db/mock"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/mail"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+//eqUpdateUserPasswordParamsMatcher checks the new password against the bcrypt hash
+type eqUpdateUserPasswordParamsMatcher struct {
+	username string
+	password string
+}
+
+func (e eqUpdateUserPasswordParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.UpdateUserPasswordParams)
+	if !ok {
+		return false
+	}
+
+	if arg.Username != e.username || arg.PasswordChangedAt.IsZero() {
+		return false
+	}
+	return util.CheckPassword(e.password, arg.HashedPassword) == nil
+}
+
+func (e eqUpdateUserPasswordParamsMatcher) String() string {
+	return fmt.Sprintf("matches username %v and password %v", e.username, e.password)
+}
+
+func TestChangePasswordAPI(t *testing.T) {
+	user, password := randomUser(t)
+	newPassword := util.RandomString(8)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"old_password": password,
+				"new_password": newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), eqUpdateUserPasswordParamsMatcher{user.Username, newPassword}).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchUser(t, recorder.Body, user)
+			},
+		},
+		{
+			name: "WrongOldPassword",
+			body: gin.H{
+				"old_password": "wrong-password",
+				"new_password": newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "TooShortNewPassword",
+			body: gin.H{
+				"old_password": password,
+				"new_password": "123",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"old_password": password,
+				"new_password": newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).AnyTimes().Return(user, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPut, "/v1/users/me/password", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestForgotPasswordAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder, mailer *mail.MemoryMailer)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"username": user.Username},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateResetPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateResetPasswordParams) (db.ResetPassword, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Len(t, arg.TokenHash, 64)
+						return db.ResetPassword{ID: 1, Username: arg.Username, TokenHash: arg.TokenHash, ExpiredAt: arg.ExpiredAt}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, mailer *mail.MemoryMailer) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+
+				messages := mailer.Messages()
+				require.Len(t, messages, 1)
+				require.Equal(t, []string{user.Email}, messages[0].To)
+				require.Contains(t, messages[0].Body, "http://localhost:8080/v1/users/reset_password?token=")
+			},
+		},
+		{
+			name: "UserNotFound",
+			body: gin.H{"username": user.Username},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().
+					CreateResetPassword(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, mailer *mail.MemoryMailer) {
+				//same answer as for an existing user
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Empty(t, mailer.Messages())
+			},
+		},
+		{
+			name: "InvalidUsername",
+			body: gin.H{"username": "not-valid#"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, mailer *mail.MemoryMailer) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/v1/users/forgot_password", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder, server.mailer.(*mail.MemoryMailer))
+		})
+	}
+}
+
+//failingMailer is a mailer whose every send fails
+type failingMailer struct{}
+
+func (failingMailer) Send(msg mail.Message) error {
+	return errors.New("mail server unavailable")
+}
+
+func TestForgotPasswordMailFailureAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	store.EXPECT().CreateResetPassword(gomock.Any(), gomock.Any()).Times(1).Return(db.ResetPassword{ID: 1, Username: user.Username}, nil)
+
+	server := newTestServer(t, store)
+	server.mailer = failingMailer{}
+
+	data, err := json.Marshal(gin.H{"username": user.Username})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, "/v1/users/forgot_password", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+
+	//the same answer as for a user that doesn't exist
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+	require.JSONEq(t, `{"message":"if the user exists, a reset link has been sent to their email address"}`, recorder.Body.String())
+}
+
+func TestResetPasswordAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	resetToken := util.RandomString(64)
+	newPassword := util.RandomString(8)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"token":        resetToken,
+				"new_password": newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ResetPasswordTxParams) (db.User, error) {
+						//only the hash of the token is ever looked up
+						require.Equal(t, util.HashSecret(resetToken), arg.TokenHash)
+						require.NoError(t, util.CheckPassword(newPassword, arg.HashedPassword))
+						return user, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchUser(t, recorder.Body, user)
+			},
+		},
+		{
+			name: "InvalidOrUsedToken",
+			body: gin.H{
+				"token":        resetToken,
+				"new_password": newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "MalformedToken",
+			body: gin.H{
+				"token":        "short",
+				"new_password": newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/v1/users/reset_password", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestCheckResetPasswordAPI(t *testing.T) {
+	resetToken := util.RandomString(64)
+	resetPassword := db.ResetPassword{
+		ID:        1,
+		Username:  util.RandomOwnerName(),
+		TokenHash: util.HashSecret(resetToken),
+		ExpiredAt: time.Now().Add(time.Hour).Truncate(time.Second),
+	}
+
+	testCases := []struct {
+		name          string
+		token         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			token: resetToken,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetResetPassword(gomock.Any(), gomock.Eq(util.HashSecret(resetToken))).
+					Times(1).
+					Return(resetPassword, nil)
+				//checking the link doesn't use the token up
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp checkResetPasswordResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.WithinDuration(t, resetPassword.ExpiredAt, rsp.ExpiredAt, time.Second)
+			},
+		},
+		{
+			name:  "InvalidOrUsedToken",
+			token: resetToken,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetResetPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ResetPassword{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "MalformedToken",
+			token: "short",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetResetPassword(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			query := url.Values{}
+			query.Set("token", tc.token)
+			request, err := http.NewRequest(http.MethodGet, "/v1/users/reset_password?"+query.Encode(), nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
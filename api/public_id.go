@@ -0,0 +1,106 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+)
+
+//Clients only ever see the public ids of accounts, entries and transfers, e.g. acc_01HF3Z...
+//The bigserial ids stay inside the bank: they are sequential, so they would let anyone enumerate
+//accounts and count how many we open. Requests name rows by public id and handlers resolve them once.
+
+//validPublicID checks the binding publicid=<prefix>, e.g. binding:"required,publicid=acc_"
+var validPublicID validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if id, ok := fieldLevel.Field().Interface().(string); ok {
+		return db.IsPublicID(id, fieldLevel.Param())
+	}
+	return false
+}
+
+//publicIDs turns the internal ids a response refers to into public ids.
+//It starts with the accounts the handler already loaded and looks up any other row once per request.
+//The first failed lookup is kept in err, json writes it instead of a response with missing ids.
+type publicIDs struct {
+	ctx       *gin.Context
+	store     db.Store
+	accounts  map[int64]db.Account
+	transfers map[int64]string
+	err       error
+}
+
+func (server *Server) publicIDs(ctx *gin.Context, accounts ...db.Account) *publicIDs {
+	ids := &publicIDs{
+		ctx:       ctx,
+		store:     server.store,
+		accounts:  make(map[int64]db.Account),
+		transfers: make(map[int64]string),
+	}
+	return ids.add(accounts...)
+}
+
+//add remembers accounts that were loaded anyway, e.g. the result of a transfer
+func (ids *publicIDs) add(accounts ...db.Account) *publicIDs {
+	for _, account := range accounts {
+		ids.accounts[account.ID] = account
+	}
+	return ids
+}
+
+//loadAccount returns the account with the internal id, from the accounts already seen when possible
+func (ids *publicIDs) loadAccount(id int64) (db.Account, error) {
+	if account, ok := ids.accounts[id]; ok {
+		return account, nil
+	}
+
+	account, err := ids.store.GetAccount(ids.ctx, id)
+	if err != nil {
+		return account, err
+	}
+	ids.accounts[id] = account
+	return account, nil
+}
+
+func (ids *publicIDs) account(id int64) string {
+	if ids.err != nil {
+		return ""
+	}
+
+	account, err := ids.loadAccount(id)
+	if err != nil {
+		//%v and not %w: a row the response refers to has to exist, its absence is no 404 for the client
+		ids.err = fmt.Errorf("cannot find the public id of account %d: %v", id, err)
+		return ""
+	}
+	return account.PublicID
+}
+
+//transfer returns the public id of a transfer, 0 means there is none and stays empty
+func (ids *publicIDs) transfer(id int64) string {
+	if id == 0 || ids.err != nil {
+		return ""
+	}
+	if publicID, ok := ids.transfers[id]; ok {
+		return publicID
+	}
+
+	transfer, err := ids.store.GetTransfer(ids.ctx, id)
+	if err != nil {
+		ids.err = fmt.Errorf("cannot find the public id of transfer %d: %v", id, err)
+		return ""
+	}
+	ids.transfers[id] = transfer.PublicID
+	return transfer.PublicID
+}
+
+//json writes the response, or the error of a lookup made while building it
+func (ids *publicIDs) json(status int, rsp interface{}) {
+	if ids.err != nil {
+		writeError(ids.ctx, http.StatusInternalServerError, ids.err)
+		return
+	}
+	ids.ctx.JSON(status, rsp)
+}
@@ -0,0 +1,314 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+)
+
+//Response bodies of the API. They are kept apart from the sqlc models so that a change to the schema
+//doesn't change what clients receive, a field is only added or renamed here on purpose.
+//Accounts, entries and transfers are always named by their public id. Bodies that refer to one of them
+//are built by publicIDs, which looks up the public ids of the rows the handler didn't load.
+
+type accountResponse struct {
+	ID        string    `json:"id"`
+	OwnerName string    `json:"owner_name"`
+	Balance   int64     `json:"balance"`
+	Currency  string    `json:"currency"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newAccountResponse(account db.Account) accountResponse {
+	return accountResponse{
+		ID:        account.PublicID,
+		OwnerName: account.OwnerName,
+		Balance:   account.Balance,
+		Currency:  account.Currency,
+		Status:    account.Status,
+		CreatedAt: account.CreatedAt,
+	}
+}
+
+type entryResponse struct {
+	ID          string    `json:"id"`
+	AccountID   string    `json:"account_id"`
+	Amount      int64     `json:"amount"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (ids *publicIDs) newEntryResponse(entry db.Entry) entryResponse {
+	return entryResponse{
+		ID:          entry.PublicID,
+		AccountID:   ids.account(entry.AccountID),
+		Amount:      entry.Amount,
+		Description: entry.Description,
+		CreatedAt:   entry.CreatedAt,
+	}
+}
+
+type transferResponse struct {
+	ID            string          `json:"id"`
+	FromAccountID string          `json:"from_account_id"`
+	ToAccountID   string          `json:"to_account_id"`
+	Amount        int64           `json:"amount"`
+	Description   string          `json:"description"`
+	Reference     string          `json:"reference"`
+	Metadata      json.RawMessage `json:"metadata"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+func (ids *publicIDs) newTransferResponse(transfer db.Transfer) transferResponse {
+	return transferResponse{
+		ID:            transfer.PublicID,
+		FromAccountID: ids.account(transfer.FromAccountID),
+		ToAccountID:   ids.account(transfer.ToAccountID),
+		Amount:        transfer.Amount,
+		Description:   transfer.Description,
+		Reference:     transfer.Reference,
+		Metadata:      transfer.Metadata,
+		CreatedAt:     transfer.CreatedAt,
+	}
+}
+
+//feeResponse is a fee that was charged, Entry debits the account and RevenueEntry credits the bank
+type feeResponse struct {
+	Kind         string        `json:"kind"`
+	Amount       int64         `json:"amount"`
+	Entry        entryResponse `json:"entry"`
+	RevenueEntry entryResponse `json:"revenue_entry"`
+}
+
+func (ids *publicIDs) newFeeResponse(fee db.FeeLine) feeResponse {
+	return feeResponse{
+		Kind:         fee.Kind,
+		Amount:       fee.Amount,
+		Entry:        ids.newEntryResponse(fee.Entry),
+		RevenueEntry: ids.newEntryResponse(fee.RevenueEntry),
+	}
+}
+
+//transferResultResponse is a transfer that was made, with both entries and both accounts after it
+type transferResultResponse struct {
+	Transfer    transferResponse `json:"transfer"`
+	FromAccount accountResponse  `json:"from_account"`
+	ToAccount   accountResponse  `json:"to_account"`
+	FromEntry   entryResponse    `json:"from_entry"`
+	ToEntry     entryResponse    `json:"to_entry"`
+	Fees        []feeResponse    `json:"fees,omitempty"`
+}
+
+func (ids *publicIDs) newTransferResultResponse(result db.TransferTxResult) transferResultResponse {
+	ids.add(result.FromAccount, result.ToAccount)
+	return transferResultResponse{
+		Transfer:    ids.newTransferResponse(result.Transfer),
+		FromAccount: newAccountResponse(result.FromAccount),
+		ToAccount:   newAccountResponse(result.ToAccount),
+		FromEntry:   ids.newEntryResponse(result.FromEntry),
+		ToEntry:     ids.newEntryResponse(result.ToEntry),
+		Fees:        newResponses(result.Fees, ids.newFeeResponse),
+	}
+}
+
+//postingResponse is a split transfer, one transfer per destination or per source
+type postingResponse struct {
+	Transfers []transferResponse `json:"transfers"`
+	Accounts  []accountResponse  `json:"accounts"`
+	Entries   []entryResponse    `json:"entries"`
+	Fees      []feeResponse      `json:"fees,omitempty"`
+}
+
+func (ids *publicIDs) newPostingResponse(result db.PostingTxResult) postingResponse {
+	ids.add(result.Accounts...)
+	return postingResponse{
+		Transfers: newResponses(result.Transfers, ids.newTransferResponse),
+		Accounts:  newResponses(result.Accounts, newAccountResponse),
+		Entries:   newResponses(result.Entries, ids.newEntryResponse),
+		Fees:      newResponses(result.Fees, ids.newFeeResponse),
+	}
+}
+
+type beneficiaryResponse struct {
+	ID        int64     `json:"id"`
+	OwnerName string    `json:"owner_name"`
+	Nickname  string    `json:"nickname"`
+	AccountID string    `json:"account_id"`
+	Currency  string    `json:"currency"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (ids *publicIDs) newBeneficiaryResponse(beneficiary db.Beneficiary) beneficiaryResponse {
+	return beneficiaryResponse{
+		ID:        beneficiary.ID,
+		OwnerName: beneficiary.OwnerName,
+		Nickname:  beneficiary.Nickname,
+		AccountID: ids.account(beneficiary.AccountID),
+		Currency:  beneficiary.Currency,
+		CreatedAt: beneficiary.CreatedAt,
+	}
+}
+
+//accountLimitResponse holds the limits of an account, 0 means unlimited
+type accountLimitResponse struct {
+	AccountID         string    `json:"account_id"`
+	MaxSingleTransfer int64     `json:"max_single_transfer"`
+	MaxDailyAmount    int64     `json:"max_daily_amount"`
+	MaxDailyCount     int64     `json:"max_daily_count"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+func (ids *publicIDs) newAccountLimitResponse(limit db.AccountLimit) accountLimitResponse {
+	return accountLimitResponse{
+		AccountID:         ids.account(limit.AccountID),
+		MaxSingleTransfer: limit.MaxSingleTransfer,
+		MaxDailyAmount:    limit.MaxDailyAmount,
+		MaxDailyCount:     limit.MaxDailyCount,
+		UpdatedAt:         limit.UpdatedAt,
+	}
+}
+
+//riskDecisionResponse is a transfer held or blocked by fraud screening, only staff see the rules that matched
+type riskDecisionResponse struct {
+	ID            int64           `json:"id"`
+	Username      string          `json:"username"`
+	FromAccountID string          `json:"from_account_id"`
+	ToAccountID   string          `json:"to_account_id"`
+	Amount        int64           `json:"amount"`
+	Currency      string          `json:"currency"`
+	Description   string          `json:"description"`
+	Reference     string          `json:"reference"`
+	Metadata      json.RawMessage `json:"metadata"`
+	Decision      string          `json:"decision"`
+	Hits          json.RawMessage `json:"hits"`
+	Status        string          `json:"status"`
+	TransferID    string          `json:"transfer_id"`
+	ReviewedBy    string          `json:"reviewed_by"`
+	ReviewedAt    time.Time       `json:"reviewed_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+func (ids *publicIDs) newRiskDecisionResponse(decision db.RiskDecision) riskDecisionResponse {
+	return riskDecisionResponse{
+		ID:            decision.ID,
+		Username:      decision.Username,
+		FromAccountID: ids.account(decision.FromAccountID),
+		ToAccountID:   ids.account(decision.ToAccountID),
+		Amount:        decision.Amount,
+		Currency:      decision.Currency,
+		Description:   decision.Description,
+		Reference:     decision.Reference,
+		Metadata:      decision.Metadata,
+		Decision:      decision.Decision,
+		Hits:          decision.Hits,
+		Status:        decision.Status,
+		TransferID:    ids.transfer(decision.TransferID),
+		ReviewedBy:    decision.ReviewedBy,
+		ReviewedAt:    decision.ReviewedAt,
+		CreatedAt:     decision.CreatedAt,
+	}
+}
+
+//approveRiskDecisionResponse is an approved decision with the transfer it made
+type approveRiskDecisionResponse struct {
+	Decision riskDecisionResponse   `json:"decision"`
+	Transfer transferResultResponse `json:"transfer"`
+}
+
+func (ids *publicIDs) newApproveRiskDecisionResponse(result db.ApproveRiskDecisionTxResult) approveRiskDecisionResponse {
+	transfer := ids.newTransferResultResponse(result.Transfer)
+	ids.transfers[result.Transfer.Transfer.ID] = result.Transfer.Transfer.PublicID
+	return approveRiskDecisionResponse{
+		Decision: ids.newRiskDecisionResponse(result.Decision),
+		Transfer: transfer,
+	}
+}
+
+//productResponse is an account product, the rate is in millionths a year, 25000 is 2.5%
+type productResponse struct {
+	Code          string    `json:"code"`
+	Name          string    `json:"name"`
+	AnnualRatePpm int64     `json:"annual_rate_ppm"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func newProductResponse(product db.AccountProduct) productResponse {
+	return productResponse{
+		Code:          product.Code,
+		Name:          product.Name,
+		AnnualRatePpm: product.AnnualRatePpm,
+		CreatedAt:     product.CreatedAt,
+	}
+}
+
+type productFeeResponse struct {
+	ProductCode string    `json:"product_code"`
+	Kind        string    `json:"kind"`
+	FlatAmount  int64     `json:"flat_amount"`
+	RatePpm     int64     `json:"rate_ppm"`
+	MaxAmount   int64     `json:"max_amount"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func newProductFeeResponse(fee db.ProductFee) productFeeResponse {
+	return productFeeResponse{
+		ProductCode: fee.ProductCode,
+		Kind:        fee.Kind,
+		FlatAmount:  fee.FlatAmount,
+		RatePpm:     fee.RatePpm,
+		MaxAmount:   fee.MaxAmount,
+		UpdatedAt:   fee.UpdatedAt,
+	}
+}
+
+//interestResponse is the interest of an account, Accrued is earned but not paid yet
+type interestResponse struct {
+	AccountID       string    `json:"account_id"`
+	ProductCode     string    `json:"product_code"`
+	Accrued         int64     `json:"accrued"`
+	AccruedFraction int64     `json:"accrued_fraction"`
+	AccruedThrough  time.Time `json:"accrued_through"`
+	Paid            int64     `json:"paid"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func (ids *publicIDs) newInterestResponse(interest db.AccountInterest) interestResponse {
+	return interestResponse{
+		AccountID:       ids.account(interest.AccountID),
+		ProductCode:     interest.ProductCode,
+		Accrued:         interest.Accrued,
+		AccruedFraction: interest.AccruedFraction,
+		AccruedThrough:  interest.AccruedThrough,
+		Paid:            interest.Paid,
+		UpdatedAt:       interest.UpdatedAt,
+	}
+}
+
+//bankAccountResponse is the account the bank uses for a currency, e.g. to pay interest or collect fees
+type bankAccountResponse struct {
+	Currency  string    `json:"currency"`
+	AccountID string    `json:"account_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (ids *publicIDs) newInterestExpenseResponse(expense db.InterestExpenseAccount) bankAccountResponse {
+	return bankAccountResponse{Currency: expense.Currency, AccountID: ids.account(expense.AccountID), UpdatedAt: expense.UpdatedAt}
+}
+
+func (ids *publicIDs) newFeeRevenueResponse(revenue db.FeeRevenueAccount) bankAccountResponse {
+	return bankAccountResponse{Currency: revenue.Currency, AccountID: ids.account(revenue.AccountID), UpdatedAt: revenue.UpdatedAt}
+}
+
+//newResponses converts every row of a list, nil stays nil
+func newResponses[T any, R any](rows []T, convert func(T) R) []R {
+	if rows == nil {
+		return nil
+	}
+	responses := make([]R, len(rows))
+	for i, row := range rows {
+		responses[i] = convert(row)
+	}
+	return responses
+}
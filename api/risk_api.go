@@ -0,0 +1,277 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/risk"
+)
+
+//errRiskDecisionReviewed is returned when staff review a decision somebody already reviewed
+var errRiskDecisionReviewed = errors.New("risk decision was already reviewed")
+
+//screenTransfer runs the fraud rules on a transfer about to be made. A transfer held for review or blocked is recorded
+//and the response is written, callers only need to return when it reports true.
+func (server *Server) screenTransfer(ctx *gin.Context, req transferRequest, from db.Account, to db.Account) bool {
+	record, err := server.evaluateTransfer(ctx, riskTransfer{
+		From:        from,
+		To:          to,
+		Amount:      req.Amount,
+		Currency:    req.Currency,
+		Description: req.Description,
+		Reference:   req.Reference,
+		Metadata:    req.Metadata,
+	})
+	if err != nil {
+		//fail closed, a transfer that couldn't be screened is not made
+		writeError(ctx, http.StatusInternalServerError, err)
+		return true
+	}
+	if record == nil {
+		return false
+	}
+
+	apiErr := riskDecisionError(*record)
+	writeError(ctx, apiErr.status, apiErr)
+	return true
+}
+
+//riskTransfer is a transfer to screen with what is recorded about it when it is held or blocked
+type riskTransfer struct {
+	From        db.Account
+	To          db.Account
+	Amount      int64
+	Currency    string
+	Description string
+	Reference   string
+	Metadata    json.RawMessage
+	//Atomic is set for the transfers of an atomic batch or a split, which are all refused when one of them is held
+	Atomic bool
+	//Earlier are the transfers of the same request screened before, the rules count them as already sent
+	Earlier []risk.Transfer
+}
+
+//evaluateTransfer runs the fraud rules on one transfer. A transfer held for review or blocked is recorded
+//and its decision returned, it is nil when the transfer is allowed.
+func (server *Server) evaluateTransfer(ctx *gin.Context, transfer riskTransfer) (*db.RiskDecision, error) {
+	decision, err := server.risk.Evaluate(ctx, risk.Transfer{
+		From:    transfer.From,
+		To:      transfer.To,
+		Amount:  transfer.Amount,
+		Earlier: transfer.Earlier,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if decision.Action == db.RiskDecisionAllow {
+		return nil, nil
+	}
+
+	hits, err := json.Marshal(decision.Hits)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := transfer.Metadata
+	if len(metadata) == 0 {
+		metadata = json.RawMessage("{}")
+	}
+
+	status := db.RiskStatusPending
+	switch {
+	case decision.Action == db.RiskDecisionBlock:
+		status = db.RiskStatusBlocked
+	case transfer.Atomic:
+		//the request is refused as a whole, approving this transfer alone would make only part of it
+		status = db.RiskStatusRefused
+	}
+
+	record, err := server.store.CreateRiskDecision(ctx, db.CreateRiskDecisionParams{
+		Username:      getAuthPayload(ctx).Username,
+		FromAccountID: transfer.From.ID,
+		ToAccountID:   transfer.To.ID,
+		Amount:        transfer.Amount,
+		Currency:      transfer.Currency,
+		Description:   transfer.Description,
+		Reference:     transfer.Reference,
+		Metadata:      metadata,
+		Decision:      decision.Action,
+		Hits:          hits,
+		Status:        status,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+//riskDecisionError is the answer to a transfer held for review or blocked.
+//The rules that matched are only shown to staff, so they can't be probed by trial and error.
+func riskDecisionError(record db.RiskDecision) *apiError {
+	if record.Decision == db.RiskDecisionBlock {
+		apiErr := newAPIError(http.StatusForbidden, codeTransferBlocked, "the transfer was blocked by fraud screening")
+		return apiErr.with("risk_decision_id", record.ID)
+	}
+
+	if record.Status == db.RiskStatusRefused {
+		apiErr := newAPIError(http.StatusUnprocessableEntity, codeTransferNeedsReview, "the transfer needs a review, send it on its own to have it held")
+		return apiErr.with("risk_decision_id", record.ID)
+	}
+
+	apiErr := newAPIError(http.StatusAccepted, codeTransferHeldForReview, "the transfer is held until it is reviewed")
+	return apiErr.with("risk_decision_id", record.ID)
+}
+
+type listRiskDecisionsRequest struct {
+	Status   string `form:"status" binding:"omitempty,oneof=pending approved rejected blocked refused"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+//listRiskDecisions is the review queue, it returns the pending decisions oldest first unless another status is asked for
+func (server *Server) listRiskDecisions(ctx *gin.Context) {
+	var req listRiskDecisionsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Status == "" {
+		req.Status = db.RiskStatusPending
+	}
+
+	decisions, err := server.store.ListRiskDecisionsByStatus(ctx, db.ListRiskDecisionsByStatusParams{
+		Status: req.Status,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ids := server.publicIDs(ctx)
+	ids.json(http.StatusOK, newResponses(decisions, ids.newRiskDecisionResponse))
+}
+
+//riskDecisionURI takes the decision id as a URI parameter Eg. admin/risk_decisions/:id
+type riskDecisionURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+//getRiskDecision returns one decision with the rules that matched
+func (server *Server) getRiskDecision(ctx *gin.Context) {
+	var uri riskDecisionURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	decision, valid := server.riskDecision(ctx, uri.ID, false)
+	if !valid {
+		return
+	}
+
+	ids := server.publicIDs(ctx)
+	ids.json(http.StatusOK, ids.newRiskDecisionResponse(decision))
+}
+
+//approveRiskDecision makes the transfer held for review. Balances and limits are checked again,
+//when the transfer fails the decision stays pending so it can be approved later or rejected.
+//Staff can't approve their own transfers, somebody else has to review them.
+func (server *Server) approveRiskDecision(ctx *gin.Context) {
+	var uri riskDecisionURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	decision, valid := server.riskDecision(ctx, uri.ID, true)
+	if !valid {
+		return
+	}
+
+	reviewer := getAuthPayload(ctx).Username
+	if decision.Username == reviewer {
+		writeError(ctx, http.StatusForbidden, db.ErrSelfApproval)
+		return
+	}
+
+	result, err := server.store.ApproveRiskDecisionTx(ctx, db.ApproveRiskDecisionTxParams{
+		ID:         uri.ID,
+		ReviewedBy: reviewer,
+	})
+	if err != nil {
+		//accounts with decisions can't be deleted, so no rows means another reviewer got there first
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(ctx, http.StatusConflict, errRiskDecisionReviewed)
+			return
+		}
+		if errors.Is(err, db.ErrSelfApproval) {
+			writeError(ctx, http.StatusForbidden, err)
+			return
+		}
+		writeError(ctx, http.StatusInternalServerError, transferError(err))
+		return
+	}
+
+	ids := server.publicIDs(ctx)
+	ids.json(http.StatusOK, ids.newApproveRiskDecisionResponse(result))
+}
+
+//rejectRiskDecision drops the transfer held for review, nothing is moved
+func (server *Server) rejectRiskDecision(ctx *gin.Context) {
+	var uri riskDecisionURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, valid := server.riskDecision(ctx, uri.ID, true); !valid {
+		return
+	}
+
+	decision, err := server.store.ReviewRiskDecision(ctx, db.ReviewRiskDecisionParams{
+		ID:         uri.ID,
+		Status:     db.RiskStatusRejected,
+		ReviewedBy: getAuthPayload(ctx).Username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(ctx, http.StatusConflict, errRiskDecisionReviewed)
+			return
+		}
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ids := server.publicIDs(ctx)
+	ids.json(http.StatusOK, ids.newRiskDecisionResponse(decision))
+}
+
+//riskDecision loads a decision, when pending is set it must still be waiting for review.
+//It writes the error response itself so callers only need to return.
+func (server *Server) riskDecision(ctx *gin.Context, id int64, pending bool) (db.RiskDecision, bool) {
+	decision, err := server.store.GetRiskDecision(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(ctx, http.StatusNotFound, err)
+			return decision, false
+		}
+		writeError(ctx, http.StatusInternalServerError, err)
+		return decision, false
+	}
+
+	if pending && decision.Status != db.RiskStatusPending {
+		err := fmt.Errorf("%w, its status is %s", errRiskDecisionReviewed, decision.Status)
+		writeError(ctx, http.StatusConflict, err)
+		return decision, false
+	}
+
+	return decision, true
+}
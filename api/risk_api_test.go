@@ -0,0 +1,465 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kingsleyocran/simple_bank_bankend/db/mock"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/risk"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+//The whole review flow against the in-memory store: transfers are held or blocked, then approved or rejected by staff
+func TestRiskDecisionAPI(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+
+	createUser := func() db.User {
+		user, err := store.CreateUser(ctx, db.CreateUserParams{
+			Username:       util.RandomOwnerName(),
+			HashedPassword: "secret",
+			FullName:       util.RandomOwnerName(),
+			Email:          util.RandomEmail(),
+		})
+		require.NoError(t, err)
+
+		user, err = store.UpdateUserEmailVerified(ctx, db.UpdateUserEmailVerifiedParams{Username: user.Username, Email: user.Email})
+		require.NoError(t, err)
+		return user
+	}
+	createAccount := func(balance int64) db.Account {
+		account, err := store.CreateAccount(ctx, db.CreateAccountParams{
+			OwnerName: createUser().Username,
+			Balance:   balance,
+			Currency:  util.USD,
+		})
+		require.NoError(t, err)
+		return account
+	}
+
+	sender := createAccount(500)
+	payee := createAccount(0)
+	other := createAccount(0)
+	banker := createUser()
+	banker, err := store.UpdateUserRole(ctx, db.UpdateUserRoleParams{Username: banker.Username, Role: util.BankerRole})
+	require.NoError(t, err)
+
+	server := newTestServer(t, store)
+	server.risk = risk.NewEngine(store, []risk.Rule{
+		{Name: "first", Type: risk.NewBeneficiaryRule, Action: db.RiskDecisionReview, MinAmount: 50},
+		{Name: "first_large", Type: risk.NewBeneficiaryRule, Action: db.RiskDecisionBlock, MinAmount: 90},
+	})
+
+	send := func(username, role, method, url string, body gin.H) *httptest.ResponseRecorder {
+		var data []byte
+		if body != nil {
+			var err error
+			data, err = json.Marshal(body)
+			require.NoError(t, err)
+		}
+
+		request, err := http.NewRequest(method, url, bytes.NewReader(data))
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, role, time.Minute)
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+	transfer := func(to db.Account, amount int64) *httptest.ResponseRecorder {
+		return send(sender.OwnerName, util.DepositorRole, http.MethodPost, "/v1/transfers", gin.H{
+			"from_account_id": sender.PublicID,
+			"to_account_id":   to.PublicID,
+			"amount":          amount,
+			"currency":        util.USD,
+		})
+	}
+	decisionID := func(recorder *httptest.ResponseRecorder, code string) int64 {
+		var body struct {
+			Code           string `json:"code"`
+			RiskDecisionID int64  `json:"risk_decision_id"`
+		}
+		err := json.Unmarshal(recorder.Body.Bytes(), &body)
+		require.NoError(t, err)
+		require.Equal(t, code, body.Code)
+		return body.RiskDecisionID
+	}
+	balance := func(account db.Account) int64 {
+		account, err := store.GetAccount(ctx, account.ID)
+		require.NoError(t, err)
+		return account.Balance
+	}
+
+	//below every rule the transfer is made right away
+	recorder := transfer(payee, 10)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	//payee was paid before, other never was
+	recorder = transfer(payee, 60)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = transfer(other, 60)
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+	heldID := decisionID(recorder, "TRANSFER_HELD_FOR_REVIEW")
+
+	recorder = transfer(other, 95)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+	blockedID := decisionID(recorder, "TRANSFER_BLOCKED")
+	require.Equal(t, int64(430), balance(sender))
+
+	//depositors can't see the queue
+	recorder = send(sender.OwnerName, util.DepositorRole, http.MethodGet, "/v1/admin/risk_decisions?page_id=1&page_size=5", nil)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+
+	recorder = send(banker.Username, util.BankerRole, http.MethodGet, "/v1/admin/risk_decisions?page_id=1&page_size=5", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var queue []riskDecisionResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &queue)
+	require.NoError(t, err)
+	require.Len(t, queue, 1)
+	require.Equal(t, heldID, queue[0].ID)
+	require.Equal(t, sender.OwnerName, queue[0].Username)
+	require.Equal(t, sender.PublicID, queue[0].FromAccountID)
+	require.Equal(t, other.PublicID, queue[0].ToAccountID)
+	require.JSONEq(t, `[{"rule":"first","type":"new_beneficiary","action":"review","reason":"first transfer to account [`+
+		other.PublicID+`] is 60, at least 50"}]`, string(queue[0].Hits))
+
+	recorder = send(banker.Username, util.BankerRole, http.MethodGet, "/v1/admin/risk_decisions?status=blocked&page_id=1&page_size=5", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	err = json.Unmarshal(recorder.Body.Bytes(), &queue)
+	require.NoError(t, err)
+	require.Len(t, queue, 1)
+	require.Equal(t, blockedID, queue[0].ID)
+	require.Equal(t, db.RiskDecisionBlock, queue[0].Decision)
+
+	//a blocked transfer can't be approved
+	recorder = send(banker.Username, util.BankerRole, http.MethodPost, fmt.Sprintf("/v1/admin/risk_decisions/%d/approve", blockedID), nil)
+	require.Equal(t, http.StatusConflict, recorder.Code)
+
+	//staff can't wave their own transfers through
+	_, err = store.UpdateUserRole(ctx, db.UpdateUserRoleParams{Username: sender.OwnerName, Role: util.BankerRole})
+	require.NoError(t, err)
+	recorder = send(sender.OwnerName, util.BankerRole, http.MethodPost, fmt.Sprintf("/v1/admin/risk_decisions/%d/approve", heldID), nil)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+	require.Equal(t, int64(430), balance(sender))
+	_, err = store.UpdateUserRole(ctx, db.UpdateUserRoleParams{Username: sender.OwnerName, Role: util.DepositorRole})
+	require.NoError(t, err)
+
+	recorder = send(banker.Username, util.BankerRole, http.MethodPost, fmt.Sprintf("/v1/admin/risk_decisions/%d/approve", heldID), nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var result approveRiskDecisionResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &result)
+	require.NoError(t, err)
+	require.Equal(t, db.RiskStatusApproved, result.Decision.Status)
+	require.Equal(t, banker.Username, result.Decision.ReviewedBy)
+	require.Equal(t, result.Transfer.Transfer.ID, result.Decision.TransferID)
+	require.Equal(t, int64(370), balance(sender))
+	require.Equal(t, int64(60), balance(other))
+
+	//reviewed only once
+	recorder = send(banker.Username, util.BankerRole, http.MethodPost, fmt.Sprintf("/v1/admin/risk_decisions/%d/reject", heldID), nil)
+	require.Equal(t, http.StatusConflict, recorder.Code)
+
+	recorder = send(banker.Username, util.BankerRole, http.MethodPost, "/v1/admin/risk_decisions/1000/reject", nil)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+
+	//a rejected transfer is never made
+	third := createAccount(0)
+	recorder = transfer(third, 70)
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+	rejectedID := decisionID(recorder, "TRANSFER_HELD_FOR_REVIEW")
+
+	recorder = send(banker.Username, util.BankerRole, http.MethodPost, fmt.Sprintf("/v1/admin/risk_decisions/%d/reject", rejectedID), nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var rejected riskDecisionResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &rejected)
+	require.NoError(t, err)
+	require.Equal(t, db.RiskStatusRejected, rejected.Status)
+	require.Zero(t, rejected.TransferID)
+	require.Equal(t, int64(370), balance(sender))
+	require.Zero(t, balance(third))
+}
+
+//Batches and splits are screened item by item, a blocking rule can't be got around by putting the transfer in one
+func TestRiskScreeningBatchAndSplitAPI(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+
+	createAccount := func(balance int64) db.Account {
+		user, err := store.CreateUser(ctx, db.CreateUserParams{
+			Username:       util.RandomOwnerName(),
+			HashedPassword: "secret",
+			FullName:       util.RandomOwnerName(),
+			Email:          util.RandomEmail(),
+		})
+		require.NoError(t, err)
+		user, err = store.UpdateUserEmailVerified(ctx, db.UpdateUserEmailVerifiedParams{Username: user.Username, Email: user.Email})
+		require.NoError(t, err)
+
+		account, err := store.CreateAccount(ctx, db.CreateAccountParams{
+			OwnerName: user.Username,
+			Balance:   balance,
+			Currency:  util.USD,
+		})
+		require.NoError(t, err)
+		return account
+	}
+
+	sender := createAccount(500)
+	payee := createAccount(0)
+	other := createAccount(0)
+	third := createAccount(0)
+
+	server := newTestServer(t, store)
+	server.risk = risk.NewEngine(store, []risk.Rule{
+		{Name: "first", Type: risk.NewBeneficiaryRule, Action: db.RiskDecisionReview, MinAmount: 50},
+		{Name: "first_large", Type: risk.NewBeneficiaryRule, Action: db.RiskDecisionBlock, MinAmount: 90},
+	})
+
+	send := func(url string, body gin.H) *httptest.ResponseRecorder {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, sender.OwnerName, util.DepositorRole, time.Minute)
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+	batch := func(mode string) *httptest.ResponseRecorder {
+		return send("/v1/transfers/batch", gin.H{
+			"from_account_id": sender.PublicID,
+			"currency":        util.USD,
+			"mode":            mode,
+			"transfers": []gin.H{
+				{"to_account_id": payee.PublicID, "amount": 10},
+				{"to_account_id": other.PublicID, "amount": 95},
+			},
+		})
+	}
+	split := func(to ...gin.H) *httptest.ResponseRecorder {
+		var total int64
+		for _, leg := range to {
+			total += leg["amount"].(int64)
+		}
+		return send("/v1/transfers/split", gin.H{
+			"currency": util.USD,
+			"from":     []gin.H{{"account_id": sender.PublicID, "amount": total}},
+			"to":       to,
+		})
+	}
+	decisions := func(status string) []db.RiskDecision {
+		decisions, err := store.ListRiskDecisionsByStatus(ctx, db.ListRiskDecisionsByStatusParams{Status: status, Limit: 10})
+		require.NoError(t, err)
+		return decisions
+	}
+	balance := func(account db.Account) int64 {
+		account, err := store.GetAccount(ctx, account.ID)
+		require.NoError(t, err)
+		return account.Balance
+	}
+
+	//an atomic batch with a blocked item makes none of its transfers
+	recorder := batch(batchModeAtomic)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+	var body struct {
+		Code    string                    `json:"code"`
+		Results []batchTransferItemResult `json:"results"`
+	}
+	err := json.Unmarshal(recorder.Body.Bytes(), &body)
+	require.NoError(t, err)
+	require.Equal(t, "TRANSFER_BLOCKED", body.Code)
+	require.Equal(t, batchItemNotExecuted, body.Results[0].Status)
+	require.Equal(t, "TRANSFER_BLOCKED", body.Results[1].Code)
+	require.Len(t, decisions(db.RiskStatusBlocked), 1)
+	require.Equal(t, body.Results[1].RiskDecisionID, decisions(db.RiskStatusBlocked)[0].ID)
+	require.Equal(t, int64(500), balance(sender))
+
+	//in best effort mode only the blocked item fails
+	recorder = batch(batchModeBestEffort)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var rsp batchTransferResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Equal(t, 1, rsp.Succeeded)
+	require.Equal(t, 1, rsp.Failed)
+	require.Equal(t, "TRANSFER_BLOCKED", rsp.Results[1].Code)
+	require.Len(t, decisions(db.RiskStatusBlocked), 2)
+	require.Equal(t, int64(490), balance(sender))
+	require.Zero(t, balance(other))
+
+	//a split is refused as a whole when one of its transfers is blocked
+	recorder = split(gin.H{"account_id": payee.PublicID, "amount": int64(5)}, gin.H{"account_id": other.PublicID, "amount": int64(95)})
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+	require.Len(t, decisions(db.RiskStatusBlocked), 3)
+	require.Equal(t, int64(490), balance(sender))
+	require.Zero(t, balance(other))
+
+	//or when one needs a review, which can't make the split in part and so is recorded as refused
+	recorder = split(gin.H{"account_id": payee.PublicID, "amount": int64(40)}, gin.H{"account_id": third.PublicID, "amount": int64(60)})
+	require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	var held struct {
+		Code            string  `json:"code"`
+		RiskDecisionIDs []int64 `json:"risk_decision_ids"`
+	}
+	err = json.Unmarshal(recorder.Body.Bytes(), &held)
+	require.NoError(t, err)
+	require.Equal(t, "TRANSFER_NEEDS_REVIEW", held.Code)
+	require.Empty(t, decisions(db.RiskStatusPending))
+	refused := decisions(db.RiskStatusRefused)
+	require.Len(t, refused, 1)
+	require.Equal(t, []int64{refused[0].ID}, held.RiskDecisionIDs)
+	require.Equal(t, third.ID, refused[0].ToAccountID)
+	require.Equal(t, int64(490), balance(sender))
+
+	//the same goes for an atomic batch
+	recorder = send("/v1/transfers/batch", gin.H{
+		"from_account_id": sender.PublicID,
+		"currency":        util.USD,
+		"transfers": []gin.H{
+			{"to_account_id": payee.PublicID, "amount": 10},
+			{"to_account_id": third.PublicID, "amount": 60},
+		},
+	})
+	require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	err = json.Unmarshal(recorder.Body.Bytes(), &body)
+	require.NoError(t, err)
+	require.Equal(t, "TRANSFER_NEEDS_REVIEW", body.Code)
+	require.Equal(t, "TRANSFER_NEEDS_REVIEW", body.Results[1].Code)
+	require.Empty(t, decisions(db.RiskStatusPending))
+	require.Len(t, decisions(db.RiskStatusRefused), 2)
+
+	//so that a reviewer can't make one transfer of a request that was refused
+	banker, err := store.UpdateUserRole(ctx, db.UpdateUserRoleParams{Username: payee.OwnerName, Role: util.BankerRole})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/v1/admin/risk_decisions/%d/approve", body.Results[1].RiskDecisionID), nil)
+	require.NoError(t, err)
+	recorder = httptest.NewRecorder()
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, banker.Username, util.BankerRole, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusConflict, recorder.Code)
+	require.Equal(t, int64(490), balance(sender))
+	require.Zero(t, balance(third))
+}
+
+//The items of a batch count towards the velocity of the items after them, a burst can't be sent as one request
+func TestRiskScreeningBatchVelocityAPI(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+
+	createAccount := func(balance int64) db.Account {
+		user, err := store.CreateUser(ctx, db.CreateUserParams{
+			Username:       util.RandomOwnerName(),
+			HashedPassword: "secret",
+			FullName:       util.RandomOwnerName(),
+			Email:          util.RandomEmail(),
+		})
+		require.NoError(t, err)
+		user, err = store.UpdateUserEmailVerified(ctx, db.UpdateUserEmailVerifiedParams{Username: user.Username, Email: user.Email})
+		require.NoError(t, err)
+
+		account, err := store.CreateAccount(ctx, db.CreateAccountParams{OwnerName: user.Username, Balance: balance, Currency: util.USD})
+		require.NoError(t, err)
+		return account
+	}
+
+	sender := createAccount(500)
+	payee := createAccount(0)
+
+	server := newTestServer(t, store)
+	server.risk = risk.NewEngine(store, []risk.Rule{
+		{Name: "burst", Type: risk.VelocityRule, Action: db.RiskDecisionReview, MaxCount: 2, Window: risk.Duration(time.Minute)},
+	})
+
+	data, err := json.Marshal(gin.H{
+		"from_account_id": sender.PublicID,
+		"currency":        util.USD,
+		"mode":            batchModeBestEffort,
+		"transfers": []gin.H{
+			{"to_account_id": payee.PublicID, "amount": 10},
+			{"to_account_id": payee.PublicID, "amount": 10},
+			{"to_account_id": payee.PublicID, "amount": 10},
+		},
+	})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, "/v1/transfers/batch", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, sender.OwnerName, util.DepositorRole, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp batchTransferResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Equal(t, 2, rsp.Succeeded)
+	require.Equal(t, "TRANSFER_HELD_FOR_REVIEW", rsp.Results[2].Code)
+	require.NotZero(t, rsp.Results[2].RiskDecisionID)
+}
+
+func TestListRiskDecisionsAPI(t *testing.T) {
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "DefaultsToPending",
+			query: "page_id=2&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListRiskDecisionsByStatusParams{Status: db.RiskStatusPending, Limit: 5, Offset: 5}
+				store.EXPECT().ListRiskDecisionsByStatus(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.RiskDecision{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidStatus",
+			query: "status=allowed&page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListRiskDecisionsByStatus(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/v1/admin/risk_decisions?"+tc.query, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, util.RandomOwnerName(), util.BankerRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
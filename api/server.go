@@ -1,10 +1,24 @@
 package api
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/mail"
+	"github.com/kingsleyocran/simple_bank_bankend/ratelimit"
+	"github.com/kingsleyocran/simple_bank_bankend/risk"
+	"github.com/kingsleyocran/simple_bank_bankend/tlsconfig"
+	"github.com/kingsleyocran/simple_bank_bankend/token"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
 )
 
 /*
@@ -13,48 +27,227 @@ banking service. It will have 2 fields:
 
 @db.Store: It will allow us to interact with the database when processing API requests from clients.
 @gin.Engine. This router will help us send each API request to the correct handler for processing.
+
+The http.Server is kept around so that Shutdown can drain it gracefully, and draining
+is flipped to 1 as soon as shutdown starts so that /readyz stops reporting ready.
 */
 
 type Server struct {
-	store  db.Store
-	router *gin.Engine
+	config     util.Config
+	store      db.Store
+	tokenMaker token.Maker
+	mailer     mail.Mailer
+	router     *gin.Engine
+	httpServer *http.Server
+	limiter    ratelimit.Limiter
+	risk       *risk.Engine
+	services   tlsconfig.ServiceIdentities
+	draining   int32
+}
+
+//route is one endpoint together with the roles allowed to call it, public routes have none.
+//Handlers can still narrow access further, e.g. depositors only see their own accounts.
+type route struct {
+	method  string
+	path    string
+	handler gin.HandlerFunc
+	roles   []string
+}
+
+var (
+	allRoles   = []string{util.DepositorRole, util.BankerRole, util.AdminRole}
+	staffRoles = []string{util.BankerRole, util.AdminRole}
+	adminRoles = []string{util.AdminRole}
+)
+
+//publicRoutes are served without authentication
+func (server *Server) publicRoutes() []route {
+	return []route{
+		{http.MethodPost, "/users", server.createUser, nil},
+		{http.MethodPost, "/users/login", server.loginUser, nil},
+		{http.MethodGet, "/verify_email", server.verifyEmail, nil},
+		{http.MethodPost, "/users/forgot_password", server.forgotPassword, nil},
+		{http.MethodGet, "/users/reset_password", server.checkResetPassword, nil},
+		{http.MethodPost, "/users/reset_password", server.resetPassword, nil},
+	}
+}
+
+//routes is the permission table of the API, every authenticated route must be listed here
+func (server *Server) routes() []route {
+	return []route{
+		{http.MethodPut, "/users/me/password", server.changePassword, allRoles},
+		{http.MethodPost, "/users/me/verify_email", server.resendVerifyEmail, allRoles},
+		{http.MethodPost, "/users/me/totp", server.enrollTOTP, allRoles},
+		{http.MethodPost, "/users/me/totp/confirm", server.confirmTOTP, allRoles},
+
+		{http.MethodPost, "/accounts", server.createAccount, allRoles},
+		{http.MethodGet, "/accounts/:id", server.getAccount, allRoles},
+		{http.MethodGet, "/accounts", server.listAccount, allRoles},
+		{http.MethodGet, "/accounts/:id/interest", server.getAccountInterest, allRoles},
+		{http.MethodGet, "/accounts/:id/balance", server.getAccountBalance, allRoles},
+
+		//{http.MethodPost, "/entries", server.createEntry, adminRoles},
+		{http.MethodGet, "/entries/:id", server.getEntry, allRoles},
+		{http.MethodGet, "/entries", server.listEntries, allRoles},
+
+		{http.MethodPost, "/transfers", server.createTransfer, allRoles},
+		{http.MethodPost, "/transfers/batch", server.createBatchTransfer, allRoles},
+		{http.MethodPost, "/transfers/quote", server.quoteTransfer, allRoles},
+		{http.MethodPost, "/transfers/split", server.createSplitTransfer, allRoles},
+		{http.MethodGet, "/transfers/:id", server.getTransfer, allRoles},
+		{http.MethodGet, "/transfers", server.listTransfers, allRoles},
+
+		{http.MethodPost, "/beneficiaries", server.createBeneficiary, allRoles},
+		{http.MethodGet, "/beneficiaries/:id", server.getBeneficiary, allRoles},
+		{http.MethodGet, "/beneficiaries", server.listBeneficiaries, allRoles},
+		{http.MethodPatch, "/beneficiaries/:id", server.updateBeneficiary, allRoles},
+		{http.MethodDelete, "/beneficiaries/:id", server.deleteBeneficiary, allRoles},
+
+		{http.MethodGet, "/admin/accounts/:id/limits", server.getAccountLimit, staffRoles},
+		{http.MethodPut, "/admin/accounts/:id/limits", server.setAccountLimit, adminRoles},
+		{http.MethodPost, "/admin/accounts/:id/freeze", server.freezeAccount, adminRoles},
+		{http.MethodPost, "/admin/accounts/:id/unfreeze", server.unfreezeAccount, adminRoles},
+		{http.MethodPut, "/admin/users/:username/role", server.updateUserRole, adminRoles},
+		{http.MethodPost, "/admin/imports", server.createImport, adminRoles},
+		{http.MethodPost, "/admin/products", server.createAccountProduct, adminRoles},
+		{http.MethodGet, "/admin/products", server.listAccountProducts, staffRoles},
+		{http.MethodPut, "/admin/accounts/:id/product", server.setAccountProduct, adminRoles},
+		{http.MethodPut, "/admin/interest_expense_accounts/:currency", server.setInterestExpenseAccount, adminRoles},
+		{http.MethodGet, "/admin/products/:code/fees", server.listProductFees, staffRoles},
+		{http.MethodPut, "/admin/products/:code/fees/:kind", server.setProductFee, adminRoles},
+		{http.MethodDelete, "/admin/products/:code/fees/:kind", server.deleteProductFee, adminRoles},
+		{http.MethodPut, "/admin/fee_revenue_accounts/:currency", server.setFeeRevenueAccount, adminRoles},
+		{http.MethodGet, "/admin/risk_decisions", server.listRiskDecisions, staffRoles},
+		{http.MethodGet, "/admin/risk_decisions/:id", server.getRiskDecision, staffRoles},
+		{http.MethodPost, "/admin/risk_decisions/:id/approve", server.approveRiskDecision, staffRoles},
+		{http.MethodPost, "/admin/risk_decisions/:id/reject", server.rejectRiskDecision, staffRoles},
+	}
 }
 
 /*
-NewServer takes a db.Store as input, and return a Server. This function will create a new Server
+NewServer takes a db.Store and a mail.Mailer as input, and return a Server. This function will create a new Server
 instance, and setup all HTTP API routes for our service on that server.
 */
 
-func NewServer(store db.Store) *Server {
-	server := &Server{store: store}
-	router := gin.Default()
+func NewServer(config util.Config, store db.Store, mailer mail.Mailer) (*Server, error) {
+	rateLimits, err := ratelimit.ParseRules(config.Server.RateLimits)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse rate limits: %w", err)
+	}
+
+	var riskRules []risk.Rule
+	if config.Transfers.RiskRulesFile != "" {
+		riskRules, err = risk.LoadRules(config.Transfers.RiskRulesFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var tlsConfig *tls.Config
+	if config.TLS.CertFile != "" {
+		tlsConfig, err = tlsconfig.New(config.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("cannot configure TLS: %w", err)
+		}
+	}
+
+	services, err := tlsconfig.ParseServiceIdentities(config.TLS.ServiceIdentities)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenMaker, err := token.NewJWTMaker(config.Auth.TokenSymmetricKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create token maker: %w", err)
+	}
+
+	server := &Server{
+		config:     config,
+		store:      store,
+		tokenMaker: tokenMaker,
+		mailer:     mailer,
+		limiter:    ratelimit.NewMemoryLimiter(),
+		risk:       risk.NewEngine(store, riskRules),
+		services:   services,
+	}
+	router := gin.New()
+	//gin believes X-Forwarded-For from any peer unless told otherwise, which would let clients pick their rate limit key
+	if err := router.SetTrustedProxies(config.Server.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("cannot set trusted proxies: %w", err)
+	}
+	//the request id is set first so that the log line and every error body carry it
+	router.Use(requestIDMiddleware(), requestLogger(config.Log), gin.CustomRecovery(recoverError))
+	router.NoRoute(func(ctx *gin.Context) {
+		writeError(ctx, http.StatusNotFound, errors.New("route not found"))
+	})
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
-		v.RegisterValidation("currency", validCurrency)
+		util.RegisterValidators(v)
+		v.RegisterValidation("publicid", validPublicID)
+		v.RegisterTagNameFunc(fieldName)
 	}
 
-	router.POST("/accounts", server.createAccount)
-	router.GET("/accounts/:id", server.getAccount)
-	router.GET("/accounts", server.listAccount)
+	router.GET("/healthz", server.healthz)
+	router.GET("/readyz", server.readyz)
+
+	//probes are registered first so that they are never rate limited
+	router.Use(rateLimitMiddleware(server.limiter, rateLimits, tokenMaker))
 
-	//router.POST("/entries", server.createEntry)
-	router.GET("/entries/:id", server.getEntry)
-	router.GET("/entries", server.listEntries)
+	versions, err := server.versions()
+	if err != nil {
+		return nil, err
+	}
+	for _, version := range versions {
+		group := router.Group(version.prefix)
+		if version.successor != "" {
+			group.Use(deprecationMiddleware(version))
+		}
 
-	router.POST("/transfers", server.createTransfer)
-	router.GET("/transfers/:id", server.getTransfer)
-	router.GET("/transfers", server.listTransfers)
+		for _, r := range version.public {
+			group.Handle(r.method, r.path, r.handler)
+		}
+		for _, r := range version.routes {
+			group.Handle(r.method, r.path, authMiddleware(tokenMaker, store, services), authorizeMiddleware(r.roles), r.handler)
+		}
+	}
 
 	server.router = router
-	return server
+	server.httpServer = &http.Server{Handler: router, TLSConfig: tlsConfig}
+	return server, nil
 }
 
-//Starts a server on an address
+//Starts a server on an address, over TLS when a certificate is configured
+//It returns nil once the server has been stopped by Shutdown
 func (server *Server) Start(address string) error {
-	return server.router.Run(address)
+	server.httpServer.Addr = address
+
+	var err error
+	if server.httpServer.TLSConfig != nil {
+		//the certificate comes from TLSConfig.GetCertificate so that it can be reloaded
+		err = server.httpServer.ListenAndServeTLS("", "")
+	} else {
+		err = server.httpServer.ListenAndServe()
+	}
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+//Shutdown marks the server as draining so the orchestrator stops routing traffic to it,
+//waits for the configured drain period and then gracefully closes all connections.
+func (server *Server) Shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&server.draining, 1)
+
+	select {
+	case <-time.After(server.config.Server.ShutdownDrainPeriod):
+	case <-ctx.Done():
+	}
+
+	return server.httpServer.Shutdown(ctx)
 }
 
-//Custom error function to handle errors
-func errorResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
+//isDraining reports whether Shutdown has been called
+func (server *Server) isDraining() bool {
+	return atomic.LoadInt32(&server.draining) == 1
 }
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/risk"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+)
+
+type splitTransferLeg struct {
+	AccountID string `json:"account_id" binding:"required,publicid=acc_"`
+	Amount    int64  `json:"amount" binding:"required,gt=0"`
+}
+
+//splitTransferRequest is one source paying several destinations or several sources paying one destination.
+//The amounts of both sides must add up to the same total.
+type splitTransferRequest struct {
+	Currency string `json:"currency" binding:"required,currency"`
+	TOTPCode string `json:"totp_code" binding:"omitempty,len=6,numeric"`
+	//every account of a split stays locked until it commits, so the number of legs is bounded
+	From []splitTransferLeg `json:"from" binding:"required,min=1,max=100,dive"`
+	To   []splitTransferLeg `json:"to" binding:"required,min=1,max=100,dive"`
+}
+
+//createSplitTransfer pays one bill split across several accounts, or collects the shares of several
+//of your own accounts into one, in a single transaction. Every source must belong to the authenticated user.
+func (server *Server) createSplitTransfer(ctx *gin.Context) {
+	var req splitTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(req.From) > 1 && len(req.To) > 1 {
+		err := errors.New("either from or to must have a single account")
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var sent, received int64
+	seen := make(map[string]bool)
+	for _, leg := range append(append([]splitTransferLeg{}, req.From...), req.To...) {
+		//checked here as well as by the store, whose error would name the internal id
+		if seen[leg.AccountID] {
+			err := fmt.Errorf("account [%s] appears in more than one leg: %w", leg.AccountID, db.ErrInvalidPosting)
+			writeError(ctx, http.StatusBadRequest, err)
+			return
+		}
+		seen[leg.AccountID] = true
+	}
+	var sentOK, receivedOK bool
+	sent, sentOK = sumLegs(req.From)
+	received, receivedOK = sumLegs(req.To)
+	if !sentOK || !receivedOK {
+		err := fmt.Errorf("the legs add up to more than an amount can hold: %w", db.ErrInvalidPosting)
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if sent != received {
+		err := fmt.Errorf("from adds up to %d but to adds up to %d", sent, received)
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	authPayload := getAuthPayload(ctx)
+	arg := db.PostingTxParams{}
+	var fromAccounts, toAccounts []db.Account
+	for _, leg := range req.From {
+		fromAccount, valid := server.validAccount(ctx, leg.AccountID, req.Currency)
+		if !valid {
+			return
+		}
+		if fromAccount.OwnerName != authPayload.Username {
+			err := fmt.Errorf("from account [%s] doesn't belong to the authenticated user", fromAccount.PublicID)
+			writeError(ctx, http.StatusForbidden, err)
+			return
+		}
+		arg.Legs = append(arg.Legs, db.PostingLeg{AccountID: fromAccount.ID, Amount: -leg.Amount})
+		fromAccounts = append(fromAccounts, fromAccount)
+	}
+
+	if !getAuthUser(ctx).IsEmailVerified {
+		writeError(ctx, http.StatusForbidden, errEmailNotVerified)
+		return
+	}
+
+	for _, leg := range req.To {
+		toAccount, valid := server.validAccount(ctx, leg.AccountID, req.Currency)
+		if !valid {
+			return
+		}
+		if server.coolingOff(ctx, toAccount, leg.Amount) {
+			return
+		}
+		arg.Legs = append(arg.Legs, db.PostingLeg{AccountID: toAccount.ID, Amount: leg.Amount})
+		toAccounts = append(toAccounts, toAccount)
+	}
+
+	//the second factor is asked for the total, splitting a large payment must not avoid it
+	secondFactor, valid := server.requireTOTP(ctx, authPayload.Username, req.TOTPCode, sent)
+	if !valid {
+		return
+	}
+	arg.SecondFactor = secondFactor
+
+	if server.screenSplitTransfer(ctx, req, fromAccounts, toAccounts) {
+		return
+	}
+
+	result, err := server.store.PostingTx(ctx, arg)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, transferError(err))
+		return
+	}
+
+	ids := server.publicIDs(ctx)
+	ids.json(http.StatusOK, ids.newPostingResponse(result))
+}
+
+//sumLegs adds up the amounts of one side of a split, ok is false when they don't fit in an amount
+func sumLegs(legs []splitTransferLeg) (total int64, ok bool) {
+	for _, leg := range legs {
+		total, ok = util.AddAmount(total, leg.Amount)
+		if !ok {
+			return 0, false
+		}
+	}
+	return total, true
+}
+
+//screenSplitTransfer runs the fraud rules on every transfer the split is made of, the same transfers PostingTx creates.
+//All of them are screened so each one held or blocked is recorded, and then the whole split is refused.
+//Held transfers are recorded as refused, a reviewer can't make part of a split.
+func (server *Server) screenSplitTransfer(ctx *gin.Context, req splitTransferRequest, fromAccounts []db.Account, toAccounts []db.Account) bool {
+	var transfers []riskTransfer
+	if len(req.From) == 1 {
+		for i, leg := range req.To {
+			transfers = append(transfers, riskTransfer{From: fromAccounts[0], To: toAccounts[i], Amount: leg.Amount, Currency: req.Currency, Atomic: true})
+		}
+	} else {
+		for i, leg := range req.From {
+			transfers = append(transfers, riskTransfer{From: fromAccounts[i], To: toAccounts[0], Amount: leg.Amount, Currency: req.Currency, Atomic: true})
+		}
+	}
+
+	decisionIDs := []int64{}
+	blocked := false
+	var screened []risk.Transfer
+	for _, transfer := range transfers {
+		transfer.Earlier = screened
+		record, err := server.evaluateTransfer(ctx, transfer)
+		if err != nil {
+			//fail closed, a transfer that couldn't be screened is not made
+			writeError(ctx, http.StatusInternalServerError, err)
+			return true
+		}
+		screened = append(screened, risk.Transfer{From: transfer.From, To: transfer.To, Amount: transfer.Amount})
+		if record == nil {
+			continue
+		}
+		decisionIDs = append(decisionIDs, record.ID)
+		blocked = blocked || record.Decision == db.RiskDecisionBlock
+	}
+	if len(decisionIDs) == 0 {
+		return false
+	}
+
+	msg := fmt.Sprintf("%d of %d transfers were held or blocked by fraud screening, nothing was transferred", len(decisionIDs), len(transfers))
+	apiErr := newAPIError(http.StatusUnprocessableEntity, codeTransferNeedsReview, msg)
+	if blocked {
+		apiErr = newAPIError(http.StatusForbidden, codeTransferBlocked, msg)
+	}
+	writeError(ctx, apiErr.status, apiErr.with("risk_decision_ids", decisionIDs))
+	return true
+}
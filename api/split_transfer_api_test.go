@@ -0,0 +1,216 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kingsleyocran/simple_bank_bankend/db/mock"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSplitTransferAPI(t *testing.T) {
+	user := util.RandomOwnerName()
+	account1 := randomAccount(user)
+	account2 := randomAccount(util.RandomOwnerName())
+	account3 := randomAccount(util.RandomOwnerName())
+	account1.ID, account2.ID, account3.ID = 1, 2, 3
+	account1.Currency, account2.Currency, account3.Currency = util.USD, util.USD, util.USD
+
+	split := gin.H{
+		"currency": util.USD,
+		"from":     []gin.H{{"account_id": account1.PublicID, "amount": 30}},
+		"to":       []gin.H{{"account_id": account2.PublicID, "amount": 10}, {"account_id": account3.PublicID, "amount": 20}},
+	}
+
+	expectAccounts := func(store *mockdb.MockStore, accounts ...db.Account) {
+		for _, account := range accounts {
+			store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).Times(1).Return(account, nil)
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: split,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account1, account2, account3)
+
+				arg := db.PostingTxParams{Legs: []db.PostingLeg{
+					{AccountID: account1.ID, Amount: -30},
+					{AccountID: account2.ID, Amount: 10},
+					{AccountID: account3.ID, Amount: 20},
+				}}
+				store.EXPECT().
+					PostingTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.PostingTxResult{
+						Transfers: []db.Transfer{
+							{ID: 1, PublicID: "tr_1", FromAccountID: account1.ID, ToAccountID: account2.ID},
+							{ID: 2, PublicID: "tr_2", FromAccountID: account1.ID, ToAccountID: account3.ID},
+						},
+						Accounts: []db.Account{account1, account2, account3},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got postingResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Len(t, got.Transfers, 2)
+				require.Equal(t, account1.PublicID, got.Transfers[1].FromAccountID)
+				require.Equal(t, account3.PublicID, got.Transfers[1].ToAccountID)
+			},
+		},
+		{
+			name: "ManyToMany",
+			body: gin.H{
+				"currency": util.USD,
+				"from":     []gin.H{{"account_id": account1.PublicID, "amount": 10}, {"account_id": account2.PublicID, "amount": 10}},
+				"to":       []gin.H{{"account_id": account3.PublicID, "amount": 10}, {"account_id": randomAccount(user).PublicID, "amount": 10}},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().PostingTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "Unbalanced",
+			body: gin.H{
+				"currency": util.USD,
+				"from":     []gin.H{{"account_id": account1.PublicID, "amount": 30}},
+				"to":       []gin.H{{"account_id": account2.PublicID, "amount": 10}},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().PostingTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "Overflow",
+			body: gin.H{
+				"currency": util.USD,
+				"from":     []gin.H{{"account_id": account1.PublicID, "amount": 5}},
+				"to": []gin.H{
+					{"account_id": account2.PublicID, "amount": int64(math.MaxInt64)},
+					{"account_id": account3.PublicID, "amount": int64(math.MaxInt64)},
+					{"account_id": randomAccount(user).PublicID, "amount": 7},
+				},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().PostingTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "SourceOfAnotherUser",
+			body: gin.H{
+				"currency": util.USD,
+				"from":     []gin.H{{"account_id": account1.PublicID, "amount": 10}, {"account_id": account2.PublicID, "amount": 10}},
+				"to":       []gin.H{{"account_id": account3.PublicID, "amount": 20}},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account1, account2)
+				store.EXPECT().PostingTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			body: gin.H{
+				"currency": util.EUR,
+				"from":     []gin.H{{"account_id": account1.PublicID, "amount": 30}},
+				"to":       []gin.H{{"account_id": account2.PublicID, "amount": 30}},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account1)
+				store.EXPECT().PostingTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			body: split,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAccounts(store, account1, account2, account3)
+				store.EXPECT().
+					PostingTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.PostingTxResult{}, fmt.Errorf("account [%s]: %w", account1.PublicID, db.ErrInsufficientFunds))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidPosting",
+			body: gin.H{
+				"currency": util.USD,
+				"from":     []gin.H{{"account_id": account1.PublicID, "amount": 30}},
+				"to":       []gin.H{{"account_id": account1.PublicID, "amount": 30}},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().PostingTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				body := requireErrorBody(t, recorder, http.StatusBadRequest, codeInvalidPosting)
+				require.Contains(t, body.Error, account1.PublicID)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/v1/transfers/split", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/totp"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+)
+
+//recoveryCodeCount is the number of recovery codes handed out when two-factor authentication is enabled
+const recoveryCodeCount = 10
+
+var errInvalidSecondFactor = errors.New("two-factor code is invalid or was already used")
+
+//enrollTOTPResponse holds what the authenticator app needs, the otpauth URI is usually shown as a QR code
+type enrollTOTPResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+//enrollTOTP starts two-factor enrollment with a new secret, it only takes effect once confirmed.
+//Calling it again before confirming replaces the secret.
+func (server *Server) enrollTOTP(ctx *gin.Context) {
+	user := getAuthUser(ctx)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	userTOTP, err := server.store.UpsertUserTOTP(ctx, db.UpsertUserTOTPParams{
+		Username: user.Username,
+		Secret:   secret,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.New("two-factor authentication is already enabled")
+			writeError(ctx, http.StatusConflict, err)
+			return
+		}
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrollTOTPResponse{
+		Secret:     userTOTP.Secret,
+		OtpauthURI: totp.URI(server.config.Auth.TOTPIssuer, user.Username, userTOTP.Secret),
+	})
+}
+
+//confirmTOTPRequest holds a code generated by the authenticator app
+type confirmTOTPRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+//confirmTOTPResponse returns the recovery codes, they are only ever shown here
+type confirmTOTPResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+//confirmTOTP enables two-factor authentication once the user sent a valid code
+func (server *Server) confirmTOTP(ctx *gin.Context) {
+	var req confirmTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user := getAuthUser(ctx)
+	userTOTP, err := server.store.GetUserTOTP(ctx, user.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.New("two-factor enrollment has not been started")
+			writeError(ctx, http.StatusNotFound, err)
+			return
+		}
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if userTOTP.IsEnabled {
+		err = errors.New("two-factor authentication is already enabled")
+		writeError(ctx, http.StatusConflict, err)
+		return
+	}
+
+	step, ok := totp.Validate(userTOTP.Secret, req.Code, time.Now())
+	if !ok || step <= userTOTP.LastUsedStep {
+		writeError(ctx, http.StatusBadRequest, errInvalidSecondFactor)
+		return
+	}
+
+	recoveryCodes := make([]string, recoveryCodeCount)
+	recoveryCodeHashes := make([]string, recoveryCodeCount)
+	for i := range recoveryCodes {
+		recoveryCodes[i], err = util.GenerateSecret(5)
+		if err != nil {
+			writeError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		recoveryCodeHashes[i] = util.HashSecret(recoveryCodes[i])
+	}
+
+	_, err = server.store.EnableTOTPTx(ctx, db.EnableTOTPTxParams{
+		Username:           user.Username,
+		Step:               step,
+		RecoveryCodeHashes: recoveryCodeHashes,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(ctx, http.StatusBadRequest, errInvalidSecondFactor)
+			return
+		}
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, confirmTOTPResponse{RecoveryCodes: recoveryCodes})
+}
+
+//getEnabledTOTP returns the user's TOTP settings, enabled is false when the user never enrolled
+//or didn't confirm the enrollment yet
+func (server *Server) getEnabledTOTP(ctx *gin.Context, username string) (userTOTP db.UserTotp, enabled bool, err error) {
+	userTOTP, err = server.store.GetUserTOTP(ctx, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return userTOTP, false, nil
+		}
+		return userTOTP, false, err
+	}
+	return userTOTP, userTOTP.IsEnabled, nil
+}
+
+//checkTOTP returns the time step of a valid code that wasn't used yet, without using it
+func checkTOTP(userTOTP db.UserTotp, code string) (int64, error) {
+	step, ok := totp.Validate(userTOTP.Secret, code, time.Now())
+	if !ok || step <= userTOTP.LastUsedStep {
+		return 0, errInvalidSecondFactor
+	}
+	return step, nil
+}
+
+//verifyTOTP checks a code and records its time step, so that every code can only be used once
+func (server *Server) verifyTOTP(ctx *gin.Context, userTOTP db.UserTotp, code string) error {
+	step, err := checkTOTP(userTOTP, code)
+	if err != nil {
+		return err
+	}
+
+	//the update only succeeds for a newer step, which also covers two requests racing with the same code
+	_, err = server.store.UseTOTPStep(ctx, db.UseTOTPStepParams{
+		Step:     step,
+		Username: userTOTP.Username,
+	})
+	if err == sql.ErrNoRows {
+		return errInvalidSecondFactor
+	}
+	return err
+}
+
+//useRecoveryCode consumes one of the user's recovery codes
+func (server *Server) useRecoveryCode(ctx *gin.Context, username string, code string) error {
+	_, err := server.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+		Username: username,
+		CodeHash: util.HashSecret(strings.ToLower(strings.TrimSpace(code))),
+	})
+	if err == sql.ErrNoRows {
+		return errInvalidSecondFactor
+	}
+	return err
+}
@@ -0,0 +1,463 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kingsleyocran/simple_bank_bankend/db/mock"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/totp"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomUserTOTP(t *testing.T, username string, enabled bool) db.UserTotp {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	return db.UserTotp{
+		Username:  username,
+		Secret:    secret,
+		IsEnabled: enabled,
+	}
+}
+
+func currentCode(t *testing.T, userTOTP db.UserTotp) string {
+	code, err := totp.Code(userTOTP.Secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+func TestEnrollTOTPAPI(t *testing.T) {
+	username := util.RandomOwnerName()
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpsertUserTOTP(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UpsertUserTOTPParams) (db.UserTotp, error) {
+						require.Equal(t, username, arg.Username)
+						return db.UserTotp{Username: arg.Username, Secret: arg.Secret}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp enrollTOTPResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.Secret)
+				require.Contains(t, rsp.OtpauthURI, "otpauth://totp/SimpleBank:"+username)
+				require.Contains(t, rsp.OtpauthURI, "secret="+rsp.Secret)
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpsertUserTOTP(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UserTotp{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/v1/users/me/totp", nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestConfirmTOTPAPI(t *testing.T) {
+	username := util.RandomOwnerName()
+	userTOTP := randomUserTOTP(t, username, false)
+	enabledTOTP := randomUserTOTP(t, username, true)
+
+	testCases := []struct {
+		name          string
+		code          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			code: currentCode(t, userTOTP),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(username)).Times(1).Return(userTOTP, nil)
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.EnableTOTPTxParams) (db.UserTotp, error) {
+						require.Equal(t, username, arg.Username)
+						require.Equal(t, totp.Step(time.Now()), arg.Step)
+						require.Len(t, arg.RecoveryCodeHashes, recoveryCodeCount)
+						return enabledTOTP, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp confirmTOTPResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp.RecoveryCodes, recoveryCodeCount)
+			},
+		},
+		{
+			name: "WrongCode",
+			code: "000000",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(username)).Times(1).Return(userTOTP, nil)
+				store.EXPECT().EnableTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NotEnrolled",
+			code: currentCode(t, userTOTP),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(username)).Times(1).Return(db.UserTotp{}, sql.ErrNoRows)
+				store.EXPECT().EnableTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			code: currentCode(t, enabledTOTP),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(username)).Times(1).Return(enabledTOTP, nil)
+				store.EXPECT().EnableTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCodeFormat",
+			code: "12ab56",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{"code": tc.code})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/v1/users/me/totp/confirm", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestLoginWithTOTPAPI(t *testing.T) {
+	user, password := randomUser(t)
+	userTOTP := randomUserTOTP(t, user.Username, true)
+	recoveryCode := util.RandomString(10)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "TOTPCode",
+			body: gin.H{
+				"username":  user.Username,
+				"password":  password,
+				"totp_code": currentCode(t, userTOTP),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Eq(db.UseTOTPStepParams{Step: totp.Step(time.Now()), Username: user.Username})).
+					Times(1).
+					Return(userTOTP, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "RecoveryCode",
+			body: gin.H{
+				"username":      user.Username,
+				"password":      password,
+				"recovery_code": recoveryCode,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UseRecoveryCodeParams{
+					Username: user.Username,
+					CodeHash: util.HashSecret(recoveryCode),
+				}
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.RecoveryCode{Username: user.Username, IsUsed: true}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "MissingCode",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "TOTP_REQUIRED")
+			},
+		},
+		{
+			name: "ReplayedCode",
+			body: gin.H{
+				"username":  user.Username,
+				"password":  password,
+				"totp_code": currentCode(t, userTOTP),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				//another request already used a code of this step
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UserTotp{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UsedRecoveryCode",
+			body: gin.H{
+				"username":      user.Username,
+				"password":      password,
+				"recovery_code": recoveryCode,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RecoveryCode{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(userTOTP, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/v1/users/login", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestCreateTransferTOTPAPI(t *testing.T) {
+	account1 := randomAccount(util.RandomOwnerName())
+	account2 := randomAccount(util.RandomOwnerName())
+	account2.Currency = account1.Currency
+	userTOTP := randomUserTOTP(t, account1.OwnerName, true)
+
+	//above the threshold of the test server
+	amount := int64(5000)
+
+	testCases := []struct {
+		name          string
+		code          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			code: currentCode(t, userTOTP),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(account1.OwnerName)).Times(1).Return(userTOTP, nil)
+				//the step is used up by the transfer transaction, not before it
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.TransferTxParams) (db.TransferTxResult, error) {
+						require.NotNil(t, arg.SecondFactor)
+						require.Equal(t, account1.OwnerName, arg.SecondFactor.Username)
+						require.Greater(t, arg.SecondFactor.Step, userTOTP.LastUsedStep)
+						return db.TransferTxResult{}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "CodeUsedMeanwhile",
+			code: currentCode(t, userTOTP),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(account1.OwnerName)).Times(1).Return(userTOTP, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrSecondFactorUsed)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "INVALID_TOTP")
+			},
+		},
+		{
+			name: "MissingCode",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(account1.OwnerName)).Times(1).Return(userTOTP, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "TOTP_REQUIRED")
+			},
+		},
+		{
+			name: "WrongCode",
+			code: "000000",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(account1.OwnerName)).Times(1).Return(userTOTP, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "INVALID_TOTP")
+			},
+		},
+		{
+			name: "NotEnrolled",
+			code: currentCode(t, userTOTP),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(account1.OwnerName)).Times(1).Return(db.UserTotp{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "TOTP_ENROLLMENT_REQUIRED")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(account1, nil)
+			store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account2.PublicID)).Times(1).Return(account2, nil)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body := gin.H{
+				"from_account_id": account1.PublicID,
+				"to_account_id":   account2.PublicID,
+				"amount":          amount,
+				"currency":        account1.Currency,
+			}
+			if tc.code != "" {
+				body["totp_code"] = tc.code
+			}
+
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/v1/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, account1.OwnerName, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
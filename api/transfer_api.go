@@ -2,57 +2,156 @@ package api
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
 )
 
+//transferRequest sends money either to to_account_id or to a beneficiary of the user, never both
 type transferRequest struct {
-	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
-	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
+	FromAccountID string `json:"from_account_id" binding:"required,publicid=acc_"`
+	ToAccountID   string `json:"to_account_id" binding:"required_without=BeneficiaryID,excluded_with=BeneficiaryID,omitempty,publicid=acc_"`
+	BeneficiaryID int64  `json:"beneficiary_id" binding:"omitempty,min=1"`
 	Amount        int64  `json:"amount" binding:"required,gt=0"`
 	Currency      string `json:"currency" binding:"required,currency"`
+	TOTPCode      string `json:"totp_code" binding:"omitempty,len=6,numeric"`
 	//Currency      string `json:"currency" binding:"required,oneof=USD EUR"`
+	Description string `json:"description" binding:"max=255"`
+	Reference   string `json:"reference" binding:"max=64"`
+	//Metadata is any JSON object the client wants to keep with the transfer, up to 2KB
+	Metadata json.RawMessage `json:"metadata" binding:"omitempty,max=2048,jsonobject"`
 }
 
 func (server *Server) createTransfer(ctx *gin.Context) {
 	var req transferRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	_, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
 	if !valid {
 		return
 	}
 
-	_, valid = server.validAccount(ctx, req.ToAccountID, req.Currency)
+	//whatever the role, money can only be sent from your own accounts
+	authPayload := getAuthPayload(ctx)
+	if fromAccount.OwnerName != authPayload.Username {
+		err := errors.New("from account doesn't belong to the authenticated user")
+		writeError(ctx, http.StatusForbidden, err)
+		return
+	}
+
+	//only users who verified their email can move money
+	if !getAuthUser(ctx).IsEmailVerified {
+		writeError(ctx, http.StatusForbidden, errEmailNotVerified)
+		return
+	}
+
+	var toAccount db.Account
+	var beneficiary db.Beneficiary
+	if req.BeneficiaryID != 0 {
+		beneficiary, valid = server.ownBeneficiary(ctx, req.BeneficiaryID)
+		if !valid {
+			return
+		}
+		//beneficiaries keep the internal id of their account
+		toAccount, valid = server.validAccountByID(ctx, beneficiary.AccountID, req.Currency)
+	} else {
+		toAccount, valid = server.validAccount(ctx, req.ToAccountID, req.Currency)
+	}
+	if !valid {
+		return
+	}
+
+	if server.coolingOff(ctx, toAccount, req.Amount) {
+		return
+	}
+
+	//large transfers need a fresh second factor so that a leaked password alone can't empty the account
+	secondFactor, valid := server.requireTOTP(ctx, authPayload.Username, req.TOTPCode, req.Amount)
 	if !valid {
 		return
 	}
 
+	//screened last, only transfers that would otherwise be made are held or blocked
+	if server.screenTransfer(ctx, req, fromAccount, toAccount) {
+		return
+	}
+
 	arg := db.TransferTxParams{
-		FromAccountID: req.FromAccountID,
-		ToAccountID:   req.ToAccountID,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
 		Amount:        req.Amount,
+		Description:   req.Description,
+		Reference:     req.Reference,
+		Metadata:      req.Metadata,
+		SecondFactor:  secondFactor,
 	}
 
 	result, err := server.store.TransferTx(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, http.StatusInternalServerError, transferError(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, result)
+	ids := server.publicIDs(ctx)
+	ids.json(http.StatusOK, ids.newTransferResultResponse(result))
+}
+
+//transferError maps an error returned by the transfer transactions, where no rows always means an account
+//that doesn't exist. The store already retried conflicts, writeError tells the client it is safe to try again.
+func transferError(err error) *apiError {
+	if errors.Is(err, sql.ErrNoRows) {
+		return newAPIError(http.StatusNotFound, codeAccountNotFound, "the account was not found")
+	}
+	return toAPIError(http.StatusInternalServerError, err)
+}
+
+//requireTOTP checks the TOTP code sent with transfers above the threshold and writes the error response when it is
+//missing or invalid. The step it returns is used up by the transfer transaction, so that a transfer that fails
+//leaves the code usable for another attempt. It is nil for amounts that don't need a second factor.
+func (server *Server) requireTOTP(ctx *gin.Context, username string, code string, amount int64) (*db.UseTOTPStepParams, bool) {
+	threshold := server.config.Auth.TOTPTransferThreshold
+	if threshold <= 0 || amount <= threshold {
+		return nil, true
+	}
+
+	userTOTP, enabled, err := server.getEnabledTOTP(ctx, username)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	if !enabled {
+		msg := fmt.Sprintf("two-factor authentication must be enabled for transfers above %d", threshold)
+		writeError(ctx, http.StatusForbidden, newAPIError(http.StatusForbidden, codeTOTPEnrollmentRequired, msg))
+		return nil, false
+	}
+
+	if code == "" {
+		msg := fmt.Sprintf("a two-factor code is required for transfers above %d", threshold)
+		writeError(ctx, http.StatusForbidden, newAPIError(http.StatusForbidden, codeTOTPRequired, msg))
+		return nil, false
+	}
+
+	step, err := checkTOTP(userTOTP, code)
+	if err != nil {
+		writeError(ctx, http.StatusForbidden, newAPIError(http.StatusForbidden, codeInvalidTOTP, err.Error()))
+		return nil, false
+	}
+	return &db.UseTOTPStepParams{Step: step, Username: username}, true
 }
 
 //getTransferRequest
-//Takes an id as a URI parameter Eg. accounts/:id
+//Takes the public id as a URI parameter Eg. transfers/tr_01HF3Z...
 type getTransferRequest struct {
-	ID int64 `uri:"id" binding:"required,min=1"`
+	ID string `uri:"id" binding:"required,publicid=tr_"`
 }
 
 //getTransfer request and response handler function
@@ -60,79 +159,150 @@ func (server *Server) getTransfer(ctx *gin.Context) {
 	//Note that for URI parameters we use ShouldBindUri
 	var req getTransferRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	transfer, err := server.store.GetTransfer(ctx, req.ID)
+	transfer, err := server.store.GetTransferByPublicID(ctx, req.ID)
 	if err != nil {
 		//if returns emptyRow error: sql.ErrNoRows
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			writeError(ctx, http.StatusNotFound, err)
 			return
 		}
 
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, transfer)
+	//depositors may only see transfers that touch one of their accounts
+	ids := server.publicIDs(ctx)
+	if !isStaff(getAuthUser(ctx)) && !ownsAnyAccount(ids, transfer.FromAccountID, transfer.ToAccountID) {
+		return
+	}
+
+	ids.json(http.StatusOK, ids.newTransferResponse(transfer))
 }
 
 //listTransferRequest struct to get paginated data
 //Take a query string instead. We use `form:"variable"`
 type listTransferRequest struct {
-	FromAccountID int64 `form:"from_account_id" binding:"required"`
-	ToAccountID   int64 `form:"to_account_id" binding:"required"`
-	PageID        int32 `form:"page_id" binding:"required,min=1"`
-	PageSize      int32 `form:"page_size" binding:"required,min=5,max=10"`
+	FromAccountID string `form:"from_account_id" binding:"required,publicid=acc_"`
+	ToAccountID   string `form:"to_account_id" binding:"required,publicid=acc_"`
+	PageID        int32  `form:"page_id" binding:"required,min=1"`
+	PageSize      int32  `form:"page_size" binding:"required,min=5,max=10"`
+	//Query matches part of the description or the whole reference
+	Query string `form:"q" binding:"max=255"`
+	//Metadata is a JSON object the metadata of the transfers must contain, e.g. {"invoice":"42"}
+	Metadata string `form:"metadata" binding:"omitempty,max=2048,jsonobject"`
 }
 
 //listTransfers request and response handler function
 func (server *Server) listTransfers(ctx *gin.Context) {
 	var req listTransferRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	//transfers are matched on either account, so a depositor must own both of them
+	fromAccount, valid := server.accessibleAccount(ctx, req.FromAccountID)
+	if !valid {
 		return
 	}
+	toAccount := fromAccount
+	if req.ToAccountID != req.FromAccountID {
+		if toAccount, valid = server.accessibleAccount(ctx, req.ToAccountID); !valid {
+			return
+		}
+	}
 
 	//Note that Limit is simply the req.PageSize.
 	//Offset is the number of records that the database should skip,
 	//we we have to calculate it from the page id and page size using this formula: (req.PageID - 1) * req.PageSize
 	arg := db.ListTransfersParams{
-		FromAccountID: req.FromAccountID,
-		ToAccountID:   req.ToAccountID,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Description:   "%" + likeEscaper.Replace(req.Query) + "%",
+		Reference:     req.Query,
+		Metadata:      json.RawMessage(`{}`),
 		Limit:         req.PageSize,
 		Offset:        (req.PageID - 1) * req.PageSize,
 	}
+	if req.Metadata != "" {
+		arg.Metadata = json.RawMessage(req.Metadata)
+	}
 
 	transfers, err := server.store.ListTransfers(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, transfers)
+	ids := server.publicIDs(ctx, fromAccount, toAccount)
+	ids.json(http.StatusOK, newResponses(transfers, ids.newTransferResponse))
 }
 
+//likeEscaper escapes the wildcards of a LIKE pattern so that a search matches them literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 //function to check if account exists in our database
-func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+func (server *Server) validAccount(ctx *gin.Context, publicID string, currency string) (db.Account, bool) {
+	account, valid := server.accountByPublicID(ctx, publicID)
+	if !valid {
+		return account, false
+	}
+
+	return account, validCurrency(ctx, account, currency)
+}
+
+//validAccountByID is validAccount for an account the bank refers to by its internal id, e.g. the one of a beneficiary
+func (server *Server) validAccountByID(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
 	account, err := server.store.GetAccount(ctx, accountID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			writeError(ctx, http.StatusNotFound, newAPIError(http.StatusNotFound, codeAccountNotFound, "the account was not found"))
 			return account, false
 		}
 
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, http.StatusInternalServerError, err)
 		return account, false
 	}
 
+	return account, validCurrency(ctx, account, currency)
+}
+
+//validCurrency writes the error response when the account is in another currency
+func validCurrency(ctx *gin.Context, account db.Account, currency string) bool {
 	if account.Currency != currency {
-		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-		return account, false
+		msg := fmt.Sprintf("account [%s] currency mismatch: %s vs %s", account.PublicID, account.Currency, currency)
+		writeError(ctx, http.StatusBadRequest, newAPIError(http.StatusBadRequest, codeCurrencyMismatch, msg))
+		return false
+	}
+	return true
+}
+
+//ownsAnyAccount checks that at least one of the accounts belongs to the authenticated user.
+//The accounts are loaded through ids so that the response can name them without loading them again.
+//It writes the error response itself so callers only need to return.
+func ownsAnyAccount(ids *publicIDs, accountIDs ...int64) bool {
+	username := getAuthPayload(ids.ctx).Username
+	for _, accountID := range accountIDs {
+		account, err := ids.loadAccount(accountID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				continue
+			}
+			writeError(ids.ctx, http.StatusInternalServerError, err)
+			return false
+		}
+
+		if account.OwnerName == username {
+			return true
+		}
 	}
 
-	return account, true
+	err := errors.New("transfer doesn't belong to the authenticated user")
+	writeError(ids.ctx, http.StatusForbidden, err)
+	return false
 }
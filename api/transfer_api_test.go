@@ -0,0 +1,698 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kingsleyocran/simple_bank_bankend/db/mock"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTransferAPI(t *testing.T) {
+	amount := int64(10)
+
+	account1 := randomAccount(util.RandomOwnerName())
+	account2 := randomAccount(util.RandomOwnerName())
+	account3 := randomAccount(util.RandomOwnerName())
+
+	account1.Currency = util.USD
+	account2.Currency = util.USD
+	account3.Currency = util.EUR
+
+	unverifiedUser := db.User{Username: account1.OwnerName, Role: util.DepositorRole}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"from_account_id": account1.PublicID,
+				"to_account_id":   account2.PublicID,
+				"amount":          amount,
+				"currency":        util.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account2.PublicID)).Times(1).Return(account2, nil)
+
+				arg := db.TransferTxParams{
+					FromAccountID: account1.ID,
+					ToAccountID:   account2.ID,
+					Amount:        amount,
+				}
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "WithDetails",
+			body: gin.H{
+				"from_account_id": account1.PublicID,
+				"to_account_id":   account2.PublicID,
+				"amount":          amount,
+				"currency":        util.USD,
+				"description":     "Rent for March",
+				"reference":       "INV-42",
+				"metadata":        gin.H{"invoice": 42},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account2.PublicID)).Times(1).Return(account2, nil)
+
+				arg := db.TransferTxParams{
+					FromAccountID: account1.ID,
+					ToAccountID:   account2.ID,
+					Amount:        amount,
+					Description:   "Rent for March",
+					Reference:     "INV-42",
+					Metadata:      json.RawMessage(`{"invoice":42}`),
+				}
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "DescriptionTooLong",
+			body: gin.H{
+				"from_account_id": account1.PublicID,
+				"to_account_id":   account2.PublicID,
+				"amount":          amount,
+				"currency":        util.USD,
+				"description":     strings.Repeat("a", 256),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "MetadataNotObject",
+			body: gin.H{
+				"from_account_id": account1.PublicID,
+				"to_account_id":   account2.PublicID,
+				"amount":          amount,
+				"currency":        util.USD,
+				"metadata":        []string{"invoice"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "MetadataTooLarge",
+			body: gin.H{
+				"from_account_id": account1.PublicID,
+				"to_account_id":   account2.PublicID,
+				"amount":          amount,
+				"currency":        util.USD,
+				"metadata":        gin.H{"note": strings.Repeat("a", 2048)},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "FromAccountNotOwned",
+			body: gin.H{
+				"from_account_id": account2.PublicID,
+				"to_account_id":   account1.PublicID,
+				"amount":          amount,
+				"currency":        util.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account2.PublicID)).Times(1).Return(account2, nil)
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "EmailNotVerified",
+			body: gin.H{
+				"from_account_id": account1.PublicID,
+				"to_account_id":   account2.PublicID,
+				"amount":          amount,
+				"currency":        util.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				//declared before expectAuthUser so that it is matched first
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(account1.OwnerName)).Times(1).Return(unverifiedUser, nil)
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account2.PublicID)).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "AccountFrozen",
+			body: gin.H{
+				"from_account_id": account1.PublicID,
+				"to_account_id":   account2.PublicID,
+				"amount":          amount,
+				"currency":        util.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account2.PublicID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, fmt.Errorf("account [%s]: %w", account2.PublicID, db.ErrAccountFrozen))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "FromAccountNotFound",
+			body: gin.H{
+				"from_account_id": account1.PublicID,
+				"to_account_id":   account2.PublicID,
+				"amount":          amount,
+				"currency":        util.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account2.PublicID)).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "ToAccountCurrencyMismatch",
+			body: gin.H{
+				"from_account_id": account1.PublicID,
+				"to_account_id":   account3.PublicID,
+				"amount":          amount,
+				"currency":        util.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account3.PublicID)).Times(1).Return(account3, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NegativeAmount",
+			body: gin.H{
+				"from_account_id": account1.PublicID,
+				"to_account_id":   account2.PublicID,
+				"amount":          -amount,
+				"currency":        util.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "LimitExceeded",
+			body: gin.H{
+				"from_account_id": account1.PublicID,
+				"to_account_id":   account2.PublicID,
+				"amount":          amount,
+				"currency":        util.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account2.PublicID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, &db.TransferLimitError{
+						AccountID: account1.PublicID,
+						Limit:     db.LimitMaxDailyAmount,
+						Max:       5,
+						Actual:    amount,
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+				var body struct {
+					Code  string                `json:"code"`
+					Limit db.TransferLimitError `json:"limit"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &body)
+				require.NoError(t, err)
+				require.Equal(t, "TRANSFER_LIMIT_EXCEEDED", body.Code)
+				require.Equal(t, db.LimitMaxDailyAmount, body.Limit.Limit)
+			},
+		},
+		{
+			name: "TransactionConflict",
+			body: gin.H{
+				"from_account_id": account1.PublicID,
+				"to_account_id":   account2.PublicID,
+				"amount":          amount,
+				"currency":        util.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account2.PublicID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, &pq.Error{Code: db.SerializationFailureCode})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				require.Equal(t, "1", recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name: "TransferTxError",
+			body: gin.H{
+				"from_account_id": account1.PublicID,
+				"to_account_id":   account2.PublicID,
+				"amount":          amount,
+				"currency":        util.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account2.PublicID)).Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/v1/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, account1.OwnerName, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+//The same endpoint against the in-memory store, so the whole path down to TransferTx runs without mocks
+func TestCreateTransferAPIMemoryStore(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+
+	createAccount := func(balance int64) db.Account {
+		user, err := store.CreateUser(ctx, db.CreateUserParams{
+			Username:       util.RandomOwnerName(),
+			HashedPassword: "secret",
+			FullName:       util.RandomOwnerName(),
+			Email:          util.RandomEmail(),
+		})
+		require.NoError(t, err)
+
+		_, err = store.UpdateUserEmailVerified(ctx, db.UpdateUserEmailVerifiedParams{Username: user.Username, Email: user.Email})
+		require.NoError(t, err)
+
+		account, err := store.CreateAccount(ctx, db.CreateAccountParams{
+			OwnerName: user.Username,
+			Balance:   balance,
+			Currency:  util.USD,
+		})
+		require.NoError(t, err)
+		return account
+	}
+
+	account1 := createAccount(100)
+	account2 := createAccount(0)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{
+		"from_account_id": account1.PublicID,
+		"to_account_id":   account2.PublicID,
+		"amount":          30,
+		"currency":        util.USD,
+	})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/v1/transfers", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, account1.OwnerName, util.DepositorRole, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var result transferResultResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &result)
+	require.NoError(t, err)
+	require.Equal(t, int64(70), result.FromAccount.Balance)
+	require.Equal(t, int64(30), result.ToAccount.Balance)
+	require.Equal(t, account1.PublicID, result.Transfer.FromAccountID)
+	require.Equal(t, account2.PublicID, result.ToEntry.AccountID)
+	require.True(t, db.IsPublicID(result.Transfer.ID, db.TransferIDPrefix))
+
+	updatedAccount2, err := store.GetAccount(ctx, account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(30), updatedAccount2.Balance)
+}
+
+//Listing against the in-memory store, so that the search is run for real
+func TestListTransfersAPISearch(t *testing.T) {
+	store := db.NewMemoryStore()
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, db.CreateUserParams{
+		Username:       util.RandomOwnerName(),
+		HashedPassword: "secret",
+		FullName:       util.RandomOwnerName(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+
+	accounts := make([]db.Account, 2)
+	for i, currency := range []string{util.USD, util.EUR} {
+		accounts[i], err = store.CreateAccount(ctx, db.CreateAccountParams{OwnerName: user.Username, Currency: currency})
+		require.NoError(t, err)
+	}
+
+	for _, arg := range []db.TransferTxParams{
+		{Description: "Rent for March", Reference: "INV-42", Metadata: json.RawMessage(`{"invoice":42}`)},
+		{Description: "Groceries 100%"},
+		{Description: "Rent for April", Reference: "INV-43", Metadata: json.RawMessage(`{"invoice":43}`)},
+	} {
+		arg.FromAccountID, arg.ToAccountID, arg.Amount = accounts[0].ID, accounts[1].ID, 1
+		_, err = store.TransferTx(ctx, arg)
+		require.NoError(t, err)
+	}
+
+	testCases := []struct {
+		name         string
+		query        url.Values
+		code         int
+		descriptions []string
+	}{
+		{
+			name:         "All",
+			query:        url.Values{},
+			code:         http.StatusOK,
+			descriptions: []string{"Rent for March", "Groceries 100%", "Rent for April"},
+		},
+		{
+			name:         "Description",
+			query:        url.Values{"q": {"rent"}},
+			code:         http.StatusOK,
+			descriptions: []string{"Rent for March", "Rent for April"},
+		},
+		{
+			name:         "Wildcard",
+			query:        url.Values{"q": {"0%"}},
+			code:         http.StatusOK,
+			descriptions: []string{"Groceries 100%"},
+		},
+		{
+			name:         "Reference",
+			query:        url.Values{"q": {"INV-43"}},
+			code:         http.StatusOK,
+			descriptions: []string{"Rent for April"},
+		},
+		{
+			name:         "Metadata",
+			query:        url.Values{"metadata": {`{"invoice":42}`}},
+			code:         http.StatusOK,
+			descriptions: []string{"Rent for March"},
+		},
+		{
+			name:  "MetadataNotObject",
+			query: url.Values{"metadata": {`[42]`}},
+			code:  http.StatusBadRequest,
+		},
+	}
+
+	server := newTestServer(t, store)
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			tc.query.Set("from_account_id", accounts[0].PublicID)
+			tc.query.Set("to_account_id", accounts[0].PublicID)
+			tc.query.Set("page_id", "1")
+			tc.query.Set("page_size", "5")
+			request, err := http.NewRequest(http.MethodGet, "/v1/transfers?"+tc.query.Encode(), nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.code, recorder.Code)
+			if tc.code != http.StatusOK {
+				return
+			}
+
+			var transfers []transferResponse
+			err = json.Unmarshal(recorder.Body.Bytes(), &transfers)
+			require.NoError(t, err)
+
+			descriptions := []string{}
+			for _, transfer := range transfers {
+				descriptions = append(descriptions, transfer.Description)
+			}
+			require.Equal(t, tc.descriptions, descriptions)
+		})
+	}
+}
+
+func TestCreateTransferToBeneficiaryAPI(t *testing.T) {
+	account1 := randomAccount(util.RandomOwnerName())
+	account2 := randomAccount(util.RandomOwnerName())
+	account1.Currency = util.USD
+	account2.Currency = util.USD
+
+	beneficiary := randomBeneficiary(account1.OwnerName, account2)
+	recent := beneficiary
+	recent.CreatedAt = time.Now().Add(-time.Hour)
+	othersBeneficiary := randomBeneficiary(util.RandomOwnerName(), account2)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"from_account_id": account1.PublicID, "beneficiary_id": beneficiary.ID, "amount": 500, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetBeneficiary(gomock.Any(), gomock.Eq(beneficiary.ID)).Times(1).Return(beneficiary, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					GetBeneficiaryByAccount(gomock.Any(), gomock.Eq(db.GetBeneficiaryByAccountParams{OwnerName: account1.OwnerName, AccountID: account2.ID})).
+					Times(1).
+					Return(beneficiary, nil)
+
+				arg := db.TransferTxParams{
+					FromAccountID: account1.ID,
+					ToAccountID:   account2.ID,
+					Amount:        500,
+				}
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "CoolingOff",
+			body: gin.H{"from_account_id": account1.PublicID, "beneficiary_id": recent.ID, "amount": 500, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetBeneficiary(gomock.Any(), gomock.Eq(recent.ID)).Times(1).Return(recent, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					GetBeneficiaryByAccount(gomock.Any(), gomock.Eq(db.GetBeneficiaryByAccountParams{OwnerName: account1.OwnerName, AccountID: account2.ID})).
+					Times(1).
+					Return(recent, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+				var body struct {
+					Code        string    `json:"code"`
+					AvailableAt time.Time `json:"available_at"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &body)
+				require.NoError(t, err)
+				require.Equal(t, "BENEFICIARY_COOLING_OFF", body.Code)
+				require.WithinDuration(t, recent.CreatedAt.Add(24*time.Hour), body.AvailableAt, time.Second)
+			},
+		},
+		{
+			//naming the account instead of the beneficiary doesn't skip the wait
+			name: "CoolingOffByAccount",
+			body: gin.H{"from_account_id": account1.PublicID, "to_account_id": account2.PublicID, "amount": 500, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account2.PublicID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					GetBeneficiaryByAccount(gomock.Any(), gomock.Eq(db.GetBeneficiaryByAccountParams{OwnerName: account1.OwnerName, AccountID: account2.ID})).
+					Times(1).
+					Return(recent, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "BENEFICIARY_COOLING_OFF")
+			},
+		},
+		{
+			name: "NotABeneficiary",
+			body: gin.H{"from_account_id": account1.PublicID, "to_account_id": account2.PublicID, "amount": 500, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account2.PublicID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					GetBeneficiaryByAccount(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Beneficiary{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "CoolingOffSmallAmount",
+			body: gin.H{"from_account_id": account1.PublicID, "beneficiary_id": recent.ID, "amount": 100, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetBeneficiary(gomock.Any(), gomock.Eq(recent.ID)).Times(1).Return(recent, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "OtherUsersBeneficiary",
+			body: gin.H{"from_account_id": account1.PublicID, "beneficiary_id": othersBeneficiary.ID, "amount": 100, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Eq(account1.PublicID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetBeneficiary(gomock.Any(), gomock.Eq(othersBeneficiary.ID)).Times(1).Return(othersBeneficiary, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "BothDestinations",
+			body: gin.H{"from_account_id": account1.PublicID, "to_account_id": account2.PublicID, "beneficiary_id": beneficiary.ID, "amount": 100, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoDestination",
+			body: gin.H{"from_account_id": account1.PublicID, "amount": 100, "currency": util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByPublicID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			server.config.Transfers.BeneficiaryCoolingOffPeriod = 24 * time.Hour
+			server.config.Transfers.BeneficiaryCoolingOffAmount = 200
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/v1/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, account1.OwnerName, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
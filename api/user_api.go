@@ -0,0 +1,333 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/mail"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+)
+
+//createUserRequest holds the data needed to register a new depositor
+type createUserRequest struct {
+	Username string `json:"username" binding:"required,alphanum"`
+	Password string `json:"password" binding:"required,min=6"`
+	FullName string `json:"full_name" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+}
+
+//userResponse is the user without the hashed password
+type userResponse struct {
+	Username          string    `json:"username"`
+	FullName          string    `json:"full_name"`
+	Email             string    `json:"email"`
+	Role              string    `json:"role"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+func newUserResponse(user db.User) userResponse {
+	return userResponse{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		Role:              user.Role,
+		IsEmailVerified:   user.IsEmailVerified,
+		PasswordChangedAt: user.PasswordChangedAt,
+		CreatedAt:         user.CreatedAt,
+	}
+}
+
+//createUser registers a new user, every new user is a depositor.
+//The verification email is sent once the user is committed, when it is lost or expires
+//the user asks for a new one with POST /users/me/verify_email.
+func (server *Server) createUser(ctx *gin.Context) {
+	var req createUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.Password)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	secretCode, err := util.GenerateSecret(32)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	arg := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       req.Username,
+			HashedPassword: hashedPassword,
+			FullName:       req.FullName,
+			Email:          req.Email,
+		},
+		VerifyEmail: db.CreateVerifyEmailParams{
+			SecretCode: secretCode,
+			ExpiredAt:  time.Now().Add(server.config.Auth.VerifyEmailDuration),
+		},
+	}
+
+	result, err := server.store.CreateUserTx(ctx, arg)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	//the email goes out after the commit, a retried transaction would otherwise mail a code that no longer works.
+	//The user exists by now, so a mail failure is logged rather than failing the request, a new link can be asked for.
+	if err := server.sendVerifyEmail(result.User, result.VerifyEmail); err != nil {
+		log.Printf("request %s: cannot send the verification email of %s: %v", requestID(ctx), result.User.Username, err)
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(result.User))
+}
+
+//sendVerifyEmail mails the link the user has to open to verify their email
+func (server *Server) sendVerifyEmail(user db.User, verifyEmail db.VerifyEmail) error {
+	return VerifyEmailSender(server.config, server.mailer)(user, verifyEmail)
+}
+
+//VerifyEmailSender returns the function sending the verification email, it is shared with the import subcommand
+func VerifyEmailSender(config util.Config, mailer mail.Mailer) func(user db.User, verifyEmail db.VerifyEmail) error {
+	return func(user db.User, verifyEmail db.VerifyEmail) error {
+		query := url.Values{}
+		query.Set("id", fmt.Sprint(verifyEmail.ID))
+		query.Set("code", verifyEmail.SecretCode)
+		link := fmt.Sprintf("%s/v1/verify_email?%s", config.Server.AppBaseURL, query.Encode())
+
+		return mailer.Send(mail.Message{
+			To:      []string{verifyEmail.Email},
+			Subject: "Welcome to Simple Bank",
+			Body: fmt.Sprintf("Hello %s,\n\nThank you for registering with us!\n"+
+				"Please verify your email address by opening the link below before %s:\n\n%s\n",
+				user.FullName, verifyEmail.ExpiredAt.Format(time.RFC1123), link),
+		})
+	}
+}
+
+//verifyEmailRequest holds the id and code from the link in the verification email
+type verifyEmailRequest struct {
+	EmailID    int64  `form:"id" binding:"required,min=1"`
+	SecretCode string `form:"code" binding:"required,len=64"`
+}
+
+//verifyEmailResponse tells whether the email is now verified
+type verifyEmailResponse struct {
+	IsVerified bool `json:"is_verified"`
+}
+
+//verifyEmail marks the user's email as verified, codes can only be used once and expire
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		EmailID:    req.EmailID,
+		SecretCode: req.SecretCode,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = fmt.Errorf("verification link is invalid, expired or already used")
+			writeError(ctx, http.StatusBadRequest, err)
+			return
+		}
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, verifyEmailResponse{IsVerified: result.User.IsEmailVerified})
+}
+
+//resendVerifyEmail mails the authenticated user a new verification link, the links sent before keep working until they expire.
+//Unlike at registration a mail failure is reported, the user can simply try again.
+func (server *Server) resendVerifyEmail(ctx *gin.Context) {
+	user := getAuthUser(ctx)
+	if user.IsEmailVerified {
+		err := errors.New("email is already verified")
+		writeError(ctx, http.StatusConflict, err)
+		return
+	}
+
+	secretCode, err := util.GenerateSecret(32)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	verifyEmail, err := server.store.CreateVerifyEmail(ctx, db.CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: secretCode,
+		ExpiredAt:  time.Now().Add(server.config.Auth.VerifyEmailDuration),
+	})
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := server.sendVerifyEmail(user, verifyEmail); err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "a new verification link has been sent to " + user.Email})
+}
+
+//loginUserRequest holds the credentials of a user.
+//Users with two-factor authentication also send a TOTP code or one of their recovery codes.
+type loginUserRequest struct {
+	Username     string `json:"username" binding:"required,alphanum"`
+	Password     string `json:"password" binding:"required,min=6"`
+	TOTPCode     string `json:"totp_code" binding:"omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+//loginUserResponse returns the access token together with the user
+type loginUserResponse struct {
+	AccessToken          string       `json:"access_token"`
+	AccessTokenExpiresAt time.Time    `json:"access_token_expires_at"`
+	User                 userResponse `json:"user"`
+}
+
+//errInvalidCredentials is returned for unknown usernames and wrong passwords alike, so logins don't reveal who has an account
+var errInvalidCredentials = errors.New("incorrect username or password")
+
+var (
+	unknownUserHashOnce sync.Once
+	unknownUserHash     string
+)
+
+//unknownUserPasswordHash is a bcrypt hash no password matches, checked when the username doesn't exist
+func unknownUserPasswordHash() string {
+	unknownUserHashOnce.Do(func() {
+		hash, err := util.HashPassword(util.RandomString(32))
+		if err == nil {
+			unknownUserHash = hash
+		}
+	})
+	return unknownUserHash
+}
+
+//loginUser checks the credentials and issues an access token carrying the user's role
+func (server *Server) loginUser(ctx *gin.Context) {
+	var req loginUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, req.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			//hash anyway so that unknown usernames take as long to refuse as wrong passwords
+			util.CheckPassword(req.Password, unknownUserPasswordHash())
+			writeError(ctx, http.StatusUnauthorized, errInvalidCredentials)
+			return
+		}
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	err = util.CheckPassword(req.Password, user.HashedPassword)
+	if err != nil {
+		writeError(ctx, http.StatusUnauthorized, errInvalidCredentials)
+		return
+	}
+
+	userTOTP, enabled, err := server.getEnabledTOTP(ctx, user.Username)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if enabled {
+		switch {
+		case req.TOTPCode != "":
+			err = server.verifyTOTP(ctx, userTOTP, req.TOTPCode)
+		case req.RecoveryCode != "":
+			err = server.useRecoveryCode(ctx, user.Username, req.RecoveryCode)
+		default:
+			writeError(ctx, http.StatusUnauthorized, newAPIError(http.StatusUnauthorized, codeTOTPRequired, "two-factor code is required"))
+			return
+		}
+
+		if err != nil {
+			if err == errInvalidSecondFactor {
+				writeError(ctx, http.StatusUnauthorized, err)
+				return
+			}
+			writeError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, server.config.Auth.AccessTokenDuration)
+	if err != nil {
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := loginUserResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpiredAt,
+		User:                 newUserResponse(user),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+//updateUserRoleRequest is used by admins to promote or demote a user
+type updateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,role"`
+}
+
+type userURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+//updateUserRole changes the role of a user, it applies from their next request as roles are read from the database
+func (server *Server) updateUserRole(ctx *gin.Context) {
+	var uri userURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var req updateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := server.store.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+		Username: uri.Username,
+		Role:     req.Role,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(ctx, http.StatusNotFound, err)
+			return
+		}
+		writeError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
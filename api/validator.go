@@ -1,13 +0,0 @@
-package api
-
-import (
-	"github.com/go-playground/validator/v10"
-	"github.com/kingsleyocran/simple_bank_bankend/util"
-)
-
-var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
-	if currency, ok := fieldLevel.Field().Interface().(string); ok {
-		return util.IsSupportedCurrency(currency)
-	}
-	return false
-}
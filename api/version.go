@@ -0,0 +1,77 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+//apiVersion is a group of routes served under a prefix, e.g. /v1. Once a version has a successor,
+//every response of the version tells clients where the same route moved and, when it is known, the day it goes away.
+type apiVersion struct {
+	prefix    string
+	public    []route
+	routes    []route
+	successor string
+	sunset    time.Time
+}
+
+//versions are the versions of the API served side by side.
+//v2 starts as v1, handlers are replaced in its tables as their requests or responses change shape.
+func (server *Server) versions() ([]apiVersion, error) {
+	v1 := apiVersion{
+		prefix:    "/v1",
+		public:    server.publicRoutes(),
+		routes:    server.routes(),
+		successor: "/v2",
+	}
+	if server.config.Server.V1Sunset != "" {
+		sunset, err := time.Parse("2006-01-02", server.config.Server.V1Sunset)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse API_V1_SUNSET: %w", err)
+		}
+		v1.sunset = sunset
+	}
+
+	v2 := apiVersion{
+		prefix: "/v2",
+		public: server.publicRoutes(),
+		routes: server.routes(),
+	}
+
+	return []apiVersion{v1, v2}, nil
+}
+
+//deprecationMiddleware sets the Deprecation, Sunset and Link headers of a version that has a successor
+func deprecationMiddleware(version apiVersion) gin.HandlerFunc {
+	sunset := ""
+	if !version.sunset.IsZero() {
+		sunset = version.sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return func(ctx *gin.Context) {
+		ctx.Header("Deprecation", "true")
+		if sunset != "" {
+			ctx.Header("Sunset", sunset)
+		}
+
+		//the same route in the successor, e.g. /v2/accounts/42 for /v1/accounts/42
+		successor := version.successor + strings.TrimPrefix(ctx.Request.URL.Path, version.prefix)
+		ctx.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		ctx.Next()
+	}
+}
+
+var versionPrefix = regexp.MustCompile(`^/v[0-9]+/`)
+
+//unversionedPath drops the version of a route pattern, e.g. /v1/transfers is /transfers
+func unversionedPath(path string) string {
+	if loc := versionPrefix.FindStringIndex(path); loc != nil {
+		return path[loc[1]-1:]
+	}
+	return path
+}
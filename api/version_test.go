@@ -0,0 +1,71 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kingsleyocran/simple_bank_bankend/db/mock"
+	"github.com/kingsleyocran/simple_bank_bankend/mail"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIVersions(t *testing.T) {
+	username := util.RandomOwnerName()
+	account := randomAccount(username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetAccountByPublicID(gomock.Any(), gomock.Eq(account.PublicID)).
+		Times(2).
+		Return(account, nil)
+	expectAuthUser(store)
+
+	config := util.Config{
+		Server: util.ServerConfig{V1Sunset: "2027-06-30"},
+		Auth:   util.AuthConfig{TokenSymmetricKey: util.RandomString(32), AccessTokenDuration: time.Minute},
+	}
+	server, err := NewServer(config, store, mail.NewMemoryMailer())
+	require.NoError(t, err)
+
+	send := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, path, nil)
+		require.NoError(t, err)
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := send(fmt.Sprintf("/v1/accounts/%s", account.PublicID))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	requireBodyMatchAccount(t, recorder.Body, account)
+	require.Equal(t, "true", recorder.Header().Get("Deprecation"))
+	require.Equal(t, "Wed, 30 Jun 2027 00:00:00 GMT", recorder.Header().Get("Sunset"))
+	require.Equal(t, fmt.Sprintf(`</v2/accounts/%s>; rel="successor-version"`, account.PublicID), recorder.Header().Get("Link"))
+
+	recorder = send(fmt.Sprintf("/v2/accounts/%s", account.PublicID))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	requireBodyMatchAccount(t, recorder.Body, account)
+	require.Empty(t, recorder.Header().Get("Deprecation"))
+	require.Empty(t, recorder.Header().Get("Link"))
+
+	//routes are only served under a version
+	recorder = send(fmt.Sprintf("/accounts/%s", account.PublicID))
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestUnversionedPath(t *testing.T) {
+	require.Equal(t, "/transfers", unversionedPath("/v1/transfers"))
+	require.Equal(t, "/accounts/:id", unversionedPath("/v12/accounts/:id"))
+	require.Equal(t, "/healthz", unversionedPath("/healthz"))
+	require.Equal(t, "/verify_email", unversionedPath("/verify_email"))
+}
@@ -0,0 +1,134 @@
+package balance
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/lib/pq"
+)
+
+// pageSize is the number of accounts loaded at a time
+const pageSize = 100
+
+// settlePeriod is how long Run waits after midnight before taking the snapshot of the day.
+// Entries are dated when their transaction starts, so one started just before midnight can
+// still commit a little after it and would be missing from a snapshot taken right away.
+const settlePeriod = 5 * time.Minute
+
+// Failure is an account whose snapshot could not be taken, it is retried by the next run
+type Failure struct {
+	AccountID int64  `json:"account_id"`
+	Error     string `json:"error"`
+}
+
+// Report is the outcome of a snapshot run
+type Report struct {
+	TakenAt   time.Time `json:"taken_at"`
+	Snapshots int       `json:"snapshots"`
+	Failures  []Failure `json:"failures"`
+}
+
+// Snapshotter records the balance of every account at the start of each UTC day,
+// so that db.BalanceAt never has to read more than a day of entries
+type Snapshotter struct {
+	store db.Store
+}
+
+// NewSnapshotter creates a snapshotter working on store
+func NewSnapshotter(store db.Store) *Snapshotter {
+	return &Snapshotter{store: store}
+}
+
+// SnapshotAt records the balance at takenAt of every account created before it that has no snapshot
+// at or after takenAt yet. Snapshots taken concurrently by another server are skipped.
+func (snapshotter *Snapshotter) SnapshotAt(ctx context.Context, takenAt time.Time) (Report, error) {
+	report := Report{
+		TakenAt:  takenAt,
+		Failures: []Failure{},
+	}
+
+	var afterID int64
+	for {
+		accounts, err := snapshotter.store.ListAccountsDueSnapshot(ctx, db.ListAccountsDueSnapshotParams{
+			AfterID:  afterID,
+			TakenAt:  takenAt,
+			RowLimit: pageSize,
+		})
+		if err != nil {
+			return report, err
+		}
+
+		for _, account := range accounts {
+			afterID = account.ID
+
+			err := snapshotter.snapshot(ctx, account.ID, takenAt)
+			if err != nil {
+				if ctx.Err() != nil {
+					return report, ctx.Err()
+				}
+				var pqErr *pq.Error
+				if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+					continue
+				}
+				report.Failures = append(report.Failures, Failure{AccountID: account.ID, Error: err.Error()})
+				continue
+			}
+			report.Snapshots++
+		}
+
+		if len(accounts) < pageSize {
+			return report, nil
+		}
+	}
+}
+
+func (snapshotter *Snapshotter) snapshot(ctx context.Context, accountID int64, takenAt time.Time) error {
+	//the snapshot only adds the entries dated before takenAt, exactly those BalanceAt leaves out when it adds
+	//the ones since, so a transfer committing while the snapshot is taken cannot be counted twice or not at all
+	balance, err := snapshotter.store.SumEntriesBefore(ctx, db.SumEntriesBeforeParams{
+		AccountID: accountID,
+		Before:    takenAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = snapshotter.store.CreateBalanceSnapshot(ctx, db.CreateBalanceSnapshotParams{
+		AccountID: accountID,
+		TakenAt:   takenAt,
+		Balance:   balance,
+	})
+	return err
+}
+
+// settledDay returns the start of the latest UTC day that began at least settlePeriod before now
+func settledDay(now time.Time) time.Time {
+	return now.UTC().Add(-settlePeriod).Truncate(24 * time.Hour)
+}
+
+// Run snapshots the start of the current UTC day right away, once it is settlePeriod old, and then checks again
+// every interval until ctx is done
+func (snapshotter *Snapshotter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := snapshotter.SnapshotAt(ctx, settledDay(time.Now()))
+		if err != nil && ctx.Err() == nil {
+			log.Println("cannot snapshot balances:", err)
+		}
+		if report.Snapshots > 0 || len(report.Failures) > 0 {
+			log.Printf("snapshot balances at %s: %d snapshots, %d failures",
+				report.TakenAt.Format(time.RFC3339), report.Snapshots, len(report.Failures))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
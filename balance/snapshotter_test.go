@@ -0,0 +1,133 @@
+package balance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createAccount(t *testing.T, store db.Store, balance int64) db.Account {
+	user, err := store.CreateUser(context.Background(), db.CreateUserParams{
+		Username:       util.RandomOwnerName(),
+		HashedPassword: "secret",
+		FullName:       util.RandomOwnerName(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+
+	//accounts open empty and are funded by an entry, as snapshots are the sum of the entries
+	account, err := store.CreateAccount(context.Background(), db.CreateAccountParams{
+		OwnerName: user.Username,
+		Balance:   0,
+		Currency:  util.USD,
+	})
+	require.NoError(t, err)
+
+	_, err = store.CreateEntry(context.Background(), db.CreateEntryParams{
+		AccountID:   account.ID,
+		Amount:      balance,
+		Description: "opening balance",
+	})
+	require.NoError(t, err)
+	account, err = store.AddAccountBalance(context.Background(), db.AddAccountBalanceParams{
+		ID:     account.ID,
+		Amount: balance,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func TestSnapshotAt(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	snapshotter := NewSnapshotter(store)
+
+	account1 := createAccount(t, store, 1_000)
+	account2 := createAccount(t, store, 1_000)
+
+	_, err := store.TransferTx(ctx, db.TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 300})
+	require.NoError(t, err)
+
+	//entries are stamped with microseconds, keep them apart from takenAt
+	time.Sleep(time.Millisecond)
+	takenAt := time.Now().Truncate(time.Microsecond)
+	time.Sleep(time.Millisecond)
+	late := createAccount(t, store, 1_000)
+
+	//entries after takenAt are not part of the snapshot
+	_, err = store.TransferTx(ctx, db.TransferTxParams{FromAccountID: account2.ID, ToAccountID: account1.ID, Amount: 100})
+	require.NoError(t, err)
+
+	report, err := snapshotter.SnapshotAt(ctx, takenAt)
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Snapshots)
+	require.Empty(t, report.Failures)
+
+	for account, want := range map[int64]int64{account1.ID: 700, account2.ID: 1_300} {
+		snapshot, err := store.GetLatestBalanceSnapshot(ctx, db.GetLatestBalanceSnapshotParams{AccountID: account, TakenAt: takenAt})
+		require.NoError(t, err)
+		require.Equal(t, want, snapshot.Balance)
+	}
+
+	//an account opened after takenAt has no balance to record yet
+	_, err = store.GetLatestBalanceSnapshot(ctx, db.GetLatestBalanceSnapshotParams{AccountID: late.ID, TakenAt: time.Now()})
+	require.Error(t, err)
+
+	balance, err := db.BalanceAt(ctx, store, account1.ID, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(800), balance)
+
+	//nothing is taken twice
+	report, err = snapshotter.SnapshotAt(ctx, takenAt)
+	require.NoError(t, err)
+	require.Zero(t, report.Snapshots)
+}
+
+func TestSnapshotMatchesEntries(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	snapshotter := NewSnapshotter(store)
+
+	account1 := createAccount(t, store, 1_000)
+	account2 := createAccount(t, store, 1_000)
+
+	var marks []time.Time
+	for _, amount := range []int64{10, 20, 30, 40} {
+		time.Sleep(time.Millisecond)
+		marks = append(marks, time.Now().Truncate(time.Microsecond))
+		time.Sleep(time.Millisecond)
+
+		_, err := store.TransferTx(ctx, db.TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: amount})
+		require.NoError(t, err)
+	}
+
+	_, err := snapshotter.SnapshotAt(ctx, marks[1])
+	require.NoError(t, err)
+
+	//the snapshot is the sum of the entries dated before it, and BalanceAt agrees on each side of it
+	snapshot, err := store.GetLatestBalanceSnapshot(ctx, db.GetLatestBalanceSnapshotParams{AccountID: account1.ID, TakenAt: marks[1]})
+	require.NoError(t, err)
+	require.Equal(t, int64(990), snapshot.Balance)
+
+	for i, want := range []int64{1_000, 990, 970, 940} {
+		balance, err := db.BalanceAt(ctx, store, account1.ID, marks[i])
+		require.NoError(t, err)
+		require.Equal(t, want, balance)
+	}
+	balance, err := db.BalanceAt(ctx, store, account1.ID, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(900), balance)
+}
+
+func TestSettledDay(t *testing.T) {
+	midnight := time.Date(2022, 6, 2, 0, 0, 0, 0, time.UTC)
+
+	//right after midnight transfers started the day before may still commit
+	require.Equal(t, midnight.Add(-24*time.Hour), settledDay(midnight.Add(time.Minute)))
+	require.Equal(t, midnight, settledDay(midnight.Add(settlePeriod)))
+	require.Equal(t, midnight, settledDay(midnight.Add(23*time.Hour)))
+}
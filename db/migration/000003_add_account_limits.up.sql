@@ -0,0 +1,17 @@
+CREATE TABLE "account_limits" (
+  "account_id" bigint PRIMARY KEY,
+  "max_single_transfer" bigint NOT NULL DEFAULT 0,
+  "max_daily_amount" bigint NOT NULL DEFAULT 0,
+  "max_daily_count" bigint NOT NULL DEFAULT 0,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "account_limits"."max_single_transfer" IS '0 means unlimited';
+
+COMMENT ON COLUMN "account_limits"."max_daily_amount" IS '0 means unlimited';
+
+COMMENT ON COLUMN "account_limits"."max_daily_count" IS '0 means unlimited';
+
+ALTER TABLE "account_limits" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+CREATE INDEX ON "transfers" ("from_account_id", "created_at");
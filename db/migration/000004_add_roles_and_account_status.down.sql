@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "status";
+ALTER TABLE IF EXISTS "users" DROP COLUMN IF EXISTS "role";
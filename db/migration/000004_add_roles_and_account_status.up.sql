@@ -0,0 +1,7 @@
+ALTER TABLE "users" ADD COLUMN "role" varchar NOT NULL DEFAULT 'depositor';
+
+ALTER TABLE "accounts" ADD COLUMN "status" varchar NOT NULL DEFAULT 'active';
+
+COMMENT ON COLUMN "users"."role" IS 'depositor, banker or admin';
+
+COMMENT ON COLUMN "accounts"."status" IS 'active or frozen';
@@ -0,0 +1,14 @@
+CREATE TABLE "reset_passwords" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "token_hash" varchar UNIQUE NOT NULL,
+  "is_used" bool NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "expired_at" timestamptz NOT NULL
+);
+
+ALTER TABLE "reset_passwords" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "reset_passwords" ("username");
+
+COMMENT ON COLUMN "reset_passwords"."token_hash" IS 'sha256 of the token sent by email, the token itself is never stored';
@@ -0,0 +1,25 @@
+CREATE TABLE "user_totps" (
+  "username" varchar PRIMARY KEY,
+  "secret" varchar NOT NULL,
+  "is_enabled" bool NOT NULL DEFAULT false,
+  "last_used_step" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "recovery_codes" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "code_hash" varchar NOT NULL,
+  "is_used" bool NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "user_totps" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE UNIQUE INDEX ON "recovery_codes" ("username", "code_hash");
+
+COMMENT ON COLUMN "user_totps"."last_used_step" IS 'codes of this time step or earlier are refused so that they cannot be replayed';
+
+COMMENT ON COLUMN "recovery_codes"."code_hash" IS 'sha256 of the recovery code';
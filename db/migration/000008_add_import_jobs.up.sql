@@ -0,0 +1,21 @@
+CREATE TABLE "import_jobs" (
+  "id" bigserial PRIMARY KEY,
+  "kind" varchar NOT NULL,
+  "file_hash" varchar NOT NULL,
+  "total_rows" bigint NOT NULL,
+  "committed_rows" bigint NOT NULL DEFAULT 0,
+  "status" varchar NOT NULL DEFAULT 'running',
+  "created_by" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "import_jobs" ("kind", "file_hash");
+
+COMMENT ON COLUMN "import_jobs"."kind" IS 'users, accounts or transfers';
+
+COMMENT ON COLUMN "import_jobs"."file_hash" IS 'sha256 of the file, importing the same file again resumes the job';
+
+COMMENT ON COLUMN "import_jobs"."committed_rows" IS 'data rows committed so far, in file order';
+
+COMMENT ON COLUMN "import_jobs"."status" IS 'running or completed';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS "entries_account_id_created_at_idx";
+DROP TABLE IF EXISTS "interest_expense_accounts";
+DROP TABLE IF EXISTS "account_interests";
+DROP TABLE IF EXISTS "account_products";
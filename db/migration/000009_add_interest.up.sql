@@ -0,0 +1,42 @@
+CREATE TABLE "account_products" (
+  "code" varchar PRIMARY KEY,
+  "name" varchar NOT NULL,
+  "annual_rate_ppm" bigint NOT NULL CHECK ("annual_rate_ppm" >= 0),
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "account_interests" (
+  "account_id" bigint PRIMARY KEY,
+  "product_code" varchar NOT NULL,
+  "accrued" bigint NOT NULL DEFAULT 0,
+  "accrued_fraction" bigint NOT NULL DEFAULT 0,
+  "accrued_through" date NOT NULL,
+  "paid" bigint NOT NULL DEFAULT 0,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "interest_expense_accounts" (
+  "currency" varchar PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "account_interests" ("accrued_through");
+
+CREATE INDEX ON "entries" ("account_id", "created_at");
+
+COMMENT ON COLUMN "account_products"."annual_rate_ppm" IS 'annual rate in millionths of the balance, 25000 is 2.5%';
+
+COMMENT ON COLUMN "account_interests"."accrued" IS 'interest accrued but not paid yet, in whole units of the currency';
+
+COMMENT ON COLUMN "account_interests"."accrued_fraction" IS 'remainder of the accrued interest in 1/365000000 units, carried to the next day';
+
+COMMENT ON COLUMN "account_interests"."accrued_through" IS 'last day whose end of day balance earned interest';
+
+COMMENT ON COLUMN "account_interests"."paid" IS 'interest paid to the account so far';
+
+ALTER TABLE "account_interests" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "account_interests" ADD FOREIGN KEY ("product_code") REFERENCES "account_products" ("code");
+
+ALTER TABLE "interest_expense_accounts" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
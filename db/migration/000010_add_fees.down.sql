@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "fee_revenue_accounts";
+DROP TABLE IF EXISTS "product_fees";
@@ -0,0 +1,25 @@
+CREATE TABLE "product_fees" (
+  "product_code" varchar NOT NULL,
+  "kind" varchar NOT NULL CHECK ("kind" IN ('maintenance', 'transfer')),
+  "flat_amount" bigint NOT NULL DEFAULT 0 CHECK ("flat_amount" >= 0),
+  "rate_ppm" bigint NOT NULL DEFAULT 0 CHECK ("rate_ppm" >= 0),
+  "max_amount" bigint NOT NULL DEFAULT 0 CHECK ("max_amount" >= 0),
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("product_code", "kind")
+);
+
+CREATE TABLE "fee_revenue_accounts" (
+  "currency" varchar PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "product_fees"."kind" IS 'maintenance or transfer';
+
+COMMENT ON COLUMN "product_fees"."rate_ppm" IS 'millionths of the transfer amount, or of the month end balance for maintenance';
+
+COMMENT ON COLUMN "product_fees"."max_amount" IS '0 means no cap';
+
+ALTER TABLE "product_fees" ADD FOREIGN KEY ("product_code") REFERENCES "account_products" ("code");
+
+ALTER TABLE "fee_revenue_accounts" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
@@ -0,0 +1,7 @@
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "description";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "metadata";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "reference";
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "description";
@@ -0,0 +1,13 @@
+ALTER TABLE "transfers" ADD COLUMN "description" varchar NOT NULL DEFAULT '' CHECK (char_length("description") <= 255);
+
+ALTER TABLE "transfers" ADD COLUMN "reference" varchar NOT NULL DEFAULT '' CHECK (char_length("reference") <= 64);
+
+ALTER TABLE "transfers" ADD COLUMN "metadata" jsonb NOT NULL DEFAULT '{}' CHECK (jsonb_typeof("metadata") = 'object');
+
+ALTER TABLE "entries" ADD COLUMN "description" varchar NOT NULL DEFAULT '';
+
+CREATE INDEX ON "transfers" ("reference");
+
+COMMENT ON COLUMN "transfers"."reference" IS 'set by the client, e.g. an invoice number';
+
+COMMENT ON COLUMN "entries"."description" IS 'copied from the transfer, or what the bank charged or paid';
@@ -0,0 +1,18 @@
+CREATE TABLE "beneficiaries" (
+  "id" bigserial PRIMARY KEY,
+  "owner_name" varchar NOT NULL,
+  "nickname" varchar NOT NULL CHECK (char_length("nickname") BETWEEN 1 AND 64),
+  "account_id" bigint NOT NULL,
+  "currency" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "beneficiaries" ("owner_name", "nickname");
+
+CREATE UNIQUE INDEX ON "beneficiaries" ("owner_name", "account_id");
+
+COMMENT ON COLUMN "beneficiaries"."created_at" IS 'large transfers wait for the cooling-off period counted from here';
+
+ALTER TABLE "beneficiaries" ADD FOREIGN KEY ("owner_name") REFERENCES "users" ("username");
+
+ALTER TABLE "beneficiaries" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id") ON DELETE CASCADE;
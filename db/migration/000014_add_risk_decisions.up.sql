@@ -0,0 +1,34 @@
+CREATE TABLE "risk_decisions" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL CHECK ("amount" > 0),
+  "currency" varchar NOT NULL,
+  "description" varchar NOT NULL DEFAULT '',
+  "reference" varchar NOT NULL DEFAULT '',
+  "metadata" jsonb NOT NULL DEFAULT '{}',
+  "decision" varchar NOT NULL,
+  "hits" jsonb NOT NULL DEFAULT '[]',
+  "status" varchar NOT NULL,
+  "transfer_id" bigint NOT NULL DEFAULT 0,
+  "reviewed_by" varchar NOT NULL DEFAULT '',
+  "reviewed_at" timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00Z',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "risk_decisions" ("status", "id");
+
+COMMENT ON COLUMN "risk_decisions"."decision" IS 'review or block, transfers that are allowed are not recorded';
+
+COMMENT ON COLUMN "risk_decisions"."hits" IS 'the rules that matched and why';
+
+COMMENT ON COLUMN "risk_decisions"."status" IS 'pending until reviewed, then approved or rejected; blocked for block decisions; refused for review decisions of a batch or split refused as a whole';
+
+COMMENT ON COLUMN "risk_decisions"."transfer_id" IS 'the transfer made once the decision was approved, 0 before';
+
+ALTER TABLE "risk_decisions" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "risk_decisions" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "risk_decisions" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
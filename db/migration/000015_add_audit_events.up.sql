@@ -0,0 +1,15 @@
+CREATE TABLE "audit_events" (
+  "id" bigserial PRIMARY KEY,
+  "actor" varchar NOT NULL,
+  "action" varchar NOT NULL,
+  "account_id" bigint NOT NULL DEFAULT 0,
+  "reason" varchar NOT NULL DEFAULT '' CHECK (char_length("reason") <= 255),
+  "details" jsonb NOT NULL DEFAULT '{}',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "audit_events" ("account_id", "id");
+
+COMMENT ON COLUMN "audit_events"."actor" IS 'username of the staff member, or cli:<user> for the admin subcommand';
+
+COMMENT ON COLUMN "audit_events"."account_id" IS 'the account acted on, 0 for other actions; no foreign key so the trail outlives the account';
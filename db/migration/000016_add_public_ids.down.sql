@@ -0,0 +1,7 @@
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "public_id";
+
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "public_id";
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "public_id";
+
+DROP FUNCTION IF EXISTS new_public_id(text);
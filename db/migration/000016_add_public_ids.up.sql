@@ -0,0 +1,53 @@
+-- a prefixed ULID: 48 bits of milliseconds then 80 random bits, in Crockford base32.
+-- Public ids sort by creation time but can't be guessed from one another, unlike the bigserial ids.
+-- The random bits are taken from an md5 of random() and the clock rather than pgcrypto's gen_random_bytes,
+-- whose extension only a superuser can create on postgres 12. Ownership is checked on every request,
+-- so public ids only need to be hard to guess, not cryptographically random.
+CREATE FUNCTION new_public_id(prefix text) RETURNS text AS $$
+DECLARE
+  alphabet CONSTANT text := '0123456789ABCDEFGHJKMNPQRSTVWXYZ';
+  ms bigint := floor(extract(epoch FROM clock_timestamp()) * 1000);
+  rnd bytea := decode(md5(random()::text || clock_timestamp()::text || pg_backend_pid()::text), 'hex');
+  start int;
+  bits bigint;
+  chunk text;
+  id text := '';
+BEGIN
+  FOR i IN 1..10 LOOP
+    id := substr(alphabet, (ms % 32)::int + 1, 1) || id;
+    ms := ms / 32;
+  END LOOP;
+
+  -- the 80 random bits are encoded as two chunks of 5 bytes
+  FOREACH start IN ARRAY ARRAY[0, 5] LOOP
+    bits := 0;
+    FOR j IN 0..4 LOOP
+      bits := bits * 256 + get_byte(rnd, start + j);
+    END LOOP;
+
+    chunk := '';
+    FOR j IN 1..8 LOOP
+      chunk := substr(alphabet, (bits % 32)::int + 1, 1) || chunk;
+      bits := bits / 32;
+    END LOOP;
+    id := id || chunk;
+  END LOOP;
+
+  RETURN prefix || id;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
+
+-- the default is evaluated for every existing row, so each gets an id of its own
+ALTER TABLE "accounts" ADD COLUMN "public_id" varchar NOT NULL DEFAULT (new_public_id('acc_'));
+
+ALTER TABLE "entries" ADD COLUMN "public_id" varchar NOT NULL DEFAULT (new_public_id('ent_'));
+
+ALTER TABLE "transfers" ADD COLUMN "public_id" varchar NOT NULL DEFAULT (new_public_id('tr_'));
+
+CREATE UNIQUE INDEX ON "accounts" ("public_id");
+
+CREATE UNIQUE INDEX ON "entries" ("public_id");
+
+CREATE UNIQUE INDEX ON "transfers" ("public_id");
+
+COMMENT ON COLUMN "accounts"."public_id" IS 'the id clients see, the bigserial id is only used inside the bank';
@@ -0,0 +1,133 @@
+// Package migration embeds the SQL migrations into the binary and applies them with golang-migrate.
+package migration
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Migrator applies the embedded migrations to a database
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+// New creates a Migrator for the database at dbSource.
+// It uses its own connection, Close must be called once done.
+func New(dbSource string) (*Migrator, error) {
+	source, err := iofs.New(files, ".")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read embedded migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", source, dbSource)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create migrator: %w", err)
+	}
+	m.Log = logger{}
+
+	return &Migrator{m: m}, nil
+}
+
+// Up applies all migrations that haven't been applied yet
+func (migrator *Migrator) Up() error {
+	return ignoreNoChange(migrator.m.Up())
+}
+
+// Steps applies n migrations, or reverts them when n is negative
+func (migrator *Migrator) Steps(n int) error {
+	return ignoreNoChange(migrator.m.Steps(n))
+}
+
+// Down reverts all migrations
+func (migrator *Migrator) Down() error {
+	return ignoreNoChange(migrator.m.Down())
+}
+
+// Version returns the current version of the database, 0 if it was never migrated
+func (migrator *Migrator) Version() (version uint, dirty bool, err error) {
+	version, dirty, err = migrator.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return
+}
+
+// CheckVersion returns an error when the database can't be used by a binary built for expected:
+// a dirty database needs manual repair, and a newer schema means the binary is outdated.
+// An older schema is accepted, it only needs to be migrated up.
+func (migrator *Migrator) CheckVersion(expected uint) error {
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		return fmt.Errorf("cannot read schema version: %w", err)
+	}
+
+	if dirty {
+		return fmt.Errorf("database is dirty at version %d, fix it and force the version before starting", version)
+	}
+
+	if version > expected {
+		return fmt.Errorf("database schema version %d is ahead of this binary, which expects version %d", version, expected)
+	}
+
+	return nil
+}
+
+// Close releases the connection of the migrator
+func (migrator *Migrator) Close() error {
+	sourceErr, dbErr := migrator.m.Close()
+	if sourceErr != nil {
+		return sourceErr
+	}
+	return dbErr
+}
+
+// LatestVersion returns the version of the newest embedded migration
+func LatestVersion() (uint, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, entry := range entries {
+		prefix := strings.SplitN(entry.Name(), "_", 2)[0]
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid migration file name %s: %w", entry.Name(), err)
+		}
+		if uint(version) > latest {
+			latest = uint(version)
+		}
+	}
+	return latest, nil
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// logger prints the progress of golang-migrate with the standard logger
+type logger struct{}
+
+func (logger) Printf(format string, v ...interface{}) {
+	log.Printf("migrate: "+format, v...)
+}
+
+func (logger) Verbose() bool {
+	return false
+}
@@ -0,0 +1,35 @@
+package migration
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestVersionMatchesStore(t *testing.T) {
+	latest, err := LatestVersion()
+	require.NoError(t, err)
+
+	//db.MigrationVersion has to be bumped together with every new migration
+	require.Equal(t, uint(db.MigrationVersion), latest)
+}
+
+func TestEveryMigrationHasADown(t *testing.T) {
+	entries, err := fs.ReadDir(files, ".")
+	require.NoError(t, err)
+
+	names := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		names[entry.Name()] = true
+	}
+
+	for name := range names {
+		if strings.HasSuffix(name, ".up.sql") {
+			down := strings.TrimSuffix(name, ".up.sql") + ".down.sql"
+			require.True(t, names[down], "missing %s", down)
+		}
+	}
+}
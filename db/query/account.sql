@@ -9,6 +9,10 @@ VALUES
 
 SELECT * FROM accounts WHERE id = $1 LIMIT 1;
 
+-- name: GetAccountByPublicID :one
+
+SELECT * FROM accounts WHERE public_id = $1 LIMIT 1;
+
 -- name: GetAccountForUpdate :one
 
 SELECT * FROM accounts WHERE id = $1 LIMIT 1 FOR UPDATE;
@@ -32,4 +36,21 @@ UPDATE
 SET
 	balance = balance + sqlc.arg(amount)
 WHERE
-	id = sqlc.arg(id) RETURNING *;
\ No newline at end of file
+	id = sqlc.arg(id) RETURNING *;
+
+-- name: ListAccountsByOwner :many
+
+SELECT * FROM accounts WHERE owner_name = $1 ORDER BY id LIMIT $2 OFFSET $3;
+
+-- name: UpdateAccountStatus :one
+
+UPDATE accounts SET status = $2 WHERE id = $1 RETURNING *;
+
+-- name: ListUnreconciledAccounts :many
+
+SELECT accounts.id, accounts.currency, accounts.balance, COALESCE(SUM(entries.amount), 0)::bigint AS entries_total
+FROM accounts
+LEFT JOIN entries ON entries.account_id = accounts.id
+GROUP BY accounts.id
+HAVING accounts.balance <> COALESCE(SUM(entries.amount), 0)
+ORDER BY accounts.id;
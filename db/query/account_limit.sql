@@ -0,0 +1,21 @@
+-- name: UpsertAccountLimit :one
+
+INSERT INTO
+	account_limits (
+		account_id,
+		max_single_transfer,
+		max_daily_amount,
+		max_daily_count
+	)
+VALUES
+	($1, $2, $3, $4) ON CONFLICT (account_id) DO
+UPDATE
+SET
+	max_single_transfer = EXCLUDED.max_single_transfer,
+	max_daily_amount = EXCLUDED.max_daily_amount,
+	max_daily_count = EXCLUDED.max_daily_count,
+	updated_at = now() RETURNING *;
+
+-- name: GetAccountLimit :one
+
+SELECT * FROM account_limits WHERE account_id = $1 LIMIT 1;
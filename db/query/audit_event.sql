@@ -0,0 +1,16 @@
+-- name: CreateAuditEvent :one
+
+INSERT INTO
+	audit_events (
+		actor,
+		action,
+		account_id,
+		reason,
+		details
+	)
+VALUES
+	($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: ListAuditEventsByAccount :many
+
+SELECT * FROM audit_events WHERE account_id = $1 ORDER BY id LIMIT $2 OFFSET $3;
@@ -0,0 +1,35 @@
+-- name: CreateBalanceSnapshot :one
+
+INSERT INTO
+	balance_snapshots (account_id, taken_at, balance)
+VALUES
+	($1, $2, $3) RETURNING *;
+
+-- name: GetLatestBalanceSnapshot :one
+
+SELECT
+	*
+FROM balance_snapshots
+WHERE
+	account_id = $1
+	AND taken_at <= $2
+ORDER BY taken_at DESC
+LIMIT 1;
+
+-- name: ListAccountsDueSnapshot :many
+
+SELECT
+	*
+FROM accounts
+WHERE
+	id > sqlc.arg(after_id)
+	AND created_at < sqlc.arg(taken_at)
+	AND NOT EXISTS (
+		SELECT 1
+		FROM balance_snapshots
+		WHERE
+			balance_snapshots.account_id = accounts.id
+			AND balance_snapshots.taken_at >= sqlc.arg(taken_at)
+	)
+ORDER BY id
+LIMIT sqlc.arg(row_limit);
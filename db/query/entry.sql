@@ -1,18 +1,43 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
   account_id,
-  amount
+  amount,
+  description
 ) VALUES (
-  $1, $2
+  $1, $2, $3
 )RETURNING *;
 
 -- name: GetEntry :one
 SELECT * FROM entries
 WHERE id = $1 LIMIT 1;
 
+-- name: GetEntryByPublicID :one
+SELECT * FROM entries
+WHERE public_id = $1 LIMIT 1;
+
 -- name: ListEntries :many
 SELECT * FROM entries
 WHERE account_id = $1
 ORDER BY id
 LIMIT $2
 OFFSET $3;
+
+-- name: SumEntriesSince :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS total FROM entries
+WHERE account_id = $1 AND created_at >= $2;
+
+-- name: SumEntriesBetween :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS total FROM entries
+WHERE account_id = sqlc.arg(account_id) AND created_at >= sqlc.arg(since) AND created_at < sqlc.arg(before);
+
+-- name: SumEntriesBefore :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS total FROM entries
+WHERE account_id = sqlc.arg(account_id) AND created_at < sqlc.arg(before);
+
+-- name: GetBalanceBefore :one
+SELECT (accounts.balance - COALESCE((
+  SELECT SUM(entries.amount) FROM entries
+  WHERE entries.account_id = accounts.id AND entries.created_at >= sqlc.arg(before)
+), 0))::bigint AS balance
+FROM accounts
+WHERE accounts.id = sqlc.arg(account_id);
@@ -0,0 +1,51 @@
+-- name: UpsertProductFee :one
+
+INSERT INTO
+	product_fees (
+		product_code,
+		kind,
+		flat_amount,
+		rate_ppm,
+		max_amount
+	)
+VALUES
+	($1, $2, $3, $4, $5) ON CONFLICT (product_code, kind) DO
+UPDATE
+SET
+	flat_amount = EXCLUDED.flat_amount,
+	rate_ppm = EXCLUDED.rate_ppm,
+	max_amount = EXCLUDED.max_amount,
+	updated_at = now() RETURNING *;
+
+-- name: DeleteProductFee :exec
+
+DELETE FROM product_fees WHERE product_code = $1 AND kind = $2;
+
+-- name: ListProductFees :many
+
+SELECT * FROM product_fees WHERE product_code = $1 ORDER BY kind;
+
+-- name: ListAccountFees :many
+
+SELECT
+	product_fees.*
+FROM product_fees
+	JOIN account_interests ON account_interests.product_code = product_fees.product_code
+WHERE
+	account_interests.account_id = $1
+ORDER BY product_fees.kind;
+
+-- name: UpsertFeeRevenueAccount :one
+
+INSERT INTO
+	fee_revenue_accounts (currency, account_id)
+VALUES
+	($1, $2) ON CONFLICT (currency) DO
+UPDATE
+SET
+	account_id = EXCLUDED.account_id,
+	updated_at = now() RETURNING *;
+
+-- name: GetFeeRevenueAccount :one
+
+SELECT * FROM fee_revenue_accounts WHERE currency = $1 LIMIT 1;
@@ -0,0 +1,30 @@
+-- name: CreateImportJob :one
+
+INSERT INTO
+	import_jobs (
+		kind,
+		file_hash,
+		total_rows,
+		created_by
+	)
+VALUES
+	($1, $2, $3, $4) RETURNING *;
+
+-- name: GetImportJobByHash :one
+
+SELECT * FROM import_jobs WHERE kind = $1 AND file_hash = $2 LIMIT 1;
+
+-- name: GetImportJobForUpdate :one
+
+SELECT * FROM import_jobs WHERE id = $1 LIMIT 1 FOR UPDATE;
+
+-- name: UpdateImportJobProgress :one
+
+UPDATE
+	import_jobs
+SET
+	committed_rows = $2,
+	status = $3,
+	updated_at = now()
+WHERE
+	id = $1 RETURNING *;
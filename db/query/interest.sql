@@ -0,0 +1,80 @@
+-- name: CreateAccountProduct :one
+
+INSERT INTO
+	account_products (
+		code,
+		name,
+		annual_rate_ppm
+	)
+VALUES
+	($1, $2, $3) RETURNING *;
+
+-- name: GetAccountProduct :one
+
+SELECT * FROM account_products WHERE code = $1 LIMIT 1;
+
+-- name: ListAccountProducts :many
+
+SELECT * FROM account_products ORDER BY code;
+
+-- name: UpsertAccountInterest :one
+
+INSERT INTO
+	account_interests (
+		account_id,
+		product_code,
+		accrued_through
+	)
+VALUES
+	($1, $2, $3) ON CONFLICT (account_id) DO
+UPDATE
+SET
+	product_code = EXCLUDED.product_code,
+	updated_at = now() RETURNING *;
+
+-- name: GetAccountInterest :one
+
+SELECT * FROM account_interests WHERE account_id = $1 LIMIT 1;
+
+-- name: GetAccountInterestForUpdate :one
+
+SELECT * FROM account_interests WHERE account_id = $1 LIMIT 1 FOR UPDATE;
+
+-- name: ListAccountInterestsDue :many
+
+SELECT
+	*
+FROM account_interests
+WHERE
+	accrued_through < $1
+	AND account_id > $2
+ORDER BY account_id
+LIMIT $3;
+
+-- name: UpdateAccountInterest :one
+
+UPDATE
+	account_interests
+SET
+	accrued = $2,
+	accrued_fraction = $3,
+	accrued_through = $4,
+	paid = $5,
+	updated_at = now()
+WHERE
+	account_id = $1 RETURNING *;
+
+-- name: UpsertInterestExpenseAccount :one
+
+INSERT INTO
+	interest_expense_accounts (currency, account_id)
+VALUES
+	($1, $2) ON CONFLICT (currency) DO
+UPDATE
+SET
+	account_id = EXCLUDED.account_id,
+	updated_at = now() RETURNING *;
+
+-- name: GetInterestExpenseAccount :one
+
+SELECT * FROM interest_expense_accounts WHERE currency = $1 LIMIT 1;
@@ -0,0 +1,24 @@
+-- name: CreateRecoveryCode :one
+
+INSERT INTO
+	recovery_codes (
+		username,
+		code_hash
+	)
+VALUES
+	($1, $2) RETURNING *;
+
+-- name: DeleteRecoveryCodes :exec
+
+DELETE FROM recovery_codes WHERE username = $1;
+
+-- name: UseRecoveryCode :one
+
+UPDATE
+	recovery_codes
+SET
+	is_used = TRUE
+WHERE
+	username = $1
+	AND code_hash = $2
+	AND is_used = FALSE RETURNING *;
@@ -0,0 +1,37 @@
+-- name: CreateResetPassword :one
+
+INSERT INTO
+	reset_passwords (
+		username,
+		token_hash,
+		expired_at
+	)
+VALUES
+	($1, $2, $3) RETURNING *;
+
+-- name: GetResetPassword :one
+
+SELECT
+	*
+FROM
+	reset_passwords
+WHERE
+	token_hash = $1
+	AND is_used = FALSE
+	AND expired_at > now()
+LIMIT 1;
+
+-- name: UseResetPassword :one
+
+UPDATE
+	reset_passwords
+SET
+	is_used = TRUE
+WHERE
+	token_hash = $1
+	AND is_used = FALSE
+	AND expired_at > now() RETURNING *;
+
+-- name: InvalidateResetPasswords :exec
+
+UPDATE reset_passwords SET is_used = TRUE WHERE username = $1 AND is_used = FALSE;
@@ -0,0 +1,42 @@
+-- name: CreateRiskDecision :one
+
+INSERT INTO
+	risk_decisions (
+		username,
+		from_account_id,
+		to_account_id,
+		amount,
+		currency,
+		description,
+		reference,
+		metadata,
+		decision,
+		hits,
+		status
+	)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING *;
+
+-- name: GetRiskDecision :one
+
+SELECT * FROM risk_decisions WHERE id = $1 LIMIT 1;
+
+-- name: ListRiskDecisionsByStatus :many
+
+SELECT * FROM risk_decisions WHERE status = $1 ORDER BY id LIMIT $2 OFFSET $3;
+
+-- name: ReviewRiskDecision :one
+
+UPDATE
+	risk_decisions
+SET
+	status = $2,
+	reviewed_by = $3,
+	reviewed_at = now()
+WHERE
+	id = $1
+	AND status = 'pending' RETURNING *;
+
+-- name: SetRiskDecisionTransfer :one
+
+UPDATE risk_decisions SET transfer_id = $2 WHERE id = $1 RETURNING *;
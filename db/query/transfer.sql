@@ -2,20 +2,45 @@
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  description,
+  reference,
+  metadata
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5, $6
 ) RETURNING *;
 
 -- name: GetTransfer :one
 SELECT * FROM transfers
 WHERE id = $1 LIMIT 1;
 
+-- name: GetTransferByPublicID :one
+SELECT * FROM transfers
+WHERE public_id = $1 LIMIT 1;
+
 -- name: ListTransfers :many
 SELECT * FROM transfers
 WHERE 
-    from_account_id = $1 OR
-    to_account_id = $2
+    (from_account_id = $1 OR
+    to_account_id = $2) AND
+    (description ILIKE $3 OR
+    reference = $4) AND
+    metadata @> $5
 ORDER BY id
-LIMIT $3
-OFFSET $4;
\ No newline at end of file
+LIMIT $6
+OFFSET $7;
+
+-- name: GetOutgoingTransferTotals :one
+SELECT
+  COALESCE(SUM(amount), 0)::bigint AS total_amount,
+  COUNT(*) AS transfer_count
+FROM transfers
+WHERE
+    from_account_id = sqlc.arg(account_id) AND
+    created_at >= sqlc.arg(since);
+
+-- name: CountTransfersBetween :one
+SELECT COUNT(*) FROM transfers
+WHERE
+    from_account_id = $1 AND
+    to_account_id = $2;
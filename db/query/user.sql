@@ -11,4 +11,23 @@ VALUES($1, $2, $3, $4) RETURNING *;
 
 -- name: GetUser :one
 
-SELECT * FROM users WHERE username = $1 LIMIT 1;
\ No newline at end of file
+SELECT * FROM users WHERE username = $1 LIMIT 1;
+
+-- name: UpdateUserRole :one
+
+UPDATE users SET role = $2 WHERE username = $1 RETURNING *;
+
+
+-- name: UpdateUserEmailVerified :one
+
+UPDATE users SET is_email_verified = TRUE WHERE username = $1 AND email = $2 RETURNING *;
+
+-- name: UpdateUserPassword :one
+
+UPDATE
+	users
+SET
+	hashed_password = $2,
+	password_changed_at = $3
+WHERE
+	username = $1 RETURNING *;
@@ -0,0 +1,33 @@
+-- name: UpsertUserTOTP :one
+
+INSERT INTO
+	user_totps (
+		username,
+		secret
+	)
+VALUES
+	($1, $2) ON CONFLICT (username) DO
+UPDATE
+SET
+	secret = EXCLUDED.secret,
+	last_used_step = 0
+WHERE
+	user_totps.is_enabled = FALSE RETURNING *;
+
+-- name: GetUserTOTP :one
+
+SELECT * FROM user_totps WHERE username = $1 LIMIT 1;
+
+-- name: EnableUserTOTP :one
+
+UPDATE user_totps SET is_enabled = TRUE WHERE username = $1 RETURNING *;
+
+-- name: UseTOTPStep :one
+
+UPDATE
+	user_totps
+SET
+	last_used_step = sqlc.arg(step)
+WHERE
+	username = sqlc.arg(username)
+	AND last_used_step < sqlc.arg(step) RETURNING *;
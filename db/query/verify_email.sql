@@ -0,0 +1,23 @@
+-- name: CreateVerifyEmail :one
+
+INSERT INTO
+	verify_emails (
+		username,
+		email,
+		secret_code,
+		expired_at
+	)
+VALUES
+	($1, $2, $3, $4) RETURNING *;
+
+-- name: UpdateVerifyEmail :one
+
+UPDATE
+	verify_emails
+SET
+	is_used = TRUE
+WHERE
+	id = sqlc.arg(id)
+	AND secret_code = sqlc.arg(secret_code)
+	AND is_used = FALSE
+	AND expired_at > now() RETURNING *;
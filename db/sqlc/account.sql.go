@@ -16,7 +16,7 @@ UPDATE
 SET
 	balance = balance + $1
 WHERE
-	id = $2 RETURNING id, owner_name, balance, currency, created_at
+	id = $2 RETURNING id, owner_name, balance, currency, created_at, status, public_id
 `
 
 type AddAccountBalanceParams struct {
@@ -33,6 +33,8 @@ func (q *Queries) AddAccountBalance(ctx context.Context, arg AddAccountBalancePa
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Status,
+		&i.PublicID,
 	)
 	return i, err
 }
@@ -42,7 +44,7 @@ const createAccount = `-- name: CreateAccount :one
 INSERT INTO
 	accounts (owner_name, balance, currency)
 VALUES
-	($1, $2, $3) RETURNING id, owner_name, balance, currency, created_at
+	($1, $2, $3) RETURNING id, owner_name, balance, currency, created_at, status, public_id
 `
 
 type CreateAccountParams struct {
@@ -60,6 +62,8 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Status,
+		&i.PublicID,
 	)
 	return i, err
 }
@@ -76,7 +80,7 @@ func (q *Queries) DeleteAccount(ctx context.Context, id int64) error {
 
 const getAccount = `-- name: GetAccount :one
 
-SELECT id, owner_name, balance, currency, created_at FROM accounts WHERE id = $1 LIMIT 1
+SELECT id, owner_name, balance, currency, created_at, status, public_id FROM accounts WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
@@ -88,13 +92,35 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Status,
+		&i.PublicID,
+	)
+	return i, err
+}
+
+const getAccountByPublicID = `-- name: GetAccountByPublicID :one
+
+SELECT id, owner_name, balance, currency, created_at, status, public_id FROM accounts WHERE public_id = $1 LIMIT 1
+`
+
+func (q *Queries) GetAccountByPublicID(ctx context.Context, publicID string) (Account, error) {
+	row := q.db.QueryRowContext(ctx, getAccountByPublicID, publicID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerName,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.Status,
+		&i.PublicID,
 	)
 	return i, err
 }
 
 const getAccountForUpdate = `-- name: GetAccountForUpdate :one
 
-SELECT id, owner_name, balance, currency, created_at FROM accounts WHERE id = $1 LIMIT 1 FOR UPDATE
+SELECT id, owner_name, balance, currency, created_at, status, public_id FROM accounts WHERE id = $1 LIMIT 1 FOR UPDATE
 `
 
 func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, error) {
@@ -106,13 +132,15 @@ func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, e
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Status,
+		&i.PublicID,
 	)
 	return i, err
 }
 
 const listAccounts = `-- name: ListAccounts :many
 
-SELECT id, owner_name, balance, currency, created_at FROM accounts ORDER BY id LIMIT $1 OFFSET $2
+SELECT id, owner_name, balance, currency, created_at, status, public_id FROM accounts ORDER BY id LIMIT $1 OFFSET $2
 `
 
 type ListAccountsParams struct {
@@ -135,6 +163,95 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 			&i.Balance,
 			&i.Currency,
 			&i.CreatedAt,
+			&i.Status,
+			&i.PublicID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountsByOwner = `-- name: ListAccountsByOwner :many
+
+SELECT id, owner_name, balance, currency, created_at, status, public_id FROM accounts WHERE owner_name = $1 ORDER BY id LIMIT $2 OFFSET $3
+`
+
+type ListAccountsByOwnerParams struct {
+	OwnerName string `json:"owner_name"`
+	Limit     int32  `json:"limit"`
+	Offset    int32  `json:"offset"`
+}
+
+func (q *Queries) ListAccountsByOwner(ctx context.Context, arg ListAccountsByOwnerParams) ([]Account, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountsByOwner, arg.OwnerName, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.OwnerName,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.Status,
+			&i.PublicID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnreconciledAccounts = `-- name: ListUnreconciledAccounts :many
+
+SELECT accounts.id, accounts.currency, accounts.balance, COALESCE(SUM(entries.amount), 0)::bigint AS entries_total
+FROM accounts
+LEFT JOIN entries ON entries.account_id = accounts.id
+GROUP BY accounts.id
+HAVING accounts.balance <> COALESCE(SUM(entries.amount), 0)
+ORDER BY accounts.id
+`
+
+type ListUnreconciledAccountsRow struct {
+	ID           int64  `json:"id"`
+	Currency     string `json:"currency"`
+	Balance      int64  `json:"balance"`
+	EntriesTotal int64  `json:"entries_total"`
+}
+
+func (q *Queries) ListUnreconciledAccounts(ctx context.Context) ([]ListUnreconciledAccountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUnreconciledAccounts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUnreconciledAccountsRow{}
+	for rows.Next() {
+		var i ListUnreconciledAccountsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Currency,
+			&i.Balance,
+			&i.EntriesTotal,
 		); err != nil {
 			return nil, err
 		}
@@ -151,7 +268,7 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 
 const updateAccountBalance = `-- name: UpdateAccountBalance :one
 
-UPDATE accounts SET balance = $2 WHERE id = $1 RETURNING id, owner_name, balance, currency, created_at
+UPDATE accounts SET balance = $2 WHERE id = $1 RETURNING id, owner_name, balance, currency, created_at, status, public_id
 `
 
 type UpdateAccountBalanceParams struct {
@@ -168,6 +285,33 @@ func (q *Queries) UpdateAccountBalance(ctx context.Context, arg UpdateAccountBal
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Status,
+		&i.PublicID,
+	)
+	return i, err
+}
+
+const updateAccountStatus = `-- name: UpdateAccountStatus :one
+
+UPDATE accounts SET status = $2 WHERE id = $1 RETURNING id, owner_name, balance, currency, created_at, status, public_id
+`
+
+type UpdateAccountStatusParams struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+}
+
+func (q *Queries) UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, updateAccountStatus, arg.ID, arg.Status)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerName,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.Status,
+		&i.PublicID,
 	)
 	return i, err
 }
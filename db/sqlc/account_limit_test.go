@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAccountLimit(t *testing.T, accountID int64) AccountLimit {
+	arg := UpsertAccountLimitParams{
+		AccountID:         accountID,
+		MaxSingleTransfer: util.RandomMoney(),
+		MaxDailyAmount:    util.RandomMoney(),
+		MaxDailyCount:     util.RandomInt(1, 10),
+	}
+
+	limit, err := testQueries.UpsertAccountLimit(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, limit)
+
+	require.Equal(t, arg.AccountID, limit.AccountID)
+	require.Equal(t, arg.MaxSingleTransfer, limit.MaxSingleTransfer)
+	require.Equal(t, arg.MaxDailyAmount, limit.MaxDailyAmount)
+	require.Equal(t, arg.MaxDailyCount, limit.MaxDailyCount)
+	require.NotZero(t, limit.UpdatedAt)
+
+	return limit
+}
+
+func TestUpsertAccountLimit(t *testing.T) {
+	account := createRandomAccount(t)
+	limit1 := createRandomAccountLimit(t, account.ID)
+	limit2 := createRandomAccountLimit(t, account.ID)
+
+	require.Equal(t, limit1.AccountID, limit2.AccountID)
+	require.False(t, limit2.UpdatedAt.Before(limit1.UpdatedAt))
+}
+
+func TestGetAccountLimit(t *testing.T) {
+	account := createRandomAccount(t)
+
+	_, err := testQueries.GetAccountLimit(context.Background(), account.ID)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	limit1 := createRandomAccountLimit(t, account.ID)
+	limit2, err := testQueries.GetAccountLimit(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, limit1, limit2)
+}
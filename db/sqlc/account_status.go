@@ -0,0 +1,26 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Constants for all account statuses
+const (
+	AccountStatusActive = "active"
+	AccountStatusFrozen = "frozen"
+)
+
+// ErrAccountFrozen is returned by TransferTx when money would move in or out of a frozen account
+var ErrAccountFrozen = errors.New("account is frozen")
+
+// checkAccountsActive returns ErrAccountFrozen for the first frozen account.
+// It is called with the rows returned by the balance update, so the status is read under the row lock.
+func checkAccountsActive(accounts ...Account) error {
+	for _, account := range accounts {
+		if account.Status == AccountStatusFrozen {
+			return fmt.Errorf("account [%s]: %w", account.PublicID, ErrAccountFrozen)
+		}
+	}
+	return nil
+}
@@ -26,6 +26,7 @@ func createRandomAccount(t *testing.T) Account {
 	require.Equal(t, arg.OwnerName, account.OwnerName)
 	require.Equal(t, arg.Balance, account.Balance)
 	require.Equal(t, arg.Currency, account.Currency)
+	require.Equal(t, AccountStatusActive, account.Status)
 
 	require.NotZero(t, account.ID)
 	require.NotZero(t, account.CreatedAt)
@@ -103,3 +104,38 @@ func TestListAccounts(t *testing.T) {
 		require.NotEmpty(t, account)
 	}
 }
+
+func TestListAccountsByOwner(t *testing.T) {
+	var lastAccount Account
+	for i := 0; i < 10; i++ {
+		lastAccount = createRandomAccount(t)
+	}
+
+	arg := ListAccountsByOwnerParams{
+		OwnerName: lastAccount.OwnerName,
+		Limit:     5,
+		Offset:    0,
+	}
+
+	accounts, err := testQueries.ListAccountsByOwner(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, accounts)
+
+	for _, account := range accounts {
+		require.NotEmpty(t, account)
+		require.Equal(t, lastAccount.OwnerName, account.OwnerName)
+	}
+}
+
+func TestUpdateAccountStatus(t *testing.T) {
+	account1 := createRandomAccount(t)
+
+	account2, err := testQueries.UpdateAccountStatus(context.Background(), UpdateAccountStatusParams{
+		ID:     account1.ID,
+		Status: AccountStatusFrozen,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account1.ID, account2.ID)
+	require.Equal(t, account1.Balance, account2.Balance)
+	require.Equal(t, AccountStatusFrozen, account2.Status)
+}
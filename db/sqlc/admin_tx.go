@@ -0,0 +1,195 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Actions recorded in the audit trail
+const (
+	AuditActionCreateUser    = "user.create"
+	AuditActionCreateAccount = "account.create"
+	AuditActionAccountStatus = "account.status"
+	AuditActionAdjustment    = "account.adjustment"
+)
+
+// ErrInvalidAdjustment is returned by AdjustBalanceTx when the adjustment moves nothing or has no reason
+var ErrInvalidAdjustment = errors.New("invalid adjustment")
+
+// UpdateAccountStatusTxParams contains the input parameters of the update account status transaction
+type UpdateAccountStatusTxParams struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+	Actor  string `json:"actor"`
+	Reason string `json:"reason"`
+}
+
+// UpdateAccountStatusTxResult is the account with its new status and the audit event of the change
+type UpdateAccountStatusTxResult struct {
+	Account Account    `json:"account"`
+	Event   AuditEvent `json:"event"`
+	//Retries is the number of times the transaction had to be run again
+	Retries int `json:"-"`
+}
+
+// UpdateAccountStatusTx freezes or unfreezes an account and records who did it and why.
+// It returns sql.ErrNoRows when the account does not exist.
+func (store *SQLStore) UpdateAccountStatusTx(ctx context.Context, arg UpdateAccountStatusTxParams) (UpdateAccountStatusTxResult, error) {
+	var result UpdateAccountStatusTxResult
+
+	retries, err := store.execTxRetry(ctx, func(q *Queries) error {
+		var err error
+		result, err = updateAccountStatusTx(ctx, q, arg)
+		return err
+	})
+
+	result.Retries = retries
+	return result, err
+}
+
+func updateAccountStatusTx(ctx context.Context, q Querier, arg UpdateAccountStatusTxParams) (UpdateAccountStatusTxResult, error) {
+	var result UpdateAccountStatusTxResult
+
+	//locked so the previous status in the trail is the one that was replaced
+	before, err := q.GetAccountForUpdate(ctx, arg.ID)
+	if err != nil {
+		return result, err
+	}
+
+	result.Account, err = q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+		ID:     arg.ID,
+		Status: arg.Status,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.Event, err = recordAuditEvent(ctx, q, arg.Actor, AuditActionAccountStatus, arg.ID, arg.Reason, map[string]string{
+		"from": before.Status,
+		"to":   result.Account.Status,
+	})
+	return result, err
+}
+
+// AdjustBalanceTxParams contains the input parameters of the adjust balance transaction.
+// Amount is added to the account, a negative amount takes money out of it. The counterpart
+// is AgainstAccountID, an account of the bank such as a suspense account, so the books stay balanced.
+type AdjustBalanceTxParams struct {
+	AccountID        int64  `json:"account_id"`
+	AgainstAccountID int64  `json:"against_account_id"`
+	Amount           int64  `json:"amount"`
+	Actor            string `json:"actor"`
+	Reason           string `json:"reason"`
+}
+
+// AdjustBalanceTxResult is the result of the adjust balance transaction
+type AdjustBalanceTxResult struct {
+	Transfer       Transfer   `json:"transfer"`
+	Account        Account    `json:"account"`
+	AgainstAccount Account    `json:"against_account"`
+	Entry          Entry      `json:"entry"`
+	AgainstEntry   Entry      `json:"against_entry"`
+	Event          AuditEvent `json:"event"`
+	//Retries is the number of times the transaction had to be run again
+	Retries int `json:"-"`
+}
+
+// AdjustBalanceTx posts a manual correction to an account and records it in the audit trail.
+// Unlike TransferTx it charges no fees, ignores the transfer limits and works on frozen accounts,
+// corrections are exactly what frozen accounts may need.
+func (store *SQLStore) AdjustBalanceTx(ctx context.Context, arg AdjustBalanceTxParams) (AdjustBalanceTxResult, error) {
+	var result AdjustBalanceTxResult
+
+	retries, err := store.execTxRetry(ctx, func(q *Queries) error {
+		var err error
+		result, err = adjustBalanceTx(ctx, q, arg)
+		return err
+	})
+
+	result.Retries = retries
+	return result, err
+}
+
+func adjustBalanceTx(ctx context.Context, q Querier, arg AdjustBalanceTxParams) (AdjustBalanceTxResult, error) {
+	var result AdjustBalanceTxResult
+
+	if arg.Amount == 0 || arg.AccountID == arg.AgainstAccountID || arg.Reason == "" {
+		return result, fmt.Errorf("account [%d] against [%d] amount %d: %w", arg.AccountID, arg.AgainstAccountID, arg.Amount, ErrInvalidAdjustment)
+	}
+
+	description := "adjustment: " + arg.Reason
+	metadata, err := json.Marshal(map[string]string{"actor": arg.Actor, "reason": arg.Reason})
+	if err != nil {
+		return result, err
+	}
+
+	from, to, amount := arg.AgainstAccountID, arg.AccountID, arg.Amount
+	if amount < 0 {
+		from, to, amount = to, from, -amount
+	}
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: from,
+		ToAccountID:   to,
+		Amount:        amount,
+		Description:   description,
+		Metadata:      metadata,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.Entry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:   arg.AccountID,
+		Amount:      arg.Amount,
+		Description: description,
+	})
+	if err != nil {
+		return result, err
+	}
+	result.AgainstEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:   arg.AgainstAccountID,
+		Amount:      -arg.Amount,
+		Description: description,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	//same lock order as transferTx
+	if arg.AccountID < arg.AgainstAccountID {
+		result.Account, result.AgainstAccount, err = addMoney(ctx, q, arg.AccountID, arg.Amount, arg.AgainstAccountID, -arg.Amount)
+	} else {
+		result.AgainstAccount, result.Account, err = addMoney(ctx, q, arg.AgainstAccountID, -arg.Amount, arg.AccountID, arg.Amount)
+	}
+	if err != nil {
+		return result, err
+	}
+	if result.Account.Currency != result.AgainstAccount.Currency {
+		return result, fmt.Errorf("account [%s] %s vs [%s] %s: %w", result.Account.PublicID, result.Account.Currency, result.AgainstAccount.PublicID, result.AgainstAccount.Currency, ErrCurrencyMismatch)
+	}
+
+	result.Event, err = recordAuditEvent(ctx, q, arg.Actor, AuditActionAdjustment, arg.AccountID, arg.Reason, map[string]int64{
+		"amount":             arg.Amount,
+		"against_account_id": arg.AgainstAccountID,
+		"transfer_id":        result.Transfer.ID,
+	})
+	return result, err
+}
+
+// recordAuditEvent records an action in the audit trail, details are stored as a JSON object
+func recordAuditEvent(ctx context.Context, q Querier, actor string, action string, accountID int64, reason string, details interface{}) (AuditEvent, error) {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return AuditEvent{}, err
+	}
+
+	return q.CreateAuditEvent(ctx, CreateAuditEventParams{
+		Actor:     actor,
+		Action:    action,
+		AccountID: accountID,
+		Reason:    reason,
+		Details:   data,
+	})
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// BalanceAt returns the balance of an account including every entry created before at.
+// It starts from the latest snapshot taken at or before at and adds the entries since, so only
+// the entries of one snapshot interval are read however long the history of the account is.
+// Without such a snapshot it walks back from the current balance.
+func BalanceAt(ctx context.Context, q Querier, accountID int64, at time.Time) (int64, error) {
+	snapshot, err := q.GetLatestBalanceSnapshot(ctx, GetLatestBalanceSnapshotParams{
+		AccountID: accountID,
+		TakenAt:   at,
+	})
+	if err == sql.ErrNoRows {
+		return q.GetBalanceBefore(ctx, GetBalanceBeforeParams{
+			Before:    at,
+			AccountID: accountID,
+		})
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	since, err := q.SumEntriesBetween(ctx, SumEntriesBetweenParams{
+		AccountID: accountID,
+		Since:     snapshot.TakenAt,
+		Before:    at,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return snapshot.Balance + since, nil
+}
@@ -0,0 +1,166 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	// ErrInsufficientFunds is returned by BatchTransferTx and PostingTx when a source balance does not cover what it pays
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrCurrencyMismatch is returned by BatchTransferTx when a destination holds another currency than the source,
+	// and by PostingTx when the legs do not sum to zero in every currency
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+)
+
+// BatchTransferItem is one destination of a batch transfer
+type BatchTransferItem struct {
+	ToAccountID int64 `json:"to_account_id"`
+	Amount      int64 `json:"amount"`
+}
+
+// BatchTransferTxParams contains the input parameters of the batch transfer transaction
+type BatchTransferTxParams struct {
+	FromAccountID int64               `json:"from_account_id"`
+	Items         []BatchTransferItem `json:"items"`
+	//SecondFactor is used up once for the whole batch, see TransferTxParams
+	SecondFactor *UseTOTPStepParams `json:"-"`
+}
+
+// BatchTransferTxResult is the result of the batch transfer transaction, Transfers is in the order of the items
+type BatchTransferTxResult struct {
+	FromAccount Account            `json:"from_account"`
+	Transfers   []TransferTxResult `json:"transfers"`
+	//Retries is the number of times the transaction had to be run again
+	Retries int `json:"-"`
+}
+
+// BatchItemError tells which item of a batch made the whole batch fail
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
+
+// BatchTransferTx moves money from one account to many in a single transaction, either every item
+// is applied or none. Currencies are checked before the first transfer is made, and every item must
+// be covered by the balance left, transfer fees included.
+func (store *SQLStore) BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error) {
+	var result BatchTransferTxResult
+
+	retries, err := store.execTxRetry(ctx, func(q *Queries) error {
+		var err error
+		result, err = batchTransferTx(ctx, q, arg)
+		return err
+	})
+
+	result.Retries = retries
+	return result, err
+}
+
+func batchTransferTx(ctx context.Context, q Querier, arg BatchTransferTxParams) (BatchTransferTxResult, error) {
+	var result BatchTransferTxResult
+
+	if err := useSecondFactor(ctx, q, arg.SecondFactor); err != nil {
+		return result, err
+	}
+
+	var quotes []FeeQuote
+	for _, item := range arg.Items {
+		itemQuotes, err := QuoteTransferFees(ctx, q, arg.FromAccountID, item.Amount)
+		if err != nil {
+			return result, err
+		}
+		quotes = append(quotes, itemQuotes...)
+	}
+	revenueID, err := transferFeeRevenueAccountID(ctx, q, arg.FromAccountID, quotes)
+	if err != nil {
+		return result, err
+	}
+
+	//every account of the batch, the fee revenue account included, is locked up front in ascending id order,
+	//the same order TransferTx locks its accounts in, so batches and single transfers can never deadlock each other
+	accounts := make(map[int64]Account)
+	for _, id := range batchAccountIDs(arg, revenueID) {
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			if err == sql.ErrNoRows && id != arg.FromAccountID && id != revenueID {
+				return result, &BatchItemError{Index: batchItemIndex(arg, id), Err: err}
+			}
+			return result, err
+		}
+		accounts[id] = account
+	}
+
+	fromAccount := accounts[arg.FromAccountID]
+	if err := checkAccountsActive(fromAccount); err != nil {
+		return result, err
+	}
+
+	for i, item := range arg.Items {
+		toAccount := accounts[item.ToAccountID]
+		if toAccount.Currency != fromAccount.Currency {
+			return result, &BatchItemError{
+				Index: i,
+				Err:   fmt.Errorf("account [%s] %s vs %s: %w", toAccount.PublicID, toAccount.Currency, fromAccount.Currency, ErrCurrencyMismatch),
+			}
+		}
+	}
+
+	//every item checks the balance left after the items before it, fees included
+	result.FromAccount = fromAccount
+	for i, item := range arg.Items {
+		transfer, err := transferTx(ctx, q, TransferTxParams{
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   item.ToAccountID,
+			Amount:        item.Amount,
+			RequireFunds:  true,
+		})
+		if err != nil {
+			return result, &BatchItemError{Index: i, Err: err}
+		}
+
+		result.Transfers = append(result.Transfers, transfer)
+		result.FromAccount = transfer.FromAccount
+	}
+
+	return result, nil
+}
+
+// batchAccountIDs returns the distinct accounts of a batch and its fee revenue account, if any, sorted ascending
+func batchAccountIDs(arg BatchTransferTxParams, revenueID int64) []int64 {
+	seen := map[int64]bool{arg.FromAccountID: true}
+	ids := []int64{arg.FromAccountID}
+	if revenueID != 0 && revenueID != arg.FromAccountID {
+		seen[revenueID] = true
+		ids = append(ids, revenueID)
+	}
+	for _, item := range arg.Items {
+		if !seen[item.ToAccountID] {
+			seen[item.ToAccountID] = true
+			ids = append(ids, item.ToAccountID)
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func batchItemIndex(arg BatchTransferTxParams, accountID int64) int {
+	for i, item := range arg.Items {
+		if item.ToAccountID == accountID {
+			return i
+		}
+	}
+	return -1
+}
@@ -7,36 +7,62 @@ package db
 
 import (
 	"context"
+	"time"
 )
 
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (
   account_id,
-  amount
+  amount,
+  description
 ) VALUES (
-  $1, $2
-)RETURNING id, account_id, amount, created_at
+  $1, $2, $3
+)RETURNING id, account_id, amount, created_at, description, public_id
 `
 
 type CreateEntryParams struct {
-	AccountID int64 `json:"account_id"`
-	Amount    int64 `json:"amount"`
+	AccountID   int64  `json:"account_id"`
+	Amount      int64  `json:"amount"`
+	Description string `json:"description"`
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount, arg.Description)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Description,
+		&i.PublicID,
 	)
 	return i, err
 }
 
+const getBalanceBefore = `-- name: GetBalanceBefore :one
+SELECT (accounts.balance - COALESCE((
+  SELECT SUM(entries.amount) FROM entries
+  WHERE entries.account_id = accounts.id AND entries.created_at >= $1
+), 0))::bigint AS balance
+FROM accounts
+WHERE accounts.id = $2
+`
+
+type GetBalanceBeforeParams struct {
+	Before    time.Time `json:"before"`
+	AccountID int64     `json:"account_id"`
+}
+
+func (q *Queries) GetBalanceBefore(ctx context.Context, arg GetBalanceBeforeParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getBalanceBefore, arg.Before, arg.AccountID)
+	var balance int64
+	err := row.Scan(&balance)
+	return balance, err
+}
+
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, description, public_id FROM entries
 WHERE id = $1 LIMIT 1
 `
 
@@ -48,12 +74,33 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Description,
+		&i.PublicID,
+	)
+	return i, err
+}
+
+const getEntryByPublicID = `-- name: GetEntryByPublicID :one
+SELECT id, account_id, amount, created_at, description, public_id FROM entries
+WHERE public_id = $1 LIMIT 1
+`
+
+func (q *Queries) GetEntryByPublicID(ctx context.Context, publicID string) (Entry, error) {
+	row := q.db.QueryRowContext(ctx, getEntryByPublicID, publicID)
+	var i Entry
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Description,
+		&i.PublicID,
 	)
 	return i, err
 }
 
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, description, public_id FROM entries
 WHERE account_id = $1
 ORDER BY id
 LIMIT $2
@@ -80,6 +127,8 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.Description,
+			&i.PublicID,
 		); err != nil {
 			return nil, err
 		}
@@ -93,3 +142,55 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 	}
 	return items, nil
 }
+
+const sumEntriesBefore = `-- name: SumEntriesBefore :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS total FROM entries
+WHERE account_id = $1 AND created_at < $2
+`
+
+type SumEntriesBeforeParams struct {
+	AccountID int64     `json:"account_id"`
+	Before    time.Time `json:"before"`
+}
+
+func (q *Queries) SumEntriesBefore(ctx context.Context, arg SumEntriesBeforeParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, sumEntriesBefore, arg.AccountID, arg.Before)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
+
+const sumEntriesBetween = `-- name: SumEntriesBetween :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS total FROM entries
+WHERE account_id = $1 AND created_at >= $2 AND created_at < $3
+`
+
+type SumEntriesBetweenParams struct {
+	AccountID int64     `json:"account_id"`
+	Since     time.Time `json:"since"`
+	Before    time.Time `json:"before"`
+}
+
+func (q *Queries) SumEntriesBetween(ctx context.Context, arg SumEntriesBetweenParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, sumEntriesBetween, arg.AccountID, arg.Since, arg.Before)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
+
+const sumEntriesSince = `-- name: SumEntriesSince :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS total FROM entries
+WHERE account_id = $1 AND created_at >= $2
+`
+
+type SumEntriesSinceParams struct {
+	AccountID int64     `json:"account_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (q *Queries) SumEntriesSince(ctx context.Context, arg SumEntriesSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, sumEntriesSince, arg.AccountID, arg.CreatedAt)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
@@ -11,8 +11,9 @@ import (
 
 func createRandomEntry(t *testing.T, accountID int64) Entry {
 	arg := CreateEntryParams{
-		AccountID: accountID,
-		Amount:    util.RandomMoney(),
+		AccountID:   accountID,
+		Amount:      util.RandomMoney(),
+		Description: util.RandomString(12),
 	}
 
 	entry, err := testQueries.CreateEntry(context.Background(), arg)
@@ -21,6 +22,7 @@ func createRandomEntry(t *testing.T, accountID int64) Entry {
 
 	require.Equal(t, arg.AccountID, entry.AccountID)
 	require.Equal(t, arg.Amount, entry.Amount)
+	require.Equal(t, arg.Description, entry.Description)
 
 	require.NotZero(t, entry.ID)
 	require.NotZero(t, entry.CreatedAt)
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Kinds of fees a product can charge
+const (
+	// FeeMaintenance is charged on the last day of every month, its rate applies to the month end balance
+	FeeMaintenance = "maintenance"
+	// FeeTransfer is charged on every outgoing transfer, its rate applies to the amount sent
+	FeeTransfer = "transfer"
+)
+
+// ErrNoFeeRevenueAccount is returned when a fee is due in a currency without a fee revenue account
+var ErrNoFeeRevenueAccount = errors.New("no fee revenue account for the currency")
+
+// FeeQuote is a fee that would be charged to an account
+type FeeQuote struct {
+	Kind   string `json:"kind"`
+	Amount int64  `json:"amount"`
+}
+
+// FeeLine is a fee that was charged, Entry debits the account and RevenueEntry credits the fee revenue account
+type FeeLine struct {
+	Kind         string `json:"kind"`
+	Amount       int64  `json:"amount"`
+	Entry        Entry  `json:"entry"`
+	RevenueEntry Entry  `json:"revenue_entry"`
+}
+
+// Charge returns the fee on base: the flat amount plus the rate in millionths of base rounded down,
+// capped at MaxAmount when it is set. A base that is not positive only pays the flat amount.
+func (fee ProductFee) Charge(base int64) int64 {
+	charge := fee.FlatAmount
+	if base > 0 && fee.RatePpm > 0 {
+		share := new(big.Int).Mul(big.NewInt(base), big.NewInt(fee.RatePpm))
+		share.Quo(share, big.NewInt(1_000_000))
+		charge += share.Int64()
+	}
+	if fee.MaxAmount > 0 && charge > fee.MaxAmount {
+		charge = fee.MaxAmount
+	}
+	return charge
+}
+
+// QuoteTransferFees returns the fees TransferTx would charge the source account for sending amount,
+// ordered by kind. Accounts without a product pay no fees.
+func QuoteTransferFees(ctx context.Context, q Querier, fromAccountID int64, amount int64) ([]FeeQuote, error) {
+	fees, err := q.ListAccountFees(ctx, fromAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := []FeeQuote{}
+	for _, fee := range fees {
+		if fee.Kind != FeeTransfer {
+			continue
+		}
+		if charge := fee.Charge(amount); charge > 0 {
+			quotes = append(quotes, FeeQuote{Kind: fee.Kind, Amount: charge})
+		}
+	}
+	return quotes, nil
+}
+
+// maintenanceFees returns the maintenance fee of a product on the month end balance, if it has one
+func maintenanceFees(fees []ProductFee, balance int64) []FeeQuote {
+	quotes := []FeeQuote{}
+	for _, fee := range fees {
+		if fee.Kind != FeeMaintenance {
+			continue
+		}
+		if charge := fee.Charge(balance); charge > 0 {
+			quotes = append(quotes, FeeQuote{Kind: fee.Kind, Amount: charge})
+		}
+	}
+	return quotes
+}
+
+// feeRevenueAccountID returns the fee revenue account the quoted fees of account are credited to,
+// or 0 when nothing is quoted or account is the fee revenue account itself
+func feeRevenueAccountID(ctx context.Context, q Querier, account Account, quotes []FeeQuote) (int64, error) {
+	if len(quotes) == 0 {
+		return 0, nil
+	}
+
+	revenue, err := q.GetFeeRevenueAccount(ctx, account.Currency)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("%s: %w", account.Currency, ErrNoFeeRevenueAccount)
+		}
+		return 0, err
+	}
+	//the bank does not charge itself
+	if revenue.AccountID == account.ID {
+		return 0, nil
+	}
+	return revenue.AccountID, nil
+}
+
+// chargeFees moves the quoted fees from account to the fee revenue account revenueID returned by
+// feeRevenueAccountID, one pair of entries per fee. The caller must already hold the locks on both
+// accounts, taken in ascending id order with lockAccounts.
+func chargeFees(ctx context.Context, q Querier, account Account, revenueID int64, quotes []FeeQuote) ([]FeeLine, Account, error) {
+	if len(quotes) == 0 || revenueID == 0 {
+		return nil, account, nil
+	}
+
+	var err error
+	lines := make([]FeeLine, len(quotes))
+	var total int64
+	for i, quote := range quotes {
+		lines[i] = FeeLine{Kind: quote.Kind, Amount: quote.Amount}
+		lines[i].Entry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID:   account.ID,
+			Amount:      -quote.Amount,
+			Description: quote.Kind + " fee",
+		})
+		if err != nil {
+			return nil, account, err
+		}
+		lines[i].RevenueEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID:   revenueID,
+			Amount:      quote.Amount,
+			Description: fmt.Sprintf("%s fee of account [%s]", quote.Kind, account.PublicID),
+		})
+		if err != nil {
+			return nil, account, err
+		}
+		total += quote.Amount
+	}
+
+	account, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
+		ID:     account.ID,
+		Amount: -total,
+	})
+	if err != nil {
+		return nil, account, err
+	}
+	revenueAccount, err := q.AddAccountBalance(ctx, AddAccountBalanceParams{
+		ID:     revenueID,
+		Amount: total,
+	})
+	if err != nil {
+		return nil, account, err
+	}
+	if revenueAccount.Currency != account.Currency {
+		return nil, account, fmt.Errorf("fee revenue account [%s] %s vs %s: %w", revenueAccount.PublicID, revenueAccount.Currency, account.Currency, ErrCurrencyMismatch)
+	}
+
+	return lines, account, nil
+}
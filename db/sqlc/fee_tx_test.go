@@ -0,0 +1,43 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductFeeCharge(t *testing.T) {
+	testCases := []struct {
+		name string
+		fee  ProductFee
+		base int64
+		want int64
+	}{
+		{"Flat", ProductFee{FlatAmount: 25}, 1_000, 25},
+		{"Rate", ProductFee{RatePpm: 15_000}, 1_000, 15},
+		{"RoundedDown", ProductFee{RatePpm: 15_000}, 99, 1},
+		{"FlatAndRate", ProductFee{FlatAmount: 10, RatePpm: 10_000}, 500, 15},
+		{"Capped", ProductFee{FlatAmount: 10, RatePpm: 10_000, MaxAmount: 12}, 500, 12},
+		{"NegativeBase", ProductFee{FlatAmount: 5, RatePpm: 10_000}, -1_000, 5},
+		//the product of base and rate does not fit in 64 bits
+		{"Large", ProductFee{RatePpm: 1_000_000}, 1 << 60, 1 << 60},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, tc.fee.Charge(tc.base))
+		})
+	}
+}
+
+func TestMaintenanceFees(t *testing.T) {
+	fees := []ProductFee{
+		{Kind: FeeTransfer, FlatAmount: 10},
+		{Kind: FeeMaintenance, FlatAmount: 100, RatePpm: 1_000},
+	}
+
+	require.Equal(t, []FeeQuote{{Kind: FeeMaintenance, Amount: 110}}, maintenanceFees(fees, 10_000))
+	require.Empty(t, maintenanceFees(fees[:1], 10_000))
+}
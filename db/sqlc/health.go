@@ -0,0 +1,26 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// MigrationVersion is the schema version this binary expects the database to be at.
+// It has to be bumped together with every new pair of files in db/migration.
+const MigrationVersion = 16
+
+// Ping verifies that the database is still reachable
+func (store *SQLStore) Ping(ctx context.Context) error {
+	return store.db.PingContext(ctx)
+}
+
+// SchemaVersion returns the version and dirty flag recorded by golang-migrate.
+// A database that has never been migrated reports version 0.
+func (store *SQLStore) SchemaVersion(ctx context.Context) (version int64, dirty bool, err error) {
+	row := store.db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1")
+	err = row.Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return
+}
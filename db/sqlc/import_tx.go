@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Statuses of an import job
+const (
+	ImportJobRunning   = "running"
+	ImportJobCompleted = "completed"
+)
+
+// ErrImportJobConflict is returned by ImportTx when the job was advanced by another run in the meantime
+var ErrImportJobConflict = errors.New("import job was advanced by another run")
+
+// ImportTxParams is one chunk of an import job, only the rows matching the kind of the job are set.
+// FirstRow is the number of rows of the job committed before this chunk.
+type ImportTxParams struct {
+	JobID     int64
+	FirstRow  int64
+	Users     []CreateUserTxParams
+	Accounts  []CreateAccountParams
+	Transfers []TransferTxParams
+}
+
+// ImportTxResult is the result of the import transaction
+type ImportTxResult struct {
+	Job       ImportJob            `json:"job"`
+	Users     []CreateUserTxResult `json:"users"`
+	Accounts  []Account            `json:"accounts"`
+	Transfers []TransferTxResult   `json:"transfers"`
+}
+
+// ImportTx commits one chunk of an import together with the progress of its job,
+// so that a job interrupted at any point resumes exactly after the last committed chunk.
+// A failing row is reported as *BatchItemError with its index in the chunk.
+func (store *SQLStore) ImportTx(ctx context.Context, arg ImportTxParams) (ImportTxResult, error) {
+	var result ImportTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = importTx(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+func importTx(ctx context.Context, q Querier, arg ImportTxParams) (ImportTxResult, error) {
+	var result ImportTxResult
+
+	//the job row is locked first, so two runs of the same file cannot commit the same chunk twice
+	job, err := q.GetImportJobForUpdate(ctx, arg.JobID)
+	if err != nil {
+		return result, err
+	}
+	if job.CommittedRows != arg.FirstRow {
+		return result, fmt.Errorf("job [%d] has %d rows committed, chunk starts at %d: %w", job.ID, job.CommittedRows, arg.FirstRow, ErrImportJobConflict)
+	}
+
+	for i, user := range arg.Users {
+		created, err := createUserTx(ctx, q, user)
+		if err != nil {
+			return result, &BatchItemError{Index: i, Err: err}
+		}
+		result.Users = append(result.Users, created)
+	}
+
+	for i, account := range arg.Accounts {
+		created, err := q.CreateAccount(ctx, account)
+		if err != nil {
+			return result, &BatchItemError{Index: i, Err: err}
+		}
+		result.Accounts = append(result.Accounts, created)
+	}
+
+	for i, transfer := range arg.Transfers {
+		created, err := transferTx(ctx, q, transfer)
+		if err != nil {
+			return result, &BatchItemError{Index: i, Err: err}
+		}
+		result.Transfers = append(result.Transfers, created)
+	}
+
+	committed := arg.FirstRow + int64(len(arg.Users)+len(arg.Accounts)+len(arg.Transfers))
+	status := ImportJobRunning
+	if committed >= job.TotalRows {
+		status = ImportJobCompleted
+	}
+
+	result.Job, err = q.UpdateImportJobProgress(ctx, UpdateImportJobProgressParams{
+		ID:            job.ID,
+		CommittedRows: committed,
+		Status:        status,
+	})
+	return result, err
+}
@@ -0,0 +1,208 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// InterestDenominator is the unit of AccountInterest.AccruedFraction.
+// Interest uses the actual/365 day count and rates in millionths, so one day of interest
+// on a balance is balance * rate / InterestDenominator and never has to be rounded.
+const InterestDenominator = 365 * 1_000_000
+
+var (
+	// ErrInterestAlreadyAccrued is returned by AccrueInterestTx for a day that was accrued before
+	ErrInterestAlreadyAccrued = errors.New("interest already accrued for this day")
+	// ErrInterestDayMissing is returned by AccrueInterestTx when earlier days were not accrued yet
+	ErrInterestDayMissing = errors.New("interest must be accrued one day after the other")
+	// ErrNoInterestExpenseAccount is returned when interest is due in a currency without an expense account
+	ErrNoInterestExpenseAccount = errors.New("no interest expense account for the currency")
+)
+
+// AccrueInterestTxParams contains the input parameters of the accrue interest transaction.
+// Day is the UTC day whose end of day balance earns interest.
+type AccrueInterestTxParams struct {
+	AccountID int64
+	Day       time.Time
+}
+
+// AccrueInterestTxResult is the result of the accrue interest transaction
+type AccrueInterestTxResult struct {
+	Interest   AccountInterest `json:"interest"`
+	EndBalance int64           `json:"end_balance"`
+	// Posting is set when the day closed a month and the accrued interest was paid
+	Posting *TransferTxResult `json:"posting,omitempty"`
+	// Fees holds the maintenance fee charged when the day closed a month
+	Fees []FeeLine `json:"fees,omitempty"`
+}
+
+// AccrueInterestTx adds one day of interest on the end of day balance to the interest accrued
+// by the account. On the last day of a month the whole units accrued are paid into the account
+// by a transfer from the interest expense account of its currency, the fraction is carried over,
+// and the maintenance fee of the product is charged on the month end balance.
+func (store *SQLStore) AccrueInterestTx(ctx context.Context, arg AccrueInterestTxParams) (AccrueInterestTxResult, error) {
+	var result AccrueInterestTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = accrueInterestTx(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+func accrueInterestTx(ctx context.Context, q Querier, arg AccrueInterestTxParams) (AccrueInterestTxResult, error) {
+	var result AccrueInterestTxResult
+	day := InterestDay(arg.Day)
+
+	interest, err := q.GetAccountInterestForUpdate(ctx, arg.AccountID)
+	if err != nil {
+		return result, err
+	}
+	accruedThrough := InterestDay(interest.AccruedThrough)
+	if !day.After(accruedThrough) {
+		return result, fmt.Errorf("account [%d] day %s: %w", arg.AccountID, day.Format("2006-01-02"), ErrInterestAlreadyAccrued)
+	}
+	if next := accruedThrough.AddDate(0, 0, 1); !day.Equal(next) {
+		return result, fmt.Errorf("account [%d] day %s, next is %s: %w", arg.AccountID, day.Format("2006-01-02"), next.Format("2006-01-02"), ErrInterestDayMissing)
+	}
+
+	product, err := q.GetAccountProduct(ctx, interest.ProductCode)
+	if err != nil {
+		return result, err
+	}
+
+	//the account row is locked so that no transfer commits between reading the balance and the entries,
+	//on the last day of a month together with the accounts interest is paid from and fees are paid to
+	ids := []int64{arg.AccountID}
+	if IsLastDayOfMonth(day) {
+		ids, err = monthEndAccountIDs(ctx, q, arg.AccountID)
+		if err != nil {
+			return result, err
+		}
+	}
+	accounts, err := lockAccounts(ctx, q, ids...)
+	if err != nil {
+		return result, err
+	}
+	account := accounts[arg.AccountID]
+	later, err := q.SumEntriesSince(ctx, SumEntriesSinceParams{
+		AccountID: account.ID,
+		CreatedAt: day.AddDate(0, 0, 1),
+	})
+	if err != nil {
+		return result, err
+	}
+	result.EndBalance = account.Balance - later
+
+	//overdrawn accounts earn nothing, they are not charged either
+	accrued, fraction := interest.Accrued, interest.AccruedFraction
+	if result.EndBalance > 0 {
+		accrued, fraction = accrueDay(accrued, fraction, result.EndBalance, product.AnnualRatePpm)
+	}
+	paid := interest.Paid
+
+	//frozen accounts keep accruing, the interest is paid at the end of the first month after they are unfrozen
+	if IsLastDayOfMonth(day) && accrued > 0 && account.Status != AccountStatusFrozen {
+		expense, err := q.GetInterestExpenseAccount(ctx, account.Currency)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return result, fmt.Errorf("%s: %w", account.Currency, ErrNoInterestExpenseAccount)
+			}
+			return result, err
+		}
+
+		posting, err := transferTx(ctx, q, TransferTxParams{
+			FromAccountID: expense.AccountID,
+			ToAccountID:   account.ID,
+			Amount:        accrued,
+			Description:   "interest " + day.Format("2006-01"),
+		})
+		if err != nil {
+			return result, err
+		}
+		if posting.FromAccount.Currency != account.Currency {
+			return result, fmt.Errorf("expense account [%s] %s vs %s: %w", posting.FromAccount.PublicID, posting.FromAccount.Currency, account.Currency, ErrCurrencyMismatch)
+		}
+		result.Posting = &posting
+		paid += accrued
+		accrued = 0
+	}
+
+	//frozen accounts are not charged either, the months they were frozen are waived
+	if IsLastDayOfMonth(day) && account.Status != AccountStatusFrozen {
+		fees, err := q.ListProductFees(ctx, product.Code)
+		if err != nil {
+			return result, err
+		}
+		quotes := maintenanceFees(fees, result.EndBalance)
+		revenueID, err := feeRevenueAccountID(ctx, q, account, quotes)
+		if err != nil {
+			return result, err
+		}
+		result.Fees, _, err = chargeFees(ctx, q, account, revenueID, quotes)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	result.Interest, err = q.UpdateAccountInterest(ctx, UpdateAccountInterestParams{
+		AccountID:       account.ID,
+		Accrued:         accrued,
+		AccruedFraction: fraction,
+		AccruedThrough:  day,
+		Paid:            paid,
+	})
+	return result, err
+}
+
+// monthEndAccountIDs returns the account with the interest expense and fee revenue accounts of its currency.
+// An account that isn't set up is left out, it is only an error once interest or a fee is due in its currency.
+func monthEndAccountIDs(ctx context.Context, q Querier, accountID int64) ([]int64, error) {
+	account, err := q.GetAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	ids := []int64{account.ID}
+
+	expense, err := q.GetInterestExpenseAccount(ctx, account.Currency)
+	if err == nil {
+		ids = append(ids, expense.AccountID)
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	revenue, err := q.GetFeeRevenueAccount(ctx, account.Currency)
+	if err == nil {
+		ids = append(ids, revenue.AccountID)
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// accrueDay adds the interest of one day on balance to the accrued whole units and fraction.
+// The product is computed with big integers, a large balance times the rate does not fit in 64 bits.
+func accrueDay(accrued int64, fraction int64, balance int64, ratePpm int64) (int64, int64) {
+	total := new(big.Int).Mul(big.NewInt(balance), big.NewInt(ratePpm))
+	total.Add(total, big.NewInt(fraction))
+
+	units, rest := new(big.Int).QuoRem(total, big.NewInt(InterestDenominator), new(big.Int))
+	return accrued + units.Int64(), rest.Int64()
+}
+
+// InterestDay returns the UTC day t falls on, interest days always start at midnight UTC
+func InterestDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// IsLastDayOfMonth reports whether the interest day closes a month
+func IsLastDayOfMonth(day time.Time) bool {
+	return day.AddDate(0, 0, 1).Day() == 1
+}
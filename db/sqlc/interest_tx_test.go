@@ -0,0 +1,44 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccrueDay(t *testing.T) {
+	testCases := []struct {
+		name         string
+		accrued      int64
+		fraction     int64
+		balance      int64
+		ratePpm      int64
+		wantAccrued  int64
+		wantFraction int64
+	}{
+		{"Exact", 0, 0, 1_000_000, 36_500, 100, 0},
+		{"Fraction", 0, 0, 1_000, 1, 0, 1_000},
+		{"FractionCarried", 5, InterestDenominator - 1, 1, 1, 6, 0},
+		{"Remainder", 0, 0, 10_000, 25_000, 0, 250_000_000},
+		{"ZeroRate", 7, 3, 1_000_000, 0, 7, 3},
+		//the product of balance and rate does not fit in 64 bits
+		{"Large", 0, 0, 1 << 50, 1_000_000, (1 << 50) / 365, (1 << 50) % 365 * 1_000_000},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			accrued, fraction := accrueDay(tc.accrued, tc.fraction, tc.balance, tc.ratePpm)
+			require.Equal(t, tc.wantAccrued, accrued)
+			require.Equal(t, tc.wantFraction, fraction)
+		})
+	}
+}
+
+func TestIsLastDayOfMonth(t *testing.T) {
+	require.True(t, IsLastDayOfMonth(time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)))
+	require.False(t, IsLastDayOfMonth(time.Date(2023, time.February, 27, 0, 0, 0, 0, time.UTC)))
+	require.True(t, IsLastDayOfMonth(time.Date(2023, time.December, 31, 0, 0, 0, 0, time.UTC)))
+}
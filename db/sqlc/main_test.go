@@ -19,7 +19,7 @@ func TestMain(m *testing.M) {
 		log.Fatal("cannot load config:", err)
 	}
 
-	testDB, err = sql.Open(config.DBDriver, config.DBSource)
+	testDB, err = sql.Open(config.DB.Driver, config.DB.Source)
 	if err != nil {
 		log.Fatal("cannot connect to db:", err)
 	}
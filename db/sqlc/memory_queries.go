@@ -0,0 +1,1240 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+)
+
+// The methods below follow the statements in db/query one by one, including which constraint
+// Postgres checks first. Methods are sorted like querier.go.
+
+func (q *memoryQueries) AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error) {
+	defer q.lock()()
+
+	account, ok := q.data.accounts[arg.ID]
+	if !ok {
+		return Account{}, sql.ErrNoRows
+	}
+	account.Balance += arg.Amount
+	memoryPut(q, q.data.accounts, account.ID, account)
+	return account, nil
+}
+
+func (q *memoryQueries) CountTransfersBetween(ctx context.Context, arg CountTransfersBetweenParams) (int64, error) {
+	defer q.lock()()
+
+	var count int64
+	for _, transfer := range q.data.transfers {
+		if transfer.FromAccountID == arg.FromAccountID && transfer.ToAccountID == arg.ToAccountID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (q *memoryQueries) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	defer q.lock()()
+
+	for _, account := range q.data.accounts {
+		if account.OwnerName == arg.OwnerName && account.Currency == arg.Currency {
+			return Account{}, uniqueViolation("accounts", "owner_currency_key")
+		}
+	}
+	if _, ok := q.data.users[arg.OwnerName]; !ok {
+		return Account{}, foreignKeyViolation("accounts", "accounts_owner_name_fkey")
+	}
+
+	account := Account{
+		ID:        q.nextID("accounts"),
+		OwnerName: arg.OwnerName,
+		Balance:   arg.Balance,
+		Currency:  arg.Currency,
+		CreatedAt: q.now(),
+		Status:    AccountStatusActive,
+		PublicID:  NewPublicID(AccountIDPrefix, q.now()),
+	}
+	memoryPut(q, q.data.accounts, account.ID, account)
+	return account, nil
+}
+
+func (q *memoryQueries) CreateAccountProduct(ctx context.Context, arg CreateAccountProductParams) (AccountProduct, error) {
+	defer q.lock()()
+
+	if _, ok := q.data.products[arg.Code]; ok {
+		return AccountProduct{}, uniqueViolation("account_products", "account_products_pkey")
+	}
+
+	product := AccountProduct{
+		Code:          arg.Code,
+		Name:          arg.Name,
+		AnnualRatePpm: arg.AnnualRatePpm,
+		CreatedAt:     q.now(),
+	}
+	memoryPut(q, q.data.products, product.Code, product)
+	return product, nil
+}
+
+func (q *memoryQueries) CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error) {
+	defer q.lock()()
+
+	if arg.Details == nil {
+		return AuditEvent{}, notNullViolation("audit_events", "details")
+	}
+	details, ok := memoryJSON(arg.Details)
+	if !ok {
+		return AuditEvent{}, invalidJSON()
+	}
+	if utf8.RuneCountInString(arg.Reason) > 255 {
+		return AuditEvent{}, checkViolation("audit_events", "audit_events_reason_check")
+	}
+
+	event := AuditEvent{
+		ID:        q.nextID("audit_events"),
+		Actor:     arg.Actor,
+		Action:    arg.Action,
+		AccountID: arg.AccountID,
+		Reason:    arg.Reason,
+		Details:   details,
+		CreatedAt: q.now(),
+	}
+	memoryPut(q, q.data.auditEvents, event.ID, event)
+	return event, nil
+}
+
+func (q *memoryQueries) CreateBalanceSnapshot(ctx context.Context, arg CreateBalanceSnapshotParams) (BalanceSnapshot, error) {
+	defer q.lock()()
+
+	if _, ok := q.data.accounts[arg.AccountID]; !ok {
+		return BalanceSnapshot{}, foreignKeyViolation("balance_snapshots", "balance_snapshots_account_id_fkey")
+	}
+	key := newBalanceSnapshotKey(arg.AccountID, arg.TakenAt)
+	if _, ok := q.data.snapshots[key]; ok {
+		return BalanceSnapshot{}, uniqueViolation("balance_snapshots", "balance_snapshots_pkey")
+	}
+
+	snapshot := BalanceSnapshot{
+		AccountID: arg.AccountID,
+		TakenAt:   arg.TakenAt,
+		Balance:   arg.Balance,
+		CreatedAt: q.now(),
+	}
+	memoryPut(q, q.data.snapshots, key, snapshot)
+	return snapshot, nil
+}
+
+func (q *memoryQueries) CreateBeneficiary(ctx context.Context, arg CreateBeneficiaryParams) (Beneficiary, error) {
+	defer q.lock()()
+
+	if utf8.RuneCountInString(arg.Nickname) < 1 || utf8.RuneCountInString(arg.Nickname) > 64 {
+		return Beneficiary{}, checkViolation("beneficiaries", "beneficiaries_nickname_check")
+	}
+	for _, beneficiary := range q.data.beneficiaries {
+		if beneficiary.OwnerName != arg.OwnerName {
+			continue
+		}
+		if beneficiary.Nickname == arg.Nickname {
+			return Beneficiary{}, uniqueViolation("beneficiaries", "beneficiaries_owner_name_nickname_idx")
+		}
+		if beneficiary.AccountID == arg.AccountID {
+			return Beneficiary{}, uniqueViolation("beneficiaries", "beneficiaries_owner_name_account_id_idx")
+		}
+	}
+	if _, ok := q.data.users[arg.OwnerName]; !ok {
+		return Beneficiary{}, foreignKeyViolation("beneficiaries", "beneficiaries_owner_name_fkey")
+	}
+	if _, ok := q.data.accounts[arg.AccountID]; !ok {
+		return Beneficiary{}, foreignKeyViolation("beneficiaries", "beneficiaries_account_id_fkey")
+	}
+
+	beneficiary := Beneficiary{
+		ID:        q.nextID("beneficiaries"),
+		OwnerName: arg.OwnerName,
+		Nickname:  arg.Nickname,
+		AccountID: arg.AccountID,
+		Currency:  arg.Currency,
+		CreatedAt: q.now(),
+	}
+	memoryPut(q, q.data.beneficiaries, beneficiary.ID, beneficiary)
+	return beneficiary, nil
+}
+
+func (q *memoryQueries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
+	defer q.lock()()
+
+	if _, ok := q.data.accounts[arg.AccountID]; !ok {
+		return Entry{}, foreignKeyViolation("entries", "entries_account_id_fkey")
+	}
+
+	entry := Entry{
+		ID:          q.nextID("entries"),
+		AccountID:   arg.AccountID,
+		Amount:      arg.Amount,
+		CreatedAt:   q.now(),
+		Description: arg.Description,
+		PublicID:    NewPublicID(EntryIDPrefix, q.now()),
+	}
+	memoryPut(q, q.data.entries, entry.ID, entry)
+	return entry, nil
+}
+
+func (q *memoryQueries) CreateImportJob(ctx context.Context, arg CreateImportJobParams) (ImportJob, error) {
+	defer q.lock()()
+
+	for _, job := range q.data.importJobs {
+		if job.Kind == arg.Kind && job.FileHash == arg.FileHash {
+			return ImportJob{}, uniqueViolation("import_jobs", "import_jobs_kind_file_hash_idx")
+		}
+	}
+
+	now := q.now()
+	job := ImportJob{
+		ID:        q.nextID("import_jobs"),
+		Kind:      arg.Kind,
+		FileHash:  arg.FileHash,
+		TotalRows: arg.TotalRows,
+		Status:    ImportJobRunning,
+		CreatedBy: arg.CreatedBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	memoryPut(q, q.data.importJobs, job.ID, job)
+	return job, nil
+}
+
+func (q *memoryQueries) CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error) {
+	defer q.lock()()
+
+	for _, code := range q.data.recoveryCodes {
+		if code.Username == arg.Username && code.CodeHash == arg.CodeHash {
+			return RecoveryCode{}, uniqueViolation("recovery_codes", "recovery_codes_username_code_hash_idx")
+		}
+	}
+	if _, ok := q.data.users[arg.Username]; !ok {
+		return RecoveryCode{}, foreignKeyViolation("recovery_codes", "recovery_codes_username_fkey")
+	}
+
+	code := RecoveryCode{
+		ID:        q.nextID("recovery_codes"),
+		Username:  arg.Username,
+		CodeHash:  arg.CodeHash,
+		CreatedAt: q.now(),
+	}
+	memoryPut(q, q.data.recoveryCodes, code.ID, code)
+	return code, nil
+}
+
+func (q *memoryQueries) CreateResetPassword(ctx context.Context, arg CreateResetPasswordParams) (ResetPassword, error) {
+	defer q.lock()()
+
+	for _, resetPassword := range q.data.resetPasswords {
+		if resetPassword.TokenHash == arg.TokenHash {
+			return ResetPassword{}, uniqueViolation("reset_passwords", "reset_passwords_token_hash_key")
+		}
+	}
+	if _, ok := q.data.users[arg.Username]; !ok {
+		return ResetPassword{}, foreignKeyViolation("reset_passwords", "reset_passwords_username_fkey")
+	}
+
+	resetPassword := ResetPassword{
+		ID:        q.nextID("reset_passwords"),
+		Username:  arg.Username,
+		TokenHash: arg.TokenHash,
+		CreatedAt: q.now(),
+		ExpiredAt: arg.ExpiredAt,
+	}
+	memoryPut(q, q.data.resetPasswords, resetPassword.ID, resetPassword)
+	return resetPassword, nil
+}
+
+func (q *memoryQueries) CreateRiskDecision(ctx context.Context, arg CreateRiskDecisionParams) (RiskDecision, error) {
+	defer q.lock()()
+
+	if arg.Metadata == nil {
+		return RiskDecision{}, notNullViolation("risk_decisions", "metadata")
+	}
+	if arg.Hits == nil {
+		return RiskDecision{}, notNullViolation("risk_decisions", "hits")
+	}
+	if arg.Amount <= 0 {
+		return RiskDecision{}, checkViolation("risk_decisions", "risk_decisions_amount_check")
+	}
+	metadata, ok := memoryJSON(arg.Metadata)
+	if !ok {
+		return RiskDecision{}, invalidJSON()
+	}
+	hits, ok := memoryJSON(arg.Hits)
+	if !ok {
+		return RiskDecision{}, invalidJSON()
+	}
+	if _, ok := q.data.users[arg.Username]; !ok {
+		return RiskDecision{}, foreignKeyViolation("risk_decisions", "risk_decisions_username_fkey")
+	}
+	if _, ok := q.data.accounts[arg.FromAccountID]; !ok {
+		return RiskDecision{}, foreignKeyViolation("risk_decisions", "risk_decisions_from_account_id_fkey")
+	}
+	if _, ok := q.data.accounts[arg.ToAccountID]; !ok {
+		return RiskDecision{}, foreignKeyViolation("risk_decisions", "risk_decisions_to_account_id_fkey")
+	}
+
+	decision := RiskDecision{
+		ID:            q.nextID("risk_decisions"),
+		Username:      arg.Username,
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		Currency:      arg.Currency,
+		Description:   arg.Description,
+		Reference:     arg.Reference,
+		Metadata:      metadata,
+		Decision:      arg.Decision,
+		Hits:          hits,
+		Status:        arg.Status,
+		ReviewedAt:    time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+		CreatedAt:     q.now(),
+	}
+	memoryPut(q, q.data.riskDecisions, decision.ID, decision)
+	return decision, nil
+}
+
+func (q *memoryQueries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
+	defer q.lock()()
+
+	if _, ok := q.data.accounts[arg.FromAccountID]; !ok {
+		return Transfer{}, foreignKeyViolation("transfers", "transfers_from_account_id_fkey")
+	}
+	if _, ok := q.data.accounts[arg.ToAccountID]; !ok {
+		return Transfer{}, foreignKeyViolation("transfers", "transfers_to_account_id_fkey")
+	}
+
+	if arg.Metadata == nil {
+		return Transfer{}, notNullViolation("transfers", "metadata")
+	}
+	if utf8.RuneCountInString(arg.Description) > 255 {
+		return Transfer{}, checkViolation("transfers", "transfers_description_check")
+	}
+	if utf8.RuneCountInString(arg.Reference) > 64 {
+		return Transfer{}, checkViolation("transfers", "transfers_reference_check")
+	}
+	metadata, ok := memoryJSONObject(arg.Metadata)
+	if !ok {
+		return Transfer{}, checkViolation("transfers", "transfers_metadata_check")
+	}
+
+	transfer := Transfer{
+		ID:            q.nextID("transfers"),
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		CreatedAt:     q.now(),
+		Description:   arg.Description,
+		Reference:     arg.Reference,
+		Metadata:      metadata,
+		PublicID:      NewPublicID(TransferIDPrefix, q.now()),
+	}
+	memoryPut(q, q.data.transfers, transfer.ID, transfer)
+	return transfer, nil
+}
+
+func (q *memoryQueries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	defer q.lock()()
+
+	if _, ok := q.data.users[arg.Username]; ok {
+		return User{}, uniqueViolation("users", "users_pkey")
+	}
+	for _, user := range q.data.users {
+		if user.Email == arg.Email {
+			return User{}, uniqueViolation("users", "users_email_key")
+		}
+	}
+
+	user := User{
+		Username:          arg.Username,
+		HashedPassword:    arg.HashedPassword,
+		FullName:          arg.FullName,
+		Email:             arg.Email,
+		PasswordChangedAt: time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+		CreatedAt:         q.now(),
+		Role:              util.DepositorRole,
+	}
+	memoryPut(q, q.data.users, user.Username, user)
+	return user, nil
+}
+
+func (q *memoryQueries) CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error) {
+	defer q.lock()()
+
+	if _, ok := q.data.users[arg.Username]; !ok {
+		return VerifyEmail{}, foreignKeyViolation("verify_emails", "verify_emails_username_fkey")
+	}
+
+	verifyEmail := VerifyEmail{
+		ID:         q.nextID("verify_emails"),
+		Username:   arg.Username,
+		Email:      arg.Email,
+		SecretCode: arg.SecretCode,
+		CreatedAt:  q.now(),
+		ExpiredAt:  arg.ExpiredAt,
+	}
+	memoryPut(q, q.data.verifyEmails, verifyEmail.ID, verifyEmail)
+	return verifyEmail, nil
+}
+
+func (q *memoryQueries) DeleteAccount(ctx context.Context, id int64) error {
+	defer q.lock()()
+
+	for _, entry := range q.data.entries {
+		if entry.AccountID == id {
+			return foreignKeyRestrict("accounts", "entries", "entries_account_id_fkey")
+		}
+	}
+	for _, transfer := range q.data.transfers {
+		if transfer.FromAccountID == id {
+			return foreignKeyRestrict("accounts", "transfers", "transfers_from_account_id_fkey")
+		}
+		if transfer.ToAccountID == id {
+			return foreignKeyRestrict("accounts", "transfers", "transfers_to_account_id_fkey")
+		}
+	}
+	if _, ok := q.data.accountLimits[id]; ok {
+		return foreignKeyRestrict("accounts", "account_limits", "account_limits_account_id_fkey")
+	}
+	if _, ok := q.data.interests[id]; ok {
+		return foreignKeyRestrict("accounts", "account_interests", "account_interests_account_id_fkey")
+	}
+	for _, expense := range q.data.expenses {
+		if expense.AccountID == id {
+			return foreignKeyRestrict("accounts", "interest_expense_accounts", "interest_expense_accounts_account_id_fkey")
+		}
+	}
+	for _, revenue := range q.data.revenues {
+		if revenue.AccountID == id {
+			return foreignKeyRestrict("accounts", "fee_revenue_accounts", "fee_revenue_accounts_account_id_fkey")
+		}
+	}
+	for _, decision := range q.data.riskDecisions {
+		if decision.FromAccountID == id {
+			return foreignKeyRestrict("accounts", "risk_decisions", "risk_decisions_from_account_id_fkey")
+		}
+		if decision.ToAccountID == id {
+			return foreignKeyRestrict("accounts", "risk_decisions", "risk_decisions_to_account_id_fkey")
+		}
+	}
+
+	//snapshots and beneficiaries are deleted with their account
+	for key := range q.data.snapshots {
+		if key.accountID == id {
+			memoryDelete(q, q.data.snapshots, key)
+		}
+	}
+	for beneficiaryID, beneficiary := range q.data.beneficiaries {
+		if beneficiary.AccountID == id {
+			memoryDelete(q, q.data.beneficiaries, beneficiaryID)
+		}
+	}
+
+	memoryDelete(q, q.data.accounts, id)
+	return nil
+}
+
+func (q *memoryQueries) DeleteBeneficiary(ctx context.Context, id int64) error {
+	defer q.lock()()
+
+	memoryDelete(q, q.data.beneficiaries, id)
+	return nil
+}
+
+func (q *memoryQueries) DeleteProductFee(ctx context.Context, arg DeleteProductFeeParams) error {
+	defer q.lock()()
+
+	memoryDelete(q, q.data.productFees, productFeeKey{productCode: arg.ProductCode, kind: arg.Kind})
+	return nil
+}
+
+func (q *memoryQueries) DeleteRecoveryCodes(ctx context.Context, username string) error {
+	defer q.lock()()
+
+	for id, code := range q.data.recoveryCodes {
+		if code.Username == username {
+			memoryDelete(q, q.data.recoveryCodes, id)
+		}
+	}
+	return nil
+}
+
+func (q *memoryQueries) EnableUserTOTP(ctx context.Context, username string) (UserTotp, error) {
+	defer q.lock()()
+
+	userTOTP, ok := q.data.userTOTPs[username]
+	if !ok {
+		return UserTotp{}, sql.ErrNoRows
+	}
+	userTOTP.IsEnabled = true
+	memoryPut(q, q.data.userTOTPs, username, userTOTP)
+	return userTOTP, nil
+}
+
+func (q *memoryQueries) GetAccount(ctx context.Context, id int64) (Account, error) {
+	defer q.lock()()
+
+	account, ok := q.data.accounts[id]
+	if !ok {
+		return Account{}, sql.ErrNoRows
+	}
+	return account, nil
+}
+
+func (q *memoryQueries) GetAccountByPublicID(ctx context.Context, publicID string) (Account, error) {
+	defer q.lock()()
+
+	for _, account := range q.data.accounts {
+		if account.PublicID == publicID {
+			return account, nil
+		}
+	}
+	return Account{}, sql.ErrNoRows
+}
+
+// GetAccountForUpdate needs no row lock of its own, a transaction already excludes every other caller
+func (q *memoryQueries) GetAccountForUpdate(ctx context.Context, id int64) (Account, error) {
+	return q.GetAccount(ctx, id)
+}
+
+func (q *memoryQueries) GetAccountInterest(ctx context.Context, accountID int64) (AccountInterest, error) {
+	defer q.lock()()
+
+	interest, ok := q.data.interests[accountID]
+	if !ok {
+		return AccountInterest{}, sql.ErrNoRows
+	}
+	return interest, nil
+}
+
+func (q *memoryQueries) GetAccountInterestForUpdate(ctx context.Context, accountID int64) (AccountInterest, error) {
+	return q.GetAccountInterest(ctx, accountID)
+}
+
+func (q *memoryQueries) GetAccountLimit(ctx context.Context, accountID int64) (AccountLimit, error) {
+	defer q.lock()()
+
+	limit, ok := q.data.accountLimits[accountID]
+	if !ok {
+		return AccountLimit{}, sql.ErrNoRows
+	}
+	return limit, nil
+}
+
+func (q *memoryQueries) GetAccountProduct(ctx context.Context, code string) (AccountProduct, error) {
+	defer q.lock()()
+
+	product, ok := q.data.products[code]
+	if !ok {
+		return AccountProduct{}, sql.ErrNoRows
+	}
+	return product, nil
+}
+
+func (q *memoryQueries) GetBalanceBefore(ctx context.Context, arg GetBalanceBeforeParams) (int64, error) {
+	defer q.lock()()
+
+	account, ok := q.data.accounts[arg.AccountID]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	balance := account.Balance
+	for _, entry := range q.data.entries {
+		if entry.AccountID == arg.AccountID && !entry.CreatedAt.Before(arg.Before) {
+			balance -= entry.Amount
+		}
+	}
+	return balance, nil
+}
+
+func (q *memoryQueries) GetBeneficiary(ctx context.Context, id int64) (Beneficiary, error) {
+	defer q.lock()()
+
+	beneficiary, ok := q.data.beneficiaries[id]
+	if !ok {
+		return Beneficiary{}, sql.ErrNoRows
+	}
+	return beneficiary, nil
+}
+
+func (q *memoryQueries) GetBeneficiaryByAccount(ctx context.Context, arg GetBeneficiaryByAccountParams) (Beneficiary, error) {
+	defer q.lock()()
+
+	for _, beneficiary := range q.data.beneficiaries {
+		if beneficiary.OwnerName == arg.OwnerName && beneficiary.AccountID == arg.AccountID {
+			return beneficiary, nil
+		}
+	}
+	return Beneficiary{}, sql.ErrNoRows
+}
+
+func (q *memoryQueries) GetEntry(ctx context.Context, id int64) (Entry, error) {
+	defer q.lock()()
+
+	entry, ok := q.data.entries[id]
+	if !ok {
+		return Entry{}, sql.ErrNoRows
+	}
+	return entry, nil
+}
+
+func (q *memoryQueries) GetEntryByPublicID(ctx context.Context, publicID string) (Entry, error) {
+	defer q.lock()()
+
+	for _, entry := range q.data.entries {
+		if entry.PublicID == publicID {
+			return entry, nil
+		}
+	}
+	return Entry{}, sql.ErrNoRows
+}
+
+func (q *memoryQueries) GetFeeRevenueAccount(ctx context.Context, currency string) (FeeRevenueAccount, error) {
+	defer q.lock()()
+
+	revenue, ok := q.data.revenues[currency]
+	if !ok {
+		return FeeRevenueAccount{}, sql.ErrNoRows
+	}
+	return revenue, nil
+}
+
+func (q *memoryQueries) GetImportJobByHash(ctx context.Context, arg GetImportJobByHashParams) (ImportJob, error) {
+	defer q.lock()()
+
+	for _, job := range q.data.importJobs {
+		if job.Kind == arg.Kind && job.FileHash == arg.FileHash {
+			return job, nil
+		}
+	}
+	return ImportJob{}, sql.ErrNoRows
+}
+
+func (q *memoryQueries) GetImportJobForUpdate(ctx context.Context, id int64) (ImportJob, error) {
+	defer q.lock()()
+
+	job, ok := q.data.importJobs[id]
+	if !ok {
+		return ImportJob{}, sql.ErrNoRows
+	}
+	return job, nil
+}
+
+func (q *memoryQueries) GetInterestExpenseAccount(ctx context.Context, currency string) (InterestExpenseAccount, error) {
+	defer q.lock()()
+
+	expense, ok := q.data.expenses[currency]
+	if !ok {
+		return InterestExpenseAccount{}, sql.ErrNoRows
+	}
+	return expense, nil
+}
+
+func (q *memoryQueries) GetLatestBalanceSnapshot(ctx context.Context, arg GetLatestBalanceSnapshotParams) (BalanceSnapshot, error) {
+	defer q.lock()()
+
+	var latest BalanceSnapshot
+	found := false
+	for key, snapshot := range q.data.snapshots {
+		if key.accountID != arg.AccountID || snapshot.TakenAt.After(arg.TakenAt) {
+			continue
+		}
+		if !found || snapshot.TakenAt.After(latest.TakenAt) {
+			latest, found = snapshot, true
+		}
+	}
+	if !found {
+		return BalanceSnapshot{}, sql.ErrNoRows
+	}
+	return latest, nil
+}
+
+func (q *memoryQueries) GetOutgoingTransferTotals(ctx context.Context, arg GetOutgoingTransferTotalsParams) (GetOutgoingTransferTotalsRow, error) {
+	defer q.lock()()
+
+	var totals GetOutgoingTransferTotalsRow
+	for _, transfer := range q.data.transfers {
+		if transfer.FromAccountID == arg.AccountID && !transfer.CreatedAt.Before(arg.Since) {
+			totals.TotalAmount += transfer.Amount
+			totals.TransferCount++
+		}
+	}
+	return totals, nil
+}
+
+func (q *memoryQueries) GetResetPassword(ctx context.Context, tokenHash string) (ResetPassword, error) {
+	defer q.lock()()
+
+	now := q.now()
+	for _, resetPassword := range q.data.resetPasswords {
+		if resetPassword.TokenHash == tokenHash && !resetPassword.IsUsed && resetPassword.ExpiredAt.After(now) {
+			return resetPassword, nil
+		}
+	}
+	return ResetPassword{}, sql.ErrNoRows
+}
+
+func (q *memoryQueries) GetRiskDecision(ctx context.Context, id int64) (RiskDecision, error) {
+	defer q.lock()()
+
+	decision, ok := q.data.riskDecisions[id]
+	if !ok {
+		return RiskDecision{}, sql.ErrNoRows
+	}
+	return decision, nil
+}
+
+func (q *memoryQueries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
+	defer q.lock()()
+
+	transfer, ok := q.data.transfers[id]
+	if !ok {
+		return Transfer{}, sql.ErrNoRows
+	}
+	return transfer, nil
+}
+
+func (q *memoryQueries) GetTransferByPublicID(ctx context.Context, publicID string) (Transfer, error) {
+	defer q.lock()()
+
+	for _, transfer := range q.data.transfers {
+		if transfer.PublicID == publicID {
+			return transfer, nil
+		}
+	}
+	return Transfer{}, sql.ErrNoRows
+}
+
+func (q *memoryQueries) GetUser(ctx context.Context, username string) (User, error) {
+	defer q.lock()()
+
+	user, ok := q.data.users[username]
+	if !ok {
+		return User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (q *memoryQueries) GetUserTOTP(ctx context.Context, username string) (UserTotp, error) {
+	defer q.lock()()
+
+	userTOTP, ok := q.data.userTOTPs[username]
+	if !ok {
+		return UserTotp{}, sql.ErrNoRows
+	}
+	return userTOTP, nil
+}
+
+func (q *memoryQueries) InvalidateResetPasswords(ctx context.Context, username string) error {
+	defer q.lock()()
+
+	for id, resetPassword := range q.data.resetPasswords {
+		if resetPassword.Username == username && !resetPassword.IsUsed {
+			resetPassword.IsUsed = true
+			memoryPut(q, q.data.resetPasswords, id, resetPassword)
+		}
+	}
+	return nil
+}
+
+func (q *memoryQueries) ListAccountFees(ctx context.Context, accountID int64) ([]ProductFee, error) {
+	defer q.lock()()
+
+	interest, ok := q.data.interests[accountID]
+	if !ok {
+		return []ProductFee{}, nil
+	}
+	return q.productFees(interest.ProductCode), nil
+}
+
+func (q *memoryQueries) ListAccountInterestsDue(ctx context.Context, arg ListAccountInterestsDueParams) ([]AccountInterest, error) {
+	defer q.lock()()
+
+	before := memoryDate(arg.AccruedThrough)
+	interests := memorySorted(q.data.interests, func(interest AccountInterest) bool {
+		return interest.AccruedThrough.Before(before) && interest.AccountID > arg.AccountID
+	})
+	return memoryPage(interests, arg.Limit, 0)
+}
+
+func (q *memoryQueries) ListAccountProducts(ctx context.Context) ([]AccountProduct, error) {
+	defer q.lock()()
+
+	products := []AccountProduct{}
+	for _, product := range q.data.products {
+		products = append(products, product)
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].Code < products[j].Code
+	})
+	return products, nil
+}
+
+func (q *memoryQueries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error) {
+	defer q.lock()()
+
+	accounts := memorySorted(q.data.accounts, func(Account) bool { return true })
+	return memoryPage(accounts, arg.Limit, arg.Offset)
+}
+
+func (q *memoryQueries) ListAccountsByOwner(ctx context.Context, arg ListAccountsByOwnerParams) ([]Account, error) {
+	defer q.lock()()
+
+	accounts := memorySorted(q.data.accounts, func(account Account) bool {
+		return account.OwnerName == arg.OwnerName
+	})
+	return memoryPage(accounts, arg.Limit, arg.Offset)
+}
+
+func (q *memoryQueries) ListAccountsDueSnapshot(ctx context.Context, arg ListAccountsDueSnapshotParams) ([]Account, error) {
+	defer q.lock()()
+
+	taken := make(map[int64]bool)
+	for key, snapshot := range q.data.snapshots {
+		if !snapshot.TakenAt.Before(arg.TakenAt) {
+			taken[key.accountID] = true
+		}
+	}
+	accounts := memorySorted(q.data.accounts, func(account Account) bool {
+		return account.ID > arg.AfterID && account.CreatedAt.Before(arg.TakenAt) && !taken[account.ID]
+	})
+	return memoryPage(accounts, arg.RowLimit, 0)
+}
+
+func (q *memoryQueries) ListAuditEventsByAccount(ctx context.Context, arg ListAuditEventsByAccountParams) ([]AuditEvent, error) {
+	defer q.lock()()
+
+	events := memorySorted(q.data.auditEvents, func(event AuditEvent) bool {
+		return event.AccountID == arg.AccountID
+	})
+	return memoryPage(events, arg.Limit, arg.Offset)
+}
+
+func (q *memoryQueries) ListBeneficiaries(ctx context.Context, arg ListBeneficiariesParams) ([]Beneficiary, error) {
+	defer q.lock()()
+
+	beneficiaries := memorySorted(q.data.beneficiaries, func(beneficiary Beneficiary) bool {
+		return beneficiary.OwnerName == arg.OwnerName
+	})
+	return memoryPage(beneficiaries, arg.Limit, arg.Offset)
+}
+
+func (q *memoryQueries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
+	defer q.lock()()
+
+	entries := memorySorted(q.data.entries, func(entry Entry) bool {
+		return entry.AccountID == arg.AccountID
+	})
+	return memoryPage(entries, arg.Limit, arg.Offset)
+}
+
+func (q *memoryQueries) ListProductFees(ctx context.Context, productCode string) ([]ProductFee, error) {
+	defer q.lock()()
+
+	return q.productFees(productCode), nil
+}
+
+// productFees returns the fees of a product ordered by kind
+func (q *memoryQueries) productFees(productCode string) []ProductFee {
+	fees := []ProductFee{}
+	for key, fee := range q.data.productFees {
+		if key.productCode == productCode {
+			fees = append(fees, fee)
+		}
+	}
+	sort.Slice(fees, func(i, j int) bool {
+		return fees[i].Kind < fees[j].Kind
+	})
+	return fees
+}
+
+func (q *memoryQueries) ListRiskDecisionsByStatus(ctx context.Context, arg ListRiskDecisionsByStatusParams) ([]RiskDecision, error) {
+	defer q.lock()()
+
+	decisions := memorySorted(q.data.riskDecisions, func(decision RiskDecision) bool {
+		return decision.Status == arg.Status
+	})
+	return memoryPage(decisions, arg.Limit, arg.Offset)
+}
+
+func (q *memoryQueries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
+	defer q.lock()()
+
+	transfers := memorySorted(q.data.transfers, func(transfer Transfer) bool {
+		return (transfer.FromAccountID == arg.FromAccountID || transfer.ToAccountID == arg.ToAccountID) &&
+			(memoryILike(transfer.Description, arg.Description) || transfer.Reference == arg.Reference) &&
+			memoryJSONContains(transfer.Metadata, arg.Metadata)
+	})
+	return memoryPage(transfers, arg.Limit, arg.Offset)
+}
+
+func (q *memoryQueries) ListUnreconciledAccounts(ctx context.Context) ([]ListUnreconciledAccountsRow, error) {
+	defer q.lock()()
+
+	totals := make(map[int64]int64)
+	for _, entry := range q.data.entries {
+		totals[entry.AccountID] += entry.Amount
+	}
+
+	rows := []ListUnreconciledAccountsRow{}
+	for _, account := range memorySorted(q.data.accounts, func(Account) bool { return true }) {
+		if account.Balance != totals[account.ID] {
+			rows = append(rows, ListUnreconciledAccountsRow{
+				ID:           account.ID,
+				Currency:     account.Currency,
+				Balance:      account.Balance,
+				EntriesTotal: totals[account.ID],
+			})
+		}
+	}
+	return rows, nil
+}
+
+func (q *memoryQueries) ReviewRiskDecision(ctx context.Context, arg ReviewRiskDecisionParams) (RiskDecision, error) {
+	defer q.lock()()
+
+	decision, ok := q.data.riskDecisions[arg.ID]
+	if !ok || decision.Status != RiskStatusPending {
+		return RiskDecision{}, sql.ErrNoRows
+	}
+	decision.Status = arg.Status
+	decision.ReviewedBy = arg.ReviewedBy
+	decision.ReviewedAt = q.now()
+	memoryPut(q, q.data.riskDecisions, decision.ID, decision)
+	return decision, nil
+}
+
+func (q *memoryQueries) SetRiskDecisionTransfer(ctx context.Context, arg SetRiskDecisionTransferParams) (RiskDecision, error) {
+	defer q.lock()()
+
+	decision, ok := q.data.riskDecisions[arg.ID]
+	if !ok {
+		return RiskDecision{}, sql.ErrNoRows
+	}
+	decision.TransferID = arg.TransferID
+	memoryPut(q, q.data.riskDecisions, decision.ID, decision)
+	return decision, nil
+}
+
+func (q *memoryQueries) SumEntriesBefore(ctx context.Context, arg SumEntriesBeforeParams) (int64, error) {
+	defer q.lock()()
+
+	var total int64
+	for _, entry := range q.data.entries {
+		if entry.AccountID == arg.AccountID && entry.CreatedAt.Before(arg.Before) {
+			total += entry.Amount
+		}
+	}
+	return total, nil
+}
+
+func (q *memoryQueries) SumEntriesBetween(ctx context.Context, arg SumEntriesBetweenParams) (int64, error) {
+	defer q.lock()()
+
+	var total int64
+	for _, entry := range q.data.entries {
+		if entry.AccountID == arg.AccountID && !entry.CreatedAt.Before(arg.Since) && entry.CreatedAt.Before(arg.Before) {
+			total += entry.Amount
+		}
+	}
+	return total, nil
+}
+
+func (q *memoryQueries) SumEntriesSince(ctx context.Context, arg SumEntriesSinceParams) (int64, error) {
+	defer q.lock()()
+
+	var total int64
+	for _, entry := range q.data.entries {
+		if entry.AccountID == arg.AccountID && !entry.CreatedAt.Before(arg.CreatedAt) {
+			total += entry.Amount
+		}
+	}
+	return total, nil
+}
+
+func (q *memoryQueries) UpdateAccountBalance(ctx context.Context, arg UpdateAccountBalanceParams) (Account, error) {
+	defer q.lock()()
+
+	account, ok := q.data.accounts[arg.ID]
+	if !ok {
+		return Account{}, sql.ErrNoRows
+	}
+	account.Balance = arg.Balance
+	memoryPut(q, q.data.accounts, account.ID, account)
+	return account, nil
+}
+
+func (q *memoryQueries) UpdateAccountInterest(ctx context.Context, arg UpdateAccountInterestParams) (AccountInterest, error) {
+	defer q.lock()()
+
+	interest, ok := q.data.interests[arg.AccountID]
+	if !ok {
+		return AccountInterest{}, sql.ErrNoRows
+	}
+	interest.Accrued = arg.Accrued
+	interest.AccruedFraction = arg.AccruedFraction
+	interest.AccruedThrough = memoryDate(arg.AccruedThrough)
+	interest.Paid = arg.Paid
+	interest.UpdatedAt = q.now()
+	memoryPut(q, q.data.interests, interest.AccountID, interest)
+	return interest, nil
+}
+
+func (q *memoryQueries) UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error) {
+	defer q.lock()()
+
+	account, ok := q.data.accounts[arg.ID]
+	if !ok {
+		return Account{}, sql.ErrNoRows
+	}
+	account.Status = arg.Status
+	memoryPut(q, q.data.accounts, account.ID, account)
+	return account, nil
+}
+
+func (q *memoryQueries) UpdateBeneficiaryNickname(ctx context.Context, arg UpdateBeneficiaryNicknameParams) (Beneficiary, error) {
+	defer q.lock()()
+
+	beneficiary, ok := q.data.beneficiaries[arg.ID]
+	if !ok {
+		return Beneficiary{}, sql.ErrNoRows
+	}
+	if utf8.RuneCountInString(arg.Nickname) < 1 || utf8.RuneCountInString(arg.Nickname) > 64 {
+		return Beneficiary{}, checkViolation("beneficiaries", "beneficiaries_nickname_check")
+	}
+	for _, other := range q.data.beneficiaries {
+		if other.ID != arg.ID && other.OwnerName == beneficiary.OwnerName && other.Nickname == arg.Nickname {
+			return Beneficiary{}, uniqueViolation("beneficiaries", "beneficiaries_owner_name_nickname_idx")
+		}
+	}
+
+	beneficiary.Nickname = arg.Nickname
+	memoryPut(q, q.data.beneficiaries, beneficiary.ID, beneficiary)
+	return beneficiary, nil
+}
+
+func (q *memoryQueries) UpdateImportJobProgress(ctx context.Context, arg UpdateImportJobProgressParams) (ImportJob, error) {
+	defer q.lock()()
+
+	job, ok := q.data.importJobs[arg.ID]
+	if !ok {
+		return ImportJob{}, sql.ErrNoRows
+	}
+	job.CommittedRows = arg.CommittedRows
+	job.Status = arg.Status
+	job.UpdatedAt = q.now()
+	memoryPut(q, q.data.importJobs, job.ID, job)
+	return job, nil
+}
+
+func (q *memoryQueries) UpdateUserEmailVerified(ctx context.Context, arg UpdateUserEmailVerifiedParams) (User, error) {
+	defer q.lock()()
+
+	user, ok := q.data.users[arg.Username]
+	if !ok || user.Email != arg.Email {
+		return User{}, sql.ErrNoRows
+	}
+	user.IsEmailVerified = true
+	memoryPut(q, q.data.users, user.Username, user)
+	return user, nil
+}
+
+func (q *memoryQueries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	defer q.lock()()
+
+	user, ok := q.data.users[arg.Username]
+	if !ok {
+		return User{}, sql.ErrNoRows
+	}
+	user.HashedPassword = arg.HashedPassword
+	user.PasswordChangedAt = arg.PasswordChangedAt
+	memoryPut(q, q.data.users, user.Username, user)
+	return user, nil
+}
+
+func (q *memoryQueries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	defer q.lock()()
+
+	user, ok := q.data.users[arg.Username]
+	if !ok {
+		return User{}, sql.ErrNoRows
+	}
+	user.Role = arg.Role
+	memoryPut(q, q.data.users, user.Username, user)
+	return user, nil
+}
+
+func (q *memoryQueries) UpdateVerifyEmail(ctx context.Context, arg UpdateVerifyEmailParams) (VerifyEmail, error) {
+	defer q.lock()()
+
+	verifyEmail, ok := q.data.verifyEmails[arg.ID]
+	if !ok || verifyEmail.SecretCode != arg.SecretCode || verifyEmail.IsUsed || !verifyEmail.ExpiredAt.After(q.now()) {
+		return VerifyEmail{}, sql.ErrNoRows
+	}
+	verifyEmail.IsUsed = true
+	memoryPut(q, q.data.verifyEmails, verifyEmail.ID, verifyEmail)
+	return verifyEmail, nil
+}
+
+func (q *memoryQueries) UpsertAccountInterest(ctx context.Context, arg UpsertAccountInterestParams) (AccountInterest, error) {
+	defer q.lock()()
+
+	if _, ok := q.data.accounts[arg.AccountID]; !ok {
+		return AccountInterest{}, foreignKeyViolation("account_interests", "account_interests_account_id_fkey")
+	}
+	if _, ok := q.data.products[arg.ProductCode]; !ok {
+		return AccountInterest{}, foreignKeyViolation("account_interests", "account_interests_product_code_fkey")
+	}
+
+	interest, ok := q.data.interests[arg.AccountID]
+	if !ok {
+		interest = AccountInterest{
+			AccountID:      arg.AccountID,
+			AccruedThrough: memoryDate(arg.AccruedThrough),
+		}
+	}
+	interest.ProductCode = arg.ProductCode
+	interest.UpdatedAt = q.now()
+	memoryPut(q, q.data.interests, interest.AccountID, interest)
+	return interest, nil
+}
+
+func (q *memoryQueries) UpsertAccountLimit(ctx context.Context, arg UpsertAccountLimitParams) (AccountLimit, error) {
+	defer q.lock()()
+
+	if _, ok := q.data.accounts[arg.AccountID]; !ok {
+		return AccountLimit{}, foreignKeyViolation("account_limits", "account_limits_account_id_fkey")
+	}
+
+	limit := AccountLimit{
+		AccountID:         arg.AccountID,
+		MaxSingleTransfer: arg.MaxSingleTransfer,
+		MaxDailyAmount:    arg.MaxDailyAmount,
+		MaxDailyCount:     arg.MaxDailyCount,
+		UpdatedAt:         q.now(),
+	}
+	memoryPut(q, q.data.accountLimits, limit.AccountID, limit)
+	return limit, nil
+}
+
+func (q *memoryQueries) UpsertFeeRevenueAccount(ctx context.Context, arg UpsertFeeRevenueAccountParams) (FeeRevenueAccount, error) {
+	defer q.lock()()
+
+	if _, ok := q.data.accounts[arg.AccountID]; !ok {
+		return FeeRevenueAccount{}, foreignKeyViolation("fee_revenue_accounts", "fee_revenue_accounts_account_id_fkey")
+	}
+
+	revenue := FeeRevenueAccount{
+		Currency:  arg.Currency,
+		AccountID: arg.AccountID,
+		UpdatedAt: q.now(),
+	}
+	memoryPut(q, q.data.revenues, revenue.Currency, revenue)
+	return revenue, nil
+}
+
+func (q *memoryQueries) UpsertInterestExpenseAccount(ctx context.Context, arg UpsertInterestExpenseAccountParams) (InterestExpenseAccount, error) {
+	defer q.lock()()
+
+	if _, ok := q.data.accounts[arg.AccountID]; !ok {
+		return InterestExpenseAccount{}, foreignKeyViolation("interest_expense_accounts", "interest_expense_accounts_account_id_fkey")
+	}
+
+	expense := InterestExpenseAccount{
+		Currency:  arg.Currency,
+		AccountID: arg.AccountID,
+		UpdatedAt: q.now(),
+	}
+	memoryPut(q, q.data.expenses, expense.Currency, expense)
+	return expense, nil
+}
+
+func (q *memoryQueries) UpsertProductFee(ctx context.Context, arg UpsertProductFeeParams) (ProductFee, error) {
+	defer q.lock()()
+
+	if _, ok := q.data.products[arg.ProductCode]; !ok {
+		return ProductFee{}, foreignKeyViolation("product_fees", "product_fees_product_code_fkey")
+	}
+
+	fee := ProductFee{
+		ProductCode: arg.ProductCode,
+		Kind:        arg.Kind,
+		FlatAmount:  arg.FlatAmount,
+		RatePpm:     arg.RatePpm,
+		MaxAmount:   arg.MaxAmount,
+		UpdatedAt:   q.now(),
+	}
+	memoryPut(q, q.data.productFees, productFeeKey{productCode: fee.ProductCode, kind: fee.Kind}, fee)
+	return fee, nil
+}
+
+func (q *memoryQueries) UpsertUserTOTP(ctx context.Context, arg UpsertUserTOTPParams) (UserTotp, error) {
+	defer q.lock()()
+
+	userTOTP, ok := q.data.userTOTPs[arg.Username]
+	if ok {
+		//ON CONFLICT ... WHERE is_enabled = FALSE leaves an enabled row alone and returns nothing
+		if userTOTP.IsEnabled {
+			return UserTotp{}, sql.ErrNoRows
+		}
+		userTOTP.Secret = arg.Secret
+		userTOTP.LastUsedStep = 0
+	} else {
+		if _, ok := q.data.users[arg.Username]; !ok {
+			return UserTotp{}, foreignKeyViolation("user_totps", "user_totps_username_fkey")
+		}
+		userTOTP = UserTotp{
+			Username:  arg.Username,
+			Secret:    arg.Secret,
+			CreatedAt: q.now(),
+		}
+	}
+	memoryPut(q, q.data.userTOTPs, userTOTP.Username, userTOTP)
+	return userTOTP, nil
+}
+
+func (q *memoryQueries) UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error) {
+	defer q.lock()()
+
+	for id, code := range q.data.recoveryCodes {
+		if code.Username == arg.Username && code.CodeHash == arg.CodeHash && !code.IsUsed {
+			code.IsUsed = true
+			memoryPut(q, q.data.recoveryCodes, id, code)
+			return code, nil
+		}
+	}
+	return RecoveryCode{}, sql.ErrNoRows
+}
+
+func (q *memoryQueries) UseResetPassword(ctx context.Context, tokenHash string) (ResetPassword, error) {
+	defer q.lock()()
+
+	now := q.now()
+	for id, resetPassword := range q.data.resetPasswords {
+		if resetPassword.TokenHash == tokenHash && !resetPassword.IsUsed && resetPassword.ExpiredAt.After(now) {
+			resetPassword.IsUsed = true
+			memoryPut(q, q.data.resetPasswords, id, resetPassword)
+			return resetPassword, nil
+		}
+	}
+	return ResetPassword{}, sql.ErrNoRows
+}
+
+func (q *memoryQueries) UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (UserTotp, error) {
+	defer q.lock()()
+
+	userTOTP, ok := q.data.userTOTPs[arg.Username]
+	if !ok || userTOTP.LastUsedStep >= arg.Step {
+		return UserTotp{}, sql.ErrNoRows
+	}
+	userTOTP.LastUsedStep = arg.Step
+	memoryPut(q, q.data.userTOTPs, userTOTP.Username, userTOTP)
+	return userTOTP, nil
+}
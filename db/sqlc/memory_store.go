@@ -0,0 +1,564 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Postgres error codes of the constraint violations emulated by MemoryStore
+const (
+	NotNullViolationCode    = "23502"
+	ForeignKeyViolationCode = "23503"
+	UniqueViolationCode     = "23505"
+	CheckViolationCode      = "23514"
+)
+
+// MemoryDriver is the DB_DRIVER value that selects MemoryStore instead of a database
+const MemoryDriver = "memory"
+
+// MemoryStore is a Store that keeps all data in process memory, nothing survives a restart.
+// It is meant for local development and tests that should not need a Postgres server.
+//
+// Every statement and every transaction holds the same mutex, a transaction from its first
+// statement until it commits or rolls back. That is stricter than the row locks SQLStore relies on,
+// so TransferTx can neither deadlock nor observe a transfer that is only half applied.
+// Constraint violations are returned as *pq.Error with the code and constraint name Postgres would report.
+type MemoryStore struct {
+	*memoryQueries
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() Store {
+	return &MemoryStore{
+		memoryQueries: &memoryQueries{
+			mu:   &sync.Mutex{},
+			data: newMemoryData(),
+		},
+	}
+}
+
+// memoryData holds the rows of every table, keyed by primary key
+type memoryData struct {
+	users          map[string]User
+	accounts       map[int64]Account
+	entries        map[int64]Entry
+	transfers      map[int64]Transfer
+	accountLimits  map[int64]AccountLimit
+	verifyEmails   map[int64]VerifyEmail
+	resetPasswords map[int64]ResetPassword
+	userTOTPs      map[string]UserTotp
+	recoveryCodes  map[int64]RecoveryCode
+	importJobs     map[int64]ImportJob
+	products       map[string]AccountProduct
+	interests      map[int64]AccountInterest
+	expenses       map[string]InterestExpenseAccount
+	productFees    map[productFeeKey]ProductFee
+	revenues       map[string]FeeRevenueAccount
+	snapshots      map[balanceSnapshotKey]BalanceSnapshot
+	beneficiaries  map[int64]Beneficiary
+	riskDecisions  map[int64]RiskDecision
+	auditEvents    map[int64]AuditEvent
+
+	//like Postgres sequences, ids handed out are never given back on rollback
+	sequences map[string]int64
+}
+
+func newMemoryData() *memoryData {
+	return &memoryData{
+		users:          make(map[string]User),
+		accounts:       make(map[int64]Account),
+		entries:        make(map[int64]Entry),
+		transfers:      make(map[int64]Transfer),
+		accountLimits:  make(map[int64]AccountLimit),
+		verifyEmails:   make(map[int64]VerifyEmail),
+		resetPasswords: make(map[int64]ResetPassword),
+		userTOTPs:      make(map[string]UserTotp),
+		recoveryCodes:  make(map[int64]RecoveryCode),
+		importJobs:     make(map[int64]ImportJob),
+		products:       make(map[string]AccountProduct),
+		interests:      make(map[int64]AccountInterest),
+		expenses:       make(map[string]InterestExpenseAccount),
+		productFees:    make(map[productFeeKey]ProductFee),
+		revenues:       make(map[string]FeeRevenueAccount),
+		snapshots:      make(map[balanceSnapshotKey]BalanceSnapshot),
+		beneficiaries:  make(map[int64]Beneficiary),
+		riskDecisions:  make(map[int64]RiskDecision),
+		auditEvents:    make(map[int64]AuditEvent),
+		sequences:      make(map[string]int64),
+	}
+}
+
+// productFeeKey is the primary key of product_fees
+type productFeeKey struct {
+	productCode string
+	kind        string
+}
+
+// balanceSnapshotKey is the primary key of balance_snapshots, time.Time is not a reliable map key
+type balanceSnapshotKey struct {
+	accountID int64
+	takenAt   int64
+}
+
+func newBalanceSnapshotKey(accountID int64, takenAt time.Time) balanceSnapshotKey {
+	return balanceSnapshotKey{accountID: accountID, takenAt: takenAt.UnixNano()}
+}
+
+// memoryQueries implements Querier on top of memoryData.
+// Outside of a transaction every call takes the store mutex itself, inside one the mutex is
+// already held by execTx and each change is recorded so that it can be undone on rollback.
+type memoryQueries struct {
+	mu   *sync.Mutex
+	data *memoryData
+
+	inTx   bool
+	txTime time.Time
+	undo   []func()
+}
+
+var _ Querier = (*memoryQueries)(nil)
+
+// lock takes the store mutex unless the caller runs inside a transaction, use it as defer q.lock()()
+func (q *memoryQueries) lock() func() {
+	if q.inTx {
+		return func() {}
+	}
+	q.mu.Lock()
+	return q.mu.Unlock
+}
+
+// now mirrors now() in Postgres, which returns the start time of the current transaction
+func (q *memoryQueries) now() time.Time {
+	if q.inTx {
+		return q.txTime
+	}
+	return time.Now().Truncate(time.Microsecond)
+}
+
+func (q *memoryQueries) nextID(table string) int64 {
+	q.data.sequences[table]++
+	return q.data.sequences[table]
+}
+
+func (q *memoryQueries) onRollback(fn func()) {
+	if q.inTx {
+		q.undo = append(q.undo, fn)
+	}
+}
+
+func (q *memoryQueries) rollback() {
+	for i := len(q.undo) - 1; i >= 0; i-- {
+		q.undo[i]()
+	}
+	q.undo = nil
+}
+
+// memoryPut stores row under key and records how to restore the previous row
+func memoryPut[K comparable, V any](q *memoryQueries, rows map[K]V, key K, row V) {
+	old, existed := rows[key]
+	q.onRollback(func() {
+		if existed {
+			rows[key] = old
+		} else {
+			delete(rows, key)
+		}
+	})
+	rows[key] = row
+}
+
+// memoryDelete removes the row under key and records how to bring it back
+func memoryDelete[K comparable, V any](q *memoryQueries, rows map[K]V, key K) {
+	old, existed := rows[key]
+	if !existed {
+		return
+	}
+	q.onRollback(func() {
+		rows[key] = old
+	})
+	delete(rows, key)
+}
+
+// memorySorted returns the rows matching keep ordered by id, like ORDER BY id
+func memorySorted[V any](rows map[int64]V, keep func(V) bool) []V {
+	ids := make([]int64, 0, len(rows))
+	for id, row := range rows {
+		if keep(row) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	result := make([]V, len(ids))
+	for i, id := range ids {
+		result[i] = rows[id]
+	}
+	return result
+}
+
+// memoryPage applies LIMIT and OFFSET, an empty page is returned as an empty slice like sqlc does
+func memoryPage[V any](rows []V, limit int32, offset int32) ([]V, error) {
+	if limit < 0 {
+		return nil, &pq.Error{Severity: "ERROR", Code: "2201W", Message: "LIMIT must not be negative"}
+	}
+	if offset < 0 {
+		return nil, &pq.Error{Severity: "ERROR", Code: "2201X", Message: "OFFSET must not be negative"}
+	}
+
+	items := []V{}
+	for i := int(offset); i < len(rows) && len(items) < int(limit); i++ {
+		items = append(items, rows[i])
+	}
+	return items, nil
+}
+
+// memoryDate drops the time of day like a Postgres date column
+func memoryDate(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// memoryILike matches s against a LIKE pattern ignoring case, with the default backslash escape
+func memoryILike(s string, pattern string) bool {
+	var expr strings.Builder
+	expr.WriteString("(?is)^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			expr.WriteString(".*")
+		case r == '_':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String()).MatchString(s)
+}
+
+// memoryJSONObject returns doc compacted like jsonb stores it, or false if it is not a JSON object
+func memoryJSONObject(doc json.RawMessage) (json.RawMessage, bool) {
+	var object map[string]interface{}
+	if err := json.Unmarshal(doc, &object); err != nil || object == nil {
+		return nil, false
+	}
+	return memoryJSON(doc)
+}
+
+// memoryJSON returns doc compacted like jsonb stores it, or false if it is not valid JSON
+func memoryJSON(doc json.RawMessage) (json.RawMessage, bool) {
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, doc); err != nil {
+		return nil, false
+	}
+	return compacted.Bytes(), true
+}
+
+// memoryJSONContains reports whether doc @> sub, both must be valid JSON
+func memoryJSONContains(doc json.RawMessage, sub json.RawMessage) bool {
+	var d, s interface{}
+	if json.Unmarshal(doc, &d) != nil || json.Unmarshal(sub, &s) != nil {
+		return false
+	}
+	return memoryContains(d, s)
+}
+
+func memoryContains(doc interface{}, sub interface{}) bool {
+	switch sub := sub.(type) {
+	case map[string]interface{}:
+		object, ok := doc.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range sub {
+			if _, ok := object[key]; !ok || !memoryContains(object[key], value) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		array, ok := doc.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, value := range sub {
+			found := false
+			for _, element := range array {
+				if memoryContains(element, value) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	default:
+		return doc == sub
+	}
+}
+
+func uniqueViolation(table string, constraint string) error {
+	return &pq.Error{
+		Severity:   "ERROR",
+		Code:       UniqueViolationCode,
+		Message:    fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		Table:      table,
+		Constraint: constraint,
+	}
+}
+
+func foreignKeyViolation(table string, constraint string) error {
+	return &pq.Error{
+		Severity:   "ERROR",
+		Code:       ForeignKeyViolationCode,
+		Message:    fmt.Sprintf("insert or update on table %q violates foreign key constraint %q", table, constraint),
+		Table:      table,
+		Constraint: constraint,
+	}
+}
+
+// invalidJSON is the error of storing a value that is not JSON in a jsonb column
+func invalidJSON() error {
+	return &pq.Error{Severity: "ERROR", Code: "22P02", Message: "invalid input syntax for type json"}
+}
+
+func notNullViolation(table string, column string) error {
+	return &pq.Error{
+		Severity: "ERROR",
+		Code:     NotNullViolationCode,
+		Message:  fmt.Sprintf("null value in column %q of relation %q violates not-null constraint", column, table),
+		Table:    table,
+		Column:   column,
+	}
+}
+
+func checkViolation(table string, constraint string) error {
+	return &pq.Error{
+		Severity:   "ERROR",
+		Code:       CheckViolationCode,
+		Message:    fmt.Sprintf("new row for relation %q violates check constraint %q", table, constraint),
+		Table:      table,
+		Constraint: constraint,
+	}
+}
+
+// foreignKeyRestrict is the error of deleting a row that is still referenced from table
+func foreignKeyRestrict(referenced string, table string, constraint string) error {
+	return &pq.Error{
+		Severity:   "ERROR",
+		Code:       ForeignKeyViolationCode,
+		Message:    fmt.Sprintf("update or delete on table %q violates foreign key constraint %q on table %q", referenced, constraint, table),
+		Table:      referenced,
+		Constraint: constraint,
+	}
+}
+
+// execTx runs fn while holding the store mutex and undoes all of its changes if it fails
+func (store *MemoryStore) execTx(ctx context.Context, fn func(Querier) error) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	q := &memoryQueries{
+		mu:     store.mu,
+		data:   store.data,
+		inTx:   true,
+		txTime: time.Now().Truncate(time.Microsecond),
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			q.rollback()
+		}
+	}()
+
+	if err = fn(q); err != nil {
+		return err
+	}
+	//a canceled context makes the commit fail in Postgres as well
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	committed = true
+	return nil
+}
+
+func (store *MemoryStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+		result, err = transferTx(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+func (store *MemoryStore) BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error) {
+	var result BatchTransferTxResult
+
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+		result, err = batchTransferTx(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+func (store *MemoryStore) PostingTx(ctx context.Context, arg PostingTxParams) (PostingTxResult, error) {
+	var result PostingTxResult
+
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+		result, err = postingTx(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+func (store *MemoryStore) ApproveRiskDecisionTx(ctx context.Context, arg ApproveRiskDecisionTxParams) (ApproveRiskDecisionTxResult, error) {
+	var result ApproveRiskDecisionTxResult
+
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+		result, err = approveRiskDecisionTx(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+func (store *MemoryStore) UpdateAccountStatusTx(ctx context.Context, arg UpdateAccountStatusTxParams) (UpdateAccountStatusTxResult, error) {
+	var result UpdateAccountStatusTxResult
+
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+		result, err = updateAccountStatusTx(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+func (store *MemoryStore) AdjustBalanceTx(ctx context.Context, arg AdjustBalanceTxParams) (AdjustBalanceTxResult, error) {
+	var result AdjustBalanceTxResult
+
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+		result, err = adjustBalanceTx(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+func (store *MemoryStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+		result, err = createUserTx(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+func (store *MemoryStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error) {
+	var result VerifyEmailTxResult
+
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+		result, err = verifyEmailTx(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+func (store *MemoryStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+		user, err = resetPasswordTx(ctx, q, arg)
+		return err
+	})
+
+	return user, err
+}
+
+func (store *MemoryStore) EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (UserTotp, error) {
+	var userTOTP UserTotp
+
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+		userTOTP, err = enableTOTPTx(ctx, q, arg)
+		return err
+	})
+
+	return userTOTP, err
+}
+
+func (store *MemoryStore) ImportTx(ctx context.Context, arg ImportTxParams) (ImportTxResult, error) {
+	var result ImportTxResult
+
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+		result, err = importTx(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+func (store *MemoryStore) AccrueInterestTx(ctx context.Context, arg AccrueInterestTxParams) (AccrueInterestTxResult, error) {
+	var result AccrueInterestTxResult
+
+	err := store.execTx(ctx, func(q Querier) error {
+		var err error
+		result, err = accrueInterestTx(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+// Ping always succeeds, there is nothing to reach
+func (store *MemoryStore) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// SchemaVersion reports the version this binary expects, the in-memory schema always matches it
+func (store *MemoryStore) SchemaVersion(ctx context.Context) (version int64, dirty bool, err error) {
+	return MigrationVersion, false, nil
+}
+
+// TxStats is always zero, transactions never conflict because they run one at a time
+func (store *MemoryStore) TxStats() TxStats {
+	return TxStats{}
+}
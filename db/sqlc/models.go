@@ -5,6 +5,7 @@
 package db
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -14,6 +15,73 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// active or frozen
+	Status string `json:"status"`
+	// the id clients see, the bigserial id is only used inside the bank
+	PublicID string `json:"public_id"`
+}
+
+type AccountInterest struct {
+	AccountID   int64  `json:"account_id"`
+	ProductCode string `json:"product_code"`
+	// interest accrued but not paid yet, in whole units of the currency
+	Accrued int64 `json:"accrued"`
+	// remainder of the accrued interest in 1/365000000 units, carried to the next day
+	AccruedFraction int64 `json:"accrued_fraction"`
+	// last day whose end of day balance earned interest
+	AccruedThrough time.Time `json:"accrued_through"`
+	// interest paid to the account so far
+	Paid      int64     `json:"paid"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type AccountLimit struct {
+	AccountID int64 `json:"account_id"`
+	// 0 means unlimited
+	MaxSingleTransfer int64 `json:"max_single_transfer"`
+	// 0 means unlimited
+	MaxDailyAmount int64 `json:"max_daily_amount"`
+	// 0 means unlimited
+	MaxDailyCount int64     `json:"max_daily_count"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type AccountProduct struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	// annual rate in millionths of the balance, 25000 is 2.5%
+	AnnualRatePpm int64     `json:"annual_rate_ppm"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type AuditEvent struct {
+	ID int64 `json:"id"`
+	// username of the staff member, or cli:<user> for the admin subcommand
+	Actor  string `json:"actor"`
+	Action string `json:"action"`
+	// the account acted on, 0 for other actions; no foreign key so the trail outlives the account
+	AccountID int64           `json:"account_id"`
+	Reason    string          `json:"reason"`
+	Details   json.RawMessage `json:"details"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type BalanceSnapshot struct {
+	AccountID int64     `json:"account_id"`
+	TakenAt   time.Time `json:"taken_at"`
+	// balance including every entry created before taken_at
+	Balance   int64     `json:"balance"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Beneficiary struct {
+	ID        int64  `json:"id"`
+	OwnerName string `json:"owner_name"`
+	Nickname  string `json:"nickname"`
+	AccountID int64  `json:"account_id"`
+	Currency  string `json:"currency"`
+	// large transfers wait for the cooling-off period counted from here
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Entry struct {
@@ -22,6 +90,91 @@ type Entry struct {
 	// can be negative or positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// copied from the transfer, or what the bank charged or paid
+	Description string `json:"description"`
+	PublicID    string `json:"public_id"`
+}
+
+type FeeRevenueAccount struct {
+	Currency  string    `json:"currency"`
+	AccountID int64     `json:"account_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ImportJob struct {
+	ID int64 `json:"id"`
+	// users, accounts or transfers
+	Kind string `json:"kind"`
+	// sha256 of the file, importing the same file again resumes the job
+	FileHash  string `json:"file_hash"`
+	TotalRows int64  `json:"total_rows"`
+	// data rows committed so far, in file order
+	CommittedRows int64 `json:"committed_rows"`
+	// running or completed
+	Status    string    `json:"status"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type InterestExpenseAccount struct {
+	Currency  string    `json:"currency"`
+	AccountID int64     `json:"account_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ProductFee struct {
+	ProductCode string `json:"product_code"`
+	// maintenance or transfer
+	Kind       string `json:"kind"`
+	FlatAmount int64  `json:"flat_amount"`
+	// millionths of the transfer amount, or of the month end balance for maintenance
+	RatePpm int64 `json:"rate_ppm"`
+	// 0 means no cap
+	MaxAmount int64     `json:"max_amount"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type RecoveryCode struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// sha256 of the recovery code
+	CodeHash  string    `json:"code_hash"`
+	IsUsed    bool      `json:"is_used"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ResetPassword struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// sha256 of the token sent by email, the token itself is never stored
+	TokenHash string    `json:"token_hash"`
+	IsUsed    bool      `json:"is_used"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+type RiskDecision struct {
+	ID            int64           `json:"id"`
+	Username      string          `json:"username"`
+	FromAccountID int64           `json:"from_account_id"`
+	ToAccountID   int64           `json:"to_account_id"`
+	Amount        int64           `json:"amount"`
+	Currency      string          `json:"currency"`
+	Description   string          `json:"description"`
+	Reference     string          `json:"reference"`
+	Metadata      json.RawMessage `json:"metadata"`
+	// review or block, transfers that are allowed are not recorded
+	Decision string `json:"decision"`
+	// the rules that matched and why
+	Hits json.RawMessage `json:"hits"`
+	// pending until reviewed, then approved or rejected; blocked for block decisions
+	Status string `json:"status"`
+	// the transfer made once the decision was approved, 0 before
+	TransferID int64     `json:"transfer_id"`
+	ReviewedBy string    `json:"reviewed_by"`
+	ReviewedAt time.Time `json:"reviewed_at"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 type Transfer struct {
@@ -29,8 +182,13 @@ type Transfer struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
 	// must be positive
-	Amount    int64     `json:"amount"`
-	CreatedAt time.Time `json:"created_at"`
+	Amount      int64     `json:"amount"`
+	CreatedAt   time.Time `json:"created_at"`
+	Description string    `json:"description"`
+	// set by the client, e.g. an invoice number
+	Reference string          `json:"reference"`
+	Metadata  json.RawMessage `json:"metadata"`
+	PublicID  string          `json:"public_id"`
 }
 
 type User struct {
@@ -40,4 +198,26 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	// depositor, banker or admin
+	Role            string `json:"role"`
+	IsEmailVerified bool   `json:"is_email_verified"`
+}
+
+type UserTotp struct {
+	Username  string `json:"username"`
+	Secret    string `json:"secret"`
+	IsEnabled bool   `json:"is_enabled"`
+	// codes of this time step or earlier are refused so that they cannot be replayed
+	LastUsedStep int64     `json:"last_used_step"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type VerifyEmail struct {
+	ID         int64     `json:"id"`
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
+	SecretCode string    `json:"secret_code"`
+	IsUsed     bool      `json:"is_used"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiredAt  time.Time `json:"expired_at"`
 }
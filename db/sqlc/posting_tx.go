@@ -0,0 +1,259 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+)
+
+// ErrInvalidPosting is returned by PostingTx when its legs do not describe a valid posting
+var ErrInvalidPosting = errors.New("invalid posting")
+
+// PostingLeg moves Amount into an account, a negative Amount takes money out of it
+type PostingLeg struct {
+	AccountID int64 `json:"account_id"`
+	Amount    int64 `json:"amount"`
+}
+
+// PostingTxParams contains the legs of a multi-leg posting: one source paying several destinations,
+// or several sources paying one destination. TransferTx is the posting with one leg on each side.
+type PostingTxParams struct {
+	Legs []PostingLeg `json:"legs"`
+	//Description, Reference and Metadata are given to every transfer, Description to every entry as well
+	Description string          `json:"description"`
+	Reference   string          `json:"reference"`
+	Metadata    json.RawMessage `json:"metadata"`
+	//SecondFactor is used up once for the whole posting, see TransferTxParams
+	SecondFactor *UseTOTPStepParams `json:"-"`
+}
+
+// PostingTxResult is the result of the posting transaction.
+// Accounts and Entries are in the order of the legs, there is one transfer per leg on the side with several legs.
+type PostingTxResult struct {
+	Transfers []Transfer `json:"transfers"`
+	Accounts  []Account  `json:"accounts"`
+	Entries   []Entry    `json:"entries"`
+	//Fees are charged to the sources on top of their legs, Accounts includes them
+	Fees []FeeLine `json:"fees,omitempty"`
+	//Retries is the number of times the transaction had to be run again
+	Retries int `json:"-"`
+}
+
+// PostingTx applies every leg of a posting in a single transaction, either all of them or none.
+// The legs must sum to zero in every currency and no source may end up below zero once its fees are charged.
+func (store *SQLStore) PostingTx(ctx context.Context, arg PostingTxParams) (PostingTxResult, error) {
+	var result PostingTxResult
+
+	retries, err := store.execTxRetry(ctx, func(q *Queries) error {
+		var err error
+		result, err = postingTx(ctx, q, arg)
+		return err
+	})
+
+	result.Retries = retries
+	return result, err
+}
+
+func postingTx(ctx context.Context, q Querier, arg PostingTxParams) (PostingTxResult, error) {
+	var result PostingTxResult
+
+	hub, err := postingHub(arg.Legs)
+	if err != nil {
+		return result, err
+	}
+
+	if err := useSecondFactor(ctx, q, arg.SecondFactor); err != nil {
+		return result, err
+	}
+
+	//each transfer is charged like a single one, its fees are quoted before anything is written
+	quotes := make([][]FeeQuote, len(arg.Legs))
+	revenueIDs := make([]int64, len(arg.Legs))
+	lockIDs := []int64{}
+	for i, leg := range arg.Legs {
+		lockIDs = append(lockIDs, leg.AccountID)
+		if leg.Amount > 0 {
+			continue
+		}
+		amounts := []int64{-leg.Amount}
+		if i == hub {
+			amounts = nil
+			for j, other := range arg.Legs {
+				if j != hub {
+					amounts = append(amounts, other.Amount)
+				}
+			}
+		}
+		for _, amount := range amounts {
+			transferQuotes, err := QuoteTransferFees(ctx, q, leg.AccountID, amount)
+			if err != nil {
+				return result, err
+			}
+			quotes[i] = append(quotes[i], transferQuotes...)
+		}
+		revenueIDs[i], err = transferFeeRevenueAccountID(ctx, q, leg.AccountID, quotes[i])
+		if err != nil {
+			return result, err
+		}
+	}
+
+	//when fees are due the fee revenue accounts are locked together with the legs in ascending id order
+	feesDue := false
+	for _, id := range revenueIDs {
+		feesDue = feesDue || id != 0
+	}
+	if feesDue {
+		if _, err := lockAccounts(ctx, q, append(lockIDs, revenueIDs...)...); err != nil {
+			return result, err
+		}
+	}
+
+	//the side with a single leg is the counterparty of every transfer
+	for i, leg := range arg.Legs {
+		if i == hub {
+			continue
+		}
+		transfer := CreateTransferParams{
+			FromAccountID: arg.Legs[hub].AccountID,
+			ToAccountID:   leg.AccountID,
+			Amount:        leg.Amount,
+			Description:   arg.Description,
+			Reference:     arg.Reference,
+			Metadata:      transferMetadata(arg.Metadata),
+		}
+		if leg.Amount < 0 {
+			transfer.FromAccountID, transfer.ToAccountID, transfer.Amount = leg.AccountID, arg.Legs[hub].AccountID, -leg.Amount
+		}
+		created, err := q.CreateTransfer(ctx, transfer)
+		if err != nil {
+			return result, err
+		}
+		result.Transfers = append(result.Transfers, created)
+	}
+
+	result.Entries = make([]Entry, len(arg.Legs))
+	for i, leg := range arg.Legs {
+		result.Entries[i], err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID:   leg.AccountID,
+			Amount:      leg.Amount,
+			Description: arg.Description,
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	//like addMoney the balances are updated in ascending account id, so postings and transfers never deadlock
+	order := make([]int, len(arg.Legs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return arg.Legs[order[i]].AccountID < arg.Legs[order[j]].AccountID })
+
+	result.Accounts = make([]Account, len(arg.Legs))
+	for _, i := range order {
+		result.Accounts[i], err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
+			ID:     arg.Legs[i].AccountID,
+			Amount: arg.Legs[i].Amount,
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if err := checkAccountsActive(result.Accounts...); err != nil {
+		return result, err
+	}
+
+	sums := make(map[string]int64)
+	for i, account := range result.Accounts {
+		sum, ok := util.AddAmount(sums[account.Currency], arg.Legs[i].Amount)
+		if !ok {
+			return result, fmt.Errorf("legs in %s add up to more than an amount can hold: %w", account.Currency, ErrInvalidPosting)
+		}
+		sums[account.Currency] = sum
+	}
+	for currency, sum := range sums {
+		if sum != 0 {
+			return result, fmt.Errorf("legs in %s sum to %d: %w", currency, sum, ErrCurrencyMismatch)
+		}
+	}
+
+	for i, leg := range arg.Legs {
+		if leg.Amount > 0 {
+			continue
+		}
+		if err := checkTransferLimits(ctx, q, result.Accounts[i], -leg.Amount); err != nil {
+			return result, err
+		}
+	}
+
+	//the fees of a source are taken in one go, its balance must pay them as well as its leg
+	for i, leg := range arg.Legs {
+		if leg.Amount > 0 {
+			continue
+		}
+		var fees []FeeLine
+		fees, result.Accounts[i], err = chargeFees(ctx, q, result.Accounts[i], revenueIDs[i], quotes[i])
+		if err != nil {
+			return result, err
+		}
+		result.Fees = append(result.Fees, fees...)
+
+		if result.Accounts[i].Balance < 0 {
+			return result, fmt.Errorf("account [%s] is %d short of the amount %d and its fees: %w", result.Accounts[i].PublicID, -result.Accounts[i].Balance, -leg.Amount, ErrInsufficientFunds)
+		}
+	}
+
+	return result, nil
+}
+
+// postingHub checks the shape of the legs and returns the index of the single leg on its side
+func postingHub(legs []PostingLeg) (int, error) {
+	if len(legs) < 2 {
+		return 0, fmt.Errorf("a posting needs at least 2 legs: %w", ErrInvalidPosting)
+	}
+
+	seen := make(map[int64]bool)
+	var sources, destinations []int
+	var total int64
+	for i, leg := range legs {
+		//a total that wraps around could balance legs creating money, and a source of MinInt64 has no opposite
+		var ok bool
+		total, ok = util.AddAmount(total, leg.Amount)
+		if !ok || leg.Amount == math.MinInt64 {
+			return 0, fmt.Errorf("leg %d takes the total beyond what an amount can hold: %w", i, ErrInvalidPosting)
+		}
+		if seen[leg.AccountID] {
+			return 0, fmt.Errorf("account [%d] appears in more than one leg: %w", leg.AccountID, ErrInvalidPosting)
+		}
+		seen[leg.AccountID] = true
+
+		switch {
+		case leg.Amount < 0:
+			sources = append(sources, i)
+		case leg.Amount > 0:
+			destinations = append(destinations, i)
+		default:
+			return 0, fmt.Errorf("leg %d has no amount: %w", i, ErrInvalidPosting)
+		}
+	}
+
+	//whatever the currencies, legs that do not even sum to zero overall are rejected before anything is locked
+	if total != 0 {
+		return 0, fmt.Errorf("legs sum to %d: %w", total, ErrInvalidPosting)
+	}
+
+	switch {
+	case len(sources) == 1:
+		return sources[0], nil
+	case len(destinations) == 1:
+		return destinations[0], nil
+	}
+	return 0, fmt.Errorf("%d sources and %d destinations, one side must have a single leg: %w", len(sources), len(destinations), ErrInvalidPosting)
+}
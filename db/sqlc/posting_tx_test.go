@@ -0,0 +1,42 @@
+package db
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostingHub(t *testing.T) {
+	testCases := []struct {
+		name    string
+		legs    []PostingLeg
+		hub     int
+		invalid bool
+	}{
+		{"OneToOne", []PostingLeg{{1, -10}, {2, 10}}, 0, false},
+		{"OneToMany", []PostingLeg{{2, 5}, {1, -10}, {3, 5}}, 1, false},
+		{"ManyToOne", []PostingLeg{{2, -5}, {3, -5}, {1, 10}}, 2, false},
+		{"SingleLeg", []PostingLeg{{1, 0}}, 0, true},
+		{"ZeroAmount", []PostingLeg{{1, -10}, {2, 10}, {3, 0}}, 0, true},
+		{"SameAccount", []PostingLeg{{1, -10}, {1, 10}}, 0, true},
+		{"Unbalanced", []PostingLeg{{1, -10}, {2, 5}}, 0, true},
+		{"ManyToMany", []PostingLeg{{1, -10}, {2, -10}, {3, 10}, {4, 10}}, 0, true},
+		{"Overflow", []PostingLeg{{1, -5}, {2, math.MaxInt64}, {3, math.MaxInt64}, {4, 7}}, 0, true},
+		{"MinAmount", []PostingLeg{{1, math.MinInt64}, {2, math.MaxInt64}, {3, 1}}, 0, true},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			hub, err := postingHub(tc.legs)
+			if tc.invalid {
+				require.ErrorIs(t, err, ErrInvalidPosting)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.hub, hub)
+		})
+	}
+}
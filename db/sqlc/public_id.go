@@ -0,0 +1,63 @@
+package db
+
+import (
+	"crypto/rand"
+	"strings"
+	"time"
+)
+
+// Prefixes of the public ids, they tell clients what kind of row an id names
+const (
+	AccountIDPrefix  = "acc_"
+	EntryIDPrefix    = "ent_"
+	TransferIDPrefix = "tr_"
+)
+
+// crockford is the base32 alphabet of ULIDs, without I, L, O and U so that ids read back unambiguously
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// publicIDLength is the length of a public id without its prefix
+const publicIDLength = 26
+
+// NewPublicID returns a prefixed ULID: 48 bits of milliseconds then 80 random bits, in Crockford base32.
+// The postgres store makes the same ids with the new_public_id function, the memory store uses this one.
+func NewPublicID(prefix string, now time.Time) string {
+	var random [10]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		panic(err)
+	}
+
+	id := make([]byte, publicIDLength)
+	ms := uint64(now.UnixMilli())
+	for i := 9; i >= 0; i-- {
+		id[i] = crockford[ms%32]
+		ms /= 32
+	}
+
+	//two groups of 40 random bits, 8 characters each
+	for group := 0; group < 2; group++ {
+		var bits uint64
+		for _, b := range random[group*5 : group*5+5] {
+			bits = bits<<8 | uint64(b)
+		}
+		for i := 7; i >= 0; i-- {
+			id[10+group*8+i] = crockford[bits%32]
+			bits /= 32
+		}
+	}
+
+	return prefix + string(id)
+}
+
+// IsPublicID reports whether id is a public id with the prefix
+func IsPublicID(id string, prefix string) bool {
+	if !strings.HasPrefix(id, prefix) || len(id) != len(prefix)+publicIDLength {
+		return false
+	}
+	for _, c := range id[len(prefix):] {
+		if !strings.ContainsRune(crockford, c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPublicID(t *testing.T) {
+	now := time.Now()
+	id1 := NewPublicID(AccountIDPrefix, now)
+	id2 := NewPublicID(AccountIDPrefix, now)
+	require.True(t, IsPublicID(id1, AccountIDPrefix))
+	require.NotEqual(t, id1, id2)
+
+	//ids made later sort after earlier ones
+	later := NewPublicID(AccountIDPrefix, now.Add(time.Millisecond))
+	require.Less(t, id1[:14], later[:14])
+
+	require.False(t, IsPublicID(id1, TransferIDPrefix))
+	require.False(t, IsPublicID(id1[:len(id1)-1], AccountIDPrefix))
+	require.False(t, IsPublicID(AccountIDPrefix+strings.Repeat("U", 26), AccountIDPrefix))
+	require.False(t, IsPublicID("42", AccountIDPrefix))
+}
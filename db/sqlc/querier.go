@@ -10,20 +10,86 @@ import (
 
 type Querier interface {
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	CountTransfersBetween(ctx context.Context, arg CountTransfersBetweenParams) (int64, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAccountProduct(ctx context.Context, arg CreateAccountProductParams) (AccountProduct, error)
+	CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error)
+	CreateBalanceSnapshot(ctx context.Context, arg CreateBalanceSnapshotParams) (BalanceSnapshot, error)
+	CreateBeneficiary(ctx context.Context, arg CreateBeneficiaryParams) (Beneficiary, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateImportJob(ctx context.Context, arg CreateImportJobParams) (ImportJob, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error)
+	CreateResetPassword(ctx context.Context, arg CreateResetPasswordParams) (ResetPassword, error)
+	CreateRiskDecision(ctx context.Context, arg CreateRiskDecisionParams) (RiskDecision, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteBeneficiary(ctx context.Context, id int64) error
+	DeleteProductFee(ctx context.Context, arg DeleteProductFeeParams) error
+	DeleteRecoveryCodes(ctx context.Context, username string) error
+	EnableUserTOTP(ctx context.Context, username string) (UserTotp, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	GetAccountByPublicID(ctx context.Context, publicID string) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetAccountInterest(ctx context.Context, accountID int64) (AccountInterest, error)
+	GetAccountInterestForUpdate(ctx context.Context, accountID int64) (AccountInterest, error)
+	GetAccountLimit(ctx context.Context, accountID int64) (AccountLimit, error)
+	GetAccountProduct(ctx context.Context, code string) (AccountProduct, error)
+	GetBalanceBefore(ctx context.Context, arg GetBalanceBeforeParams) (int64, error)
+	GetBeneficiary(ctx context.Context, id int64) (Beneficiary, error)
+	GetBeneficiaryByAccount(ctx context.Context, arg GetBeneficiaryByAccountParams) (Beneficiary, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetEntryByPublicID(ctx context.Context, publicID string) (Entry, error)
+	GetFeeRevenueAccount(ctx context.Context, currency string) (FeeRevenueAccount, error)
+	GetImportJobByHash(ctx context.Context, arg GetImportJobByHashParams) (ImportJob, error)
+	GetImportJobForUpdate(ctx context.Context, id int64) (ImportJob, error)
+	GetInterestExpenseAccount(ctx context.Context, currency string) (InterestExpenseAccount, error)
+	GetLatestBalanceSnapshot(ctx context.Context, arg GetLatestBalanceSnapshotParams) (BalanceSnapshot, error)
+	GetOutgoingTransferTotals(ctx context.Context, arg GetOutgoingTransferTotalsParams) (GetOutgoingTransferTotalsRow, error)
+	GetResetPassword(ctx context.Context, tokenHash string) (ResetPassword, error)
+	GetRiskDecision(ctx context.Context, id int64) (RiskDecision, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferByPublicID(ctx context.Context, publicID string) (Transfer, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserTOTP(ctx context.Context, username string) (UserTotp, error)
+	InvalidateResetPasswords(ctx context.Context, username string) error
+	ListAccountFees(ctx context.Context, accountID int64) ([]ProductFee, error)
+	ListAccountInterestsDue(ctx context.Context, arg ListAccountInterestsDueParams) ([]AccountInterest, error)
+	ListAccountProducts(ctx context.Context) ([]AccountProduct, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListAccountsByOwner(ctx context.Context, arg ListAccountsByOwnerParams) ([]Account, error)
+	ListAccountsDueSnapshot(ctx context.Context, arg ListAccountsDueSnapshotParams) ([]Account, error)
+	ListAuditEventsByAccount(ctx context.Context, arg ListAuditEventsByAccountParams) ([]AuditEvent, error)
+	ListBeneficiaries(ctx context.Context, arg ListBeneficiariesParams) ([]Beneficiary, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListProductFees(ctx context.Context, productCode string) ([]ProductFee, error)
+	ListRiskDecisionsByStatus(ctx context.Context, arg ListRiskDecisionsByStatusParams) ([]RiskDecision, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	ListUnreconciledAccounts(ctx context.Context) ([]ListUnreconciledAccountsRow, error)
+	ReviewRiskDecision(ctx context.Context, arg ReviewRiskDecisionParams) (RiskDecision, error)
+	SetRiskDecisionTransfer(ctx context.Context, arg SetRiskDecisionTransferParams) (RiskDecision, error)
+	SumEntriesBefore(ctx context.Context, arg SumEntriesBeforeParams) (int64, error)
+	SumEntriesBetween(ctx context.Context, arg SumEntriesBetweenParams) (int64, error)
+	SumEntriesSince(ctx context.Context, arg SumEntriesSinceParams) (int64, error)
 	UpdateAccountBalance(ctx context.Context, arg UpdateAccountBalanceParams) (Account, error)
+	UpdateAccountInterest(ctx context.Context, arg UpdateAccountInterestParams) (AccountInterest, error)
+	UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error)
+	UpdateBeneficiaryNickname(ctx context.Context, arg UpdateBeneficiaryNicknameParams) (Beneficiary, error)
+	UpdateImportJobProgress(ctx context.Context, arg UpdateImportJobProgressParams) (ImportJob, error)
+	UpdateUserEmailVerified(ctx context.Context, arg UpdateUserEmailVerifiedParams) (User, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UpdateVerifyEmail(ctx context.Context, arg UpdateVerifyEmailParams) (VerifyEmail, error)
+	UpsertAccountInterest(ctx context.Context, arg UpsertAccountInterestParams) (AccountInterest, error)
+	UpsertAccountLimit(ctx context.Context, arg UpsertAccountLimitParams) (AccountLimit, error)
+	UpsertFeeRevenueAccount(ctx context.Context, arg UpsertFeeRevenueAccountParams) (FeeRevenueAccount, error)
+	UpsertInterestExpenseAccount(ctx context.Context, arg UpsertInterestExpenseAccountParams) (InterestExpenseAccount, error)
+	UpsertProductFee(ctx context.Context, arg UpsertProductFeeParams) (ProductFee, error)
+	UpsertUserTOTP(ctx context.Context, arg UpsertUserTOTPParams) (UserTotp, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error)
+	UseResetPassword(ctx context.Context, tokenHash string) (ResetPassword, error)
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (UserTotp, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomResetPassword(t *testing.T, username string, expiredAt time.Time) ResetPassword {
+	arg := CreateResetPasswordParams{
+		Username:  username,
+		TokenHash: util.HashSecret(util.RandomString(32)),
+		ExpiredAt: expiredAt,
+	}
+
+	resetPassword, err := testQueries.CreateResetPassword(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, resetPassword.Username)
+	require.Equal(t, arg.TokenHash, resetPassword.TokenHash)
+	require.False(t, resetPassword.IsUsed)
+
+	return resetPassword
+}
+
+func TestUpdateUserPassword(t *testing.T) {
+	user1 := createRandomUser(t)
+	changedAt := time.Now().Truncate(time.Microsecond)
+
+	user2, err := testQueries.UpdateUserPassword(context.Background(), UpdateUserPasswordParams{
+		Username:          user1.Username,
+		HashedPassword:    "new-secret",
+		PasswordChangedAt: changedAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "new-secret", user2.HashedPassword)
+	require.WithinDuration(t, changedAt, user2.PasswordChangedAt, time.Microsecond)
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	resetPassword1 := createRandomResetPassword(t, user.Username, time.Now().Add(time.Hour))
+	resetPassword2 := createRandomResetPassword(t, user.Username, time.Now().Add(time.Hour))
+
+	arg := ResetPasswordTxParams{
+		TokenHash:         resetPassword1.TokenHash,
+		HashedPassword:    "new-secret",
+		PasswordChangedAt: time.Now(),
+	}
+
+	updated, err := store.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, updated.Username)
+	require.Equal(t, "new-secret", updated.HashedPassword)
+
+	//tokens are single use
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	//and the other outstanding tokens were invalidated with the reset
+	arg.TokenHash = resetPassword2.TokenHash
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestResetPasswordTxExpired(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	resetPassword := createRandomResetPassword(t, user.Username, time.Now().Add(-time.Minute))
+
+	_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:         resetPassword.TokenHash,
+		HashedPassword:    "new-secret",
+		PasswordChangedAt: time.Now(),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// Constants for the decisions of the fraud screening and the statuses they are recorded with
+const (
+	RiskDecisionAllow  = "allow"
+	RiskDecisionReview = "review"
+	RiskDecisionBlock  = "block"
+
+	RiskStatusPending  = "pending"
+	RiskStatusApproved = "approved"
+	RiskStatusRejected = "rejected"
+	RiskStatusBlocked  = "blocked"
+	//RiskStatusRefused is a transfer held for review as part of an atomic batch or a split, which was refused
+	//as a whole. Approving it alone would make only part of the request, so it can't be approved.
+	RiskStatusRefused = "refused"
+)
+
+// ErrSelfApproval is returned by ApproveRiskDecisionTx when the reviewer is the user who made the transfer
+var ErrSelfApproval = errors.New("a transfer held for review can't be approved by the user who made it")
+
+// ApproveRiskDecisionTxParams contains the input parameters of the approve risk decision transaction
+type ApproveRiskDecisionTxParams struct {
+	ID         int64  `json:"id"`
+	ReviewedBy string `json:"reviewed_by"`
+}
+
+// ApproveRiskDecisionTxResult is the approved decision together with the transfer it was held for
+type ApproveRiskDecisionTxResult struct {
+	Decision RiskDecision     `json:"decision"`
+	Transfer TransferTxResult `json:"transfer"`
+	//Retries is the number of times the transaction had to be run again
+	Retries int `json:"-"`
+}
+
+// ApproveRiskDecisionTx makes the transfer a pending decision held back and marks the decision approved,
+// both or neither. It returns sql.ErrNoRows when the decision does not exist or was already reviewed,
+// and ErrSelfApproval when the reviewer made the transfer.
+func (store *SQLStore) ApproveRiskDecisionTx(ctx context.Context, arg ApproveRiskDecisionTxParams) (ApproveRiskDecisionTxResult, error) {
+	var result ApproveRiskDecisionTxResult
+
+	retries, err := store.execTxRetry(ctx, func(q *Queries) error {
+		var err error
+		result, err = approveRiskDecisionTx(ctx, q, arg)
+		return err
+	})
+
+	result.Retries = retries
+	return result, err
+}
+
+func approveRiskDecisionTx(ctx context.Context, q Querier, arg ApproveRiskDecisionTxParams) (ApproveRiskDecisionTxResult, error) {
+	var result ApproveRiskDecisionTxResult
+
+	//only a pending decision is updated, so two reviewers can't both send the transfer
+	decision, err := q.ReviewRiskDecision(ctx, ReviewRiskDecisionParams{
+		ID:         arg.ID,
+		Status:     RiskStatusApproved,
+		ReviewedBy: arg.ReviewedBy,
+	})
+	if err != nil {
+		return result, err
+	}
+	//checked on the updated row, the transaction rolls back and the decision stays pending
+	if decision.Username == arg.ReviewedBy {
+		return result, ErrSelfApproval
+	}
+
+	//balances, limits and frozen accounts are checked again, they may have changed while the transfer waited
+	result.Transfer, err = transferTx(ctx, q, TransferTxParams{
+		FromAccountID: decision.FromAccountID,
+		ToAccountID:   decision.ToAccountID,
+		Amount:        decision.Amount,
+		Description:   decision.Description,
+		Reference:     decision.Reference,
+		Metadata:      decision.Metadata,
+		RequireFunds:  true,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.Decision, err = q.SetRiskDecisionTransfer(ctx, SetRiskDecisionTransferParams{
+		ID:         decision.ID,
+		TransferID: result.Transfer.Transfer.ID,
+	})
+	return result, err
+}
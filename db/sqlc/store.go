@@ -3,51 +3,70 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
 )
 
 //So in order to use a mock DB in the API server tests, we have to replace that store object with an interface.
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error)
+	PostingTx(ctx context.Context, arg PostingTxParams) (PostingTxResult, error)
+	ApproveRiskDecisionTx(ctx context.Context, arg ApproveRiskDecisionTxParams) (ApproveRiskDecisionTxResult, error)
+	UpdateAccountStatusTx(ctx context.Context, arg UpdateAccountStatusTxParams) (UpdateAccountStatusTxResult, error)
+	AdjustBalanceTx(ctx context.Context, arg AdjustBalanceTxParams) (AdjustBalanceTxResult, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error)
+	EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (UserTotp, error)
+	ImportTx(ctx context.Context, arg ImportTxParams) (ImportTxResult, error)
+	AccrueInterestTx(ctx context.Context, arg AccrueInterestTxParams) (AccrueInterestTxResult, error)
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (version int64, dirty bool, err error)
+	TxStats() TxStats
 }
 
 type SQLStore struct {
 	db *sql.DB
 	*Queries
+	retryPolicy RetryPolicy
+	stats       TxStats
 }
 
 func NewStore(db *sql.DB) Store {
+	return NewStoreWithRetry(db, DefaultRetryPolicy)
+}
+
+//NewStoreWithRetry creates a store whose transactions are retried according to policy
+func NewStoreWithRetry(db *sql.DB, policy RetryPolicy) Store {
 	return &SQLStore{
-		db:      db,
-		Queries: New(db),
+		db:          db,
+		Queries:     New(db),
+		retryPolicy: policy,
 	}
 }
 
+//execTx executes fn within a database transaction, retrying it on serialization failures and deadlocks
 func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
-
-	tx, err := store.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-
-	q := New(tx)
-	err = fn(q)
-	if err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
-		}
-		return err
-	}
-
-	return tx.Commit()
+	_, err := store.execTxRetry(ctx, fn)
+	return err
 }
 
 type TransferTxParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Description   string `json:"description"`
+	Reference     string `json:"reference"`
+	//Metadata must be a JSON object, it is stored as {} when empty
+	Metadata json.RawMessage `json:"metadata"`
+	//SecondFactor is the TOTP step a large transfer was authorised with, it is only used up when the transfer commits
+	SecondFactor *UseTOTPStepParams `json:"-"`
+	//RequireFunds refuses the transfer with ErrInsufficientFunds when the source can't pay the amount and its fees
+	RequireFunds bool `json:"-"`
 }
 
 type TransferTxResult struct {
@@ -56,6 +75,10 @@ type TransferTxResult struct {
 	ToAccount   Account  `json:"to_account"`
 	FromEntry   Entry    `json:"from_entry"`
 	ToEntry     Entry    `json:"to_entry"`
+	//Fees are charged to the source account on top of the amount, FromAccount includes them
+	Fees []FeeLine `json:"fees,omitempty"`
+	//Retries is the number of times the transaction had to be run again
+	Retries int `json:"-"`
 }
 
 //Declare txkey here
@@ -67,96 +90,192 @@ var txKey = struct{}{}
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
-	err := store.execTx(ctx, func(q *Queries) error {
+	retries, err := store.execTxRetry(ctx, func(q *Queries) error {
 		var err error
+		result, err = transferTx(ctx, q, arg)
+		return err
+	})
 
-		//We declare the txName here and use the txKey to get the context value
-		txName := ctx.Value(txKey)
+	result.Retries = retries
+	return result, err
+}
 
-		log.Println(txName, "create transfer")
+//transferTx holds the statements of TransferTx so that every Store runs the same ones inside its own transaction
+func transferTx(ctx context.Context, q Querier, arg TransferTxParams) (result TransferTxResult, err error) {
+	//We declare the txName here and use the txKey to get the context value
+	txName := ctx.Value(txKey)
 
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: arg.FromAccountID,
-			ToAccountID:   arg.ToAccountID,
-			Amount:        arg.Amount,
-		})
+	if err = useSecondFactor(ctx, q, arg.SecondFactor); err != nil {
+		return
+	}
+
+	//Fees are quoted before anything is written, when one is due the fee revenue account is locked
+	//together with the two accounts in ascending id order, never after them
+	log.Println(txName, "quote fees")
+	quotes, err := QuoteTransferFees(ctx, q, arg.FromAccountID, arg.Amount)
+	if err != nil {
+		return
+	}
+	revenueID, err := transferFeeRevenueAccountID(ctx, q, arg.FromAccountID, quotes)
+	if err != nil {
+		return
+	}
+	if revenueID != 0 {
+		if _, err = lockAccounts(ctx, q, arg.FromAccountID, arg.ToAccountID, revenueID); err != nil {
+			return
+		}
+	}
+
+	log.Println(txName, "create transfer")
+
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		Description:   arg.Description,
+		Reference:     arg.Reference,
+		Metadata:      transferMetadata(arg.Metadata),
+	})
+	if err != nil {
+		return
+	}
+
+	//the entries carry the description so that statements read without looking up the transfer
+	log.Println(txName, "create entry 1")
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:   arg.FromAccountID,
+		Amount:      -arg.Amount,
+		Description: arg.Description,
+	})
+	if err != nil {
+		return
+	}
+
+	log.Println(txName, "create entry 2")
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:   arg.ToAccountID,
+		Amount:      arg.Amount,
+		Description: arg.Description,
+	})
+	if err != nil {
+		return
+	}
+
+	/*
+		// move money out of account1
+		fmt.Println(txName, "get account 1")
+		account1, err := q.GetAccountForUpdate(ctx, arg.FromAccountID)
 		if err != nil {
 			return err
 		}
 
-		log.Println(txName, "create entry 1")
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
+		fmt.Println(txName, "update account 1")
+		result.FromAccount, err = q.UpdateAccount(ctx, UpdateAccountParams{
+			ID:      arg.FromAccountID,
+			Balance: account1.Balance - arg.Amount,
 		})
 		if err != nil {
 			return err
 		}
 
-		log.Println(txName, "create entry 2")
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
+		// move money into account2
+		log.Println(txName, "get account 2")
+		account2, err := q.GetAccountForUpdate(ctx, arg.ToAccountID)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(txName, "update account 2")
+		result.ToAccount, err = q.UpdateAccount(ctx, UpdateAccountParams{
+			ID:      arg.ToAccountID,
+			Balance: account2.Balance + arg.Amount,
 		})
 		if err != nil {
 			return err
 		}
+	*/
 
-		/*
-			// move money out of account1
-			fmt.Println(txName, "get account 1")
-			account1, err := q.GetAccountForUpdate(ctx, arg.FromAccountID)
-			if err != nil {
-				return err
-			}
-
-			fmt.Println(txName, "update account 1")
-			result.FromAccount, err = q.UpdateAccount(ctx, UpdateAccountParams{
-				ID:      arg.FromAccountID,
-				Balance: account1.Balance - arg.Amount,
-			})
-			if err != nil {
-				return err
-			}
-
-			// move money into account2
-			log.Println(txName, "get account 2")
-			account2, err := q.GetAccountForUpdate(ctx, arg.ToAccountID)
-			if err != nil {
-				return err
-			}
-
-			fmt.Println(txName, "update account 2")
-			result.ToAccount, err = q.UpdateAccount(ctx, UpdateAccountParams{
-				ID:      arg.ToAccountID,
-				Balance: account2.Balance + arg.Amount,
-			})
-			if err != nil {
-				return err
-			}
-		*/
-
-		//INSTEAD OF THE ABOVE COMMENTED CODE DO THIS
-		// move money out of account1
-		if arg.FromAccountID < arg.ToAccountID {
-			log.Println(txName, "add account 1")
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-		} else {
-			log.Println(txName, "add account 2")
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+	//INSTEAD OF THE ABOVE COMMENTED CODE DO THIS
+	// move money out of account1
+	if arg.FromAccountID < arg.ToAccountID {
+		log.Println(txName, "add account 1")
+		result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
+	} else {
+		log.Println(txName, "add account 2")
+		result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+	}
+	if err != nil {
+		return
+	}
+
+	if err = checkAccountsActive(result.FromAccount, result.ToAccount); err != nil {
+		return
+	}
+
+	//The limits are checked while we hold the row lock taken by the debit above,
+	//so concurrent transfers from the same account cannot slip past the daily totals
+	log.Println(txName, "check limits")
+	if err = checkTransferLimits(ctx, q, result.FromAccount, arg.Amount); err != nil {
+		return
+	}
+
+	//Fees do not count towards the limits, they are charged once the transfer itself is accepted
+	log.Println(txName, "charge fees")
+	result.Fees, result.FromAccount, err = chargeFees(ctx, q, result.FromAccount, revenueID, quotes)
+	if err != nil {
+		return
+	}
+
+	//the balance was read under the row lock and already pays the amount and the fees
+	if arg.RequireFunds && result.FromAccount.Balance < 0 {
+		err = fmt.Errorf("account [%s] is %d short of the amount %d and its fees: %w", result.FromAccount.PublicID, -result.FromAccount.Balance, arg.Amount, ErrInsufficientFunds)
+	}
+	return
+}
+
+// lockAccounts locks the distinct accounts ids in ascending id order, the order addMoney updates
+// two accounts in, so transactions locking more accounts never deadlock with transfers. Zero ids are skipped.
+func lockAccounts(ctx context.Context, q Querier, ids ...int64) (map[int64]Account, error) {
+	sorted := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if id != 0 {
+			sorted = append(sorted, id)
 		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
 
-		return err
-	})
+	accounts := make(map[int64]Account)
+	for _, id := range sorted {
+		if _, ok := accounts[id]; ok {
+			continue
+		}
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		accounts[id] = account
+	}
+	return accounts, nil
+}
 
-	return result, err
+// transferFeeRevenueAccountID is feeRevenueAccountID for an account that is not locked yet,
+// its currency never changes so it can be read without the lock
+func transferFeeRevenueAccountID(ctx context.Context, q Querier, accountID int64, quotes []FeeQuote) (int64, error) {
+	if len(quotes) == 0 {
+		return 0, nil
+	}
+	account, err := q.GetAccount(ctx, accountID)
+	if err != nil {
+		return 0, err
+	}
+	return feeRevenueAccountID(ctx, q, account, quotes)
 }
 
 //The best defense against deadlocks is to avoid them by making sure that our application always acquire locks in a consistent order.
 //In our case, we can easily change our code so that it always updates the account with smaller ID first.
 func addMoney(
 	ctx context.Context,
-	q *Queries,
+	q Querier,
 	accountID1 int64,
 	amount1 int64,
 	accountID2 int64,
@@ -176,3 +295,11 @@ func addMoney(
 	})
 	return
 }
+
+//transferMetadata returns the metadata to store for a transfer, the column is NOT NULL so nothing given means {}
+func transferMetadata(metadata json.RawMessage) json.RawMessage {
+	if len(metadata) == 0 {
+		return json.RawMessage(`{}`)
+	}
+	return metadata
+}
@@ -0,0 +1,1201 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// The conformance suite runs against every Store implementation, so that code written
+// against the in-memory store behaves the same once it talks to Postgres.
+// It only uses the Store itself and never relies on a fresh database.
+
+func TestMemoryStoreConformance(t *testing.T) {
+	runStoreConformance(t, func() Store { return NewMemoryStore() })
+}
+
+func TestSQLStoreConformance(t *testing.T) {
+	runStoreConformance(t, func() Store { return NewStore(testDB) })
+}
+
+func runStoreConformance(t *testing.T, newStore func() Store) {
+	testCases := []struct {
+		name string
+		run  func(t *testing.T, store Store)
+	}{
+		{"Accounts", testConformanceAccounts},
+		{"NotFound", testConformanceNotFound},
+		{"UniqueViolation", testConformanceUniqueViolation},
+		{"ForeignKeyViolation", testConformanceForeignKeyViolation},
+		{"TransferTx", testConformanceTransferTx},
+		{"TransferTxRollback", testConformanceTransferTxRollback},
+		{"TransferTxFees", testConformanceTransferTxFees},
+		{"TransferDetails", testConformanceTransferDetails},
+		{"BatchTransferTx", testConformanceBatchTransferTx},
+		{"BatchTransferTxRollback", testConformanceBatchTransferTxRollback},
+		{"PostingTx", testConformancePostingTx},
+		{"PostingTxRollback", testConformancePostingTxRollback},
+		{"CreateUserTx", testConformanceCreateUserTx},
+		{"VerifyEmailTx", testConformanceVerifyEmailTx},
+		{"ResetPasswordTx", testConformanceResetPasswordTx},
+		{"EnableTOTPTx", testConformanceEnableTOTPTx},
+		{"TransferTxSecondFactor", testConformanceTransferTxSecondFactor},
+		{"AccrueInterestTx", testConformanceAccrueInterestTx},
+		{"BalanceAt", testConformanceBalanceAt},
+		{"Beneficiaries", testConformanceBeneficiaries},
+		{"ApproveRiskDecisionTx", testConformanceApproveRiskDecisionTx},
+		{"AdminTx", testConformanceAdminTx},
+		{"PublicIDs", testConformancePublicIDs},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			tc.run(t, newStore())
+		})
+	}
+}
+
+func conformanceUser(t *testing.T, store Store) User {
+	user, err := store.CreateUser(context.Background(), CreateUserParams{
+		Username:       util.RandomOwnerName(),
+		HashedPassword: "secret",
+		FullName:       util.RandomOwnerName(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.DepositorRole, user.Role)
+	require.False(t, user.IsEmailVerified)
+	require.True(t, user.PasswordChangedAt.IsZero())
+	return user
+}
+
+func conformanceAccount(t *testing.T, store Store, balance int64) Account {
+	user := conformanceUser(t, store)
+
+	account, err := store.CreateAccount(context.Background(), CreateAccountParams{
+		OwnerName: user.Username,
+		Balance:   balance,
+		Currency:  util.USD,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusActive, account.Status)
+	require.NotZero(t, account.ID)
+	return account
+}
+
+func requirePQError(t *testing.T, err error, code string, constraint string) {
+	var pqErr *pq.Error
+	require.True(t, errors.As(err, &pqErr), "expected *pq.Error, got %v", err)
+	require.Equal(t, pq.ErrorCode(code), pqErr.Code)
+	require.Equal(t, constraint, pqErr.Constraint)
+}
+
+func testConformanceAccounts(t *testing.T, store Store) {
+	ctx := context.Background()
+	account1 := conformanceAccount(t, store, 100)
+
+	account2, err := store.CreateAccount(ctx, CreateAccountParams{
+		OwnerName: account1.OwnerName,
+		Balance:   0,
+		Currency:  util.EUR,
+	})
+	require.NoError(t, err)
+	require.Greater(t, account2.ID, account1.ID)
+
+	accounts, err := store.ListAccountsByOwner(ctx, ListAccountsByOwnerParams{
+		OwnerName: account1.OwnerName,
+		Limit:     5,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []Account{account1, account2}, accounts)
+
+	accounts, err = store.ListAccountsByOwner(ctx, ListAccountsByOwnerParams{
+		OwnerName: account1.OwnerName,
+		Limit:     5,
+		Offset:    2,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, accounts)
+	require.Empty(t, accounts)
+
+	updated, err := store.AddAccountBalance(ctx, AddAccountBalanceParams{ID: account1.ID, Amount: -30})
+	require.NoError(t, err)
+	require.Equal(t, int64(70), updated.Balance)
+
+	updated, err = store.UpdateAccountStatus(ctx, UpdateAccountStatusParams{ID: account1.ID, Status: AccountStatusFrozen})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusFrozen, updated.Status)
+
+	got, err := store.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, updated, got)
+
+	err = store.DeleteAccount(ctx, account2.ID)
+	require.NoError(t, err)
+
+	_, err = store.GetAccount(ctx, account2.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func testConformanceNotFound(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	_, err := store.GetAccount(ctx, math.MaxInt64)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = store.AddAccountBalance(ctx, AddAccountBalanceParams{ID: math.MaxInt64, Amount: 1})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = store.GetUser(ctx, util.RandomOwnerName())
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = store.GetAccountLimit(ctx, math.MaxInt64)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = store.UseResetPassword(ctx, util.RandomString(32))
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func testConformanceUniqueViolation(t *testing.T, store Store) {
+	ctx := context.Background()
+	account := conformanceAccount(t, store, 0)
+
+	user, err := store.GetUser(ctx, account.OwnerName)
+	require.NoError(t, err)
+
+	_, err = store.CreateUser(ctx, CreateUserParams{
+		Username:       user.Username,
+		HashedPassword: "secret",
+		FullName:       user.FullName,
+		Email:          util.RandomEmail(),
+	})
+	requirePQError(t, err, UniqueViolationCode, "users_pkey")
+
+	_, err = store.CreateUser(ctx, CreateUserParams{
+		Username:       util.RandomOwnerName(),
+		HashedPassword: "secret",
+		FullName:       user.FullName,
+		Email:          user.Email,
+	})
+	requirePQError(t, err, UniqueViolationCode, "users_email_key")
+
+	_, err = store.CreateAccount(ctx, CreateAccountParams{
+		OwnerName: account.OwnerName,
+		Currency:  account.Currency,
+	})
+	requirePQError(t, err, UniqueViolationCode, "owner_currency_key")
+}
+
+func testConformanceForeignKeyViolation(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	_, err := store.CreateAccount(ctx, CreateAccountParams{
+		OwnerName: util.RandomOwnerName(),
+		Currency:  util.USD,
+	})
+	requirePQError(t, err, ForeignKeyViolationCode, "accounts_owner_name_fkey")
+
+	_, err = store.CreateEntry(ctx, CreateEntryParams{AccountID: math.MaxInt64, Amount: 10})
+	requirePQError(t, err, ForeignKeyViolationCode, "entries_account_id_fkey")
+
+	_, err = store.UpsertAccountLimit(ctx, UpsertAccountLimitParams{AccountID: math.MaxInt64})
+	requirePQError(t, err, ForeignKeyViolationCode, "account_limits_account_id_fkey")
+
+	account := conformanceAccount(t, store, 0)
+	_, err = store.CreateEntry(ctx, CreateEntryParams{AccountID: account.ID, Amount: 10})
+	require.NoError(t, err)
+
+	err = store.DeleteAccount(ctx, account.ID)
+	requirePQError(t, err, ForeignKeyViolationCode, "entries_account_id_fkey")
+}
+
+func testConformanceTransferTx(t *testing.T, store Store) {
+	account1 := conformanceAccount(t, store, 1000)
+	account2 := conformanceAccount(t, store, 1000)
+
+	//half of the transfers go the other way round, so that lock ordering is exercised as well
+	n := 10
+	amount := int64(10)
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		fromAccountID, toAccountID := account1.ID, account2.ID
+		if i%2 == 1 {
+			fromAccountID, toAccountID = account2.ID, account1.ID
+		}
+
+		go func() {
+			result, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: fromAccountID,
+				ToAccountID:   toAccountID,
+				Amount:        amount,
+			})
+			if err == nil && result.FromEntry.Amount+result.ToEntry.Amount != 0 {
+				err = errors.New("entries do not balance")
+			}
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	transfers, err := store.ListTransfers(context.Background(), ListTransfersParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account1.ID,
+		Description:   "%",
+		Metadata:      json.RawMessage(`{}`),
+		Limit:         int32(n + 1),
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, n)
+
+	entries, err := store.ListEntries(context.Background(), ListEntriesParams{
+		AccountID: account2.ID,
+		Limit:     int32(n + 1),
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, n)
+}
+
+func testConformanceTransferTxRollback(t *testing.T, store Store) {
+	ctx := context.Background()
+	account1 := conformanceAccount(t, store, 100)
+	account2 := conformanceAccount(t, store, 100)
+
+	_, err := store.UpsertAccountLimit(ctx, UpsertAccountLimitParams{
+		AccountID:         account1.ID,
+		MaxSingleTransfer: 50,
+	})
+	require.NoError(t, err)
+
+	var limitErr *TransferLimitError
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        60,
+	})
+	require.ErrorAs(t, err, &limitErr)
+
+	_, err = store.UpdateAccountStatus(ctx, UpdateAccountStatusParams{ID: account2.ID, Status: AccountStatusFrozen})
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	//neither transfer may have left a row or a balance change behind
+	updatedAccount1, err := store.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	transfers, err := store.ListTransfers(ctx, ListTransfersParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account1.ID,
+		Description:   "%",
+		Metadata:      json.RawMessage(`{}`),
+		Limit:         5,
+	})
+	require.NoError(t, err)
+	require.Empty(t, transfers)
+
+	entries, err := store.ListEntries(ctx, ListEntriesParams{AccountID: account2.ID, Limit: 5})
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func testConformanceTransferDetails(t *testing.T, store Store) {
+	ctx := context.Background()
+	account1 := conformanceAccount(t, store, 1000)
+	account2 := conformanceAccount(t, store, 1000)
+
+	result, err := store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		Description:   "Rent for March",
+		Reference:     "INV-42",
+		Metadata:      json.RawMessage(`{"invoice": {"id": 42, "lines": ["rent", "parking"]}}`),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Rent for March", result.Transfer.Description)
+	require.Equal(t, "INV-42", result.Transfer.Reference)
+	require.JSONEq(t, `{"invoice": {"id": 42, "lines": ["rent", "parking"]}}`, string(result.Transfer.Metadata))
+	require.Equal(t, "Rent for March", result.FromEntry.Description)
+	require.Equal(t, "Rent for March", result.ToEntry.Description)
+
+	//without metadata the transfer is stored with an empty object
+	plain, err := store.TransferTx(ctx, TransferTxParams{FromAccountID: account2.ID, ToAccountID: account1.ID, Amount: 5})
+	require.NoError(t, err)
+	require.JSONEq(t, `{}`, string(plain.Transfer.Metadata))
+
+	search := func(description string, reference string, metadata string) []Transfer {
+		transfers, err := store.ListTransfers(ctx, ListTransfersParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account1.ID,
+			Description:   description,
+			Reference:     reference,
+			Metadata:      json.RawMessage(metadata),
+			Limit:         5,
+		})
+		require.NoError(t, err)
+		return transfers
+	}
+
+	require.Len(t, search("%", "", `{}`), 2)
+	require.Len(t, search("%MARCH%", "MARCH", `{}`), 1)
+	require.Len(t, search("%r\\_nt%", "r_nt", `{}`), 0)
+	require.Len(t, search("%INV-42%", "INV-42", `{}`), 1)
+	require.Len(t, search("%", "", `{"invoice": {"lines": ["parking"]}}`), 1)
+	require.Len(t, search("%", "", `{"invoice": {"id": 43}}`), 0)
+
+	_, err = store.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		Description:   strings.Repeat("a", 256),
+		Metadata:      json.RawMessage(`{}`),
+	})
+	requirePQError(t, err, CheckViolationCode, "transfers_description_check")
+
+	_, err = store.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		Metadata:      json.RawMessage(`["not", "an", "object"]`),
+	})
+	requirePQError(t, err, CheckViolationCode, "transfers_metadata_check")
+}
+
+func testConformanceTransferTxFees(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	product, err := store.CreateAccountProduct(ctx, CreateAccountProductParams{
+		Code: util.RandomString(8),
+		Name: "Current",
+	})
+	require.NoError(t, err)
+
+	//10 plus 1% of the amount, at most 20
+	_, err = store.UpsertProductFee(ctx, UpsertProductFeeParams{
+		ProductCode: product.Code,
+		Kind:        FeeTransfer,
+		FlatAmount:  10,
+		RatePpm:     10_000,
+		MaxAmount:   20,
+	})
+	require.NoError(t, err)
+
+	account1 := conformanceAccount(t, store, 10_000)
+	account2 := conformanceAccount(t, store, 0)
+	revenue := conformanceAccount(t, store, 0)
+
+	_, err = store.UpsertAccountInterest(ctx, UpsertAccountInterestParams{
+		AccountID:      account1.ID,
+		ProductCode:    product.Code,
+		AccruedThrough: InterestDay(time.Now()),
+	})
+	require.NoError(t, err)
+	_, err = store.UpsertFeeRevenueAccount(ctx, UpsertFeeRevenueAccountParams{Currency: util.USD, AccountID: revenue.ID})
+	require.NoError(t, err)
+
+	quotes, err := QuoteTransferFees(ctx, store, account1.ID, 500)
+	require.NoError(t, err)
+	require.Equal(t, []FeeQuote{{Kind: FeeTransfer, Amount: 15}}, quotes)
+
+	result, err := store.TransferTx(ctx, TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 500})
+	require.NoError(t, err)
+	require.Len(t, result.Fees, 1)
+	require.Equal(t, int64(15), result.Fees[0].Amount)
+	require.Equal(t, account1.ID, result.Fees[0].Entry.AccountID)
+	require.Equal(t, int64(-15), result.Fees[0].Entry.Amount)
+	require.Equal(t, revenue.ID, result.Fees[0].RevenueEntry.AccountID)
+	require.Equal(t, int64(15), result.Fees[0].RevenueEntry.Amount)
+	require.Equal(t, int64(10_000-500-15), result.FromAccount.Balance)
+	require.Equal(t, int64(500), result.ToAccount.Balance)
+
+	//the cap applies to large transfers
+	result, err = store.TransferTx(ctx, TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 5_000})
+	require.NoError(t, err)
+	require.Equal(t, int64(20), result.Fees[0].Amount)
+
+	revenue, err = store.GetAccount(ctx, revenue.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(35), revenue.Balance)
+
+	//the receiving account is not on the product and pays nothing
+	result, err = store.TransferTx(ctx, TransferTxParams{FromAccountID: account2.ID, ToAccountID: account1.ID, Amount: 100})
+	require.NoError(t, err)
+	require.Empty(t, result.Fees)
+
+	//the amounts of a batch fit the balance but the fees don't, 250 pays 12 in fees
+	account3 := conformanceAccount(t, store, 510)
+	_, err = store.UpsertAccountInterest(ctx, UpsertAccountInterestParams{
+		AccountID:      account3.ID,
+		ProductCode:    product.Code,
+		AccruedThrough: InterestDay(time.Now()),
+	})
+	require.NoError(t, err)
+
+	_, err = store.BatchTransferTx(ctx, BatchTransferTxParams{
+		FromAccountID: account3.ID,
+		Items: []BatchTransferItem{
+			{ToAccountID: account2.ID, Amount: 250},
+			{ToAccountID: account2.ID, Amount: 250},
+		},
+	})
+	var itemErr *BatchItemError
+	require.ErrorAs(t, err, &itemErr)
+	require.Equal(t, 1, itemErr.Index)
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = store.TransferTx(ctx, TransferTxParams{FromAccountID: account3.ID, ToAccountID: account2.ID, Amount: 505, RequireFunds: true})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	account3, err = store.GetAccount(ctx, account3.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(510), account3.Balance)
+
+	//a split covers its legs but not the 12 in fees of each of its two transfers
+	_, err = store.PostingTx(ctx, PostingTxParams{Legs: []PostingLeg{
+		{AccountID: account3.ID, Amount: -500},
+		{AccountID: account1.ID, Amount: 250},
+		{AccountID: account2.ID, Amount: 250},
+	}})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	posting, err := store.PostingTx(ctx, PostingTxParams{Legs: []PostingLeg{
+		{AccountID: account3.ID, Amount: -480},
+		{AccountID: account1.ID, Amount: 240},
+		{AccountID: account2.ID, Amount: 240},
+	}})
+	require.NoError(t, err)
+	require.Len(t, posting.Fees, 2)
+	require.Equal(t, int64(510-480-24), posting.Accounts[0].Balance)
+}
+
+func testConformanceBatchTransferTx(t *testing.T, store Store) {
+	ctx := context.Background()
+	account1 := conformanceAccount(t, store, 100)
+	account2 := conformanceAccount(t, store, 0)
+	account3 := conformanceAccount(t, store, 0)
+
+	result, err := store.BatchTransferTx(ctx, BatchTransferTxParams{
+		FromAccountID: account1.ID,
+		Items: []BatchTransferItem{
+			{ToAccountID: account3.ID, Amount: 30},
+			{ToAccountID: account2.ID, Amount: 20},
+			{ToAccountID: account3.ID, Amount: 10},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Transfers, 3)
+	require.Equal(t, int64(40), result.FromAccount.Balance)
+	require.Equal(t, account2.ID, result.Transfers[1].Transfer.ToAccountID)
+
+	updatedAccount3, err := store.GetAccount(ctx, account3.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(40), updatedAccount3.Balance)
+
+	_, err = store.BatchTransferTx(ctx, BatchTransferTxParams{
+		FromAccountID: account1.ID,
+		Items: []BatchTransferItem{
+			{ToAccountID: account2.ID, Amount: 30},
+			{ToAccountID: account3.ID, Amount: 30},
+		},
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func testConformanceBatchTransferTxRollback(t *testing.T, store Store) {
+	ctx := context.Background()
+	account1 := conformanceAccount(t, store, 100)
+	account2 := conformanceAccount(t, store, 0)
+	account3 := conformanceAccount(t, store, 0)
+
+	_, err := store.UpdateAccountStatus(ctx, UpdateAccountStatusParams{ID: account3.ID, Status: AccountStatusFrozen})
+	require.NoError(t, err)
+
+	_, err = store.BatchTransferTx(ctx, BatchTransferTxParams{
+		FromAccountID: account1.ID,
+		Items: []BatchTransferItem{
+			{ToAccountID: account2.ID, Amount: 10},
+			{ToAccountID: account3.ID, Amount: 10},
+		},
+	})
+	var itemErr *BatchItemError
+	require.ErrorAs(t, err, &itemErr)
+	require.Equal(t, 1, itemErr.Index)
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	//the first item was applied inside the transaction and must be gone again
+	updatedAccount2, err := store.GetAccount(ctx, account2.ID)
+	require.NoError(t, err)
+	require.Zero(t, updatedAccount2.Balance)
+
+	_, err = store.BatchTransferTx(ctx, BatchTransferTxParams{
+		FromAccountID: account1.ID,
+		Items:         []BatchTransferItem{{ToAccountID: math.MaxInt64, Amount: 10}},
+	})
+	require.ErrorAs(t, err, &itemErr)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func testConformancePostingTx(t *testing.T, store Store) {
+	ctx := context.Background()
+	account1 := conformanceAccount(t, store, 100)
+	account2 := conformanceAccount(t, store, 0)
+	account3 := conformanceAccount(t, store, 0)
+
+	//one source split across two destinations, the legs are not in id order
+	result, err := store.PostingTx(ctx, PostingTxParams{Legs: []PostingLeg{
+		{AccountID: account3.ID, Amount: 30},
+		{AccountID: account1.ID, Amount: -50},
+		{AccountID: account2.ID, Amount: 20},
+	}})
+	require.NoError(t, err)
+	require.Len(t, result.Transfers, 2)
+	require.Equal(t, account1.ID, result.Transfers[0].FromAccountID)
+	require.Equal(t, account3.ID, result.Transfers[0].ToAccountID)
+	require.Equal(t, int64(30), result.Transfers[0].Amount)
+	require.Equal(t, account2.ID, result.Transfers[1].ToAccountID)
+
+	require.Len(t, result.Entries, 3)
+	require.Equal(t, int64(-50), result.Entries[1].Amount)
+	require.Equal(t, int64(30), result.Accounts[0].Balance)
+	require.Equal(t, int64(50), result.Accounts[1].Balance)
+	require.Equal(t, int64(20), result.Accounts[2].Balance)
+
+	//several sources paying one destination
+	result, err = store.PostingTx(ctx, PostingTxParams{Legs: []PostingLeg{
+		{AccountID: account2.ID, Amount: -20},
+		{AccountID: account3.ID, Amount: -10},
+		{AccountID: account1.ID, Amount: 30},
+	}})
+	require.NoError(t, err)
+	require.Len(t, result.Transfers, 2)
+	require.Equal(t, account2.ID, result.Transfers[0].FromAccountID)
+	require.Equal(t, account1.ID, result.Transfers[0].ToAccountID)
+	require.Equal(t, int64(80), result.Accounts[2].Balance)
+
+	_, err = store.PostingTx(ctx, PostingTxParams{Legs: []PostingLeg{
+		{AccountID: account2.ID, Amount: -10},
+		{AccountID: account1.ID, Amount: 20},
+	}})
+	require.ErrorIs(t, err, ErrInvalidPosting)
+}
+
+func testConformancePostingTxRollback(t *testing.T, store Store) {
+	ctx := context.Background()
+	account1 := conformanceAccount(t, store, 100)
+	account2 := conformanceAccount(t, store, 0)
+
+	account3, err := store.CreateAccount(ctx, CreateAccountParams{
+		OwnerName: account2.OwnerName,
+		Balance:   0,
+		Currency:  util.EUR,
+	})
+	require.NoError(t, err)
+
+	//the legs sum to zero overall but not per currency
+	_, err = store.PostingTx(ctx, PostingTxParams{Legs: []PostingLeg{
+		{AccountID: account1.ID, Amount: -20},
+		{AccountID: account2.ID, Amount: 10},
+		{AccountID: account3.ID, Amount: 10},
+	}})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = store.PostingTx(ctx, PostingTxParams{Legs: []PostingLeg{
+		{AccountID: account1.ID, Amount: -200},
+		{AccountID: account2.ID, Amount: 200},
+	}})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	//nothing of the failed postings is left
+	for _, account := range []Account{account1, account2, account3} {
+		updated, err := store.GetAccount(ctx, account.ID)
+		require.NoError(t, err)
+		require.Equal(t, account.Balance, updated.Balance)
+	}
+}
+
+func testConformanceCreateUserTx(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	arg := CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       util.RandomOwnerName(),
+			HashedPassword: "secret",
+			FullName:       util.RandomOwnerName(),
+			Email:          util.RandomEmail(),
+		},
+		VerifyEmail: CreateVerifyEmailParams{
+			SecretCode: util.RandomString(32),
+			ExpiredAt:  time.Now().Add(time.Hour),
+		},
+	}
+
+	result, err := store.CreateUserTx(ctx, arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, result.VerifyEmail.Username)
+	require.Equal(t, arg.Email, result.VerifyEmail.Email)
+
+	//the transaction rolls back when the username is taken, the first user is left as it was
+	_, err = store.CreateUserTx(ctx, arg)
+	requirePQError(t, err, UniqueViolationCode, "users_pkey")
+
+	user, err := store.GetUser(ctx, arg.Username)
+	require.NoError(t, err)
+	require.Equal(t, result.User, user)
+}
+
+func testConformanceVerifyEmailTx(t *testing.T, store Store) {
+	ctx := context.Background()
+	user := conformanceUser(t, store)
+
+	verifyEmail, err := store.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: util.RandomString(32),
+		ExpiredAt:  time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	_, err = store.VerifyEmailTx(ctx, VerifyEmailTxParams{EmailID: verifyEmail.ID, SecretCode: "wrong"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	result, err := store.VerifyEmailTx(ctx, VerifyEmailTxParams{EmailID: verifyEmail.ID, SecretCode: verifyEmail.SecretCode})
+	require.NoError(t, err)
+	require.True(t, result.User.IsEmailVerified)
+	require.True(t, result.VerifyEmail.IsUsed)
+
+	_, err = store.VerifyEmailTx(ctx, VerifyEmailTxParams{EmailID: verifyEmail.ID, SecretCode: verifyEmail.SecretCode})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func testConformanceResetPasswordTx(t *testing.T, store Store) {
+	ctx := context.Background()
+	user := conformanceUser(t, store)
+
+	tokens := []string{util.RandomString(32), util.RandomString(32)}
+	for _, token := range tokens {
+		_, err := store.CreateResetPassword(ctx, CreateResetPasswordParams{
+			Username:  user.Username,
+			TokenHash: token,
+			ExpiredAt: time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+	}
+
+	_, err := store.CreateResetPassword(ctx, CreateResetPasswordParams{
+		Username:  user.Username,
+		TokenHash: tokens[0],
+		ExpiredAt: time.Now().Add(time.Hour),
+	})
+	requirePQError(t, err, UniqueViolationCode, "reset_passwords_token_hash_key")
+
+	//looking a token up doesn't use it
+	for i := 0; i < 2; i++ {
+		resetPassword, err := store.GetResetPassword(ctx, tokens[0])
+		require.NoError(t, err)
+		require.Equal(t, user.Username, resetPassword.Username)
+	}
+
+	changedAt := time.Now().Truncate(time.Microsecond)
+	updated, err := store.ResetPasswordTx(ctx, ResetPasswordTxParams{
+		TokenHash:         tokens[0],
+		HashedPassword:    "new secret",
+		PasswordChangedAt: changedAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "new secret", updated.HashedPassword)
+	require.WithinDuration(t, changedAt, updated.PasswordChangedAt, time.Second)
+
+	//the second token was invalidated by the reset
+	_, err = store.ResetPasswordTx(ctx, ResetPasswordTxParams{
+		TokenHash:         tokens[1],
+		HashedPassword:    "another secret",
+		PasswordChangedAt: changedAt,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = store.GetResetPassword(ctx, tokens[0])
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func testConformanceEnableTOTPTx(t *testing.T, store Store) {
+	ctx := context.Background()
+	user := conformanceUser(t, store)
+
+	_, err := store.UpsertUserTOTP(ctx, UpsertUserTOTPParams{Username: user.Username, Secret: "secret"})
+	require.NoError(t, err)
+
+	codeHashes := []string{util.RandomString(32), util.RandomString(32)}
+	userTOTP, err := store.EnableTOTPTx(ctx, EnableTOTPTxParams{
+		Username:           user.Username,
+		Step:               10,
+		RecoveryCodeHashes: codeHashes,
+	})
+	require.NoError(t, err)
+	require.True(t, userTOTP.IsEnabled)
+
+	//the same step cannot be used twice and nothing changes when it is tried
+	_, err = store.EnableTOTPTx(ctx, EnableTOTPTxParams{Username: user.Username, Step: 10})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = store.UseRecoveryCode(ctx, UseRecoveryCodeParams{Username: user.Username, CodeHash: codeHashes[0]})
+	require.NoError(t, err)
+
+	_, err = store.UseRecoveryCode(ctx, UseRecoveryCodeParams{Username: user.Username, CodeHash: codeHashes[0]})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	//an enabled secret is never replaced by a new enrollment
+	_, err = store.UpsertUserTOTP(ctx, UpsertUserTOTPParams{Username: user.Username, Secret: "other"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func testConformanceTransferTxSecondFactor(t *testing.T, store Store) {
+	ctx := context.Background()
+	account1 := conformanceAccount(t, store, 100)
+	account2 := conformanceAccount(t, store, 100)
+
+	_, err := store.UpsertUserTOTP(ctx, UpsertUserTOTPParams{Username: account1.OwnerName, Secret: "secret"})
+	require.NoError(t, err)
+	step := &UseTOTPStepParams{Username: account1.OwnerName, Step: 5}
+
+	//a transfer that fails leaves the step usable
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID + 1000000,
+		Amount:        10,
+		SecondFactor:  step,
+	})
+	require.Error(t, err)
+
+	_, err = store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		SecondFactor:  step,
+	})
+	require.NoError(t, err)
+
+	//once a transfer committed with it, the step can't authorise another one
+	_, err = store.BatchTransferTx(ctx, BatchTransferTxParams{
+		FromAccountID: account1.ID,
+		Items:         []BatchTransferItem{{ToAccountID: account2.ID, Amount: 10}},
+		SecondFactor:  step,
+	})
+	require.ErrorIs(t, err, ErrSecondFactorUsed)
+
+	_, err = store.PostingTx(ctx, PostingTxParams{
+		Legs:         []PostingLeg{{AccountID: account1.ID, Amount: -10}, {AccountID: account2.ID, Amount: 10}},
+		SecondFactor: step,
+	})
+	require.ErrorIs(t, err, ErrSecondFactorUsed)
+
+	updated, err := store.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(90), updated.Balance)
+}
+
+func testConformanceAccrueInterestTx(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	//3.65% makes one day of interest on 1_000_000 exactly 100
+	product, err := store.CreateAccountProduct(ctx, CreateAccountProductParams{
+		Code:          util.RandomString(8),
+		Name:          "Savings",
+		AnnualRatePpm: 36_500,
+	})
+	require.NoError(t, err)
+
+	account := conformanceAccount(t, store, 1_000_000)
+	other := conformanceAccount(t, store, 1_000_000)
+	expense := conformanceAccount(t, store, 0)
+
+	//a day that does not close a month only accrues
+	day := InterestDay(time.Now()).AddDate(0, 0, -1)
+	if IsLastDayOfMonth(day) {
+		day = day.AddDate(0, 0, -1)
+	}
+	_, err = store.UpsertAccountInterest(ctx, UpsertAccountInterestParams{
+		AccountID:      account.ID,
+		ProductCode:    product.Code,
+		AccruedThrough: day.AddDate(0, 0, -1),
+	})
+	require.NoError(t, err)
+
+	//money received today does not count for the end of day balance
+	_, err = store.TransferTx(ctx, TransferTxParams{FromAccountID: other.ID, ToAccountID: account.ID, Amount: 500_000})
+	require.NoError(t, err)
+
+	result, err := store.AccrueInterestTx(ctx, AccrueInterestTxParams{AccountID: account.ID, Day: day})
+	require.NoError(t, err)
+	require.Equal(t, int64(1_000_000), result.EndBalance)
+	require.True(t, result.Interest.AccruedThrough.Equal(day))
+	require.Nil(t, result.Posting)
+	require.Equal(t, int64(100), result.Interest.Accrued)
+	require.Zero(t, result.Interest.AccruedFraction)
+
+	_, err = store.AccrueInterestTx(ctx, AccrueInterestTxParams{AccountID: account.ID, Day: day})
+	require.ErrorIs(t, err, ErrInterestAlreadyAccrued)
+
+	_, err = store.AccrueInterestTx(ctx, AccrueInterestTxParams{AccountID: account.ID, Day: day.AddDate(0, 0, 2)})
+	require.ErrorIs(t, err, ErrInterestDayMissing)
+
+	//the last day of a month pays the interest from the expense account
+	monthEnd := day.AddDate(0, 0, 1-day.Day()).AddDate(0, 0, -1)
+	_, err = store.UpsertAccountInterest(ctx, UpsertAccountInterestParams{
+		AccountID:      other.ID,
+		ProductCode:    product.Code,
+		AccruedThrough: monthEnd.AddDate(0, 0, -1),
+	})
+	require.NoError(t, err)
+
+	_, err = store.UpsertInterestExpenseAccount(ctx, UpsertInterestExpenseAccountParams{
+		Currency:  util.USD,
+		AccountID: expense.ID,
+	})
+	require.NoError(t, err)
+
+	revenue := conformanceAccount(t, store, 0)
+	_, err = store.UpsertFeeRevenueAccount(ctx, UpsertFeeRevenueAccountParams{Currency: util.USD, AccountID: revenue.ID})
+	require.NoError(t, err)
+	_, err = store.UpsertProductFee(ctx, UpsertProductFeeParams{ProductCode: product.Code, Kind: FeeMaintenance, FlatAmount: 50})
+	require.NoError(t, err)
+
+	//other sent 500_000 after the month ended, so it had 1_000_000 at its end
+	result, err = store.AccrueInterestTx(ctx, AccrueInterestTxParams{AccountID: other.ID, Day: monthEnd})
+	require.NoError(t, err)
+	require.Equal(t, int64(1_000_000), result.EndBalance)
+	require.NotNil(t, result.Posting)
+	require.Equal(t, expense.ID, result.Posting.Transfer.FromAccountID)
+	require.Equal(t, int64(100), result.Posting.Transfer.Amount)
+	require.Equal(t, int64(500_100), result.Posting.ToAccount.Balance)
+	require.Equal(t, int64(-100), result.Posting.FromAccount.Balance)
+	require.Zero(t, result.Interest.Accrued)
+	require.Equal(t, int64(100), result.Interest.Paid)
+
+	require.Len(t, result.Fees, 1)
+	require.Equal(t, FeeMaintenance, result.Fees[0].Kind)
+	require.Equal(t, int64(-50), result.Fees[0].Entry.Amount)
+	require.Equal(t, revenue.ID, result.Fees[0].RevenueEntry.AccountID)
+
+	other, err = store.GetAccount(ctx, other.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(500_050), other.Balance)
+}
+
+func testConformanceBalanceAt(t *testing.T, store Store) {
+	ctx := context.Background()
+	account := conformanceAccount(t, store, 1_000)
+	other := conformanceAccount(t, store, 1_000)
+
+	opened := time.Now().Truncate(time.Microsecond)
+	time.Sleep(time.Millisecond)
+	_, err := store.TransferTx(ctx, TransferTxParams{FromAccountID: account.ID, ToAccountID: other.ID, Amount: 100})
+	require.NoError(t, err)
+
+	//without a snapshot the balance is walked back from the current one
+	balance, err := BalanceAt(ctx, store, account.ID, opened)
+	require.NoError(t, err)
+	require.Equal(t, int64(1_000), balance)
+
+	//entries are stamped with microseconds, keep them apart from the instants below
+	time.Sleep(time.Millisecond)
+	takenAt := time.Now().Truncate(time.Microsecond)
+	time.Sleep(time.Millisecond)
+	balance, err = store.GetBalanceBefore(ctx, GetBalanceBeforeParams{Before: takenAt, AccountID: account.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(900), balance)
+
+	snapshot, err := store.CreateBalanceSnapshot(ctx, CreateBalanceSnapshotParams{AccountID: account.ID, TakenAt: takenAt, Balance: balance})
+	require.NoError(t, err)
+	require.True(t, snapshot.TakenAt.Equal(takenAt))
+
+	_, err = store.CreateBalanceSnapshot(ctx, CreateBalanceSnapshotParams{AccountID: account.ID, TakenAt: takenAt, Balance: balance})
+	requirePQError(t, err, UniqueViolationCode, "balance_snapshots_pkey")
+
+	accounts, err := store.ListAccountsDueSnapshot(ctx, ListAccountsDueSnapshotParams{AfterID: account.ID - 1, TakenAt: takenAt, RowLimit: 2})
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	require.Equal(t, other.ID, accounts[0].ID)
+
+	_, err = store.TransferTx(ctx, TransferTxParams{FromAccountID: other.ID, ToAccountID: account.ID, Amount: 50})
+	require.NoError(t, err)
+
+	//from the snapshot on only the later entries are added
+	balance, err = BalanceAt(ctx, store, account.ID, takenAt)
+	require.NoError(t, err)
+	require.Equal(t, int64(900), balance)
+
+	balance, err = BalanceAt(ctx, store, account.ID, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(950), balance)
+}
+
+func testConformanceBeneficiaries(t *testing.T, store Store) {
+	ctx := context.Background()
+	owner := conformanceUser(t, store)
+	account1 := conformanceAccount(t, store, 0)
+	account2 := conformanceAccount(t, store, 0)
+
+	beneficiary, err := store.CreateBeneficiary(ctx, CreateBeneficiaryParams{
+		OwnerName: owner.Username,
+		Nickname:  "Landlord",
+		AccountID: account1.ID,
+		Currency:  account1.Currency,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, beneficiary.ID)
+	require.NotZero(t, beneficiary.CreatedAt)
+
+	//an account and a nickname are in an address book only once
+	_, err = store.CreateBeneficiary(ctx, CreateBeneficiaryParams{
+		OwnerName: owner.Username,
+		Nickname:  "Landlord",
+		AccountID: account2.ID,
+		Currency:  account2.Currency,
+	})
+	requirePQError(t, err, UniqueViolationCode, "beneficiaries_owner_name_nickname_idx")
+
+	_, err = store.CreateBeneficiary(ctx, CreateBeneficiaryParams{
+		OwnerName: owner.Username,
+		Nickname:  "Flat",
+		AccountID: account1.ID,
+		Currency:  account1.Currency,
+	})
+	requirePQError(t, err, UniqueViolationCode, "beneficiaries_owner_name_account_id_idx")
+
+	other, err := store.CreateBeneficiary(ctx, CreateBeneficiaryParams{
+		OwnerName: owner.Username,
+		Nickname:  "Plumber",
+		AccountID: account2.ID,
+		Currency:  account2.Currency,
+	})
+	require.NoError(t, err)
+
+	_, err = store.UpdateBeneficiaryNickname(ctx, UpdateBeneficiaryNicknameParams{ID: other.ID, Nickname: "Landlord"})
+	requirePQError(t, err, UniqueViolationCode, "beneficiaries_owner_name_nickname_idx")
+
+	renamed, err := store.UpdateBeneficiaryNickname(ctx, UpdateBeneficiaryNicknameParams{ID: other.ID, Nickname: "Electrician"})
+	require.NoError(t, err)
+	require.Equal(t, "Electrician", renamed.Nickname)
+	require.Equal(t, other.CreatedAt, renamed.CreatedAt)
+
+	byAccount, err := store.GetBeneficiaryByAccount(ctx, GetBeneficiaryByAccountParams{OwnerName: owner.Username, AccountID: account2.ID})
+	require.NoError(t, err)
+	require.Equal(t, renamed, byAccount)
+
+	_, err = store.GetBeneficiaryByAccount(ctx, GetBeneficiaryByAccountParams{OwnerName: conformanceUser(t, store).Username, AccountID: account2.ID})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	beneficiaries, err := store.ListBeneficiaries(ctx, ListBeneficiariesParams{OwnerName: owner.Username, Limit: 5})
+	require.NoError(t, err)
+	require.Equal(t, []Beneficiary{beneficiary, renamed}, beneficiaries)
+
+	require.NoError(t, store.DeleteBeneficiary(ctx, beneficiary.ID))
+	_, err = store.GetBeneficiary(ctx, beneficiary.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	//beneficiaries go away with their account
+	require.NoError(t, store.DeleteAccount(ctx, account2.ID))
+	_, err = store.GetBeneficiary(ctx, other.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func testConformanceApproveRiskDecisionTx(t *testing.T, store Store) {
+	ctx := context.Background()
+	account1 := conformanceAccount(t, store, 100)
+	account2 := conformanceAccount(t, store, 0)
+
+	holdFrom := func(from Account, to Account, amount int64) RiskDecision {
+		decision, err := store.CreateRiskDecision(ctx, CreateRiskDecisionParams{
+			Username:      from.OwnerName,
+			FromAccountID: from.ID,
+			ToAccountID:   to.ID,
+			Amount:        amount,
+			Currency:      from.Currency,
+			Description:   "Rent",
+			Metadata:      json.RawMessage(`{"invoice":42}`),
+			Decision:      RiskDecisionReview,
+			Hits:          json.RawMessage(`[]`),
+			Status:        RiskStatusPending,
+		})
+		require.NoError(t, err)
+		require.Zero(t, decision.TransferID)
+		require.True(t, decision.ReviewedAt.IsZero())
+		return decision
+	}
+	hold := func(to Account, amount int64) RiskDecision {
+		return holdFrom(account1, to, amount)
+	}
+
+	_, err := store.UpsertAccountLimit(ctx, UpsertAccountLimitParams{
+		AccountID:         account1.ID,
+		MaxSingleTransfer: 50,
+	})
+	require.NoError(t, err)
+
+	//a transfer that fails leaves the decision pending
+	tooLarge := hold(account2, 60)
+	var limitErr *TransferLimitError
+	_, err = store.ApproveRiskDecisionTx(ctx, ApproveRiskDecisionTxParams{ID: tooLarge.ID, ReviewedBy: "banker"})
+	require.ErrorAs(t, err, &limitErr)
+
+	decision, err := store.GetRiskDecision(ctx, tooLarge.ID)
+	require.NoError(t, err)
+	require.Equal(t, RiskStatusPending, decision.Status)
+
+	//the user who made the transfer can't approve it, even with a staff role
+	own := hold(account2, 30)
+	_, err = store.ApproveRiskDecisionTx(ctx, ApproveRiskDecisionTxParams{ID: own.ID, ReviewedBy: account1.OwnerName})
+	require.ErrorIs(t, err, ErrSelfApproval)
+
+	decision, err = store.GetRiskDecision(ctx, own.ID)
+	require.NoError(t, err)
+	require.Equal(t, RiskStatusPending, decision.Status)
+
+	//the balance may have been spent while the transfer waited
+	poor := conformanceAccount(t, store, 10)
+	unfunded := holdFrom(poor, account2, 20)
+	_, err = store.ApproveRiskDecisionTx(ctx, ApproveRiskDecisionTxParams{ID: unfunded.ID, ReviewedBy: "banker"})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	held := hold(account2, 30)
+	result, err := store.ApproveRiskDecisionTx(ctx, ApproveRiskDecisionTxParams{ID: held.ID, ReviewedBy: "banker"})
+	require.NoError(t, err)
+	require.Equal(t, RiskStatusApproved, result.Decision.Status)
+	require.Equal(t, "banker", result.Decision.ReviewedBy)
+	require.False(t, result.Decision.ReviewedAt.IsZero())
+	require.Equal(t, result.Transfer.Transfer.ID, result.Decision.TransferID)
+	require.Equal(t, "Rent", result.Transfer.Transfer.Description)
+	require.JSONEq(t, `{"invoice":42}`, string(result.Transfer.Transfer.Metadata))
+	require.Equal(t, int64(70), result.Transfer.FromAccount.Balance)
+	require.Equal(t, int64(30), result.Transfer.ToAccount.Balance)
+
+	//a decision is reviewed only once
+	_, err = store.ApproveRiskDecisionTx(ctx, ApproveRiskDecisionTxParams{ID: held.ID, ReviewedBy: "banker"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	rejected, err := store.ReviewRiskDecision(ctx, ReviewRiskDecisionParams{ID: tooLarge.ID, Status: RiskStatusRejected, ReviewedBy: "banker"})
+	require.NoError(t, err)
+	require.Equal(t, RiskStatusRejected, rejected.Status)
+
+	decisions, err := store.ListRiskDecisionsByStatus(ctx, ListRiskDecisionsByStatusParams{Status: RiskStatusApproved, Limit: 5})
+	require.NoError(t, err)
+	require.Equal(t, []RiskDecision{result.Decision}, decisions)
+
+	//decisions are kept as long as the accounts
+	account3 := conformanceAccount(t, store, 0)
+	hold(account3, 10)
+	err = store.DeleteAccount(ctx, account3.ID)
+	requirePQError(t, err, ForeignKeyViolationCode, "risk_decisions_to_account_id_fkey")
+}
+
+func testConformanceAdminTx(t *testing.T, store Store) {
+	ctx := context.Background()
+	account := conformanceAccount(t, store, 0)
+	suspense := conformanceAccount(t, store, 0)
+
+	status, err := store.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{
+		ID:     account.ID,
+		Status: AccountStatusFrozen,
+		Actor:  "admin",
+		Reason: "dispute",
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusFrozen, status.Account.Status)
+	require.Equal(t, AuditActionAccountStatus, status.Event.Action)
+	require.JSONEq(t, `{"from":"active","to":"frozen"}`, string(status.Event.Details))
+
+	_, err = store.UpdateAccountStatusTx(ctx, UpdateAccountStatusTxParams{ID: math.MaxInt64, Status: AccountStatusFrozen})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	adjustment, err := store.AdjustBalanceTx(ctx, AdjustBalanceTxParams{
+		AccountID:        account.ID,
+		AgainstAccountID: suspense.ID,
+		Amount:           -40,
+		Actor:            "admin",
+		Reason:           "chargeback",
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(-40), adjustment.Account.Balance)
+	require.Equal(t, int64(40), adjustment.AgainstAccount.Balance)
+	require.Equal(t, account.ID, adjustment.Transfer.FromAccountID)
+	require.Equal(t, int64(40), adjustment.Transfer.Amount)
+	require.JSONEq(t, `{"actor":"admin","reason":"chargeback"}`, string(adjustment.Transfer.Metadata))
+	require.Equal(t, int64(-40), adjustment.Entry.Amount)
+	require.Equal(t, account.ID, adjustment.Event.AccountID)
+
+	_, err = store.AdjustBalanceTx(ctx, AdjustBalanceTxParams{AccountID: account.ID, AgainstAccountID: suspense.ID, Amount: 10})
+	require.ErrorIs(t, err, ErrInvalidAdjustment)
+
+	//a currency mismatch rolls the whole adjustment back
+	user := conformanceUser(t, store)
+	euros, err := store.CreateAccount(ctx, CreateAccountParams{OwnerName: user.Username, Currency: util.EUR})
+	require.NoError(t, err)
+	_, err = store.AdjustBalanceTx(ctx, AdjustBalanceTxParams{AccountID: account.ID, AgainstAccountID: euros.ID, Amount: 10, Reason: "wrong book"})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	events, err := store.ListAuditEventsByAccount(ctx, ListAuditEventsByAccountParams{AccountID: account.ID, Limit: 5})
+	require.NoError(t, err)
+	require.Equal(t, []AuditEvent{status.Event, adjustment.Event}, events)
+
+	updated, err := store.GetAccount(ctx, account.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(-40), updated.Balance)
+}
+
+func testConformancePublicIDs(t *testing.T, store Store) {
+	ctx := context.Background()
+	account1 := conformanceAccount(t, store, 100)
+	account2 := conformanceAccount(t, store, 100)
+	require.True(t, IsPublicID(account1.PublicID, AccountIDPrefix))
+	require.NotEqual(t, account1.PublicID, account2.PublicID)
+
+	result, err := store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+	require.True(t, IsPublicID(result.Transfer.PublicID, TransferIDPrefix))
+	require.True(t, IsPublicID(result.FromEntry.PublicID, EntryIDPrefix))
+	require.NotEqual(t, result.FromEntry.PublicID, result.ToEntry.PublicID)
+
+	account, err := store.GetAccountByPublicID(ctx, account1.PublicID)
+	require.NoError(t, err)
+	require.Equal(t, account1.ID, account.ID)
+
+	transfer, err := store.GetTransferByPublicID(ctx, result.Transfer.PublicID)
+	require.NoError(t, err)
+	require.Equal(t, result.Transfer.ID, transfer.ID)
+
+	entry, err := store.GetEntryByPublicID(ctx, result.ToEntry.PublicID)
+	require.NoError(t, err)
+	require.Equal(t, result.ToEntry.ID, entry.ID)
+
+	_, err = store.GetAccountByPublicID(ctx, NewPublicID(AccountIDPrefix, time.Now()))
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
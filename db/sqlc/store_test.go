@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"log"
 	"testing"
+	"time"
 
+	"github.com/kingsleyocran/simple_bank_bankend/util"
 	"github.com/stretchr/testify/require"
 )
 
@@ -232,3 +234,137 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.Equal(t, account2.Balance, updatedAccount2.Balance)
 
 }
+
+//Limits are enforced inside TransferTx, a rejected transfer must leave no trace behind
+func TestTransferTxFeeDeadlock(t *testing.T) {
+	store := NewStore(testDB)
+	ctx := context.Background()
+
+	//the fee revenue account has the lowest id, transfers paying a fee must lock it before their own accounts
+	revenue := conformanceAccount(t, store, 1_000)
+	account1 := conformanceAccount(t, store, 1_000)
+	account2 := conformanceAccount(t, store, 1_000)
+
+	product, err := store.CreateAccountProduct(ctx, CreateAccountProductParams{
+		Code: util.RandomString(8),
+		Name: "Current",
+	})
+	require.NoError(t, err)
+	_, err = store.UpsertProductFee(ctx, UpsertProductFeeParams{ProductCode: product.Code, Kind: FeeTransfer, FlatAmount: 1})
+	require.NoError(t, err)
+	for _, account := range []Account{account1, account2} {
+		_, err = store.UpsertAccountInterest(ctx, UpsertAccountInterestParams{
+			AccountID:      account.ID,
+			ProductCode:    product.Code,
+			AccruedThrough: InterestDay(time.Now()),
+		})
+		require.NoError(t, err)
+	}
+	_, err = store.UpsertFeeRevenueAccount(ctx, UpsertFeeRevenueAccountParams{Currency: util.USD, AccountID: revenue.ID})
+	require.NoError(t, err)
+
+	//the two accounts pay each other and a fee while the revenue account, which pays none, sends money back
+	n := 12
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		arg := TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10}
+		switch i % 3 {
+		case 1:
+			arg.FromAccountID, arg.ToAccountID = account2.ID, account1.ID
+		case 2:
+			arg.FromAccountID = revenue.ID
+		}
+
+		go func() {
+			_, err := store.TransferTx(ctx, arg)
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		err := <-errs
+		require.NoError(t, err)
+	}
+
+	//money only moved between the three accounts
+	var total int64
+	for _, id := range []int64{revenue.ID, account1.ID, account2.ID} {
+		account, err := store.GetAccount(ctx, id)
+		require.NoError(t, err)
+		total += account.Balance
+	}
+	require.Equal(t, int64(3_000), total)
+}
+
+func TestTransferTxLimits(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	_, err := testQueries.UpsertAccountLimit(context.Background(), UpsertAccountLimitParams{
+		AccountID:         account1.ID,
+		MaxSingleTransfer: 50,
+		MaxDailyAmount:    80,
+		MaxDailyCount:     3,
+	})
+	require.NoError(t, err)
+
+	transfer := func(amount int64) error {
+		_, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        amount,
+		})
+		return err
+	}
+
+	var limitErr *TransferLimitError
+
+	err = transfer(60)
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, LimitMaxSingleTransfer, limitErr.Limit)
+
+	require.NoError(t, transfer(50))
+
+	err = transfer(40)
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, LimitMaxDailyAmount, limitErr.Limit)
+	require.Equal(t, int64(90), limitErr.Actual)
+
+	require.NoError(t, transfer(10))
+	require.NoError(t, transfer(10))
+
+	err = transfer(1)
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, LimitMaxDailyCount, limitErr.Limit)
+
+	//only the three successful transfers moved money
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-70, updatedAccount1.Balance)
+}
+
+func TestTransferTxFrozenAccount(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	_, err := testQueries.UpdateAccountStatus(context.Background(), UpdateAccountStatusParams{
+		ID:     account2.ID,
+		Status: AccountStatusFrozen,
+	})
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+}
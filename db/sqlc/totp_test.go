@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomUserTOTP(t *testing.T, username string) UserTotp {
+	arg := UpsertUserTOTPParams{
+		Username: username,
+		Secret:   util.RandomString(32),
+	}
+
+	userTOTP, err := testQueries.UpsertUserTOTP(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, userTOTP.Username)
+	require.Equal(t, arg.Secret, userTOTP.Secret)
+	require.False(t, userTOTP.IsEnabled)
+	require.Zero(t, userTOTP.LastUsedStep)
+
+	return userTOTP
+}
+
+func TestEnableTOTPTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	createRandomUserTOTP(t, user.Username)
+
+	//enrolling again before confirming replaces the secret
+	userTOTP := createRandomUserTOTP(t, user.Username)
+
+	codeHashes := []string{util.HashSecret("code1"), util.HashSecret("code2")}
+	enabled, err := store.EnableTOTPTx(context.Background(), EnableTOTPTxParams{
+		Username:           user.Username,
+		Step:               100,
+		RecoveryCodeHashes: codeHashes,
+	})
+	require.NoError(t, err)
+	require.True(t, enabled.IsEnabled)
+	require.Equal(t, userTOTP.Secret, enabled.Secret)
+	require.Equal(t, int64(100), enabled.LastUsedStep)
+
+	//an enabled secret can't be replaced
+	_, err = testQueries.UpsertUserTOTP(context.Background(), UpsertUserTOTPParams{
+		Username: user.Username,
+		Secret:   util.RandomString(32),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	//recovery codes are single use
+	arg := UseRecoveryCodeParams{Username: user.Username, CodeHash: codeHashes[0]}
+	_, err = testQueries.UseRecoveryCode(context.Background(), arg)
+	require.NoError(t, err)
+	_, err = testQueries.UseRecoveryCode(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUseTOTPStep(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomUserTOTP(t, user.Username)
+
+	arg := UseTOTPStepParams{Step: 10, Username: user.Username}
+	userTOTP, err := testQueries.UseTOTPStep(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), userTOTP.LastUsedStep)
+
+	//the same or an earlier step can't be used again
+	_, err = testQueries.UseTOTPStep(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	arg.Step = 9
+	_, err = testQueries.UseTOTPStep(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ErrSecondFactorUsed is returned by the transfer transactions when the TOTP step they were
+// authorised with was used in the meantime, e.g. by a concurrent request with the same code
+var ErrSecondFactorUsed = errors.New("two-factor code was already used")
+
+// EnableTOTPTxParams contains the input parameters of the enable TOTP transaction.
+// Step is the time step of the code that confirmed the enrollment.
+type EnableTOTPTxParams struct {
+	Username           string
+	Step               int64
+	RecoveryCodeHashes []string
+}
+
+// EnableTOTPTx turns on two-factor authentication once the user proved they can generate codes,
+// and replaces any previous recovery codes with the new ones.
+// It returns sql.ErrNoRows when the confirmation code was already used.
+func (store *SQLStore) EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (UserTotp, error) {
+	var userTOTP UserTotp
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		userTOTP, err = enableTOTPTx(ctx, q, arg)
+		return err
+	})
+
+	return userTOTP, err
+}
+
+func enableTOTPTx(ctx context.Context, q Querier, arg EnableTOTPTxParams) (UserTotp, error) {
+	_, err := q.UseTOTPStep(ctx, UseTOTPStepParams{
+		Step:     arg.Step,
+		Username: arg.Username,
+	})
+	if err != nil {
+		return UserTotp{}, err
+	}
+
+	userTOTP, err := q.EnableUserTOTP(ctx, arg.Username)
+	if err != nil {
+		return userTOTP, err
+	}
+
+	err = q.DeleteRecoveryCodes(ctx, arg.Username)
+	if err != nil {
+		return userTOTP, err
+	}
+
+	for _, codeHash := range arg.RecoveryCodeHashes {
+		_, err = q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+			Username: arg.Username,
+			CodeHash: codeHash,
+		})
+		if err != nil {
+			return userTOTP, err
+		}
+	}
+	return userTOTP, nil
+}
+
+// useSecondFactor uses up the TOTP step a transfer was authorised with. It runs inside the transfer's
+// transaction, so a transfer that fails leaves the code usable for another attempt.
+func useSecondFactor(ctx context.Context, q Querier, step *UseTOTPStepParams) error {
+	if step == nil {
+		return nil
+	}
+
+	_, err := q.UseTOTPStep(ctx, *step)
+	if err == sql.ErrNoRows {
+		return ErrSecondFactorUsed
+	}
+	return err
+}
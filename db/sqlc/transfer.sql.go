@@ -7,26 +7,60 @@ package db
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 )
 
+const countTransfersBetween = `-- name: CountTransfersBetween :one
+SELECT COUNT(*) FROM transfers
+WHERE
+    from_account_id = $1 AND
+    to_account_id = $2
+`
+
+type CountTransfersBetweenParams struct {
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+}
+
+func (q *Queries) CountTransfersBetween(ctx context.Context, arg CountTransfersBetweenParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countTransfersBetween, arg.FromAccountID, arg.ToAccountID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  description,
+  reference,
+  metadata
 ) VALUES (
-  $1, $2, $3
-) RETURNING id, from_account_id, to_account_id, amount, created_at
+  $1, $2, $3, $4, $5, $6
+) RETURNING id, from_account_id, to_account_id, amount, created_at, description, reference, metadata, public_id
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64           `json:"from_account_id"`
+	ToAccountID   int64           `json:"to_account_id"`
+	Amount        int64           `json:"amount"`
+	Description   string          `json:"description"`
+	Reference     string          `json:"reference"`
+	Metadata      json.RawMessage `json:"metadata"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Description,
+		arg.Reference,
+		arg.Metadata,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -34,12 +68,43 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Description,
+		&i.Reference,
+		&i.Metadata,
+		&i.PublicID,
 	)
 	return i, err
 }
 
+const getOutgoingTransferTotals = `-- name: GetOutgoingTransferTotals :one
+SELECT
+  COALESCE(SUM(amount), 0)::bigint AS total_amount,
+  COUNT(*) AS transfer_count
+FROM transfers
+WHERE
+    from_account_id = $1 AND
+    created_at >= $2
+`
+
+type GetOutgoingTransferTotalsParams struct {
+	AccountID int64     `json:"account_id"`
+	Since     time.Time `json:"since"`
+}
+
+type GetOutgoingTransferTotalsRow struct {
+	TotalAmount   int64 `json:"total_amount"`
+	TransferCount int64 `json:"transfer_count"`
+}
+
+func (q *Queries) GetOutgoingTransferTotals(ctx context.Context, arg GetOutgoingTransferTotalsParams) (GetOutgoingTransferTotalsRow, error) {
+	row := q.db.QueryRowContext(ctx, getOutgoingTransferTotals, arg.AccountID, arg.Since)
+	var i GetOutgoingTransferTotalsRow
+	err := row.Scan(&i.TotalAmount, &i.TransferCount)
+	return i, err
+}
+
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, description, reference, metadata, public_id FROM transfers
 WHERE id = $1 LIMIT 1
 `
 
@@ -52,31 +117,66 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Description,
+		&i.Reference,
+		&i.Metadata,
+		&i.PublicID,
+	)
+	return i, err
+}
+
+const getTransferByPublicID = `-- name: GetTransferByPublicID :one
+SELECT id, from_account_id, to_account_id, amount, created_at, description, reference, metadata, public_id FROM transfers
+WHERE public_id = $1 LIMIT 1
+`
+
+func (q *Queries) GetTransferByPublicID(ctx context.Context, publicID string) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, getTransferByPublicID, publicID)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Description,
+		&i.Reference,
+		&i.Metadata,
+		&i.PublicID,
 	)
 	return i, err
 }
 
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, description, reference, metadata, public_id FROM transfers
 WHERE 
-    from_account_id = $1 OR
-    to_account_id = $2
+    (from_account_id = $1 OR
+    to_account_id = $2) AND
+    (description ILIKE $3 OR
+    reference = $4) AND
+    metadata @> $5
 ORDER BY id
-LIMIT $3
-OFFSET $4
+LIMIT $6
+OFFSET $7
 `
 
 type ListTransfersParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Limit         int32 `json:"limit"`
-	Offset        int32 `json:"offset"`
+	FromAccountID int64           `json:"from_account_id"`
+	ToAccountID   int64           `json:"to_account_id"`
+	Description   string          `json:"description"`
+	Reference     string          `json:"reference"`
+	Metadata      json.RawMessage `json:"metadata"`
+	Limit         int32           `json:"limit"`
+	Offset        int32           `json:"offset"`
 }
 
 func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
 	rows, err := q.db.QueryContext(ctx, listTransfers,
 		arg.FromAccountID,
 		arg.ToAccountID,
+		arg.Description,
+		arg.Reference,
+		arg.Metadata,
 		arg.Limit,
 		arg.Offset,
 	)
@@ -93,6 +193,10 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.Description,
+			&i.Reference,
+			&i.Metadata,
+			&i.PublicID,
 		); err != nil {
 			return nil, err
 		}
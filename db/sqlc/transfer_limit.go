@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Names of the limits reported in TransferLimitError
+const (
+	LimitMaxSingleTransfer = "max_single_transfer"
+	LimitMaxDailyAmount    = "max_daily_amount"
+	LimitMaxDailyCount     = "max_daily_count"
+)
+
+// TransferLimitError is returned by TransferTx when a transfer would exceed one of the limits of the source account
+type TransferLimitError struct {
+	// AccountID is the public id of the source account
+	AccountID string `json:"account_id"`
+	Limit     string `json:"limit"`
+	Max       int64  `json:"max"`
+	Actual    int64  `json:"actual"`
+}
+
+func (e *TransferLimitError) Error() string {
+	return fmt.Sprintf("account [%s] %s exceeded: %d > %d", e.AccountID, e.Limit, e.Actual, e.Max)
+}
+
+// startOfDay returns the UTC midnight that daily limits are counted from
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// checkTransferLimits must run after the source account row has been locked by the debit,
+// so the totals include every concurrent transfer that committed before us and the one just created.
+func checkTransferLimits(ctx context.Context, q Querier, account Account, amount int64) error {
+	limit, err := q.GetAccountLimit(ctx, account.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	if limit.MaxSingleTransfer > 0 && amount > limit.MaxSingleTransfer {
+		return &TransferLimitError{AccountID: account.PublicID, Limit: LimitMaxSingleTransfer, Max: limit.MaxSingleTransfer, Actual: amount}
+	}
+
+	if limit.MaxDailyAmount == 0 && limit.MaxDailyCount == 0 {
+		return nil
+	}
+
+	totals, err := q.GetOutgoingTransferTotals(ctx, GetOutgoingTransferTotalsParams{
+		AccountID: account.ID,
+		Since:     startOfDay(time.Now()),
+	})
+	if err != nil {
+		return err
+	}
+
+	if limit.MaxDailyAmount > 0 && totals.TotalAmount > limit.MaxDailyAmount {
+		return &TransferLimitError{AccountID: account.PublicID, Limit: LimitMaxDailyAmount, Max: limit.MaxDailyAmount, Actual: totals.TotalAmount}
+	}
+
+	if limit.MaxDailyCount > 0 && totals.TransferCount > limit.MaxDailyCount {
+		return &TransferLimitError{AccountID: account.PublicID, Limit: LimitMaxDailyCount, Max: limit.MaxDailyCount, Actual: totals.TransferCount}
+	}
+
+	return nil
+}
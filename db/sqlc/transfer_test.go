@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -14,6 +15,9 @@ func createRandomTransfer(t *testing.T, fromAccountID int64, toAccountID int64)
 		FromAccountID: fromAccountID,
 		ToAccountID:   toAccountID,
 		Amount:        util.RandomMoney(),
+		Description:   util.RandomString(12),
+		Reference:     util.RandomString(6),
+		Metadata:      json.RawMessage(`{}`),
 	}
 
 	transfer, err := testQueries.CreateTransfer(context.Background(), arg)
@@ -23,6 +27,8 @@ func createRandomTransfer(t *testing.T, fromAccountID int64, toAccountID int64)
 	require.Equal(t, arg.FromAccountID, transfer.FromAccountID)
 	require.Equal(t, arg.ToAccountID, transfer.ToAccountID)
 	require.Equal(t, arg.Amount, transfer.Amount)
+	require.Equal(t, arg.Description, transfer.Description)
+	require.Equal(t, arg.Reference, transfer.Reference)
 
 	require.NotZero(t, transfer.ID)
 	require.NotZero(t, transfer.CreatedAt)
@@ -64,6 +70,8 @@ func TestListTransfer(t *testing.T) {
 	arg := ListTransfersParams{
 		FromAccountID: account1.ID,
 		ToAccountID:   account1.ID,
+		Description:   "%",
+		Metadata:      json.RawMessage(`{}`),
 		Limit:         5,
 		Offset:        5,
 	}
@@ -0,0 +1,176 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Postgres error codes of transactions that failed only because of concurrent transactions
+// and succeed when they are simply run again
+const (
+	SerializationFailureCode = "40001"
+	DeadlockDetectedCode     = "40P01"
+)
+
+// RetryPolicy controls how often a transaction is retried and how long to wait in between.
+// The wait grows exponentially from BaseDelay up to MaxDelay and is jittered so that
+// transactions that conflicted once don't collide again.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// DefaultRetryPolicy is used by NewStore
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  10 * time.Millisecond,
+	MaxDelay:   500 * time.Millisecond,
+}
+
+// TxOptions are the options of the transactions started with a context, see WithTxOptions
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+}
+
+type txOptionsKey struct{}
+
+// WithTxOptions returns a context that makes the store run its transactions with the given options,
+// e.g. sql.LevelSerializable. Without it the database default isolation level is used.
+func WithTxOptions(ctx context.Context, opts TxOptions) context.Context {
+	return context.WithValue(ctx, txOptionsKey{}, opts)
+}
+
+func txOptionsFromContext(ctx context.Context) *sql.TxOptions {
+	opts, ok := ctx.Value(txOptionsKey{}).(TxOptions)
+	if !ok {
+		return nil
+	}
+	return &sql.TxOptions{Isolation: opts.Isolation}
+}
+
+// TxStats counts the transaction retries of a store since it was created
+type TxStats struct {
+	SerializationFailures int64 `json:"serialization_failures"`
+	Deadlocks             int64 `json:"deadlocks"`
+	// transactions that still failed after MaxRetries retries
+	Exhausted int64 `json:"exhausted"`
+}
+
+// Retries is the total number of retries
+func (stats TxStats) Retries() int64 {
+	return stats.SerializationFailures + stats.Deadlocks
+}
+
+// TxStats returns the retry counters of the store
+func (store *SQLStore) TxStats() TxStats {
+	return TxStats{
+		SerializationFailures: atomic.LoadInt64(&store.stats.SerializationFailures),
+		Deadlocks:             atomic.LoadInt64(&store.stats.Deadlocks),
+		Exhausted:             atomic.LoadInt64(&store.stats.Exhausted),
+	}
+}
+
+// retryableCode returns the postgres error code when err is worth retrying
+func retryableCode(err error) (string, bool) {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return "", false
+	}
+
+	code := string(pqErr.Code)
+	return code, code == SerializationFailureCode || code == DeadlockDetectedCode
+}
+
+// IsRetryableError reports whether err is a serialization failure or a deadlock,
+// i.e. a transaction that may succeed when tried again later
+func IsRetryableError(err error) bool {
+	_, ok := retryableCode(err)
+	return ok
+}
+
+// backoff returns how long to wait before the given retry, half of it is random
+func (policy RetryPolicy) backoff(retry int) time.Duration {
+	delay := policy.MaxDelay
+	if retry < 32 && policy.BaseDelay<<retry < policy.MaxDelay {
+		delay = policy.BaseDelay << retry
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retry runs fn until it succeeds, fails with an error that is not retryable or the policy is exhausted.
+// It returns the number of retries, the last error is returned unchanged so callers can still inspect it.
+func (store *SQLStore) retry(ctx context.Context, fn func() error) (int, error) {
+	for retries := 0; ; retries++ {
+		err := fn()
+		if err == nil {
+			return retries, nil
+		}
+
+		code, ok := retryableCode(err)
+		if !ok {
+			return retries, err
+		}
+
+		if retries >= store.retryPolicy.MaxRetries {
+			atomic.AddInt64(&store.stats.Exhausted, 1)
+			log.Printf("tx failed after %d retries: %v", retries, err)
+			return retries, err
+		}
+
+		if code == DeadlockDetectedCode {
+			atomic.AddInt64(&store.stats.Deadlocks, 1)
+		} else {
+			atomic.AddInt64(&store.stats.SerializationFailures, 1)
+		}
+
+		select {
+		case <-time.After(store.retryPolicy.backoff(retries)):
+		case <-ctx.Done():
+			return retries, fmt.Errorf("%w (giving up retry: %v)", err, ctx.Err())
+		}
+	}
+}
+
+// execTxRetry executes fn within a database transaction and runs it again when the transaction
+// hits a serialization failure or a deadlock. fn may therefore be called more than once and must
+// not have side effects outside the transaction that cannot be repeated.
+// It returns the number of retries it took.
+func (store *SQLStore) execTxRetry(ctx context.Context, fn func(*Queries) error) (int, error) {
+	return store.retry(ctx, func() error {
+		return store.runTx(ctx, fn)
+	})
+}
+
+// runTx executes fn within a single database transaction
+func (store *SQLStore) runTx(ctx context.Context, fn func(*Queries) error) error {
+	tx, err := store.db.BeginTx(ctx, txOptionsFromContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	q := New(tx)
+	err = fn(q)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("tx err: %w, rb err: %v", err, rbErr)
+		}
+		return err
+	}
+
+	//with serializable isolation the conflict can also be detected on commit
+	return tx.Commit()
+}
@@ -0,0 +1,178 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryTx(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+	testCases := []struct {
+		name          string
+		errs          []error
+		checkResult   func(t *testing.T, retries int, err error, stats TxStats)
+		expectedCalls int
+	}{
+		{
+			name: "NoError",
+			errs: []error{nil},
+			checkResult: func(t *testing.T, retries int, err error, stats TxStats) {
+				require.NoError(t, err)
+				require.Zero(t, retries)
+				require.Zero(t, stats.Retries())
+			},
+			expectedCalls: 1,
+		},
+		{
+			name: "SerializationFailureThenSuccess",
+			errs: []error{&pq.Error{Code: SerializationFailureCode}, nil},
+			checkResult: func(t *testing.T, retries int, err error, stats TxStats) {
+				require.NoError(t, err)
+				require.Equal(t, 1, retries)
+				require.Equal(t, int64(1), stats.SerializationFailures)
+			},
+			expectedCalls: 2,
+		},
+		{
+			name: "WrappedDeadlockThenSuccess",
+			errs: []error{fmt.Errorf("tx err: %w", &pq.Error{Code: DeadlockDetectedCode}), nil},
+			checkResult: func(t *testing.T, retries int, err error, stats TxStats) {
+				require.NoError(t, err)
+				require.Equal(t, 1, retries)
+				require.Equal(t, int64(1), stats.Deadlocks)
+			},
+			expectedCalls: 2,
+		},
+		{
+			name: "Exhausted",
+			errs: []error{
+				&pq.Error{Code: DeadlockDetectedCode},
+				&pq.Error{Code: SerializationFailureCode},
+				&pq.Error{Code: SerializationFailureCode},
+			},
+			checkResult: func(t *testing.T, retries int, err error, stats TxStats) {
+				var pqErr *pq.Error
+				require.ErrorAs(t, err, &pqErr)
+				require.Equal(t, SerializationFailureCode, string(pqErr.Code))
+				require.Equal(t, 2, retries)
+				require.Equal(t, int64(1), stats.Exhausted)
+				require.Equal(t, int64(2), stats.Retries())
+			},
+			expectedCalls: 3,
+		},
+		{
+			name: "NotRetryable",
+			errs: []error{&pq.Error{Code: "23505"}},
+			checkResult: func(t *testing.T, retries int, err error, stats TxStats) {
+				require.Error(t, err)
+				require.Zero(t, retries)
+				require.Zero(t, stats.Retries())
+			},
+			expectedCalls: 1,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			store := &SQLStore{retryPolicy: policy}
+
+			calls := 0
+			retries, err := store.retry(context.Background(), func() error {
+				err := tc.errs[calls]
+				calls++
+				return err
+			})
+
+			require.Equal(t, tc.expectedCalls, calls)
+			tc.checkResult(t, retries, err, store.TxStats())
+		})
+	}
+}
+
+func TestRetryTxContextCanceled(t *testing.T) {
+	store := &SQLStore{retryPolicy: RetryPolicy{MaxRetries: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	retries, err := store.retry(ctx, func() error {
+		return &pq.Error{Code: SerializationFailureCode}
+	})
+	require.Zero(t, retries)
+	require.Contains(t, err.Error(), context.Canceled.Error())
+
+	var pqErr *pq.Error
+	require.ErrorAs(t, err, &pqErr)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	for retry := 0; retry < 40; retry++ {
+		delay := policy.backoff(retry)
+
+		ceiling := policy.MaxDelay
+		if retry < 3 {
+			ceiling = policy.BaseDelay << retry
+		}
+		require.GreaterOrEqual(t, delay, ceiling/2)
+		require.LessOrEqual(t, delay, ceiling)
+	}
+}
+
+func TestTransferTxSerializable(t *testing.T) {
+	store := NewStoreWithRetry(testDB, RetryPolicy{MaxRetries: 20, BaseDelay: time.Millisecond, MaxDelay: 50 * time.Millisecond})
+	ctx := WithTxOptions(context.Background(), TxOptions{Isolation: sql.LevelSerializable})
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	//transfers in both directions make conflicts between the transactions very likely
+	n := 10
+	amount := int64(10)
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		fromAccountID, toAccountID := account1.ID, account2.ID
+		if i%2 == 1 {
+			fromAccountID, toAccountID = account2.ID, account1.ID
+		}
+
+		go func() {
+			_, err := store.TransferTx(ctx, TransferTxParams{
+				FromAccountID: fromAccountID,
+				ToAccountID:   toAccountID,
+				Amount:        amount,
+			})
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		err := <-errs
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			t.Logf("transfer failed with %s", pqErr.Code)
+		}
+		require.NoError(t, err)
+	}
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+	require.Zero(t, store.TxStats().Exhausted)
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// CreateUserTxParams contains the input parameters of the create user transaction
+type CreateUserTxParams struct {
+	CreateUserParams
+	VerifyEmail CreateVerifyEmailParams
+}
+
+// CreateUserTxResult is the result of the create user transaction
+type CreateUserTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// CreateUserTx creates a user together with the email verification record.
+// Callers send the verification message once it returns: the transaction may run
+// more than once when it is retried, and mail can't be taken back.
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = createUserTx(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+func createUserTx(ctx context.Context, q Querier, arg CreateUserTxParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+	var err error
+
+	result.User, err = q.CreateUser(ctx, arg.CreateUserParams)
+	if err != nil {
+		return result, err
+	}
+
+	verifyEmailArg := arg.VerifyEmail
+	verifyEmailArg.Username = result.User.Username
+	verifyEmailArg.Email = result.User.Email
+
+	result.VerifyEmail, err = q.CreateVerifyEmail(ctx, verifyEmailArg)
+	return result, err
+}
+
+// VerifyEmailTxParams contains the input parameters of the verify email transaction
+type VerifyEmailTxParams struct {
+	EmailID    int64
+	SecretCode string
+}
+
+// VerifyEmailTxResult is the result of the verify email transaction
+type VerifyEmailTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// VerifyEmailTx marks the verification code as used and the user's email as verified.
+// It returns sql.ErrNoRows when the code is wrong, expired or already used.
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error) {
+	var result VerifyEmailTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = verifyEmailTx(ctx, q, arg)
+		return err
+	})
+
+	return result, err
+}
+
+func verifyEmailTx(ctx context.Context, q Querier, arg VerifyEmailTxParams) (VerifyEmailTxResult, error) {
+	var result VerifyEmailTxResult
+	var err error
+
+	result.VerifyEmail, err = q.UpdateVerifyEmail(ctx, UpdateVerifyEmailParams{
+		ID:         arg.EmailID,
+		SecretCode: arg.SecretCode,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	//the email must still be the one the code was sent to
+	result.User, err = q.UpdateUserEmailVerified(ctx, UpdateUserEmailVerifiedParams{
+		Username: result.VerifyEmail.Username,
+		Email:    result.VerifyEmail.Email,
+	})
+	return result, err
+}
+
+// ResetPasswordTxParams contains the input parameters of the reset password transaction
+type ResetPasswordTxParams struct {
+	TokenHash         string
+	HashedPassword    string
+	PasswordChangedAt time.Time
+}
+
+// ResetPasswordTx consumes the reset token and sets the new password.
+// Any other outstanding reset token of the user is invalidated as well.
+// It returns sql.ErrNoRows when the token is wrong, expired or already used.
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		user, err = resetPasswordTx(ctx, q, arg)
+		return err
+	})
+
+	return user, err
+}
+
+func resetPasswordTx(ctx context.Context, q Querier, arg ResetPasswordTxParams) (User, error) {
+	resetPassword, err := q.UseResetPassword(ctx, arg.TokenHash)
+	if err != nil {
+		return User{}, err
+	}
+
+	user, err := q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+		Username:          resetPassword.Username,
+		HashedPassword:    arg.HashedPassword,
+		PasswordChangedAt: arg.PasswordChangedAt,
+	})
+	if err != nil {
+		return user, err
+	}
+
+	return user, q.InvalidateResetPasswords(ctx, resetPassword.Username)
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomUserTx(t *testing.T, store Store) CreateUserTxResult {
+	arg := CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       util.RandomOwnerName(),
+			HashedPassword: "secret",
+			FullName:       util.RandomOwnerName(),
+			Email:          util.RandomEmail(),
+		},
+		VerifyEmail: CreateVerifyEmailParams{
+			SecretCode: util.RandomString(32),
+			ExpiredAt:  time.Now().Add(time.Hour),
+		},
+	}
+
+	result, err := store.CreateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, arg.Username, result.User.Username)
+	require.False(t, result.User.IsEmailVerified)
+	require.Equal(t, arg.Username, result.VerifyEmail.Username)
+	require.Equal(t, arg.Email, result.VerifyEmail.Email)
+	require.Equal(t, arg.VerifyEmail.SecretCode, result.VerifyEmail.SecretCode)
+	require.False(t, result.VerifyEmail.IsUsed)
+
+	return result
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	store := NewStore(testDB)
+	created := createRandomUserTx(t, store)
+
+	arg := VerifyEmailTxParams{
+		EmailID:    created.VerifyEmail.ID,
+		SecretCode: created.VerifyEmail.SecretCode,
+	}
+
+	_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{EmailID: arg.EmailID, SecretCode: "wrong"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	result, err := store.VerifyEmailTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result.VerifyEmail.IsUsed)
+	require.True(t, result.User.IsEmailVerified)
+
+	//codes can only be used once
+	_, err = store.VerifyEmailTx(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestVerifyEmailExpired(t *testing.T) {
+	user := createRandomUser(t)
+
+	verifyEmail, err := testQueries.CreateVerifyEmail(context.Background(), CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: util.RandomString(32),
+		ExpiredAt:  time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.UpdateVerifyEmail(context.Background(), UpdateVerifyEmailParams{
+		ID:         verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
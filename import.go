@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kingsleyocran/simple_bank_bankend/api"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/importer"
+	"github.com/kingsleyocran/simple_bank_bankend/mail"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+)
+
+const importUsage = "usage: import [-dry-run] [-chunk-size N] users|accounts|transfers FILE"
+
+//runImport implements the import subcommand, the report is printed as JSON on stdout.
+//Running it again with the same file resumes after the last committed chunk.
+func runImport(config util.Config, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	dryRun := flags.Bool("dry-run", false, "validate the file and report the errors without importing")
+	chunkSize := flags.Int("chunk-size", importer.DefaultChunkSize, "number of rows committed per transaction")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		return errors.New(importUsage)
+	}
+	if config.DB.Driver == db.MemoryDriver {
+		return errors.New("the in-memory store would lose the imported data on exit")
+	}
+
+	file, err := os.Open(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	store, err := newStore(config)
+	if err != nil {
+		return err
+	}
+
+	mailer, err := mail.NewMailer(config)
+	if err != nil {
+		return err
+	}
+
+	report, err := importer.New(store).Import(context.Background(), file, importer.Options{
+		Kind:                flags.Arg(0),
+		DryRun:              *dryRun,
+		ChunkSize:           *chunkSize,
+		CreatedBy:           "cli",
+		VerifyEmailDuration: config.Auth.VerifyEmailDuration,
+		AfterCreateUser:     api.VerifyEmailSender(config, mailer),
+	})
+	if err != nil {
+		return err
+	}
+
+	out := json.NewEncoder(os.Stdout)
+	out.SetIndent("", "  ")
+	if err := out.Encode(report); err != nil {
+		return err
+	}
+
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("the file has %d errors, nothing after the last committed chunk was imported", len(report.Errors))
+	}
+	return nil
+}
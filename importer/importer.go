@@ -0,0 +1,277 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+)
+
+// Kinds of files that can be imported. A branch is onboarded by importing
+// its users first, then their accounts and finally the opening transfers.
+const (
+	KindUsers     = "users"
+	KindAccounts  = "accounts"
+	KindTransfers = "transfers"
+)
+
+// DefaultChunkSize is the number of rows committed per transaction
+const DefaultChunkSize = 100
+
+// ErrInvalidFile is returned when the file itself cannot be read as CSV of the given kind
+var ErrInvalidFile = errors.New("invalid import file")
+
+// columns lists the header every kind of file must have, in any order
+var columns = map[string][]string{
+	KindUsers:     {"username", "full_name", "email", "password"},
+	KindAccounts:  {"owner_name", "currency"},
+	KindTransfers: {"from_account_id", "owner_name", "currency", "amount"},
+}
+
+// Options control a single import run
+type Options struct {
+	Kind      string
+	DryRun    bool
+	ChunkSize int
+	// CreatedBy is recorded on the import job, the admin's username or "cli"
+	CreatedBy           string
+	VerifyEmailDuration time.Duration
+	// AfterCreateUser is called for every imported user once its chunk is committed,
+	// e.g. to send the verification email. Failures are logged and do not stop the import.
+	AfterCreateUser func(user db.User, verifyEmail db.VerifyEmail) error
+}
+
+// RowError describes why a row was rejected, Line is the line in the CSV file
+type RowError struct {
+	Line    int    `json:"line"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report is the outcome of an import run. Rows committed by an earlier run of the same file
+// are counted as skipped, a run with errors commits nothing beyond the chunks before the failing row.
+type Report struct {
+	Kind          string     `json:"kind"`
+	DryRun        bool       `json:"dry_run"`
+	JobID         int64      `json:"job_id,omitempty"`
+	Status        string     `json:"status,omitempty"`
+	TotalRows     int        `json:"total_rows"`
+	SkippedRows   int        `json:"skipped_rows"`
+	ValidRows     int        `json:"valid_rows"`
+	CommittedRows int        `json:"committed_rows"`
+	Errors        []RowError `json:"errors"`
+}
+
+// Importer loads CSV files into the store with the same validation rules as the API
+type Importer struct {
+	store    db.Store
+	validate *validator.Validate
+}
+
+// New creates an importer writing to store
+func New(store db.Store) *Importer {
+	validate := validator.New()
+	validate.SetTagName("binding")
+	util.RegisterValidators(validate)
+
+	//report the CSV column instead of the Go field name
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("csv")
+	})
+
+	return &Importer{
+		store:    store,
+		validate: validate,
+	}
+}
+
+// record is one data row of the file keyed by column
+type record struct {
+	line   int
+	fields map[string]string
+}
+
+// Import validates every row that was not committed yet and, unless it is a dry run and all rows are valid,
+// commits them in chunks. Importing the same file again resumes after the last committed chunk.
+func (importer *Importer) Import(ctx context.Context, r io.Reader, opts Options) (Report, error) {
+	report := Report{
+		Kind:   opts.Kind,
+		DryRun: opts.DryRun,
+		Errors: []RowError{},
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return report, err
+	}
+	records, err := parse(data, opts.Kind)
+	if err != nil {
+		return report, err
+	}
+	report.TotalRows = len(records)
+
+	hash := fileHash(data)
+
+	job, err := importer.store.GetImportJobByHash(ctx, db.GetImportJobByHashParams{
+		Kind:     opts.Kind,
+		FileHash: hash,
+	})
+	if err != nil && err != sql.ErrNoRows {
+		return report, err
+	}
+	resumed := err == nil
+	if resumed {
+		report.JobID = job.ID
+		report.Status = job.Status
+		report.SkippedRows = int(job.CommittedRows)
+		records = records[job.CommittedRows:]
+	}
+
+	rows, err := importer.validateRows(ctx, opts.Kind, records, &report)
+	if err != nil {
+		return report, err
+	}
+	report.ValidRows = len(rows)
+	if len(report.Errors) > 0 || opts.DryRun || len(records) == 0 {
+		return report, nil
+	}
+
+	if !resumed {
+		job, err = importer.store.CreateImportJob(ctx, db.CreateImportJobParams{
+			Kind:      opts.Kind,
+			FileHash:  hash,
+			TotalRows: int64(report.TotalRows),
+			CreatedBy: opts.CreatedBy,
+		})
+		if err != nil {
+			return report, fmt.Errorf("cannot create import job: %w", err)
+		}
+		report.JobID = job.ID
+		report.Status = job.Status
+	}
+
+	for start := 0; start < len(rows); start += opts.ChunkSize {
+		end := start + opts.ChunkSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		arg, err := importer.chunk(opts, rows[start:end])
+		if err != nil {
+			return report, err
+		}
+		arg.JobID = job.ID
+		arg.FirstRow = int64(report.SkippedRows + start)
+
+		result, err := importer.store.ImportTx(ctx, arg)
+		if err != nil {
+			var itemErr *db.BatchItemError
+			if errors.As(err, &itemErr) {
+				report.Errors = append(report.Errors, RowError{
+					Line:    rows[start+itemErr.Index].line,
+					Message: itemErr.Err.Error(),
+				})
+				return report, nil
+			}
+			return report, err
+		}
+
+		report.CommittedRows += end - start
+		report.Status = result.Job.Status
+		importer.afterCreateUsers(opts, result.Users)
+	}
+
+	return report, nil
+}
+
+func (importer *Importer) afterCreateUsers(opts Options, users []db.CreateUserTxResult) {
+	if opts.AfterCreateUser == nil {
+		return
+	}
+	for _, user := range users {
+		if err := opts.AfterCreateUser(user.User, user.VerifyEmail); err != nil {
+			log.Printf("import: after create user %s: %v", user.User.Username, err)
+		}
+	}
+}
+
+// fileHash identifies a file, importing the same content again resumes its job
+func fileHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// parse reads the header and the data rows, the columns must match the kind exactly
+func parse(data []byte, kind string) ([]record, error) {
+	want, ok := columns[kind]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidFile, kind)
+	}
+
+	reader := csv.NewReader(strings.NewReader(string(data)))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: the file is empty", ErrInvalidFile)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	if !sameColumns(header, want) {
+		return nil, fmt.Errorf("%w: %s files need the columns %s, got %s",
+			ErrInvalidFile, kind, strings.Join(want, ","), strings.Join(header, ","))
+	}
+
+	var records []record
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		rec := record{line: line, fields: make(map[string]string, len(header))}
+		for i, column := range header {
+			rec.fields[column] = strings.TrimSpace(fields[i])
+		}
+		records = append(records, rec)
+	}
+}
+
+func sameColumns(header []string, want []string) bool {
+	if len(header) != len(want) {
+		return false
+	}
+	seen := make(map[string]bool, len(header))
+	for _, column := range header {
+		seen[column] = true
+	}
+	for _, column := range want {
+		if !seen[column] {
+			return false
+		}
+	}
+	return true
+}
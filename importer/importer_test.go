@@ -0,0 +1,195 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func usersCSV(usernames ...string) string {
+	var b strings.Builder
+	b.WriteString("username,full_name,email,password\n")
+	for _, username := range usernames {
+		fmt.Fprintf(&b, "%s,%s Doe,%s@email.com,secret\n", username, username, username)
+	}
+	return b.String()
+}
+
+func TestImportUsers(t *testing.T) {
+	store := db.NewMemoryStore()
+	importer := New(store)
+
+	var sent []string
+	opts := Options{
+		Kind:      KindUsers,
+		ChunkSize: 2,
+		CreatedBy: "test",
+		AfterCreateUser: func(user db.User, verifyEmail db.VerifyEmail) error {
+			sent = append(sent, verifyEmail.Email)
+			return nil
+		},
+	}
+
+	report, err := importer.Import(context.Background(), strings.NewReader(usersCSV("alice", "bob", "carol")), opts)
+	require.NoError(t, err)
+	require.Empty(t, report.Errors)
+	require.Equal(t, 3, report.TotalRows)
+	require.Equal(t, 3, report.CommittedRows)
+	require.Equal(t, db.ImportJobCompleted, report.Status)
+	require.Len(t, sent, 3)
+
+	user, err := store.GetUser(context.Background(), "bob")
+	require.NoError(t, err)
+	require.Equal(t, "bob@email.com", user.Email)
+	require.NoError(t, util.CheckPassword("secret", user.HashedPassword))
+
+	//the same file again has nothing left to do
+	report, err = importer.Import(context.Background(), strings.NewReader(usersCSV("alice", "bob", "carol")), opts)
+	require.NoError(t, err)
+	require.Empty(t, report.Errors)
+	require.Equal(t, 3, report.SkippedRows)
+	require.Zero(t, report.CommittedRows)
+}
+
+func TestImportDryRun(t *testing.T) {
+	store := db.NewMemoryStore()
+	importer := New(store)
+
+	file := usersCSV("alice", "bob") + "bad-name,Bad Name,not-an-email,123\n" + "alice,Alice Again,other@email.com,secret\n"
+
+	report, err := importer.Import(context.Background(), strings.NewReader(file), Options{Kind: KindUsers, DryRun: true})
+	require.NoError(t, err)
+	require.Equal(t, 4, report.TotalRows)
+	require.Equal(t, 2, report.ValidRows)
+	require.Zero(t, report.CommittedRows)
+
+	fields := map[string]bool{}
+	for _, rowErr := range report.Errors {
+		fields[fmt.Sprintf("%d:%s", rowErr.Line, rowErr.Field)] = true
+	}
+	require.Equal(t, map[string]bool{
+		"4:username": true,
+		"4:email":    true,
+		"4:password": true,
+		"5:username": true,
+	}, fields)
+
+	//a dry run never writes
+	_, err = store.GetUser(context.Background(), "alice")
+	require.Error(t, err)
+
+	//nor does a real run of a file with errors
+	report, err = importer.Import(context.Background(), strings.NewReader(file), Options{Kind: KindUsers})
+	require.NoError(t, err)
+	require.NotEmpty(t, report.Errors)
+	require.Zero(t, report.CommittedRows)
+	_, err = store.GetUser(context.Background(), "alice")
+	require.Error(t, err)
+}
+
+func TestImportAccountsAndTransfers(t *testing.T) {
+	store := db.NewMemoryStore()
+	importer := New(store)
+	ctx := context.Background()
+
+	_, err := importer.Import(ctx, strings.NewReader(usersCSV("branch", "alice", "bob")), Options{Kind: KindUsers})
+	require.NoError(t, err)
+
+	funding, err := store.CreateAccount(ctx, db.CreateAccountParams{OwnerName: "branch", Currency: util.USD, Balance: 100})
+	require.NoError(t, err)
+
+	accounts := "owner_name,currency\nalice,USD\nbob,USD\nbob,EUR\n"
+	report, err := importer.Import(ctx, strings.NewReader(accounts), Options{Kind: KindAccounts})
+	require.NoError(t, err)
+	require.Empty(t, report.Errors)
+	require.Equal(t, 3, report.CommittedRows)
+
+	//accounts that now exist, unknown owners and duplicates are rejected
+	report, err = importer.Import(ctx, strings.NewReader("owner_name,currency\nalice,USD\nnobody,USD\nalice,EUR\nalice,EUR\n"), Options{Kind: KindAccounts, DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, report.Errors, 3)
+	require.Equal(t, 1, report.ValidRows)
+
+	//the funding account covers each transfer but not all of them together
+	transfers := fmt.Sprintf("from_account_id,owner_name,currency,amount\n%d,alice,USD,60\n%d,bob,USD,60\n", funding.ID, funding.ID)
+	report, err = importer.Import(ctx, strings.NewReader(transfers), Options{Kind: KindTransfers})
+	require.NoError(t, err)
+	require.Len(t, report.Errors, 1)
+	require.Equal(t, 3, report.Errors[0].Line)
+	require.Equal(t, "amount", report.Errors[0].Field)
+
+	transfers = fmt.Sprintf("from_account_id,owner_name,currency,amount\n%d,alice,USD,60\n%d,bob,USD,40\n", funding.ID, funding.ID)
+	report, err = importer.Import(ctx, strings.NewReader(transfers), Options{Kind: KindTransfers})
+	require.NoError(t, err)
+	require.Empty(t, report.Errors)
+	require.Equal(t, 2, report.CommittedRows)
+
+	funding, err = store.GetAccount(ctx, funding.ID)
+	require.NoError(t, err)
+	require.Zero(t, funding.Balance)
+}
+
+func TestImportResume(t *testing.T) {
+	store := db.NewMemoryStore()
+	importer := New(store)
+	ctx := context.Background()
+
+	file := usersCSV("alice", "bob", "carol", "dave")
+
+	//an earlier run committed the first chunk and stopped
+	job, err := store.CreateImportJob(ctx, db.CreateImportJobParams{
+		Kind:      KindUsers,
+		FileHash:  fileHash([]byte(file)),
+		TotalRows: 4,
+		CreatedBy: "test",
+	})
+	require.NoError(t, err)
+	_, err = store.ImportTx(ctx, db.ImportTxParams{
+		JobID: job.ID,
+		Users: []db.CreateUserTxParams{
+			{CreateUserParams: db.CreateUserParams{Username: "alice", HashedPassword: "x", FullName: "Alice", Email: "alice@email.com"}},
+			{CreateUserParams: db.CreateUserParams{Username: "bob", HashedPassword: "x", FullName: "Bob", Email: "bob@email.com"}},
+		},
+	})
+	require.NoError(t, err)
+
+	report, err := importer.Import(ctx, strings.NewReader(file), Options{Kind: KindUsers, ChunkSize: 2})
+	require.NoError(t, err)
+	require.Empty(t, report.Errors)
+	require.Equal(t, job.ID, report.JobID)
+	require.Equal(t, 2, report.SkippedRows)
+	require.Equal(t, 2, report.CommittedRows)
+	require.Equal(t, db.ImportJobCompleted, report.Status)
+
+	_, err = store.GetUser(ctx, "dave")
+	require.NoError(t, err)
+}
+
+func TestImportInvalidFile(t *testing.T) {
+	importer := New(db.NewMemoryStore())
+
+	testCases := []struct {
+		name string
+		kind string
+		file string
+	}{
+		{name: "Empty", kind: KindUsers, file: ""},
+		{name: "WrongColumns", kind: KindAccounts, file: "owner,currency\nalice,USD\n"},
+		{name: "UnknownKind", kind: "loans", file: "owner_name,currency\n"},
+		{name: "RaggedRow", kind: KindAccounts, file: "owner_name,currency\nalice\n"},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := importer.Import(context.Background(), strings.NewReader(tc.file), Options{Kind: tc.kind})
+			require.ErrorIs(t, err, ErrInvalidFile)
+		})
+	}
+}
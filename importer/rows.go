@@ -0,0 +1,347 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+)
+
+//The row types carry the same binding rules as the matching API requests
+
+type userRow struct {
+	Username string `csv:"username" binding:"required,alphanum"`
+	Password string `csv:"password" binding:"required,min=6"`
+	FullName string `csv:"full_name" binding:"required"`
+	Email    string `csv:"email" binding:"required,email"`
+}
+
+type accountRow struct {
+	OwnerName string `csv:"owner_name" binding:"required,alphanum"`
+	Currency  string `csv:"currency" binding:"required,currency"`
+}
+
+//transferRow moves money from an existing account, usually the branch's funding account,
+//to the account the owner holds in the currency
+type transferRow struct {
+	FromAccountID int64  `csv:"from_account_id" binding:"required,min=1"`
+	OwnerName     string `csv:"owner_name" binding:"required,alphanum"`
+	Currency      string `csv:"currency" binding:"required,currency"`
+	Amount        int64  `csv:"amount" binding:"required,gt=0"`
+}
+
+//row is a validated row ready to be committed
+type row struct {
+	line     int
+	user     userRow
+	account  db.CreateAccountParams
+	transfer db.TransferTxParams
+}
+
+//validation keeps what was seen in earlier rows of the file, rows are checked
+//against the store and against each other since none of them is committed yet
+type validation struct {
+	importer  *Importer
+	report    *Report
+	usernames map[string]bool
+	emails    map[string]bool
+	owners    map[string][]db.Account
+	balances  map[int64]int64
+}
+
+//validateRows checks every record and adds an error to the report for each problem found.
+//The error return is only for failures of the store.
+func (importer *Importer) validateRows(ctx context.Context, kind string, records []record, report *Report) ([]row, error) {
+	v := &validation{
+		importer:  importer,
+		report:    report,
+		usernames: make(map[string]bool),
+		emails:    make(map[string]bool),
+		owners:    make(map[string][]db.Account),
+		balances:  make(map[int64]int64),
+	}
+
+	rows := make([]row, 0, len(records))
+	for _, rec := range records {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var r row
+		var ok bool
+		var err error
+
+		switch kind {
+		case KindUsers:
+			r, ok, err = v.user(ctx, rec)
+		case KindAccounts:
+			r, ok, err = v.account(ctx, rec)
+		case KindTransfers:
+			r, ok, err = v.transfer(ctx, rec)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			rows = append(rows, r)
+		}
+	}
+	return rows, nil
+}
+
+func (v *validation) fail(line int, field string, format string, args ...interface{}) {
+	v.report.Errors = append(v.report.Errors, RowError{
+		Line:    line,
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+//check runs the binding rules and reports one error per invalid field
+func (v *validation) check(rec record, s interface{}) bool {
+	err := v.importer.validate.Struct(s)
+	if err == nil {
+		return true
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		v.fail(rec.line, "", "%v", err)
+		return false
+	}
+	for _, fieldErr := range fieldErrs {
+		v.fail(rec.line, fieldErr.Field(), "%q failed on the '%s' rule", rec.fields[fieldErr.Field()], fieldErr.Tag())
+	}
+	return false
+}
+
+func (v *validation) user(ctx context.Context, rec record) (row, bool, error) {
+	r := row{
+		line: rec.line,
+		user: userRow{
+			Username: rec.fields["username"],
+			Password: rec.fields["password"],
+			FullName: rec.fields["full_name"],
+			Email:    rec.fields["email"],
+		},
+	}
+	if !v.check(rec, r.user) {
+		return r, false, nil
+	}
+
+	if v.usernames[r.user.Username] {
+		v.fail(rec.line, "username", "username %s appears more than once in the file", r.user.Username)
+		return r, false, nil
+	}
+	v.usernames[r.user.Username] = true
+
+	email := strings.ToLower(r.user.Email)
+	if v.emails[email] {
+		v.fail(rec.line, "email", "email %s appears more than once in the file", r.user.Email)
+		return r, false, nil
+	}
+	v.emails[email] = true
+
+	_, err := v.importer.store.GetUser(ctx, r.user.Username)
+	if err == nil {
+		v.fail(rec.line, "username", "user %s already exists", r.user.Username)
+		return r, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return r, false, err
+	}
+
+	return r, true, nil
+}
+
+func (v *validation) account(ctx context.Context, rec record) (row, bool, error) {
+	input := accountRow{
+		OwnerName: rec.fields["owner_name"],
+		Currency:  rec.fields["currency"],
+	}
+	r := row{line: rec.line}
+	if !v.check(rec, input) {
+		return r, false, nil
+	}
+
+	_, err := v.importer.store.GetUser(ctx, input.OwnerName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			v.fail(rec.line, "owner_name", "user %s does not exist, import the users first", input.OwnerName)
+			return r, false, nil
+		}
+		return r, false, err
+	}
+
+	_, found, err := v.ownerAccount(ctx, input.OwnerName, input.Currency)
+	if err != nil {
+		return r, false, err
+	}
+	if found {
+		v.fail(rec.line, "currency", "user %s already has a %s account", input.OwnerName, input.Currency)
+		return r, false, nil
+	}
+
+	//later rows of the file see this account as taken
+	r.account = db.CreateAccountParams{
+		OwnerName: input.OwnerName,
+		Currency:  input.Currency,
+		Balance:   0,
+	}
+	v.owners[input.OwnerName] = append(v.owners[input.OwnerName], db.Account{
+		OwnerName: input.OwnerName,
+		Currency:  input.Currency,
+	})
+
+	return r, true, nil
+}
+
+func (v *validation) transfer(ctx context.Context, rec record) (row, bool, error) {
+	r := row{line: rec.line}
+
+	input := transferRow{
+		OwnerName: rec.fields["owner_name"],
+		Currency:  rec.fields["currency"],
+	}
+	var err error
+	if input.FromAccountID, err = parseInt(rec.fields["from_account_id"]); err != nil {
+		v.fail(rec.line, "from_account_id", "%q is not a whole number", rec.fields["from_account_id"])
+		return r, false, nil
+	}
+	if input.Amount, err = parseInt(rec.fields["amount"]); err != nil {
+		v.fail(rec.line, "amount", "%q is not a whole number", rec.fields["amount"])
+		return r, false, nil
+	}
+	if !v.check(rec, input) {
+		return r, false, nil
+	}
+
+	fromAccount, err := v.importer.store.GetAccount(ctx, input.FromAccountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			v.fail(rec.line, "from_account_id", "account %d does not exist", input.FromAccountID)
+			return r, false, nil
+		}
+		return r, false, err
+	}
+	if fromAccount.Currency != input.Currency {
+		v.fail(rec.line, "currency", "account %d currency mismatch: %s vs %s", fromAccount.ID, fromAccount.Currency, input.Currency)
+		return r, false, nil
+	}
+	if fromAccount.Status == db.AccountStatusFrozen {
+		v.fail(rec.line, "from_account_id", "account %d is frozen", fromAccount.ID)
+		return r, false, nil
+	}
+
+	toAccount, found, err := v.ownerAccount(ctx, input.OwnerName, input.Currency)
+	if err != nil {
+		return r, false, err
+	}
+	if !found {
+		v.fail(rec.line, "owner_name", "user %s has no %s account, import the accounts first", input.OwnerName, input.Currency)
+		return r, false, nil
+	}
+	if toAccount.ID == fromAccount.ID {
+		v.fail(rec.line, "owner_name", "account %d cannot transfer to itself", fromAccount.ID)
+		return r, false, nil
+	}
+	if toAccount.Status == db.AccountStatusFrozen {
+		v.fail(rec.line, "owner_name", "account %d is frozen", toAccount.ID)
+		return r, false, nil
+	}
+
+	//the funding account has to cover every transfer of the file, not just each one on its own
+	balance, seen := v.balances[fromAccount.ID]
+	if !seen {
+		balance = fromAccount.Balance
+	}
+	if balance < input.Amount {
+		v.fail(rec.line, "amount", "account %d balance %d is below the amount %d", fromAccount.ID, balance, input.Amount)
+		return r, false, nil
+	}
+	v.balances[fromAccount.ID] = balance - input.Amount
+
+	r.transfer = db.TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        input.Amount,
+	}
+	return r, true, nil
+}
+
+//ownerAccount finds the account the owner holds in the currency, the accounts of an owner are loaded once
+func (v *validation) ownerAccount(ctx context.Context, owner string, currency string) (db.Account, bool, error) {
+	accounts, loaded := v.owners[owner]
+	if !loaded {
+		var err error
+		//an owner has at most one account per currency
+		accounts, err = v.importer.store.ListAccountsByOwner(ctx, db.ListAccountsByOwnerParams{
+			OwnerName: owner,
+			Limit:     100,
+			Offset:    0,
+		})
+		if err != nil {
+			return db.Account{}, false, err
+		}
+		v.owners[owner] = accounts
+	}
+
+	for _, account := range accounts {
+		if account.Currency == currency {
+			return account, true, nil
+		}
+	}
+	return db.Account{}, false, nil
+}
+
+//chunk builds the ImportTx arguments for the rows, passwords are only hashed for rows about to be committed
+func (importer *Importer) chunk(opts Options, rows []row) (db.ImportTxParams, error) {
+	var arg db.ImportTxParams
+
+	for _, r := range rows {
+		switch opts.Kind {
+		case KindUsers:
+			hashedPassword, err := util.HashPassword(r.user.Password)
+			if err != nil {
+				return arg, err
+			}
+			secretCode, err := util.GenerateSecret(32)
+			if err != nil {
+				return arg, err
+			}
+
+			arg.Users = append(arg.Users, db.CreateUserTxParams{
+				CreateUserParams: db.CreateUserParams{
+					Username:       r.user.Username,
+					HashedPassword: hashedPassword,
+					FullName:       r.user.FullName,
+					Email:          r.user.Email,
+				},
+				VerifyEmail: db.CreateVerifyEmailParams{
+					SecretCode: secretCode,
+					ExpiredAt:  time.Now().Add(opts.VerifyEmailDuration),
+				},
+			})
+		case KindAccounts:
+			arg.Accounts = append(arg.Accounts, r.account)
+		case KindTransfers:
+			arg.Transfers = append(arg.Transfers, r.transfer)
+		}
+	}
+
+	return arg, nil
+}
+
+func parseInt(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
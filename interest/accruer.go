@@ -0,0 +1,126 @@
+package interest
+
+import (
+	"context"
+	"log"
+	"time"
+
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+)
+
+// pageSize is the number of accounts loaded at a time
+const pageSize = 100
+
+// Failure is an account whose interest could not be accrued, its later days are left for the next run
+type Failure struct {
+	AccountID int64     `json:"account_id"`
+	Day       time.Time `json:"day"`
+	Error     string    `json:"error"`
+}
+
+// Report is the outcome of an accrual run
+type Report struct {
+	Through  time.Time        `json:"through"`
+	Accounts int              `json:"accounts"`
+	Days     int              `json:"days"`
+	Postings int              `json:"postings"`
+	Paid     map[string]int64 `json:"paid"`
+	// Fees is the maintenance fees charged at month ends, per product
+	Fees     map[string]int64 `json:"fees"`
+	Failures []Failure        `json:"failures"`
+}
+
+// Accruer runs the daily interest accrual of every account that has a product
+type Accruer struct {
+	store db.Store
+}
+
+// NewAccruer creates an accruer working on store
+func NewAccruer(store db.Store) *Accruer {
+	return &Accruer{store: store}
+}
+
+// AccrueThrough accrues interest for every day up to and including through that was not accrued yet,
+// so a run that was missed is caught up by the next one. Each day of each account is its own transaction.
+func (accruer *Accruer) AccrueThrough(ctx context.Context, through time.Time) (Report, error) {
+	through = db.InterestDay(through)
+	report := Report{
+		Through:  through,
+		Paid:     make(map[string]int64),
+		Fees:     make(map[string]int64),
+		Failures: []Failure{},
+	}
+
+	var afterID int64
+	for {
+		interests, err := accruer.store.ListAccountInterestsDue(ctx, db.ListAccountInterestsDueParams{
+			AccruedThrough: through,
+			AccountID:      afterID,
+			Limit:          pageSize,
+		})
+		if err != nil {
+			return report, err
+		}
+
+		for _, interest := range interests {
+			afterID = interest.AccountID
+			report.Accounts++
+
+			day := db.InterestDay(interest.AccruedThrough).AddDate(0, 0, 1)
+			for ; !day.After(through); day = day.AddDate(0, 0, 1) {
+				result, err := accruer.store.AccrueInterestTx(ctx, db.AccrueInterestTxParams{
+					AccountID: interest.AccountID,
+					Day:       day,
+				})
+				if err != nil {
+					if ctx.Err() != nil {
+						return report, ctx.Err()
+					}
+					report.Failures = append(report.Failures, Failure{
+						AccountID: interest.AccountID,
+						Day:       day,
+						Error:     err.Error(),
+					})
+					break
+				}
+
+				report.Days++
+				if result.Posting != nil {
+					report.Postings++
+					report.Paid[result.Posting.ToAccount.Currency] += result.Posting.Transfer.Amount
+				}
+				for _, fee := range result.Fees {
+					report.Fees[result.Interest.ProductCode] += fee.Amount
+				}
+			}
+		}
+
+		if len(interests) < pageSize {
+			return report, nil
+		}
+	}
+}
+
+// Run accrues through the previous day right away and then every interval until ctx is done
+func (accruer *Accruer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		yesterday := time.Now().UTC().AddDate(0, 0, -1)
+		report, err := accruer.AccrueThrough(ctx, yesterday)
+		if err != nil && ctx.Err() == nil {
+			log.Println("cannot accrue interest:", err)
+		}
+		if report.Days > 0 || len(report.Failures) > 0 {
+			log.Printf("accrued interest through %s: %d days of %d accounts, %d postings, %d failures",
+				report.Through.Format("2006-01-02"), report.Days, report.Accounts, report.Postings, len(report.Failures))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
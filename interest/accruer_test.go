@@ -0,0 +1,99 @@
+package interest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createAccount(t *testing.T, store db.Store, balance int64) db.Account {
+	user, err := store.CreateUser(context.Background(), db.CreateUserParams{
+		Username:       util.RandomOwnerName(),
+		HashedPassword: "secret",
+		FullName:       util.RandomOwnerName(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+
+	account, err := store.CreateAccount(context.Background(), db.CreateAccountParams{
+		OwnerName: user.Username,
+		Balance:   balance,
+		Currency:  util.USD,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func TestAccrueThrough(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	accruer := NewAccruer(store)
+
+	//3.65% makes one day of interest on 1_000_000 exactly 100
+	product, err := store.CreateAccountProduct(ctx, db.CreateAccountProductParams{Code: "savings", Name: "Savings", AnnualRatePpm: 36_500})
+	require.NoError(t, err)
+
+	saver := createAccount(t, store, 1_000_000)
+	unpaid := createAccount(t, store, 1_000_000)
+	expense := createAccount(t, store, 0)
+
+	through := db.InterestDay(time.Now()).AddDate(0, 0, -1)
+	start := through.AddDate(0, 0, -40)
+	for _, account := range []db.Account{saver, unpaid} {
+		_, err = store.UpsertAccountInterest(ctx, db.UpsertAccountInterestParams{
+			AccountID:      account.ID,
+			ProductCode:    product.Code,
+			AccruedThrough: start,
+		})
+		require.NoError(t, err)
+	}
+
+	//without an expense account the first month end fails and the account stops there
+	report, err := accruer.AccrueThrough(ctx, through)
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Accounts)
+	require.Len(t, report.Failures, 2)
+	require.Zero(t, report.Postings)
+
+	monthEnd := through.AddDate(0, 0, 1-through.Day()).AddDate(0, 0, -1)
+	require.True(t, report.Failures[0].Day.Equal(monthEnd))
+
+	interest, err := store.GetAccountInterest(ctx, saver.ID)
+	require.NoError(t, err)
+	require.True(t, interest.AccruedThrough.Equal(monthEnd.AddDate(0, 0, -1)))
+
+	_, err = store.UpsertInterestExpenseAccount(ctx, db.UpsertInterestExpenseAccountParams{Currency: util.USD, AccountID: expense.ID})
+	require.NoError(t, err)
+
+	//the next run catches up every day that is left
+	report, err = accruer.AccrueThrough(ctx, through)
+	require.NoError(t, err)
+	require.Empty(t, report.Failures)
+	require.GreaterOrEqual(t, report.Postings, 2)
+
+	days := int64(through.Sub(start).Hours() / 24)
+	for _, account := range []db.Account{saver, unpaid} {
+		interest, err := store.GetAccountInterest(ctx, account.ID)
+		require.NoError(t, err)
+		require.True(t, interest.AccruedThrough.Equal(through))
+		require.Equal(t, 100*days, interest.Paid+interest.Accrued)
+
+		account, err = store.GetAccount(ctx, account.ID)
+		require.NoError(t, err)
+		require.Equal(t, 1_000_000+interest.Paid, account.Balance)
+	}
+
+	expense, err = store.GetAccount(ctx, expense.ID)
+	require.NoError(t, err)
+	require.Equal(t, -report.Paid[util.USD], expense.Balance)
+
+	//nothing is accrued twice
+	report, err = accruer.AccrueThrough(ctx, through)
+	require.NoError(t, err)
+	require.Zero(t, report.Accounts)
+	require.Zero(t, report.Days)
+}
@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// FileMailer writes every email as an .eml file into a directory, handy for local development
+type FileMailer struct {
+	from  string
+	dir   string
+	count uint64
+}
+
+// NewFileMailer creates a new FileMailer, creating the directory if needed
+func NewFileMailer(from string, dir string) (Mailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cannot create mail directory: %w", err)
+	}
+	return &FileMailer{from: from, dir: dir}, nil
+}
+
+// Send writes the message to a new file
+func (mailer *FileMailer) Send(msg Message) error {
+	now := time.Now()
+	n := atomic.AddUint64(&mailer.count, 1)
+	name := fmt.Sprintf("%s-%d.eml", now.Format("20060102T150405.000000000"), n)
+
+	err := os.WriteFile(filepath.Join(mailer.dir, name), format(mailer.from, msg, now), 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+)
+
+// Constants for all supported mail drivers
+const (
+	DriverSMTP   = "smtp"
+	DriverFile   = "file"
+	DriverMemory = "memory"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails. SMTPMailer is used in production, FileMailer and MemoryMailer
+// let local development and tests run without a mail server.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// NewMailer creates the mailer selected by MAIL_DRIVER
+func NewMailer(config util.Config) (Mailer, error) {
+	switch config.Mail.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(config.Mail.From, config.Mail.SMTPHost, config.Mail.SMTPPort, config.Mail.SMTPUsername, config.Mail.SMTPPassword), nil
+	case DriverFile:
+		return NewFileMailer(config.Mail.From, config.Mail.FileDir)
+	case DriverMemory:
+		return NewMemoryMailer(), nil
+	}
+	return nil, fmt.Errorf("unsupported mail driver %q", config.Mail.Driver)
+}
+
+// format renders the message as an RFC 5322 email
+func format(from string, msg Message, date time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return buf.Bytes()
+}
@@ -0,0 +1,61 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomMessage() Message {
+	return Message{
+		To:      []string{util.RandomEmail()},
+		Subject: util.RandomString(10),
+		Body:    "line 1\nline 2",
+	}
+}
+
+func TestMemoryMailer(t *testing.T) {
+	mailer := NewMemoryMailer()
+	msg := randomMessage()
+
+	err := mailer.Send(msg)
+	require.NoError(t, err)
+	require.Equal(t, []Message{msg}, mailer.Messages())
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer, err := NewFileMailer("bank@email.com", dir)
+	require.NoError(t, err)
+
+	msg := randomMessage()
+	require.NoError(t, mailer.Send(msg))
+	require.NoError(t, mailer.Send(msg))
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "From: bank@email.com\r\n")
+	require.Contains(t, string(data), "To: "+msg.To[0]+"\r\n")
+	require.Contains(t, string(data), "Subject: "+msg.Subject+"\r\n")
+	require.Contains(t, string(data), "\r\n\r\nline 1\r\nline 2")
+}
+
+func TestNewMailer(t *testing.T) {
+	mailer, err := NewMailer(util.Config{Mail: util.MailConfig{Driver: DriverMemory}})
+	require.NoError(t, err)
+	require.IsType(t, &MemoryMailer{}, mailer)
+
+	mailer, err = NewMailer(util.Config{Mail: util.MailConfig{Driver: DriverSMTP, SMTPHost: "localhost", SMTPPort: 25}})
+	require.NoError(t, err)
+	require.IsType(t, &SMTPMailer{}, mailer)
+
+	_, err = NewMailer(util.Config{Mail: util.MailConfig{Driver: "pigeon"}})
+	require.Error(t, err)
+}
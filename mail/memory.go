@@ -0,0 +1,31 @@
+package mail
+
+import "sync"
+
+// MemoryMailer keeps sent emails in memory so tests can inspect them
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemoryMailer creates a new MemoryMailer
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+// Send records the message
+func (mailer *MemoryMailer) Send(msg Message) error {
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+
+	mailer.messages = append(mailer.messages, msg)
+	return nil
+}
+
+// Messages returns a copy of all messages sent so far
+func (mailer *MemoryMailer) Messages() []Message {
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+
+	return append([]Message(nil), mailer.messages...)
+}
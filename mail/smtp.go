@@ -0,0 +1,41 @@
+package mail
+
+import (
+	"fmt"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer sends emails through an SMTP server
+type SMTPMailer struct {
+	from     string
+	address  string
+	host     string
+	username string
+	password string
+}
+
+// NewSMTPMailer creates a new SMTPMailer, authentication is skipped when username is empty
+func NewSMTPMailer(from string, host string, port int, username string, password string) Mailer {
+	return &SMTPMailer{
+		from:     from,
+		address:  fmt.Sprintf("%s:%d", host, port),
+		host:     host,
+		username: username,
+		password: password,
+	}
+}
+
+// Send delivers the message to the SMTP server
+func (mailer *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if mailer.username != "" {
+		auth = smtp.PlainAuth("", mailer.username, mailer.password, mailer.host)
+	}
+
+	err := smtp.SendMail(mailer.address, auth, mailer.from, msg.To, format(mailer.from, msg, time.Now()))
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
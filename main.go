@@ -2,11 +2,20 @@ package main
 
 //DONT FORGET _ "github.com/lib/pq" ELSE FAILURE
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/gin-gonic/gin"
 	"github.com/kingsleyocran/simple_bank_bankend/api"
+	"github.com/kingsleyocran/simple_bank_bankend/balance"
 	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/interest"
+	"github.com/kingsleyocran/simple_bank_bankend/mail"
 	"github.com/kingsleyocran/simple_bank_bankend/util"
 	_ "github.com/lib/pq"
 )
@@ -17,16 +26,121 @@ func main() {
 		log.Fatal("cannot load config:", err)
 	}
 
-	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	//config prints the effective configuration, e.g. to check a deployment before starting it
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		fmt.Print(config.Redacted())
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(config, os.Args[2:]); err != nil {
+			log.Fatal("cannot migrate:", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := runImport(config, os.Args[2:]); err != nil {
+			log.Fatal("cannot import:", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "accrue-interest" {
+		if err := runAccrueInterest(config, os.Args[2:]); err != nil {
+			log.Fatal("cannot accrue interest:", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		if err := runAdmin(config, os.Args[2:]); err != nil {
+			log.Fatal("cannot run admin command:", err)
+		}
+		return
+	}
+
+	log.Printf("starting with the %s profile:\n%s", config.Profile, config.Redacted())
+	if config.Log.Level != util.LogLevelDebug {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	store, err := newStore(config)
+	if err != nil {
+		log.Fatal("cannot use database:", err)
+	}
+
+	mailer, err := mail.NewMailer(config)
+	if err != nil {
+		log.Fatal("cannot create mailer:", err)
+	}
+
+	server, err := api.NewServer(config, store, mailer)
 	if err != nil {
-		log.Fatal("cannot connect to db:", err)
+		log.Fatal("cannot create server:", err)
+	}
+
+	//background jobs stop as soon as shutdown starts, a day being accrued is rolled back and redone on the next start
+	jobs, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	if config.Workers.InterestAccrualInterval > 0 {
+		go interest.NewAccruer(store).Run(jobs, config.Workers.InterestAccrualInterval)
 	}
+	if config.Workers.BalanceSnapshotInterval > 0 {
+		go balance.NewSnapshotter(store).Run(jobs, config.Workers.BalanceSnapshotInterval)
+	}
+
+	//Shutdown on SIGINT/SIGTERM so that in-flight requests can finish
+	//while /readyz already reports the server as draining
+	idle := make(chan struct{})
+	go func() {
+		defer close(idle)
 
-	store := db.NewStore(conn)
-	server := api.NewServer(store)
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		<-quit
 
-	err = server.Start(config.ServerAddress)
+		log.Println("shutting down server")
+		stopJobs()
+		ctx, cancel := context.WithTimeout(context.Background(), config.Server.ShutdownDrainPeriod+config.Server.ShutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(ctx); err != nil {
+			log.Println("cannot shutdown server gracefully:", err)
+		}
+	}()
+
+	err = server.Start(config.Server.Address)
 	if err != nil {
 		log.Fatal("cannot start server:", err)
 	}
+
+	<-idle
+}
+
+//newStore opens the store selected by DB_DRIVER, the memory driver needs no database at all
+func newStore(config util.Config) (db.Store, error) {
+	if config.DB.Driver == db.MemoryDriver {
+		log.Println("using the in-memory store, all data is lost on exit")
+		return db.NewMemoryStore(), nil
+	}
+
+	if err := prepareDatabase(config); err != nil {
+		return nil, err
+	}
+
+	conn, err := sql.Open(config.DB.Driver, config.DB.Source)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to db: %w", err)
+	}
+	conn.SetMaxOpenConns(config.DB.MaxOpenConns)
+	conn.SetMaxIdleConns(config.DB.MaxIdleConns)
+	conn.SetConnMaxLifetime(config.DB.ConnMaxLifetime)
+	conn.SetConnMaxIdleTime(config.DB.ConnMaxIdleTime)
+
+	return db.NewStoreWithRetry(conn, db.RetryPolicy{
+		MaxRetries: config.DB.TxMaxRetries,
+		BaseDelay:  config.DB.TxRetryBaseDelay,
+		MaxDelay:   config.DB.TxRetryMaxDelay,
+	}), nil
 }
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/kingsleyocran/simple_bank_bankend/db/migration"
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+)
+
+const migrateUsage = "usage: migrate up [N] | down N|all | version"
+
+//runMigrate implements the migrate subcommand with the migrations embedded in the binary
+func runMigrate(config util.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	if config.DB.Driver == db.MemoryDriver {
+		return errors.New("the in-memory store has no schema to migrate")
+	}
+
+	migrator, err := migration.New(config.DB.Source)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	switch args[0] {
+	case "up":
+		if len(args) == 1 {
+			err = migrator.Up()
+			break
+		}
+		var steps int
+		steps, err = parseSteps(args[1])
+		if err == nil {
+			err = migrator.Steps(steps)
+		}
+
+	case "down":
+		//reverting everything drops all data, so it has to be asked for explicitly
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		if args[1] == "all" {
+			err = migrator.Down()
+			break
+		}
+		var steps int
+		steps, err = parseSteps(args[1])
+		if err == nil {
+			err = migrator.Steps(-steps)
+		}
+
+	case "version":
+	default:
+		return errors.New(migrateUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+	log.Printf("schema version %d (dirty: %t), binary expects %d", version, dirty, db.MigrationVersion)
+	return nil
+}
+
+func parseSteps(s string) (int, error) {
+	steps, err := strconv.Atoi(s)
+	if err != nil || steps <= 0 {
+		return 0, fmt.Errorf("invalid number of steps %q", s)
+	}
+	return steps, nil
+}
+
+//prepareDatabase migrates the database when MIGRATE_ON_START is set and refuses databases
+//that are dirty or ahead of the binary, a database that is behind only fails readiness
+func prepareDatabase(config util.Config) error {
+	migrator, err := migration.New(config.DB.Source)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	if config.DB.MigrateOnStart {
+		log.Println("applying migrations")
+		if err := migrator.Up(); err != nil {
+			return fmt.Errorf("cannot migrate database: %w", err)
+		}
+	}
+
+	if err := migrator.CheckVersion(db.MigrationVersion); err != nil {
+		return err
+	}
+
+	version, _, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+	if version < db.MigrationVersion {
+		log.Printf("database schema version %d is behind version %d, run the migrate subcommand", version, db.MigrationVersion)
+	}
+	return nil
+}
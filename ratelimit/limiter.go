@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests requests per Period. Requests is also the size of the burst.
+type Limit struct {
+	Requests int64
+	Period   time.Duration
+}
+
+// Result describes the outcome of a single Allow call
+type Result struct {
+	Allowed    bool
+	Limit      int64
+	Remaining  int64
+	ResetAfter time.Duration // time until the bucket is full again
+	RetryAfter time.Duration // time until the next request is allowed, zero when allowed
+}
+
+// Limiter is a rate limiting backend. MemoryLimiter keeps the state in process,
+// a shared implementation (e.g. redis) can be plugged in for multiple replicas.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// ParseLimit parses a limit written as "<requests>/<period>", e.g. "20/1m" or "5/30s"
+func ParseLimit(s string) (Limit, error) {
+	parts := strings.SplitN(strings.TrimSpace(s), "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <requests>/<period>", s)
+	}
+
+	requests, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive integer", s)
+	}
+
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: period must be a positive duration", s)
+	}
+
+	return Limit{Requests: requests, Period: period}, nil
+}
+
+// ParseRules parses rules written as "<route>=<limit>", e.g. "POST /transfers=20/1m".
+// The route "*" is the default applied to every route without its own rule.
+func ParseRules(rules []string) (map[string]Limit, error) {
+	limits := make(map[string]Limit, len(rules))
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		i := strings.LastIndex(rule, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid rate limit rule %q: expected <route>=<limit>", rule)
+		}
+
+		limit, err := ParseLimit(rule[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		limits[strings.TrimSpace(rule[:i])] = limit
+	}
+	return limits, nil
+}
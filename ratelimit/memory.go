@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle buckets are dropped from memory
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	fullAt time.Time
+}
+
+// MemoryLimiter is a token bucket limiter that keeps its buckets in process memory
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryLimiter creates a new in-memory token bucket limiter
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes one token from the bucket identified by key
+func (limiter *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := limiter.now()
+	limiter.sweep(now)
+
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Period.Seconds()
+
+	b, ok := limiter.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		limiter.buckets[key] = b
+	}
+
+	// refill the bucket for the time elapsed since the last request
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	result := Result{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.tokens) / rate)
+	}
+
+	result.Remaining = int64(math.Floor(b.tokens))
+	result.ResetAfter = secondsToDuration((capacity - b.tokens) / rate)
+	b.fullAt = now.Add(result.ResetAfter)
+
+	return result, nil
+}
+
+// sweep drops buckets that have refilled completely, they are equivalent to a new bucket
+func (limiter *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(limiter.lastSweep) < sweepInterval {
+		return
+	}
+	limiter.lastSweep = now
+
+	for key, b := range limiter.buckets {
+		if !now.Before(b.fullAt) {
+			delete(limiter.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter() (*MemoryLimiter, *time.Time) {
+	now := time.Now()
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+func TestMemoryLimiterBurst(t *testing.T) {
+	limiter, _ := newTestLimiter()
+	limit := Limit{Requests: 3, Period: time.Minute}
+
+	for i := int64(0); i < limit.Requests; i++ {
+		result, err := limiter.Allow(context.Background(), "key", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, limit.Requests-i-1, result.Remaining)
+		require.Zero(t, result.RetryAfter)
+	}
+
+	result, err := limiter.Allow(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+	require.Equal(t, 20*time.Second, result.RetryAfter)
+	require.Equal(t, time.Minute, result.ResetAfter)
+}
+
+func TestMemoryLimiterRefill(t *testing.T) {
+	limiter, now := newTestLimiter()
+	limit := Limit{Requests: 2, Period: time.Minute}
+
+	for i := 0; i < 2; i++ {
+		result, err := limiter.Allow(context.Background(), "key", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+
+	*now = now.Add(30 * time.Second)
+
+	result, err := limiter.Allow(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	result, err = limiter.Allow(context.Background(), "key", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+}
+
+func TestMemoryLimiterKeysAreIndependent(t *testing.T) {
+	limiter, _ := newTestLimiter()
+	limit := Limit{Requests: 1, Period: time.Minute}
+
+	result, err := limiter.Allow(context.Background(), "key1", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	result, err = limiter.Allow(context.Background(), "key2", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+}
+
+func TestMemoryLimiterSweep(t *testing.T) {
+	limiter, now := newTestLimiter()
+	limit := Limit{Requests: 1, Period: time.Second}
+
+	_, err := limiter.Allow(context.Background(), "key1", limit)
+	require.NoError(t, err)
+	require.Len(t, limiter.buckets, 1)
+
+	*now = now.Add(2 * sweepInterval)
+
+	_, err = limiter.Allow(context.Background(), "key2", limit)
+	require.NoError(t, err)
+	require.Len(t, limiter.buckets, 1)
+	require.Contains(t, limiter.buckets, "key2")
+}
+
+func TestParseRules(t *testing.T) {
+	limits, err := ParseRules([]string{"*=100/1m", " POST /transfers=5/30s ", ""})
+	require.NoError(t, err)
+	require.Equal(t, map[string]Limit{
+		"*":               {Requests: 100, Period: time.Minute},
+		"POST /transfers": {Requests: 5, Period: 30 * time.Second},
+	}, limits)
+
+	for _, rule := range []string{"POST /transfers", "=1/1m", "* =x/1m", "*=0/1m", "*=1/0s", "*=1"} {
+		_, err := ParseRules([]string{rule})
+		require.Error(t, err, rule)
+	}
+}
@@ -0,0 +1,143 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+)
+
+// Transfer is the transfer being screened
+type Transfer struct {
+	From   db.Account
+	To     db.Account
+	Amount int64
+	// Earlier are the transfers of the same batch or split screened before this one. They are not made yet,
+	// so the velocity and anomaly rules count them as if they were, a request can't hide a burst in its items.
+	Earlier []Transfer
+}
+
+// outgoing adds the earlier transfers of the request sent from the same account to the totals of its history
+func (transfer Transfer) outgoing(totals db.GetOutgoingTransferTotalsRow) db.GetOutgoingTransferTotalsRow {
+	for _, earlier := range transfer.Earlier {
+		if earlier.From.ID != transfer.From.ID {
+			continue
+		}
+		totals.TransferCount++
+		totals.TotalAmount += earlier.Amount
+	}
+	return totals
+}
+
+// Hit is a rule that matched and why
+type Hit struct {
+	Rule   string `json:"rule"`
+	Type   string `json:"type"`
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+}
+
+// Decision is the outcome of screening a transfer, the strictest action of the rules that matched
+type Decision struct {
+	Action string `json:"action"`
+	Hits   []Hit  `json:"hits"`
+}
+
+// Engine screens transfers against the configured rules before they are made
+type Engine struct {
+	store db.Querier
+	rules []Rule
+	now   func() time.Time
+}
+
+// NewEngine creates an engine checking rules, an engine without rules allows everything
+func NewEngine(store db.Querier, rules []Rule) *Engine {
+	return &Engine{store: store, rules: rules, now: time.Now}
+}
+
+// Evaluate runs every rule against the transfer. It fails when the history of the accounts can't be loaded,
+// the caller decides whether to fail open or closed.
+func (engine *Engine) Evaluate(ctx context.Context, transfer Transfer) (Decision, error) {
+	decision := Decision{Action: db.RiskDecisionAllow, Hits: []Hit{}}
+
+	for _, rule := range engine.rules {
+		reason, matched, err := engine.check(ctx, rule, transfer)
+		if err != nil {
+			return decision, fmt.Errorf("risk rule %q: %w", rule.Name, err)
+		}
+		if !matched {
+			continue
+		}
+
+		decision.Hits = append(decision.Hits, Hit{Rule: rule.Name, Type: rule.Type, Action: rule.Action, Reason: reason})
+		if severity(rule.Action) > severity(decision.Action) {
+			decision.Action = rule.Action
+		}
+	}
+	return decision, nil
+}
+
+func (engine *Engine) check(ctx context.Context, rule Rule, transfer Transfer) (string, bool, error) {
+	switch rule.Type {
+	case VelocityRule:
+		window := time.Duration(rule.Window)
+		totals, err := engine.store.GetOutgoingTransferTotals(ctx, db.GetOutgoingTransferTotalsParams{
+			AccountID: transfer.From.ID,
+			Since:     engine.now().Add(-window),
+		})
+		if err != nil {
+			return "", false, err
+		}
+		totals = transfer.outgoing(totals)
+		if totals.TransferCount < rule.MaxCount {
+			return "", false, nil
+		}
+		return fmt.Sprintf("%d transfers sent in the last %s", totals.TransferCount, window), true, nil
+
+	case NewBeneficiaryRule:
+		if transfer.Amount < rule.MinAmount {
+			return "", false, nil
+		}
+		count, err := engine.store.CountTransfersBetween(ctx, db.CountTransfersBetweenParams{
+			FromAccountID: transfer.From.ID,
+			ToAccountID:   transfer.To.ID,
+		})
+		if err != nil || count > 0 {
+			return "", false, err
+		}
+		return fmt.Sprintf("first transfer to account [%s] is %d, at least %d", transfer.To.PublicID, transfer.Amount, rule.MinAmount), true, nil
+
+	case AnomalyRule:
+		lookback := time.Duration(rule.Lookback)
+		totals, err := engine.store.GetOutgoingTransferTotals(ctx, db.GetOutgoingTransferTotalsParams{
+			AccountID: transfer.From.ID,
+			Since:     engine.now().Add(-lookback),
+		})
+		if err != nil {
+			return "", false, err
+		}
+		totals = transfer.outgoing(totals)
+		//too little history to know what is unusual
+		if totals.TransferCount < rule.MinHistory {
+			return "", false, nil
+		}
+		average := float64(totals.TotalAmount) / float64(totals.TransferCount)
+		if float64(transfer.Amount) <= rule.Multiplier*average {
+			return "", false, nil
+		}
+		return fmt.Sprintf("amount %d is more than %g times the average of %.0f over the last %s", transfer.Amount, rule.Multiplier, average, lookback), true, nil
+	}
+	return "", false, fmt.Errorf("unknown type %q", rule.Type)
+}
+
+// severity orders the actions so that block wins over review and review over allow
+func severity(action string) int {
+	switch action {
+	case db.RiskDecisionBlock:
+		return 2
+	case db.RiskDecisionReview:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,169 @@
+package risk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createAccount(t *testing.T, store db.Store) db.Account {
+	user, err := store.CreateUser(context.Background(), db.CreateUserParams{
+		Username:       util.RandomOwnerName(),
+		HashedPassword: "secret",
+		FullName:       util.RandomOwnerName(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+
+	account, err := store.CreateAccount(context.Background(), db.CreateAccountParams{
+		OwnerName: user.Username,
+		Balance:   1_000_000,
+		Currency:  util.USD,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func transfer(t *testing.T, store db.Store, from, to db.Account, amount int64) {
+	_, err := store.TransferTx(context.Background(), db.TransferTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        amount,
+	})
+	require.NoError(t, err)
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules([]byte(`{"rules": [
+		{"name": "burst", "type": "velocity", "action": "review", "max_count": 5, "window": "10m"},
+		{"name": "first", "type": "new_beneficiary", "action": "review", "min_amount": 500000},
+		{"name": "unusual", "type": "anomaly", "action": "block", "lookback": "720h", "multiplier": 10, "min_history": 3}
+	]}`))
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+	require.Equal(t, Duration(10*time.Minute), rules[0].Window)
+	require.Equal(t, Duration(720*time.Hour), rules[2].Lookback)
+
+	invalid := []string{
+		`{"rules": [{"name": "burst", "type": "velocity", "action": "review", "max_count": 5, "window": "soon"}]}`,
+		`{"rules": [{"name": "burst", "type": "velocity", "action": "review", "max_count": 0, "window": "10m"}]}`,
+		`{"rules": [{"name": "burst", "type": "velocity", "action": "allow", "max_count": 5, "window": "10m"}]}`,
+		`{"rules": [{"name": "burst", "type": "geo", "action": "review"}]}`,
+		`{"rules": [{"type": "new_beneficiary", "action": "review", "min_amount": 10}]}`,
+		`{"rules": [{"name": "unusual", "type": "anomaly", "action": "block", "lookback": "1h", "multiplier": 1, "min_history": 3}]}`,
+		`{"rules": [{"name": "a", "type": "new_beneficiary", "action": "review", "min_amount": 10},
+			{"name": "a", "type": "new_beneficiary", "action": "block", "min_amount": 20}]}`,
+		`[]`,
+	}
+	for _, data := range invalid {
+		_, err := ParseRules([]byte(data))
+		require.Error(t, err, data)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+
+	from := createAccount(t, store)
+	known := createAccount(t, store)
+	unknown := createAccount(t, store)
+	for i := 0; i < 3; i++ {
+		transfer(t, store, from, known, 100)
+	}
+
+	engine := NewEngine(store, []Rule{
+		{Name: "burst", Type: VelocityRule, Action: db.RiskDecisionReview, MaxCount: 3, Window: Duration(time.Minute)},
+		{Name: "first", Type: NewBeneficiaryRule, Action: db.RiskDecisionReview, MinAmount: 1000},
+		{Name: "unusual", Type: AnomalyRule, Action: db.RiskDecisionBlock, Lookback: Duration(time.Hour), Multiplier: 10, MinHistory: 3},
+	})
+
+	//three transfers in the last minute
+	decision, err := engine.Evaluate(ctx, Transfer{From: from, To: known, Amount: 100})
+	require.NoError(t, err)
+	require.Equal(t, db.RiskDecisionReview, decision.Action)
+	require.Len(t, decision.Hits, 1)
+	require.Equal(t, "burst", decision.Hits[0].Rule)
+
+	//a minute later only the other rules can match
+	engine.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+
+	decision, err = engine.Evaluate(ctx, Transfer{From: from, To: known, Amount: 1000})
+	require.NoError(t, err)
+	require.Equal(t, db.RiskDecisionAllow, decision.Action)
+	require.Empty(t, decision.Hits)
+
+	decision, err = engine.Evaluate(ctx, Transfer{From: from, To: unknown, Amount: 1000})
+	require.NoError(t, err)
+	require.Equal(t, db.RiskDecisionReview, decision.Action)
+	require.Len(t, decision.Hits, 1)
+	require.Equal(t, "first", decision.Hits[0].Rule)
+
+	//the strictest action wins
+	decision, err = engine.Evaluate(ctx, Transfer{From: from, To: unknown, Amount: 1001})
+	require.NoError(t, err)
+	require.Equal(t, db.RiskDecisionBlock, decision.Action)
+	require.Len(t, decision.Hits, 2)
+
+	//without enough history the anomaly rule doesn't apply
+	other := createAccount(t, store)
+	decision, err = engine.Evaluate(ctx, Transfer{From: other, To: known, Amount: 999})
+	require.NoError(t, err)
+	require.Equal(t, db.RiskDecisionAllow, decision.Action)
+}
+
+func TestEvaluateEarlierTransfers(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+
+	from := createAccount(t, store)
+	to := createAccount(t, store)
+	for i := 0; i < 2; i++ {
+		transfer(t, store, from, to, 100)
+	}
+
+	engine := NewEngine(store, []Rule{
+		{Name: "burst", Type: VelocityRule, Action: db.RiskDecisionReview, MaxCount: 4, Window: Duration(time.Minute)},
+		{Name: "unusual", Type: AnomalyRule, Action: db.RiskDecisionBlock, Lookback: Duration(time.Hour), Multiplier: 10, MinHistory: 3},
+	})
+
+	//two transfers sent and one earlier in the same request are too little for either rule
+	earlier := []Transfer{{From: from, To: to, Amount: 100}}
+	decision, err := engine.Evaluate(ctx, Transfer{From: from, To: to, Amount: 100, Earlier: earlier})
+	require.NoError(t, err)
+	require.Equal(t, db.RiskDecisionAllow, decision.Action)
+
+	//the earlier items of the request count towards the velocity
+	earlier = append(earlier, Transfer{From: from, To: to, Amount: 100})
+	decision, err = engine.Evaluate(ctx, Transfer{From: from, To: to, Amount: 100, Earlier: earlier})
+	require.NoError(t, err)
+	require.Equal(t, db.RiskDecisionReview, decision.Action)
+	require.Equal(t, "burst", decision.Hits[0].Rule)
+
+	//and to the history the anomaly rule averages
+	earlier = earlier[:1]
+	decision, err = engine.Evaluate(ctx, Transfer{From: from, To: to, Amount: 1001, Earlier: earlier})
+	require.NoError(t, err)
+	require.Equal(t, db.RiskDecisionBlock, decision.Action)
+	require.Equal(t, "unusual", decision.Hits[0].Rule)
+
+	//transfers of the request sent from another account are not part of the history
+	other := createAccount(t, store)
+	decision, err = engine.Evaluate(ctx, Transfer{From: from, To: to, Amount: 1001, Earlier: []Transfer{{From: other, To: to, Amount: 100}}})
+	require.NoError(t, err)
+	require.Equal(t, db.RiskDecisionAllow, decision.Action)
+}
+
+func TestEvaluateWithoutRules(t *testing.T) {
+	//a nil querier proves that no rule means no query
+	engine := NewEngine(nil, nil)
+
+	decision, err := engine.Evaluate(context.Background(), Transfer{Amount: 1_000_000})
+	require.NoError(t, err)
+	require.Equal(t, db.RiskDecisionAllow, decision.Action)
+	require.Empty(t, decision.Hits)
+}
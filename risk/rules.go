@@ -0,0 +1,118 @@
+package risk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	db "github.com/kingsleyocran/simple_bank_bankend/db/sqlc"
+)
+
+// Types of rule
+const (
+	// VelocityRule matches when the account already sent MaxCount transfers within Window
+	VelocityRule = "velocity"
+	// NewBeneficiaryRule matches a transfer of at least MinAmount to an account never paid before
+	NewBeneficiaryRule = "new_beneficiary"
+	// AnomalyRule matches a transfer above Multiplier times the average transfer of the account over Lookback
+	AnomalyRule = "anomaly"
+)
+
+// Duration is a time.Duration written as a string in the rules file, e.g. "10m"
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration such as "10m" or "720h"
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"10m\": %w", err)
+	}
+
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// MarshalJSON writes the duration back as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Rule is one check of the rules file. Only the fields of its type are used.
+type Rule struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Action is review or block
+	Action     string   `json:"action"`
+	MaxCount   int64    `json:"max_count,omitempty"`
+	Window     Duration `json:"window,omitempty"`
+	MinAmount  int64    `json:"min_amount,omitempty"`
+	Lookback   Duration `json:"lookback,omitempty"`
+	Multiplier float64  `json:"multiplier,omitempty"`
+	MinHistory int64    `json:"min_history,omitempty"`
+}
+
+// ruleFile is the layout of the rules file: {"rules": [...]}
+type ruleFile struct {
+	Rules []Rule `json:"rules"`
+}
+
+// LoadRules reads and validates the rules file at path
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read risk rules: %w", err)
+	}
+	return ParseRules(data)
+}
+
+// ParseRules parses and validates the content of a rules file
+func ParseRules(data []byte) ([]Rule, error) {
+	var file ruleFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid risk rules: %w", err)
+	}
+
+	names := make(map[string]bool, len(file.Rules))
+	for i, rule := range file.Rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("invalid risk rule %d (%q): %w", i+1, rule.Name, err)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("invalid risk rule %d: duplicate name %q", i+1, rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return file.Rules, nil
+}
+
+func (rule Rule) validate() error {
+	if rule.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if rule.Action != db.RiskDecisionReview && rule.Action != db.RiskDecisionBlock {
+		return fmt.Errorf("action must be %s or %s", db.RiskDecisionReview, db.RiskDecisionBlock)
+	}
+
+	switch rule.Type {
+	case VelocityRule:
+		if rule.MaxCount <= 0 || rule.Window <= 0 {
+			return fmt.Errorf("max_count and window must be positive")
+		}
+	case NewBeneficiaryRule:
+		if rule.MinAmount <= 0 {
+			return fmt.Errorf("min_amount must be positive")
+		}
+	case AnomalyRule:
+		if rule.Lookback <= 0 || rule.Multiplier <= 1 || rule.MinHistory <= 0 {
+			return fmt.Errorf("lookback and min_history must be positive and multiplier greater than 1")
+		}
+	default:
+		return fmt.Errorf("unknown type %q", rule.Type)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+)
+
+// ServiceIdentity is the internal identity of a service client and the role it is authorized with
+type ServiceIdentity struct {
+	Name string
+	Role string
+}
+
+// Username is how the service appears wherever a username is recorded, e.g. service:reporting
+func (identity ServiceIdentity) Username() string {
+	return "service:" + identity.Name
+}
+
+// ServiceIdentities maps the subject common name of a client certificate to a service identity
+type ServiceIdentities map[string]ServiceIdentity
+
+// ParseServiceIdentities parses identities written as "<common name>=<service>:<role>", e.g. reports.internal=reporting:banker
+func ParseServiceIdentities(entries []string) (ServiceIdentities, error) {
+	identities := make(ServiceIdentities, len(entries))
+	for _, entry := range entries {
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid service identity %q: expected <common name>=<service>:<role>", entry)
+		}
+		commonName := strings.TrimSpace(entry[:i])
+
+		parts := strings.SplitN(entry[i+1:], ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid service identity %q: expected <common name>=<service>:<role>", entry)
+		}
+		identity := ServiceIdentity{Name: strings.TrimSpace(parts[0]), Role: strings.TrimSpace(parts[1])}
+		if !util.IsSupportedRole(identity.Role) {
+			return nil, fmt.Errorf("invalid service identity %q: unknown role %q", entry, identity.Role)
+		}
+
+		if _, ok := identities[commonName]; ok {
+			return nil, fmt.Errorf("duplicate service identity for %q", commonName)
+		}
+		identities[commonName] = identity
+	}
+	return identities, nil
+}
+
+// Lookup returns the identity of the client certificate of a connection. Only a certificate verified
+// against the client CA counts, a connection without one has no identity.
+func (identities ServiceIdentities) Lookup(state *tls.ConnectionState) (ServiceIdentity, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return ServiceIdentity{}, false
+	}
+
+	identity, ok := identities[state.VerifiedChains[0][0].Subject.CommonName]
+	return identity, ok
+}
@@ -0,0 +1,112 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrNoCertificate is returned when a reloader is created without certificate files
+var ErrNoCertificate = errors.New("TLS needs a certificate and a key file")
+
+// CertReloader serves a certificate and loads it again once its files change, e.g. after a renewal.
+// The files are checked at most once per interval, during a handshake.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	checkedAt time.Time
+}
+
+// NewCertReloader loads the certificate, the server can't start without a valid one
+func NewCertReloader(certFile, keyFile string, interval time.Duration) (*CertReloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, ErrNoCertificate
+	}
+
+	reloader := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+		now:      time.Now,
+	}
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// Reload loads the certificate and its key, the current certificate is kept when they are invalid
+func (reloader *CertReloader) Reload() error {
+	certMod, keyMod, err := reloader.modTimes()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(reloader.certFile, reloader.keyFile)
+	if err != nil {
+		return fmt.Errorf("cannot load certificate: %w", err)
+	}
+
+	reloader.mu.Lock()
+	defer reloader.mu.Unlock()
+	reloader.cert = &cert
+	reloader.certMod = certMod
+	reloader.keyMod = keyMod
+	reloader.checkedAt = reloader.now()
+	return nil
+}
+
+// GetCertificate is the tls.Config callback, it reloads the certificate when the files changed since the last check
+func (reloader *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	reloader.mu.Lock()
+	now := reloader.now()
+	due := now.Sub(reloader.checkedAt) >= reloader.interval
+	if due {
+		reloader.checkedAt = now
+	}
+	cert, certMod, keyMod := reloader.cert, reloader.certMod, reloader.keyMod
+	reloader.mu.Unlock()
+
+	if !due {
+		return cert, nil
+	}
+
+	newCertMod, newKeyMod, err := reloader.modTimes()
+	if err != nil || (newCertMod.Equal(certMod) && newKeyMod.Equal(keyMod)) {
+		return cert, nil
+	}
+
+	//the cert and the key are often written one after the other, a pair that doesn't match yet is retried on the next check
+	if err := reloader.Reload(); err != nil {
+		log.Println("cannot reload TLS certificate, keeping the current one:", err)
+		return cert, nil
+	}
+	log.Println("reloaded TLS certificate", reloader.certFile)
+
+	reloader.mu.Lock()
+	defer reloader.mu.Unlock()
+	return reloader.cert, nil
+}
+
+func (reloader *CertReloader) modTimes() (certMod time.Time, keyMod time.Time, err error) {
+	certInfo, err := os.Stat(reloader.certFile)
+	if err != nil {
+		return
+	}
+	keyInfo, err := os.Stat(reloader.keyFile)
+	if err != nil {
+		return
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
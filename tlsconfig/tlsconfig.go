@@ -0,0 +1,101 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+)
+
+// Constants for all supported client authentication modes
+const (
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+)
+
+// New creates the TLS configuration of the server. The certificate is reloaded when its files change,
+// so a renewed certificate is picked up without a restart.
+func New(config util.TLSConfig) (*tls.Config, error) {
+	minVersion, err := ParseVersion(config.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherSuites, err := ParseCipherSuites(config.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	reloader, err := NewCertReloader(config.CertFile, config.KeyFile, config.ReloadInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if config.ClientCAFile != "" {
+		pem, err := os.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read client CA: %w", err)
+		}
+		tlsConfig.ClientCAs = x509.NewCertPool()
+		if !tlsConfig.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in client CA %s", config.ClientCAFile)
+		}
+
+		//optional lets users keep using tokens while services present a certificate
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		if config.ClientAuth == ClientAuthRequire {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// ParseVersion parses a TLS version written as 1.2 or 1.3, empty is 1.2
+func ParseVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS version %q: expected 1.2 or 1.3", version)
+}
+
+// ParseCipherSuites looks up cipher suites by their standard names, e.g. TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256.
+// Insecure suites are refused. The suites of TLS 1.3 are not configurable, so they only apply to TLS 1.2.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	secure := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		secure[suite.Name] = suite.ID
+	}
+	insecure := make(map[string]bool)
+	for _, suite := range tls.InsecureCipherSuites() {
+		insecure[suite.Name] = true
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := secure[name]
+		if !ok {
+			if insecure[name] {
+				return nil, fmt.Errorf("cipher suite %s is insecure", name)
+			}
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
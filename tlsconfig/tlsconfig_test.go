@@ -0,0 +1,244 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kingsleyocran/simple_bank_bankend/util"
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+//newTestCert creates a certificate for commonName signed by parent, or self-signed when parent is nil
+func newTestCert(t *testing.T, commonName string, parent *testCert, serial int64) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key}
+}
+
+//write saves the certificate and its key as PEM files in dir
+func (c *testCert) write(t *testing.T, dir string, name string) (certFile string, keyFile string) {
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+func TestParseVersion(t *testing.T) {
+	version, err := ParseVersion("")
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS12), version)
+
+	version, err = ParseVersion("1.3")
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS13), version)
+
+	_, err = ParseVersion("1.0")
+	require.EqualError(t, err, `unsupported TLS version "1.0": expected 1.2 or 1.3`)
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	suites, err := ParseCipherSuites(nil)
+	require.NoError(t, err)
+	require.Nil(t, suites)
+
+	suites, err = ParseCipherSuites([]string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256"})
+	require.NoError(t, err)
+	require.Equal(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256}, suites)
+
+	_, err = ParseCipherSuites([]string{"TLS_RSA_WITH_RC4_128_SHA"})
+	require.EqualError(t, err, "cipher suite TLS_RSA_WITH_RC4_128_SHA is insecure")
+
+	_, err = ParseCipherSuites([]string{"TLS_MADE_UP"})
+	require.EqualError(t, err, `unknown cipher suite "TLS_MADE_UP"`)
+}
+
+func TestParseServiceIdentities(t *testing.T) {
+	identities, err := ParseServiceIdentities([]string{"reports.internal=reporting:banker", " ops.internal = ops:admin "})
+	require.NoError(t, err)
+	require.Equal(t, ServiceIdentities{
+		"reports.internal": {Name: "reporting", Role: util.BankerRole},
+		"ops.internal":     {Name: "ops", Role: util.AdminRole},
+	}, identities)
+	require.Equal(t, "service:reporting", identities["reports.internal"].Username())
+
+	for _, entry := range []string{"reports.internal", "=reporting:banker", "reports.internal=reporting", "reports.internal=:banker", "reports.internal=reporting:root"} {
+		_, err = ParseServiceIdentities([]string{entry})
+		require.Error(t, err, entry)
+	}
+
+	_, err = ParseServiceIdentities([]string{"a=one:banker", "a=two:admin"})
+	require.EqualError(t, err, `duplicate service identity for "a"`)
+
+	//only verified chains count
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "reports.internal"}}
+	_, ok := identities.Lookup(&tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}})
+	require.False(t, ok)
+	identity, ok := identities.Lookup(&tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}})
+	require.True(t, ok)
+	require.Equal(t, "reporting", identity.Name)
+	_, ok = identities.Lookup(nil)
+	require.False(t, ok)
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := newTestCert(t, "first", nil, 1).write(t, dir, "server")
+
+	_, err := NewCertReloader("", keyFile, 0)
+	require.ErrorIs(t, err, ErrNoCertificate)
+
+	reloader, err := NewCertReloader(certFile, keyFile, time.Minute)
+	require.NoError(t, err)
+	now := time.Now()
+	reloader.now = func() time.Time { return now }
+
+	serial := func() int64 {
+		cert, err := reloader.GetCertificate(nil)
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		require.NoError(t, err)
+		return leaf.SerialNumber.Int64()
+	}
+	require.Equal(t, int64(1), serial())
+
+	//a renewed certificate is only looked for once the interval passed
+	newTestCert(t, "second", nil, 2).write(t, dir, "server")
+	changed := now.Add(time.Second)
+	require.NoError(t, os.Chtimes(certFile, changed, changed))
+	require.NoError(t, os.Chtimes(keyFile, changed, changed))
+	require.Equal(t, int64(1), serial())
+
+	now = now.Add(time.Minute)
+	require.Equal(t, int64(2), serial())
+
+	//a broken renewal keeps the current certificate
+	require.NoError(t, os.WriteFile(keyFile, []byte("not a key"), 0600))
+	now = now.Add(time.Minute)
+	require.Equal(t, int64(2), serial())
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "Test CA", nil, 1)
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := newTestCert(t, "localhost", ca, 2).write(t, dir, "server")
+	service := newTestCert(t, "reports.internal", ca, 3)
+	stranger := newTestCert(t, "reports.internal", newTestCert(t, "Other CA", nil, 4), 5)
+
+	identities, err := ParseServiceIdentities([]string{"reports.internal=reporting:banker"})
+	require.NoError(t, err)
+
+	serve := func(clientAuth string) string {
+		tlsConfig, err := New(util.TLSConfig{
+			CertFile:     certFile,
+			KeyFile:      keyFile,
+			MinVersion:   "1.2",
+			ClientCAFile: caFile,
+			ClientAuth:   clientAuth,
+		})
+		require.NoError(t, err)
+
+		listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+		require.NoError(t, err)
+		server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, ok := identities.Lookup(r.TLS)
+			fmt.Fprintf(w, "%s %t", identity.Username(), ok)
+		})}
+		go server.Serve(listener)
+		t.Cleanup(func() { server.Close() })
+		return "https://" + listener.Addr().String()
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(url string, client *testCert) (string, error) {
+		tlsConfig := &tls.Config{RootCAs: roots}
+		if client != nil {
+			//sent even when its CA isn't one the server asks for
+			tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				cert := client.tlsCertificate()
+				return &cert, nil
+			}
+		}
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+
+		response, err := httpClient.Get(url)
+		if err != nil {
+			return "", err
+		}
+		defer response.Body.Close()
+		body, err := io.ReadAll(response.Body)
+		return string(body), err
+	}
+
+	url := serve(ClientAuthOptional)
+	body, err := get(url, service)
+	require.NoError(t, err)
+	require.Equal(t, "service:reporting true", body)
+
+	//users connect without a certificate
+	body, err = get(url, nil)
+	require.NoError(t, err)
+	require.Equal(t, "service: false", body)
+
+	//the same name from another CA is refused
+	_, err = get(url, stranger)
+	require.Error(t, err)
+
+	url = serve(ClientAuthRequire)
+	_, err = get(url, nil)
+	require.Error(t, err)
+	body, err = get(url, service)
+	require.NoError(t, err)
+	require.Equal(t, "service:reporting true", body)
+}
@@ -0,0 +1,10 @@
+package util
+
+// AddAmount adds two amounts of money, ok is false when the sum doesn't fit in an int64
+func AddAmount(a int64, b int64) (sum int64, ok bool) {
+	sum = a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return 0, false
+	}
+	return sum, true
+}
@@ -0,0 +1,24 @@
+package util
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddAmount(t *testing.T) {
+	sum, ok := AddAmount(5, -7)
+	require.True(t, ok)
+	require.Equal(t, int64(-2), sum)
+
+	sum, ok = AddAmount(math.MaxInt64-1, 1)
+	require.True(t, ok)
+	require.Equal(t, int64(math.MaxInt64), sum)
+
+	_, ok = AddAmount(math.MaxInt64, 1)
+	require.False(t, ok)
+
+	_, ok = AddAmount(math.MinInt64, -1)
+	require.False(t, ok)
+}
@@ -1,26 +1,379 @@
 package util
 
-import "github.com/spf13/viper"
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
 
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+)
+
+// Constants for all supported profiles, APP_ENV selects one
+const (
+	ProfileDev  = "dev"
+	ProfileTest = "test"
+	ProfileProd = "prod"
+)
+
+// Constants for all supported log levels
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// Constants for all supported log formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// Config is the configuration of the application, read from app.env, the file of the profile and the environment.
+// Every setting keeps the name of its environment variable in the mapstructure tag.
 type Config struct {
-	DBDriver      string `mapstructure:"DB_DRIVER"`
-	DBSource      string `mapstructure:"DB_SOURCE"`
-	ServerAddress string `mapstructure:"SERVER_ADDRESS"`
+	// Profile is dev, test or prod, app.<profile>.env is read on top of app.env when it exists
+	Profile   string          `mapstructure:"APP_ENV" validate:"required,oneof=dev test prod"`
+	Server    ServerConfig    `mapstructure:",squash"`
+	TLS       TLSConfig       `mapstructure:",squash"`
+	DB        DBConfig        `mapstructure:",squash"`
+	Auth      AuthConfig      `mapstructure:",squash"`
+	Mail      MailConfig      `mapstructure:",squash"`
+	Workers   WorkersConfig   `mapstructure:",squash"`
+	Transfers TransfersConfig `mapstructure:",squash"`
+	Log       LogConfig       `mapstructure:",squash"`
+}
+
+// ServerConfig is the HTTP server
+type ServerConfig struct {
+	Address             string        `mapstructure:"SERVER_ADDRESS" validate:"required,hostname_port"`
+	ShutdownDrainPeriod time.Duration `mapstructure:"SHUTDOWN_DRAIN_PERIOD" validate:"min=0"`
+	ShutdownTimeout     time.Duration `mapstructure:"SHUTDOWN_TIMEOUT" validate:"min=1s"`
+	RateLimits          []string      `mapstructure:"RATE_LIMITS"`
+	// TrustedProxies are the addresses or CIDRs of the proxies whose X-Forwarded-For is believed,
+	// empty trusts none and clients are rate limited by the address they connect from
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES" validate:"dive,ip|cidr"`
+	// AppBaseURL is where the links sent by email point to
+	AppBaseURL string `mapstructure:"APP_BASE_URL" validate:"required,url"`
+	// V1Sunset is the day /v1 stops being served, announced in the Sunset header of its responses
+	V1Sunset string `mapstructure:"API_V1_SUNSET" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// TLSConfig makes the server serve HTTPS when a certificate is set. With a client CA, service clients
+// can authenticate with a certificate whose subject common name is mapped to a service identity.
+type TLSConfig struct {
+	CertFile string `mapstructure:"TLS_CERT_FILE"`
+	KeyFile  string `mapstructure:"TLS_KEY_FILE"`
+	// MinVersion is 1.2 or 1.3
+	MinVersion string `mapstructure:"TLS_MIN_VERSION" validate:"omitempty,oneof=1.2 1.3"`
+	// CipherSuites are the names of the TLS 1.2 suites allowed, empty keeps the Go defaults
+	CipherSuites []string `mapstructure:"TLS_CIPHER_SUITES"`
+	// ReloadInterval is how often the certificate files are checked for changes, 0 checks on every handshake
+	ReloadInterval time.Duration `mapstructure:"TLS_RELOAD_INTERVAL" validate:"min=0"`
+	ClientCAFile   string        `mapstructure:"TLS_CLIENT_CA_FILE"`
+	// ClientAuth is optional, so users can still connect without a certificate, or require
+	ClientAuth string `mapstructure:"TLS_CLIENT_AUTH" validate:"omitempty,oneof=optional require"`
+	// ServiceIdentities map a common name to a service and its role, e.g. reports.internal=reporting:banker
+	ServiceIdentities []string `mapstructure:"TLS_SERVICE_IDENTITIES"`
+}
+
+// DBConfig is the database and its connection pool
+type DBConfig struct {
+	Driver string `mapstructure:"DB_DRIVER" validate:"required,oneof=postgres memory"`
+	// Source is only needed by the postgres driver, the password in it is redacted
+	Source           string        `mapstructure:"DB_SOURCE" secret:"url"`
+	MigrateOnStart   bool          `mapstructure:"MIGRATE_ON_START"`
+	TxMaxRetries     int           `mapstructure:"DB_TX_MAX_RETRIES" validate:"min=0,max=10"`
+	TxRetryBaseDelay time.Duration `mapstructure:"DB_TX_RETRY_BASE_DELAY" validate:"min=0"`
+	TxRetryMaxDelay  time.Duration `mapstructure:"DB_TX_RETRY_MAX_DELAY" validate:"min=0"`
+	// MaxOpenConns of 0 leaves the pool unlimited
+	MaxOpenConns    int           `mapstructure:"DB_MAX_OPEN_CONNS" validate:"min=0"`
+	MaxIdleConns    int           `mapstructure:"DB_MAX_IDLE_CONNS" validate:"min=0"`
+	ConnMaxLifetime time.Duration `mapstructure:"DB_CONN_MAX_LIFETIME" validate:"min=0"`
+	ConnMaxIdleTime time.Duration `mapstructure:"DB_CONN_MAX_IDLE_TIME" validate:"min=0"`
+}
+
+// AuthConfig is the tokens, the links sent by email and two factor authentication
+type AuthConfig struct {
+	TokenSymmetricKey     string        `mapstructure:"TOKEN_SYMMETRIC_KEY" validate:"required,min=32" secret:"true"`
+	AccessTokenDuration   time.Duration `mapstructure:"ACCESS_TOKEN_DURATION" validate:"min=1s"`
+	VerifyEmailDuration   time.Duration `mapstructure:"VERIFY_EMAIL_DURATION" validate:"min=1m"`
+	ResetPasswordDuration time.Duration `mapstructure:"RESET_PASSWORD_DURATION" validate:"min=1m"`
+	TOTPIssuer            string        `mapstructure:"TOTP_ISSUER" validate:"required"`
+	// TOTPTransferThreshold is the amount from which a transfer needs a TOTP code
+	TOTPTransferThreshold int64 `mapstructure:"TOTP_TRANSFER_THRESHOLD" validate:"min=0"`
+}
+
+// MailConfig is how emails are sent, the SMTP settings are only needed by the smtp driver
+type MailConfig struct {
+	Driver       string `mapstructure:"MAIL_DRIVER" validate:"required,oneof=smtp file memory"`
+	From         string `mapstructure:"MAIL_FROM" validate:"required,email"`
+	FileDir      string `mapstructure:"MAIL_FILE_DIR"`
+	SMTPHost     string `mapstructure:"SMTP_HOST"`
+	SMTPPort     int    `mapstructure:"SMTP_PORT" validate:"min=0,max=65535"`
+	SMTPUsername string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword string `mapstructure:"SMTP_PASSWORD" secret:"true"`
+}
+
+// WorkersConfig is the background jobs of the server
+type WorkersConfig struct {
+	// InterestAccrualInterval is how often the server checks for days to accrue, 0 leaves accrual to the accrue-interest subcommand
+	InterestAccrualInterval time.Duration `mapstructure:"INTEREST_ACCRUAL_INTERVAL" validate:"min=0"`
+	// BalanceSnapshotInterval is how often the server checks for accounts without a snapshot of the current day, 0 disables snapshots
+	BalanceSnapshotInterval time.Duration `mapstructure:"BALANCE_SNAPSHOT_INTERVAL" validate:"min=0"`
+}
+
+// TransfersConfig is the checks transfers go through
+type TransfersConfig struct {
+	// BeneficiaryCoolingOffPeriod is how long a new beneficiary only receives up to BeneficiaryCoolingOffAmount per transfer, 0 disables it
+	BeneficiaryCoolingOffPeriod time.Duration `mapstructure:"BENEFICIARY_COOLING_OFF_PERIOD" validate:"min=0"`
+	BeneficiaryCoolingOffAmount int64         `mapstructure:"BENEFICIARY_COOLING_OFF_AMOUNT" validate:"min=0"`
+	// RiskRulesFile is the JSON file of fraud rules transfers are screened with, empty screens nothing
+	RiskRulesFile string `mapstructure:"RISK_RULES_FILE"`
+}
+
+// LogConfig is the request log of the server, debug also puts gin in debug mode
+type LogConfig struct {
+	Level  string `mapstructure:"LOG_LEVEL" validate:"required,oneof=debug info warn error"`
+	Format string `mapstructure:"LOG_FORMAT" validate:"required,oneof=text json"`
+}
+
+// configDefaults are used when neither the files nor the environment set a value
+var configDefaults = map[string]interface{}{
+	"APP_ENV":             ProfileDev,
+	"LOG_LEVEL":           LogLevelInfo,
+	"LOG_FORMAT":          LogFormatText,
+	"TLS_MIN_VERSION":     "1.2",
+	"TLS_RELOAD_INTERVAL": "10s",
+	"TLS_CLIENT_AUTH":     "optional",
 }
 
 // LoadConfig reads configuration from file or environment variables.
+// app.env is optional, the environment alone is enough as long as the config is valid.
+// A secret can also be read from the file named by its variable with a _FILE suffix, e.g. DB_SOURCE_FILE.
 func LoadConfig(path string) (config Config, err error) {
-	viper.AddConfigPath(path)
-	viper.SetConfigName("app")
-	viper.SetConfigType("env")
+	v := viper.New()
+	v.AddConfigPath(path)
+	v.SetConfigName("app")
+	v.SetConfigType("env")
+
+	//every setting is bound so that it can come from the environment only
+	for key, value := range configDefaults {
+		v.SetDefault(key, value)
+	}
+	for _, field := range configFields(reflect.ValueOf(&config).Elem()) {
+		v.BindEnv(field.key)
+	}
+	v.AutomaticEnv()
+
+	err = v.ReadInConfig()
+	if err != nil && !errors.As(err, &viper.ConfigFileNotFoundError{}) {
+		return
+	}
 
-	viper.AutomaticEnv()
+	profile := filepath.Join(path, "app."+v.GetString("APP_ENV")+".env")
+	if _, statErr := os.Stat(profile); statErr == nil {
+		v.SetConfigFile(profile)
+		if err = v.MergeInConfig(); err != nil {
+			return
+		}
+	}
 
-	err = viper.ReadInConfig()
+	if err = readSecretFiles(v); err != nil {
+		return
+	}
+
+	err = v.Unmarshal(&config)
 	if err != nil {
 		return
 	}
 
-	err = viper.Unmarshal(&config)
+	err = config.Validate()
 	return
 }
+
+// readSecretFiles sets every secret whose KEY_FILE variable names a file to the content of that file
+func readSecretFiles(v *viper.Viper) error {
+	for _, field := range configFields(reflect.ValueOf(&Config{}).Elem()) {
+		if field.secret == "" {
+			continue
+		}
+
+		path := os.Getenv(field.key + "_FILE")
+		if path == "" {
+			continue
+		}
+		if _, ok := os.LookupEnv(field.key); ok {
+			return fmt.Errorf("invalid config: set either %s or %s_FILE, not both", field.key, field.key)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("invalid config: %s_FILE: %w", field.key, err)
+		}
+		v.Set(field.key, strings.TrimRight(string(data), "\r\n"))
+	}
+	return nil
+}
+
+// Validate checks that every setting is present and in range, all the problems are reported at once
+func (config Config) Validate() error {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return strings.SplitN(field.Tag.Get("mapstructure"), ",", 2)[0]
+	})
+
+	var problems []string
+	if err := validate.Struct(config); err != nil {
+		var fieldErrs validator.ValidationErrors
+		if !errors.As(err, &fieldErrs) {
+			return err
+		}
+		for _, fieldErr := range fieldErrs {
+			problems = append(problems, fieldErr.Field()+" "+describeConfigError(fieldErr))
+		}
+	}
+
+	if config.DB.Driver != "memory" && config.DB.Source == "" {
+		problems = append(problems, "DB_SOURCE is required by the "+config.DB.Driver+" driver")
+	}
+	if config.DB.TxRetryMaxDelay < config.DB.TxRetryBaseDelay {
+		problems = append(problems, "DB_TX_RETRY_MAX_DELAY must be at least DB_TX_RETRY_BASE_DELAY")
+	}
+	if config.DB.MaxOpenConns > 0 && config.DB.MaxIdleConns > config.DB.MaxOpenConns {
+		problems = append(problems, "DB_MAX_IDLE_CONNS must be at most DB_MAX_OPEN_CONNS")
+	}
+
+	if (config.TLS.CertFile == "") != (config.TLS.KeyFile == "") {
+		problems = append(problems, "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if config.TLS.ClientCAFile != "" && config.TLS.CertFile == "" {
+		problems = append(problems, "TLS_CLIENT_CA_FILE needs TLS_CERT_FILE")
+	}
+	if len(config.TLS.ServiceIdentities) > 0 && config.TLS.ClientCAFile == "" {
+		problems = append(problems, "TLS_SERVICE_IDENTITIES needs TLS_CLIENT_CA_FILE")
+	}
+
+	switch config.Mail.Driver {
+	case "smtp":
+		if config.Mail.SMTPHost == "" {
+			problems = append(problems, "SMTP_HOST is required by the smtp mail driver")
+		}
+		if config.Mail.SMTPPort == 0 {
+			problems = append(problems, "SMTP_PORT is required by the smtp mail driver")
+		}
+	case "file":
+		if config.Mail.FileDir == "" {
+			problems = append(problems, "MAIL_FILE_DIR is required by the file mail driver")
+		}
+	}
+
+	//nothing in production may lose data or emails
+	if config.Profile == ProfileProd {
+		if config.DB.Driver == "memory" {
+			problems = append(problems, "DB_DRIVER can't be memory in prod")
+		}
+		if config.Mail.Driver != "smtp" {
+			problems = append(problems, "MAIL_DRIVER must be smtp in prod")
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// describeConfigError turns a failed validation tag into the end of a sentence starting with the setting
+func describeConfigError(fieldErr validator.FieldError) string {
+	unit := ""
+	if fieldErr.Kind() == reflect.String {
+		unit = " characters"
+	}
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return "must be at least " + fieldErr.Param() + unit
+	case "max":
+		return "must be at most " + fieldErr.Param() + unit
+	case "oneof":
+		return fmt.Sprintf("must be one of %s, got %q", strings.ReplaceAll(fieldErr.Param(), " ", ", "), fieldErr.Value())
+	case "url":
+		return "must be a URL"
+	case "email":
+		return "must be an email address"
+	case "hostname_port":
+		return "must be host:port"
+	case "datetime":
+		return "must be a date like " + fieldErr.Param()
+	}
+	return "fails the " + fieldErr.Tag() + " check"
+}
+
+// Redacted lists the effective config as KEY=value lines in the order of the sections, with the secrets hidden
+func (config Config) Redacted() string {
+	var b strings.Builder
+	for _, field := range configFields(reflect.ValueOf(&config).Elem()) {
+		var value string
+		switch v := field.value.Interface().(type) {
+		case []string:
+			value = strings.Join(v, ",")
+		case time.Duration:
+			value = v.String()
+		default:
+			value = fmt.Sprint(v)
+		}
+
+		if value != "" {
+			switch field.secret {
+			case "true":
+				value = "REDACTED"
+			case "url":
+				value = redactURL(value)
+			}
+		}
+		fmt.Fprintf(&b, "%s=%s\n", field.key, value)
+	}
+	return b.String()
+}
+
+// redactURL hides the password of a URL, anything that isn't a URL is hidden as a whole
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" {
+		return "REDACTED"
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "REDACTED")
+	}
+	return u.String()
+}
+
+type configField struct {
+	key    string
+	secret string
+	value  reflect.Value
+}
+
+// configFields flattens the sections of the config into its settings
+func configFields(v reflect.Value) []configField {
+	var fields []configField
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		key := strings.SplitN(field.Tag.Get("mapstructure"), ",", 2)[0]
+		if key == "" {
+			fields = append(fields, configFields(v.Field(i))...)
+			continue
+		}
+		fields = append(fields, configField{key: key, secret: field.Tag.Get("secret"), value: v.Field(i)})
+	}
+	return fields
+}
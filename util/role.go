@@ -0,0 +1,17 @@
+package util
+
+// Constants for all user roles
+const (
+	DepositorRole = "depositor"
+	BankerRole    = "banker"
+	AdminRole     = "admin"
+)
+
+// IsSupportedRole returns true if the role is supported
+func IsSupportedRole(role string) bool {
+	switch role {
+	case DepositorRole, BankerRole, AdminRole:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// GenerateSecret returns a hex encoded secret built from n cryptographically secure random bytes
+func GenerateSecret(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("cannot generate secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashSecret returns the hex encoded sha256 of a secret so that it can be stored and looked up
+// without keeping the secret itself
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
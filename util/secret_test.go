@@ -0,0 +1,26 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSecret(t *testing.T) {
+	secret1, err := GenerateSecret(32)
+	require.NoError(t, err)
+	require.Len(t, secret1, 64)
+
+	secret2, err := GenerateSecret(32)
+	require.NoError(t, err)
+	require.NotEqual(t, secret1, secret2)
+}
+
+func TestHashSecret(t *testing.T) {
+	secret := RandomString(64)
+
+	hash := HashSecret(secret)
+	require.Len(t, hash, 64)
+	require.Equal(t, hash, HashSecret(secret))
+	require.NotEqual(t, hash, HashSecret(RandomString(64)))
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// RegisterValidators adds the custom tags used by the request bindings, e.g. binding:"currency"
+func RegisterValidators(v *validator.Validate) {
+	v.RegisterValidation("currency", validCurrency)
+	v.RegisterValidation("role", validRole)
+	v.RegisterValidation("jsonobject", validJSONObject)
+}
+
+var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if currency, ok := fieldLevel.Field().Interface().(string); ok {
+		return IsSupportedCurrency(currency)
+	}
+	return false
+}
+
+var validRole validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if role, ok := fieldLevel.Field().Interface().(string); ok {
+		return IsSupportedRole(role)
+	}
+	return false
+}
+
+// validJSONObject accepts a string or json.RawMessage holding a JSON object, e.g. the metadata of a transfer
+var validJSONObject validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	var doc []byte
+	switch value := fieldLevel.Field().Interface().(type) {
+	case json.RawMessage:
+		doc = value
+	case string:
+		doc = []byte(value)
+	default:
+		return false
+	}
+
+	doc = bytes.TrimSpace(doc)
+	return json.Valid(doc) && len(doc) > 0 && doc[0] == '{'
+}